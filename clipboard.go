@@ -0,0 +1,80 @@
+package main
+
+import (
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/imjamesonzeller/tasklight-v3/notionblocks"
+)
+
+// maxClipboardRunes bounds the clipboard text attached to a capture.
+const maxClipboardRunes = 20000
+
+// attachClipboard keeps text to be attached to the next capture and tells
+// the input window about it. Blank text clears the attachment.
+func (ts *TaskService) attachClipboard(text string) {
+	text = strings.TrimSpace(text)
+	if utf8.RuneCountInString(text) > maxClipboardRunes {
+		text = string([]rune(text)[:maxClipboardRunes]) + "…"
+	}
+
+	ts.clipMu.Lock()
+	ts.clipboard = text
+	ts.clipMu.Unlock()
+
+	if ts.app != nil {
+		ts.app.EmitEvent("Backend:ClipboardAttached", text)
+	}
+}
+
+// GetClipboardContext returns the clipboard text the next capture will carry,
+// or "" when the capture was not started from the clipboard hotkey. Called
+// from frontend
+func (ts *TaskService) GetClipboardContext() string {
+	ts.clipMu.Lock()
+	defer ts.clipMu.Unlock()
+	return ts.clipboard
+}
+
+// ClearClipboardContext detaches the clipboard from the next capture. Called
+// from frontend
+func (ts *TaskService) ClearClipboardContext() {
+	ts.takeClipboard()
+}
+
+// takeClipboard returns the attached clipboard text and detaches it.
+func (ts *TaskService) takeClipboard() string {
+	ts.clipMu.Lock()
+	defer ts.clipMu.Unlock()
+	text := ts.clipboard
+	ts.clipboard = ""
+	return text
+}
+
+// clipboardLink returns text as a URL when the clipboard holds nothing but
+// a web link.
+func clipboardLink(text string) (string, bool) {
+	text = strings.TrimSpace(text)
+	if text == "" || strings.ContainsAny(text, " \t\r\n") {
+		return "", false
+	}
+	u, err := url.Parse(text)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", false
+	}
+	return text, true
+}
+
+// clipboardBlocks turns clipboard text into plain paragraph blocks for the
+// page body. Copied text is kept as it is rather than read as markdown.
+func clipboardBlocks(text string) []notionblocks.Block {
+	if link, ok := clipboardLink(text); ok {
+		return []notionblocks.Block{{
+			"object":    "block",
+			"type":      "paragraph",
+			"paragraph": map[string]any{"rich_text": notionblocks.Link(link, link)},
+		}}
+	}
+	return notionblocks.Paragraphs(text)
+}
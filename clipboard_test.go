@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/imjamesonzeller/tasklight-v3/notionblocks"
+)
+
+func TestClipboardLink(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]bool{
+		"https://example.com/issue/42": true,
+		"  http://example.com  ":       true,
+		"example.com":                  false,
+		"mailto:me@example.com":        false,
+		"see https://example.com":      false,
+		"https://":                     false,
+	}
+	for text, want := range tests {
+		if _, got := clipboardLink(text); got != want {
+			t.Errorf("clipboardLink(%q) = %v, want %v", text, got, want)
+		}
+	}
+}
+
+func TestClipboardBlocks(t *testing.T) {
+	t.Parallel()
+
+	blocks := clipboardBlocks("panic: **nil** map\r\n\r\n- at main.go:12\n\n\n")
+	if len(blocks) != 2 {
+		t.Fatalf("expected one block per paragraph, got %d", len(blocks))
+	}
+	for _, b := range blocks {
+		if b["type"] != "paragraph" {
+			t.Fatalf("clipboard text must not be read as markdown, got %v", b)
+		}
+	}
+	first := blocks[0]["paragraph"].(map[string]any)["rich_text"].([]notionblocks.RichText)
+	if len(first) != 1 || first[0]["text"].(map[string]any)["content"] != "panic: **nil** map" {
+		t.Fatalf("unexpected paragraph: %v", first)
+	}
+
+	link := clipboardBlocks("https://example.com/post")[0]["paragraph"].(map[string]any)["rich_text"].([]notionblocks.RichText)
+	if link[0]["text"].(map[string]any)["link"] == nil {
+		t.Fatalf("expected a linked paragraph, got %v", link)
+	}
+}
+
+func TestBuildNotionPagePayloadWithClipboard(t *testing.T) {
+	t.Parallel()
+
+	withURL := detectTaskFieldProperties(&NotionDataSourceDetail{Properties: map[string]PropertyObj{
+		"Name":      {Name: "Name", Type: "title"},
+		"Reference": {Name: "Reference", Type: "url"},
+	}}, "")
+	if withURL.URL == nil || withURL.URL.Name != "Reference" {
+		t.Fatalf("expected the only URL property to be used, got %+v", withURL.URL)
+	}
+
+	link := TaskInformation{Title: "Read article", Clipboard: "https://example.com/post"}
+	payload := buildNotionPagePayload(link, "ds-1", "Name", "", withURL)
+	props := payload["properties"].(map[string]any)
+	if props["Reference"].(map[string]any)["url"] != "https://example.com/post" {
+		t.Fatalf("expected the link in the URL property, got %v", props)
+	}
+	if _, ok := payload["children"]; ok {
+		t.Fatal("a link stored in a property must not also fill the body")
+	}
+
+	// Without a URL property the link goes to the body.
+	payload = buildNotionPagePayload(link, "ds-1", "Name", "", taskFieldProperties{})
+	if blocks, ok := payload["children"].([]map[string]any); !ok || len(blocks) != 1 {
+		t.Fatalf("expected the link in the page body, got %v", payload["children"])
+	}
+
+	snippet := TaskInformation{Title: "Fix crash", Clipboard: "panic: nil map\n\ngoroutine 1"}
+	payload = buildNotionPagePayload(snippet, "ds-1", "Name", "", withURL)
+	if len(payload["properties"].(map[string]any)) != 1 {
+		t.Fatalf("text must not go to the URL property, got %v", payload["properties"])
+	}
+	if blocks := payload["children"].([]map[string]any); len(blocks) != 2 {
+		t.Fatalf("expected two body paragraphs, got %v", blocks)
+	}
+}
+
+func TestClipboardSurvivesRestart(t *testing.T) {
+	now := time.Date(2025, time.October, 15, 9, 0, 0, 0, time.UTC)
+	o := newTestOutbox(t, &now, func(context.Context, TaskInformation) PageResult {
+		return PageResult{PageID: "page-1"}
+	})
+	ts := o.tasks
+
+	ts.attachClipboard("  https://example.com/issue/42\n")
+	if got := ts.GetClipboardContext(); got != "https://example.com/issue/42" {
+		t.Fatalf("unexpected attachment: %q", got)
+	}
+	if _, err := o.enqueue([]string{"triage issue"}, nil, ts.takeClipboard()); err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	if ts.GetClipboardContext() != "" {
+		t.Fatal("a submitted capture must detach the clipboard")
+	}
+	// The app quits before the task is parsed.
+
+	var sent []TaskInformation
+	restarted := NewOutboxService(ts, nil)
+	restarted.now = o.now
+	restarted.path = o.path
+	ts.createPage = func(_ context.Context, task TaskInformation) PageResult {
+		sent = append(sent, task)
+		return PageResult{PageID: "page-1"}
+	}
+	restarted.deliverDue()
+
+	if len(sent) != 1 || sent[0].Title != "triage issue" || sent[0].Clipboard != "https://example.com/issue/42" {
+		t.Fatalf("expected the clipboard to be sent with the task, got %+v", sent)
+	}
+}
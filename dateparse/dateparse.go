@@ -0,0 +1,859 @@
+// Package dateparse resolves natural-language date phrases without calling a
+// model. It is the deterministic fallback used when the AI parse path is
+// unavailable, and follows the same future-leaning rules as the AI prompt.
+package dateparse
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata" // zone names in input must resolve on every platform
+	"unicode"
+	"unicode/utf8"
+)
+
+// Options controls how relative phrases are resolved.
+type Options struct {
+	// Now anchors every relative phrase, and its location is the time zone
+	// used unless the input names one. The zero value means time.Now().
+	Now time.Time
+	// Language is the input language as a BCP 47 code. Besides English, date
+	// words in German ("de") are understood.
+	Language string
+	// Order reads numeric dates such as 03/04. Empty means MDY.
+	Order DateOrder
+	// WeekStart is the first day of the week for "next week", "end of week"
+	// and "this weekend". Nil means Monday.
+	WeekStart *time.Weekday
+}
+
+// Result is the outcome of Parse. Date is nil when no phrase was recognised.
+type Result struct {
+	Title string
+	// Date is the start. It is midnight for all-day tasks and carries the
+	// time of day when HasTime is set.
+	Date *time.Time
+	// End is the end of a range such as "Mon–Wed" or "3-5pm", or nil.
+	End     *time.Time
+	HasTime bool
+	// TimeZone is the IANA zone named in the input ("9:30 PST"), or empty
+	// when the times are in the location of Options.Now.
+	TimeZone string
+	Phrase   string
+}
+
+// Parse finds the first date phrase and time of day in input, resolves them
+// against opts.Now and returns the input with those phrases (and any
+// "by"/"on"/"at" lead-in) removed.
+func Parse(input string, opts Options) Result {
+	now := opts.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	p := parser{today: startOfDay(now), weekStart: time.Monday, order: opts.Order}
+	if opts.WeekStart != nil {
+		p.weekStart = *opts.WeekStart
+	}
+	text := stripLeadIn(input)
+	toks := translate(tokenize(text), opts.Language)
+
+	var res Result
+	var spans []span
+
+	dateFrom, dateTo, start, end, found := p.findDate(toks)
+	if found {
+		res.Date, res.End = &start, end
+		spans = append(spans, span{dateFrom, dateTo})
+	}
+
+	if tm, ok := findTime(text, toks, spans); ok {
+		res.HasTime = true
+		res.TimeZone = tm.zoneName
+		spans = append(spans, span{tm.from, tm.to})
+		applyTime(&res, tm, now, found)
+	}
+
+	res.Title, res.Phrase = cutSpans(text, toks, spans)
+	if res.Title == "" {
+		res.Title = strings.TrimSpace(input)
+	}
+	return res
+}
+
+// findDate returns the token span, start and optional end of the first date
+// phrase in toks.
+func (p parser) findDate(toks []token) (int, int, time.Time, *time.Time, bool) {
+	for i := range toks {
+		n, date, ok := p.match(toks, i)
+		if !ok {
+			continue
+		}
+
+		from, to := i, i+n
+		if from > 0 && toks[from-1].text == "the" && (from == 1 || connectors[toks[from-2].text]) {
+			from--
+		}
+		for from > 0 && connectors[toks[from-1].text] {
+			from--
+		}
+
+		var end *time.Time
+		if n, last, ok := p.matchRangeEnd(toks, to, date); ok {
+			end = &last
+			to += n
+			if at(toks, from-1) == "from" {
+				from--
+			}
+		}
+		return from, to, date, end, true
+	}
+	return 0, 0, time.Time{}, nil, false
+}
+
+// applyTime moves the resolved dates to the matched time of day. Without a
+// date phrase the time is taken as the next occurrence of that time.
+func applyTime(res *Result, tm timeMatch, now time.Time, hasDate bool) {
+	loc := now.Location()
+	if tm.zone != nil {
+		loc = tm.zone
+	}
+
+	day := now.In(loc)
+	if hasDate {
+		day = *res.Date
+	}
+	start := atClock(day, *tm.start, loc)
+	if !hasDate && start.Before(now) {
+		start = start.AddDate(0, 0, 1)
+	}
+	res.Date = &start
+
+	endDay := start
+	if res.End != nil {
+		endDay = *res.End
+	}
+	switch {
+	case tm.end != nil:
+		end := atClock(endDay, *tm.end, loc)
+		if !end.After(start) {
+			end = end.AddDate(0, 0, 1)
+		}
+		res.End = &end
+	case res.End != nil:
+		end := atClock(endDay, *tm.start, loc)
+		res.End = &end
+	}
+}
+
+func atClock(day time.Time, c clock, loc *time.Location) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), c.hour24(), c.minute, 0, 0, loc)
+}
+
+// cutSpans removes the token spans from text and returns the cleaned title and
+// the removed phrase.
+func cutSpans(text string, toks []token, spans []span) (string, string) {
+	sort.Slice(spans, func(a, b int) bool { return spans[a].from < spans[b].from })
+
+	var title, phrase []string
+	pos := 0
+	for _, s := range spans {
+		from, to := toks[s.from].start, toks[s.to-1].end
+		title = append(title, text[pos:from])
+		phrase = append(phrase, text[from:to])
+		pos = to
+	}
+	title = append(title, text[pos:])
+
+	return cleanTitle(strings.Join(title, " ")), strings.TrimSpace(strings.Join(phrase, " "))
+}
+
+// ====== Tokens ======
+
+type token struct {
+	text  string // lower-cased with surrounding punctuation trimmed
+	start int    // byte offset of the trimmed word in the source text
+	end   int
+}
+
+const trimChars = ",.;:!?()[]\"“”"
+
+func tokenize(s string) []token {
+	var toks []token
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if unicode.IsSpace(r) {
+			i += size
+			continue
+		}
+
+		j := i
+		for j < len(s) {
+			r, size := utf8.DecodeRuneInString(s[j:])
+			if unicode.IsSpace(r) {
+				break
+			}
+			j += size
+		}
+
+		word := s[i:j]
+		lead := len(word) - len(strings.TrimLeft(word, trimChars))
+		core := strings.Trim(word, trimChars)
+		start := i + lead
+		if left, dash, right, ok := splitRange(core); ok {
+			toks = append(toks,
+				token{text: strings.ToLower(left), start: start, end: start + len(left)},
+				token{text: "-", start: start + len(left), end: start + len(left) + len(dash)},
+				token{text: strings.ToLower(right), start: start + len(left) + len(dash), end: start + len(core)},
+			)
+		} else if core == "–" || core == "—" {
+			toks = append(toks, token{text: "-", start: start, end: start + len(core)})
+		} else if core != "" {
+			toks = append(toks, token{
+				text:  strings.ToLower(core),
+				start: start,
+				end:   start + len(core),
+			})
+		}
+		i = j
+	}
+	return toks
+}
+
+// connectors are words that introduce a date and are removed along with it.
+var connectors = map[string]bool{
+	"by":     true,
+	"on":     true,
+	"due":    true,
+	"before": true,
+	"until":  true,
+	"till":   true,
+}
+
+var leadIns = []string{
+	"remind me to ",
+	"remind me that ",
+	"remind me ",
+	"remember to ",
+	"don't forget to ",
+	"dont forget to ",
+}
+
+func stripLeadIn(s string) string {
+	trimmed := strings.TrimSpace(s)
+	lower := strings.ToLower(trimmed)
+	for _, prefix := range leadIns {
+		if strings.HasPrefix(lower, prefix) {
+			return trimmed[len(prefix):]
+		}
+	}
+	return trimmed
+}
+
+func cleanTitle(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	for _, p := range []string{",", ";", ":", ".", "!", "?"} {
+		s = strings.ReplaceAll(s, " "+p, p)
+	}
+	s = strings.Trim(s, " ,;:.-–—")
+
+	lower := strings.ToLower(s)
+	if strings.HasPrefix(lower, "to ") {
+		s = s[3:]
+	}
+	return strings.TrimSpace(s)
+}
+
+// ====== Matching ======
+
+type parser struct {
+	today     time.Time
+	weekStart time.Weekday
+	order     DateOrder
+}
+
+// match tries every recogniser at toks[i] and reports how many tokens the
+// date phrase consumed.
+func (p parser) match(toks []token, i int) (int, time.Time, bool) {
+	matchers := []func([]token, int) (int, time.Time, bool){
+		p.matchRelativeDay,
+		p.matchOffset,
+		p.matchNextLast,
+		p.matchEndOf,
+		p.matchWeekend,
+		p.matchMonthDay,
+		p.matchDayMonth,
+		p.matchNumeric,
+		p.matchOrdinalDay,
+		p.matchWeekday,
+	}
+	for _, m := range matchers {
+		if n, date, ok := m(toks, i); ok {
+			return n, date, true
+		}
+	}
+	return 0, time.Time{}, false
+}
+
+func at(toks []token, i int) string {
+	if i < 0 || i >= len(toks) {
+		return ""
+	}
+	return toks[i].text
+}
+
+// matchRelativeDay handles today, tonight, tomorrow and the day after tomorrow.
+func (p parser) matchRelativeDay(toks []token, i int) (int, time.Time, bool) {
+	word := at(toks, i)
+
+	if word == "the" && at(toks, i+1) == "day" && at(toks, i+2) == "after" && isTomorrow(at(toks, i+3)) {
+		return 4, p.today.AddDate(0, 0, 2), true
+	}
+	if word == "day" && at(toks, i+1) == "after" && isTomorrow(at(toks, i+2)) {
+		return 3, p.today.AddDate(0, 0, 2), true
+	}
+
+	switch {
+	case word == "today" || word == "tonight" || word == "tonite" || word == "eod":
+		return 1, p.today, true
+	case word == "overmorrow":
+		return 1, p.today.AddDate(0, 0, 2), true
+	case word == "end" && at(toks, i+1) == "of" && at(toks, i+2) == "day":
+		return 3, p.today, true
+	case isTomorrow(word):
+		return 1, p.today.AddDate(0, 0, 1), true
+	}
+	return 0, time.Time{}, false
+}
+
+func isTomorrow(word string) bool {
+	switch word {
+	case "tomorrow", "tmrw", "tmr", "tmw", "tomoz":
+		return true
+	}
+	return len(word) >= 6 && strings.HasPrefix(word, "to") && distance(word, "tomorrow") <= 2
+}
+
+// matchOffset handles "in 3 days", "in a week" and "2 weeks from now".
+func (p parser) matchOffset(toks []token, i int) (int, time.Time, bool) {
+	if at(toks, i) == "in" {
+		j := i + 1
+		if at(toks, j) == "a" && (at(toks, j+1) == "couple" || at(toks, j+1) == "few") {
+			j++
+		}
+		n, ok := parseCount(at(toks, j))
+		if !ok {
+			return 0, time.Time{}, false
+		}
+		j++
+		if at(toks, j) == "of" {
+			j++
+		}
+		if date, ok := p.addUnit(n, at(toks, j)); ok {
+			return j - i + 1, date, true
+		}
+		return 0, time.Time{}, false
+	}
+
+	n, ok := parseCount(at(toks, i))
+	if !ok {
+		return 0, time.Time{}, false
+	}
+	date, ok := p.addUnit(n, at(toks, i+1))
+	if !ok || at(toks, i+2) != "from" {
+		return 0, time.Time{}, false
+	}
+	switch at(toks, i+3) {
+	case "now", "today":
+		return 4, date, true
+	}
+	return 0, time.Time{}, false
+}
+
+func (p parser) addUnit(n int, unit string) (time.Time, bool) {
+	switch strings.TrimSuffix(unit, "s") {
+	case "day":
+		return p.today.AddDate(0, 0, n), true
+	case "week", "wk":
+		return p.today.AddDate(0, 0, 7*n), true
+	case "fortnight":
+		return p.today.AddDate(0, 0, 14*n), true
+	case "month", "mo":
+		return addMonths(p.today, n), true
+	case "year", "yr":
+		return addMonths(p.today, 12*n), true
+	}
+	return time.Time{}, false
+}
+
+var countWords = map[string]int{
+	"a": 1, "an": 1, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5,
+	"six": 6, "seven": 7, "eight": 8, "nine": 9, "ten": 10, "eleven": 11,
+	"twelve": 12, "couple": 2, "few": 3,
+}
+
+func parseCount(word string) (int, bool) {
+	if n, ok := countWords[word]; ok {
+		return n, true
+	}
+	n, err := strconv.Atoi(word)
+	if err != nil || n < 0 || n > 1000 {
+		return 0, false
+	}
+	return n, true
+}
+
+// matchNextLast handles "next week/month/year", "this week" and
+// next/this/last/coming followed by a weekday.
+func (p parser) matchNextLast(toks []token, i int) (int, time.Time, bool) {
+	word := at(toks, i)
+	switch word {
+	case "next", "this", "coming", "last", "previous":
+	default:
+		return 0, time.Time{}, false
+	}
+	target := at(toks, i+1)
+
+	switch target {
+	case "week", "wk":
+		switch word {
+		case "next":
+			return 2, p.startOfWeek().AddDate(0, 0, 7), true
+		case "this":
+			return 2, p.startOfWeek().AddDate(0, 0, 6), true
+		}
+		return 0, time.Time{}, false
+	case "month":
+		if word == "next" {
+			first := time.Date(p.today.Year(), p.today.Month(), 1, 0, 0, 0, 0, p.today.Location())
+			return 2, first.AddDate(0, 1, 0), true
+		}
+		return 0, time.Time{}, false
+	case "year":
+		if word == "next" {
+			return 2, time.Date(p.today.Year()+1, time.January, 1, 0, 0, 0, 0, p.today.Location()), true
+		}
+		return 0, time.Time{}, false
+	}
+
+	day, ok := parseWeekday(target, true)
+	if !ok {
+		return 0, time.Time{}, false
+	}
+
+	switch word {
+	case "next":
+		offset := (int(day) - int(p.weekStart) + 7) % 7
+		return 2, p.startOfWeek().AddDate(0, 0, 7+offset), true
+	case "last", "previous":
+		back := (int(p.today.Weekday()) - int(day) + 7) % 7
+		if back == 0 {
+			back = 7
+		}
+		return 2, p.today.AddDate(0, 0, -back), true
+	default:
+		return 2, p.upcoming(day), true
+	}
+}
+
+// matchEndOf handles "end of (the) week/month/year" and the eow/eom shorthands.
+func (p parser) matchEndOf(toks []token, i int) (int, time.Time, bool) {
+	switch at(toks, i) {
+	case "eow":
+		return 1, p.startOfWeek().AddDate(0, 0, 6), true
+	case "eom":
+		return 1, p.endOfMonth(), true
+	case "end":
+	default:
+		return 0, time.Time{}, false
+	}
+
+	if at(toks, i+1) != "of" {
+		return 0, time.Time{}, false
+	}
+	n, unit := 3, at(toks, i+2)
+	if unit == "the" || unit == "this" {
+		n, unit = 4, at(toks, i+3)
+	}
+
+	switch unit {
+	case "week":
+		return n, p.startOfWeek().AddDate(0, 0, 6), true
+	case "month":
+		return n, p.endOfMonth(), true
+	case "year":
+		return n, time.Date(p.today.Year(), time.December, 31, 0, 0, 0, 0, p.today.Location()), true
+	}
+	return 0, time.Time{}, false
+}
+
+// matchWeekend resolves "weekend", "this weekend" and "next weekend" to a Saturday.
+func (p parser) matchWeekend(toks []token, i int) (int, time.Time, bool) {
+	word := at(toks, i)
+	if word == "weekend" {
+		return 1, p.thisWeekend(), true
+	}
+	if at(toks, i+1) != "weekend" {
+		return 0, time.Time{}, false
+	}
+	switch word {
+	case "this", "the":
+		return 2, p.thisWeekend(), true
+	case "next":
+		return 2, p.thisWeekend().AddDate(0, 0, 7), true
+	}
+	return 0, time.Time{}, false
+}
+
+// thisWeekend returns the Saturday of the current week. On a Sunday that
+// closes the week the weekend has already begun, so it returns today; when
+// weeks start on Sunday, Sunday opens a new week and its Saturday is ahead.
+func (p parser) thisWeekend() time.Time {
+	saturday := p.startOfWeek().AddDate(0, 0, (int(time.Saturday)-int(p.weekStart)+7)%7)
+	if !saturday.Before(p.today) {
+		return saturday
+	}
+	if p.today.Weekday() == time.Sunday {
+		return p.today
+	}
+	return saturday.AddDate(0, 0, 7)
+}
+
+// matchMonthDay handles "Oct 5", "October 5th, 2026".
+func (p parser) matchMonthDay(toks []token, i int) (int, time.Time, bool) {
+	month, ok := parseMonth(at(toks, i))
+	if !ok {
+		return 0, time.Time{}, false
+	}
+	day, ok := parseDayNumber(at(toks, i+1))
+	if !ok {
+		return 0, time.Time{}, false
+	}
+	if year, ok := parseYear(at(toks, i+2)); ok {
+		date, ok := p.exactDate(year, month, day)
+		return 3, date, ok
+	}
+	date, ok := p.upcomingDate(month, day)
+	return 2, date, ok
+}
+
+// matchDayMonth handles "5 Oct", "5th of October 2026".
+func (p parser) matchDayMonth(toks []token, i int) (int, time.Time, bool) {
+	day, ok := parseDayNumber(at(toks, i))
+	if !ok {
+		return 0, time.Time{}, false
+	}
+	n := 1
+	if at(toks, i+1) == "of" {
+		n = 2
+	}
+	month, ok := parseMonth(at(toks, i+n))
+	if !ok {
+		return 0, time.Time{}, false
+	}
+	n++
+	if year, ok := parseYear(at(toks, i+n)); ok {
+		date, ok := p.exactDate(year, month, day)
+		return n + 1, date, ok
+	}
+	date, ok := p.upcomingDate(month, day)
+	return n, date, ok
+}
+
+// matchNumeric handles ISO dates and month-first slash dates such as 5/10 or 5/10/2026.
+func (p parser) matchNumeric(toks []token, i int) (int, time.Time, bool) {
+	word := at(toks, i)
+
+	if t, err := time.ParseInLocation("2006-01-02", word, p.today.Location()); err == nil {
+		return 1, t, true
+	}
+
+	parts := strings.Split(word, "/")
+	if len(parts) == 1 && p.order == DMY {
+		// Day-first locales also write 03.04. and 03.04.2025.
+		parts = strings.Split(word, ".")
+	}
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, time.Time{}, false
+	}
+	nums := make([]int, len(parts))
+	for idx, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return 0, time.Time{}, false
+		}
+		nums[idx] = n
+	}
+
+	year, month, day := numericParts(p.order, parts, nums)
+	if year != 0 || len(nums) == 3 {
+		if year < 100 {
+			year += 2000
+		}
+		date, ok := p.exactDate(year, time.Month(month), day)
+		return 1, date, ok
+	}
+	date, ok := p.upcomingDate(time.Month(month), day)
+	return 1, date, ok
+}
+
+// matchOrdinalDay handles "the 21st", resolved to the next such day of a month.
+func (p parser) matchOrdinalDay(toks []token, i int) (int, time.Time, bool) {
+	if at(toks, i) != "the" {
+		return 0, time.Time{}, false
+	}
+	word := at(toks, i+1)
+	if !hasOrdinalSuffix(word) {
+		return 0, time.Time{}, false
+	}
+	day, ok := parseDayNumber(word)
+	if !ok {
+		return 0, time.Time{}, false
+	}
+	if n, _, ok := p.matchDayMonth(toks, i+1); ok && n > 1 {
+		// "the 3rd of December" is a calendar date, not a day of this month.
+		return 0, time.Time{}, false
+	}
+
+	for offset := 0; offset < 12; offset++ {
+		first := time.Date(p.today.Year(), p.today.Month(), 1, 0, 0, 0, 0, p.today.Location()).AddDate(0, offset, 0)
+		candidate := time.Date(first.Year(), first.Month(), day, 0, 0, 0, 0, first.Location())
+		if candidate.Month() != first.Month() || candidate.Before(p.today) {
+			continue
+		}
+		n := 2
+		if at(toks, i+2) == "of" && at(toks, i+3) == "the" && at(toks, i+4) == "month" {
+			n = 5
+		}
+		return n, candidate, true
+	}
+	return 0, time.Time{}, false
+}
+
+// matchWeekday handles bare weekday names. Three-letter abbreviations are only
+// accepted after a connector or as part of a range so that words like "sat"
+// or "sun" stay in titles.
+func (p parser) matchWeekday(toks []token, i int) (int, time.Time, bool) {
+	allowShort := connectors[at(toks, i-1)] || at(toks, i-1) == "from"
+	if rangeWords[at(toks, i+1)] {
+		// "Mon–Wed" is clearly a pair of days.
+		_, endsInDay := parseWeekday(at(toks, i+2), true)
+		allowShort = allowShort || endsInDay
+	}
+	day, ok := parseWeekday(at(toks, i), allowShort)
+	if !ok {
+		return 0, time.Time{}, false
+	}
+	return 1, p.upcoming(day), true
+}
+
+// ====== Calendar helpers ======
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+func (p parser) startOfWeek() time.Time {
+	back := (int(p.today.Weekday()) - int(p.weekStart) + 7) % 7
+	return p.today.AddDate(0, 0, -back)
+}
+
+func (p parser) endOfMonth() time.Time {
+	first := time.Date(p.today.Year(), p.today.Month(), 1, 0, 0, 0, 0, p.today.Location())
+	return first.AddDate(0, 1, -1)
+}
+
+// upcoming returns the next occurrence of day, counting today.
+func (p parser) upcoming(day time.Weekday) time.Time {
+	ahead := (int(day) - int(p.today.Weekday()) + 7) % 7
+	return p.today.AddDate(0, 0, ahead)
+}
+
+// upcomingDate returns month/day in the current year, or next year if that
+// date has already passed.
+func (p parser) upcomingDate(month time.Month, day int) (time.Time, bool) {
+	date, ok := p.exactDate(p.today.Year(), month, day)
+	if !ok {
+		// Feb 29 in a non-leap year; try the following years.
+		for year := p.today.Year() + 1; year <= p.today.Year()+4; year++ {
+			if date, ok := p.exactDate(year, month, day); ok {
+				return date, true
+			}
+		}
+		return time.Time{}, false
+	}
+	if date.Before(p.today) {
+		if next, ok := p.exactDate(p.today.Year()+1, month, day); ok {
+			return next, true
+		}
+	}
+	return date, true
+}
+
+func (p parser) exactDate(year int, month time.Month, day int) (time.Time, bool) {
+	if month < time.January || month > time.December || day < 1 || day > 31 {
+		return time.Time{}, false
+	}
+	date := time.Date(year, month, day, 0, 0, 0, 0, p.today.Location())
+	if date.Month() != month {
+		return time.Time{}, false
+	}
+	return date, true
+}
+
+func addMonths(t time.Time, n int) time.Time {
+	first := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location()).AddDate(0, n, 0)
+	last := first.AddDate(0, 1, -1).Day()
+	day := t.Day()
+	if day > last {
+		day = last
+	}
+	return time.Date(first.Year(), first.Month(), day, 0, 0, 0, 0, t.Location())
+}
+
+// ====== Word tables ======
+
+var weekdayNames = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
+
+var weekdayAbbrev = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "tues": time.Tuesday,
+	"wed": time.Wednesday, "weds": time.Wednesday, "thu": time.Thursday, "thur": time.Thursday,
+	"thurs": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// parseWeekday matches full weekday names, tolerating small typos such as
+// "firday" or "wendsday". allowShort is set next to a date word; elsewhere a
+// typo must keep the "day" ending, so "sundae" is not a Sunday.
+func parseWeekday(word string, allowShort bool) (time.Weekday, bool) {
+	word = strings.TrimSuffix(word, "'s")
+	if day, ok := weekdayNames[word]; ok {
+		return day, true
+	}
+	if allowShort {
+		if day, ok := weekdayAbbrev[word]; ok {
+			return day, true
+		}
+	}
+	if len(word) < 5 || !(allowShort || strings.HasSuffix(word, "day")) {
+		return 0, false
+	}
+	for name, day := range weekdayNames {
+		if distance(word, name) <= typoBudget(name) {
+			return day, true
+		}
+	}
+	return 0, false
+}
+
+var monthNames = map[string]time.Month{
+	"january": time.January, "february": time.February, "march": time.March,
+	"april": time.April, "may": time.May, "june": time.June, "july": time.July,
+	"august": time.August, "september": time.September, "october": time.October,
+	"november": time.November, "december": time.December,
+}
+
+var monthAbbrev = map[string]time.Month{
+	"jan": time.January, "feb": time.February, "mar": time.March, "apr": time.April,
+	"jun": time.June, "jul": time.July, "aug": time.August, "sep": time.September,
+	"sept": time.September, "oct": time.October, "nov": time.November, "dec": time.December,
+}
+
+// parseMonth matches month names and abbreviations, tolerating small typos
+// such as "janury". Months are only ever matched next to a day number.
+func parseMonth(word string) (time.Month, bool) {
+	word = strings.TrimSuffix(word, ".")
+	if m, ok := monthNames[word]; ok {
+		return m, true
+	}
+	if m, ok := monthAbbrev[word]; ok {
+		return m, true
+	}
+	if len(word) < 5 {
+		return 0, false
+	}
+	for name, m := range monthNames {
+		if len(name) >= 5 && distance(word, name) <= typoBudget(name) {
+			return m, true
+		}
+	}
+	return 0, false
+}
+
+func typoBudget(name string) int {
+	if len(name) >= 8 {
+		return 2
+	}
+	return 1
+}
+
+func hasOrdinalSuffix(word string) bool {
+	for _, suffix := range []string{"st", "nd", "rd", "th"} {
+		if strings.HasSuffix(word, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+func parseDayNumber(word string) (int, bool) {
+	for _, suffix := range []string{"st", "nd", "rd", "th"} {
+		word = strings.TrimSuffix(word, suffix)
+	}
+	if word == "" || len(word) > 2 {
+		return 0, false
+	}
+	n, err := strconv.Atoi(word)
+	if err != nil || n < 1 || n > 31 {
+		return 0, false
+	}
+	return n, true
+}
+
+func parseYear(word string) (int, bool) {
+	if len(word) != 4 {
+		return 0, false
+	}
+	n, err := strconv.Atoi(word)
+	if err != nil || n < 1900 || n > 2200 {
+		return 0, false
+	}
+	return n, true
+}
+
+// distance is the optimal string alignment distance between a and b, so a
+// single transposition ("firday") costs one edit.
+func distance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	rows := make([][]int, len(ra)+1)
+	for i := range rows {
+		rows[i] = make([]int, len(rb)+1)
+		rows[i][0] = i
+	}
+	for j := range rows[0] {
+		rows[0][j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			best := min(rows[i-1][j]+1, rows[i][j-1]+1, rows[i-1][j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				best = min(best, rows[i-2][j-2]+1)
+			}
+			rows[i][j] = best
+		}
+	}
+	return rows[len(ra)][len(rb)]
+}
@@ -0,0 +1,281 @@
+package dateparse
+
+import (
+	"testing"
+	"time"
+)
+
+// fixedNow is Wednesday 15 October 2025.
+var fixedNow = time.Date(2025, time.October, 15, 10, 30, 0, 0, time.UTC)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		input     string
+		wantTitle string
+		wantDate  string // empty means no date
+	}{
+		// Relative days
+		{name: "today", input: "file taxes today", wantTitle: "file taxes", wantDate: "2025-10-15"},
+		{name: "tonight", input: "take out trash tonight", wantTitle: "take out trash", wantDate: "2025-10-15"},
+		{name: "tomorrow", input: "call mom tomorrow", wantTitle: "call mom", wantDate: "2025-10-16"},
+		{name: "tomorrow shorthand", input: "dentist tmrw", wantTitle: "dentist", wantDate: "2025-10-16"},
+		{name: "tomorrow typo", input: "gym tommorow", wantTitle: "gym", wantDate: "2025-10-16"},
+		{name: "day after tomorrow", input: "pick up parcel the day after tomorrow", wantTitle: "pick up parcel", wantDate: "2025-10-17"},
+		{name: "end of day", input: "send invoice by end of day", wantTitle: "send invoice", wantDate: "2025-10-15"},
+
+		// Weekdays
+		{name: "bare weekday", input: "submit report friday", wantTitle: "submit report", wantDate: "2025-10-17"},
+		{name: "weekday typo", input: "submit report firday", wantTitle: "submit report", wantDate: "2025-10-17"},
+		{name: "weekday double typo", input: "standup wendsday", wantTitle: "standup", wantDate: "2025-10-15"},
+		{name: "weekday-like word", input: "buy sundae toppings", wantTitle: "buy sundae toppings"},
+		{name: "weekday typo after connector", input: "haircut on fridy", wantTitle: "haircut", wantDate: "2025-10-17"},
+		{name: "weekday is today", input: "water plants wednesday", wantTitle: "water plants", wantDate: "2025-10-15"},
+		{name: "weekday wraps to next week", input: "haircut on tuesday", wantTitle: "haircut", wantDate: "2025-10-21"},
+		{name: "next weekday", input: "pay rent next friday", wantTitle: "pay rent", wantDate: "2025-10-24"},
+		{name: "next monday", input: "plan sprint next monday", wantTitle: "plan sprint", wantDate: "2025-10-20"},
+		{name: "this weekday", input: "book flights this friday", wantTitle: "book flights", wantDate: "2025-10-17"},
+		{name: "last weekday", input: "log hours from last friday", wantTitle: "log hours from", wantDate: "2025-10-10"},
+		{name: "abbreviation after connector", input: "laundry on sat", wantTitle: "laundry", wantDate: "2025-10-18"},
+		{name: "abbreviation without connector", input: "sat exam prep", wantTitle: "sat exam prep"},
+		{name: "capitalised weekday", input: "Review PR on Thursday", wantTitle: "Review PR", wantDate: "2025-10-16"},
+
+		// Offsets
+		{name: "in days", input: "follow up in 3 days", wantTitle: "follow up", wantDate: "2025-10-18"},
+		{name: "in weeks", input: "renew passport in 2 weeks", wantTitle: "renew passport", wantDate: "2025-10-29"},
+		{name: "in a week", input: "check results in a week", wantTitle: "check results", wantDate: "2025-10-22"},
+		{name: "in number word", input: "vet visit in two days", wantTitle: "vet visit", wantDate: "2025-10-17"},
+		{name: "in a few days", input: "ping Alex in a few days", wantTitle: "ping Alex", wantDate: "2025-10-18"},
+		{name: "in months", input: "service car in 6 months", wantTitle: "service car", wantDate: "2026-04-15"},
+		{name: "from now", input: "cancel trial 2 days from now", wantTitle: "cancel trial", wantDate: "2025-10-17"},
+
+		// Periods
+		{name: "next week", input: "clean garage next week", wantTitle: "clean garage", wantDate: "2025-10-20"},
+		{name: "next month", input: "dentist next month", wantTitle: "dentist", wantDate: "2025-11-01"},
+		{name: "next year", input: "plan trip next year", wantTitle: "plan trip", wantDate: "2026-01-01"},
+		{name: "end of week", input: "finish draft end of week", wantTitle: "finish draft", wantDate: "2025-10-19"},
+		{name: "end of the month", input: "budget review by the end of the month", wantTitle: "budget review", wantDate: "2025-10-31"},
+		{name: "eom shorthand", input: "expenses eom", wantTitle: "expenses", wantDate: "2025-10-31"},
+		{name: "this weekend", input: "hike this weekend", wantTitle: "hike", wantDate: "2025-10-18"},
+		{name: "next weekend", input: "visit parents next weekend", wantTitle: "visit parents", wantDate: "2025-10-25"},
+
+		// Calendar dates
+		{name: "month day upcoming", input: "concert Oct 20", wantTitle: "concert", wantDate: "2025-10-20"},
+		{name: "month day passed rolls to next year", input: "renew insurance Oct 5", wantTitle: "renew insurance", wantDate: "2026-10-05"},
+		{name: "month day with year", input: "wedding October 20th, 2027", wantTitle: "wedding", wantDate: "2027-10-20"},
+		{name: "month typo", input: "tax deadline janury 5", wantTitle: "tax deadline", wantDate: "2026-01-05"},
+		{name: "day month", input: "party 5 Nov", wantTitle: "party", wantDate: "2025-11-05"},
+		{name: "day of month", input: "gift shopping by the 3rd of December", wantTitle: "gift shopping", wantDate: "2025-12-03"},
+		{name: "leap day", input: "celebrate Feb 29", wantTitle: "celebrate", wantDate: "2028-02-29"},
+		{name: "slash date passed", input: "car inspection 5/10", wantTitle: "car inspection", wantDate: "2026-05-10"},
+		{name: "slash date upcoming", input: "wrap presents 12/24", wantTitle: "wrap presents", wantDate: "2025-12-24"},
+		{name: "slash date with year", input: "visa appointment 11/3/26", wantTitle: "visa appointment", wantDate: "2026-11-03"},
+		{name: "iso date", input: "launch 2025-11-03", wantTitle: "launch", wantDate: "2025-11-03"},
+		{name: "invalid slash date", input: "ratio 13/45", wantTitle: "ratio 13/45"},
+		{name: "ordinal day this month", input: "pay card on the 21st", wantTitle: "pay card", wantDate: "2025-10-21"},
+		{name: "ordinal day next month", input: "invoice on the 1st", wantTitle: "invoice", wantDate: "2025-11-01"},
+
+		// Connectors and lead-ins
+		{name: "by date", input: "submit essay by friday", wantTitle: "submit essay", wantDate: "2025-10-17"},
+		{name: "due by date", input: "library books due by Oct 20", wantTitle: "library books", wantDate: "2025-10-20"},
+		{name: "remind me to", input: "remind me to call mom tomorrow", wantTitle: "call mom", wantDate: "2025-10-16"},
+		{name: "remind me on", input: "Remind me on friday to call the bank", wantTitle: "call the bank", wantDate: "2025-10-17"},
+		{name: "date first", input: "tomorrow: buy milk", wantTitle: "buy milk", wantDate: "2025-10-16"},
+		{name: "trailing punctuation", input: "Submit report, by Friday.", wantTitle: "Submit report", wantDate: "2025-10-17"},
+		{name: "first phrase wins", input: "move dentist to friday instead of monday", wantTitle: "move dentist to instead of monday", wantDate: "2025-10-17"},
+
+		// No date
+		{name: "no date", input: "buy milk", wantTitle: "buy milk"},
+		{name: "month name without day", input: "march with the band", wantTitle: "march with the band"},
+		{name: "date only keeps input", input: "tomorrow", wantTitle: "tomorrow", wantDate: "2025-10-16"},
+		{name: "unicode title", input: "café meetup tomorrow", wantTitle: "café meetup", wantDate: "2025-10-16"},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := Parse(tc.input, Options{Now: fixedNow})
+
+			if got.Title != tc.wantTitle {
+				t.Errorf("title mismatch: got %q want %q", got.Title, tc.wantTitle)
+			}
+
+			switch {
+			case tc.wantDate == "" && got.Date != nil:
+				t.Fatalf("expected no date, got %s (phrase %q)", got.Date.Format("2006-01-02"), got.Phrase)
+			case tc.wantDate != "" && got.Date == nil:
+				t.Fatalf("expected date %s, got nil", tc.wantDate)
+			case tc.wantDate != "" && got.Date.Format("2006-01-02") != tc.wantDate:
+				t.Fatalf("date mismatch: got %s want %s (phrase %q)", got.Date.Format("2006-01-02"), tc.wantDate, got.Phrase)
+			}
+		})
+	}
+}
+
+func TestParseClampsMonthOffsets(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, time.January, 31, 9, 0, 0, 0, time.UTC)
+	got := Parse("close books in 1 month", Options{Now: now})
+	if got.Date == nil || got.Date.Format("2006-01-02") != "2025-02-28" {
+		t.Fatalf("expected clamp to 2025-02-28, got %v", got.Date)
+	}
+}
+
+func TestParseKeepsLocation(t *testing.T) {
+	t.Parallel()
+
+	loc := time.FixedZone("UTC-8", -8*60*60)
+	now := time.Date(2025, time.October, 15, 23, 30, 0, 0, loc)
+	got := Parse("call Sam tomorrow", Options{Now: now})
+	if got.Date == nil {
+		t.Fatal("expected a date")
+	}
+	if got.Date.Location() != loc || got.Date.Format("2006-01-02") != "2025-10-16" {
+		t.Fatalf("expected 2025-10-16 in %v, got %v", loc, got.Date)
+	}
+}
+
+func TestDistance(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"friday", "friday", 0},
+		{"firday", "friday", 1},
+		{"fridy", "friday", 1},
+		{"wendsday", "wednesday", 2},
+		{"", "abc", 3},
+	}
+
+	for _, tc := range tests {
+		if got := distance(tc.a, tc.b); got != tc.want {
+			t.Errorf("distance(%q, %q) = %d, want %d", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
+
+func TestParseTimesAndRanges(t *testing.T) {
+	t.Parallel()
+
+	const layout = "2006-01-02T15:04"
+	tests := []struct {
+		name      string
+		input     string
+		wantTitle string
+		wantStart string
+		wantEnd   string // empty means no range
+		wantTimed bool
+		wantZone  string
+	}{
+		{name: "time and date", input: "call Sam at 3pm tomorrow", wantTitle: "call Sam", wantStart: "2025-10-16T15:00", wantTimed: true},
+		{name: "spaced meridiem", input: "dentist friday 9:15 a.m.", wantTitle: "dentist", wantStart: "2025-10-17T09:15", wantTimed: true},
+		{name: "24 hour clock", input: "deploy on thursday 17:45", wantTitle: "deploy", wantStart: "2025-10-16T17:45", wantTimed: true},
+		{name: "noon", input: "lunch with Ana at noon friday", wantTitle: "lunch with Ana", wantStart: "2025-10-17T12:00", wantTimed: true},
+		{name: "bare hour after at", input: "call plumber at 3", wantTitle: "call plumber", wantStart: "2025-10-15T15:00", wantTimed: true},
+		{name: "time only rolls forward", input: "gym at 7am", wantTitle: "gym", wantStart: "2025-10-16T07:00", wantTimed: true},
+		{name: "time only later today", input: "standup 11:00", wantTitle: "standup", wantStart: "2025-10-15T11:00", wantTimed: true},
+		{name: "zone abbreviation", input: "standup 9:30 PST", wantTitle: "standup", wantStart: "2025-10-15T09:30", wantTimed: true, wantZone: "America/Los_Angeles"},
+		{name: "iana zone", input: "sync on Mon 10:00 Europe/Berlin", wantTitle: "sync", wantStart: "2025-10-20T10:00", wantTimed: true, wantZone: "Europe/Berlin"},
+		{name: "weekday range", input: "conference Mon–Wed", wantTitle: "conference", wantStart: "2025-10-20T00:00", wantEnd: "2025-10-22T00:00"},
+		{name: "spaced weekday range", input: "offsite from thu to sat", wantTitle: "offsite", wantStart: "2025-10-16T00:00", wantEnd: "2025-10-18T00:00"},
+		{name: "calendar range", input: "vacation Oct 20 to Oct 24", wantTitle: "vacation", wantStart: "2025-10-20T00:00", wantEnd: "2025-10-24T00:00"},
+		{name: "day number range", input: "summit Nov 3-5", wantTitle: "summit", wantStart: "2025-11-03T00:00", wantEnd: "2025-11-05T00:00"},
+		{name: "time range", input: "workshop 3-5pm friday", wantTitle: "workshop", wantStart: "2025-10-17T15:00", wantEnd: "2025-10-17T17:00", wantTimed: true},
+		{name: "time range across noon", input: "review 11-1pm", wantTitle: "review", wantStart: "2025-10-15T11:00", wantEnd: "2025-10-15T13:00", wantTimed: true},
+		{name: "time range past midnight", input: "party saturday 10pm to 1am", wantTitle: "party", wantStart: "2025-10-18T22:00", wantEnd: "2025-10-19T01:00", wantTimed: true},
+		{name: "bare number stays", input: "buy 2 apples", wantTitle: "buy 2 apples"},
+		{name: "hyphenated word stays", input: "follow-up with Jo", wantTitle: "follow-up with Jo"},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := Parse(tc.input, Options{Now: fixedNow})
+
+			if got.Title != tc.wantTitle {
+				t.Errorf("title mismatch: got %q want %q", got.Title, tc.wantTitle)
+			}
+			if got.HasTime != tc.wantTimed {
+				t.Errorf("HasTime = %v, want %v", got.HasTime, tc.wantTimed)
+			}
+			if got.TimeZone != tc.wantZone {
+				t.Errorf("zone mismatch: got %q want %q", got.TimeZone, tc.wantZone)
+			}
+
+			switch {
+			case tc.wantStart == "" && got.Date != nil:
+				t.Fatalf("expected no date, got %s", got.Date.Format(layout))
+			case tc.wantStart != "" && (got.Date == nil || got.Date.Format(layout) != tc.wantStart):
+				t.Fatalf("start mismatch: got %v want %s (phrase %q)", got.Date, tc.wantStart, got.Phrase)
+			}
+			switch {
+			case tc.wantEnd == "" && got.End != nil:
+				t.Fatalf("expected no end, got %s", got.End.Format(layout))
+			case tc.wantEnd != "" && (got.End == nil || got.End.Format(layout) != tc.wantEnd):
+				t.Fatalf("end mismatch: got %v want %s", got.End, tc.wantEnd)
+			}
+		})
+	}
+}
+
+func TestParseLocale(t *testing.T) {
+	t.Parallel()
+
+	sunday, monday := time.Sunday, time.Monday
+	sundayNow := time.Date(2025, time.October, 19, 9, 0, 0, 0, time.UTC)
+
+	const layout = "2006-01-02T15:04"
+	tests := []struct {
+		name      string
+		input     string
+		opts      Options
+		wantTitle string
+		wantStart string
+	}{
+		{name: "month first by default", input: "pay rent 03/04", opts: Options{Now: fixedNow}, wantTitle: "pay rent", wantStart: "2026-03-04T00:00"},
+		{name: "day first", input: "pay rent 03/04", opts: Options{Now: fixedNow, Order: DMY}, wantTitle: "pay rent", wantStart: "2026-04-03T00:00"},
+		{name: "day first with year", input: "pay rent 03/11/2025", opts: Options{Now: fixedNow, Order: DMY}, wantTitle: "pay rent", wantStart: "2025-11-03T00:00"},
+		{name: "dotted day first", input: "Feier am 25.12.", opts: Options{Now: fixedNow, Order: DMY}, wantTitle: "Feier am", wantStart: "2025-12-25T00:00"},
+		{name: "dots are not dates month first", input: "buy 2.5 kg flour", opts: Options{Now: fixedNow}, wantTitle: "buy 2.5 kg flour"},
+		{name: "year first", input: "launch 2025/11/03", opts: Options{Now: fixedNow, Order: YMD}, wantTitle: "launch", wantStart: "2025-11-03T00:00"},
+		{name: "year first without year", input: "launch 11/03", opts: Options{Now: fixedNow, Order: YMD}, wantTitle: "launch", wantStart: "2025-11-03T00:00"},
+		{name: "four digit year leads", input: "launch 2025/11/03", opts: Options{Now: fixedNow, Order: DMY}, wantTitle: "launch", wantStart: "2025-11-03T00:00"},
+		{name: "german tomorrow", input: "morgen Zahnarzt", opts: Options{Now: fixedNow, Language: "de"}, wantTitle: "Zahnarzt", wantStart: "2025-10-16T00:00"},
+		{name: "german weekday and time", input: "Zahnarzt am Freitag um 15 Uhr", opts: Options{Now: fixedNow, Language: "de-DE"}, wantTitle: "Zahnarzt", wantStart: "2025-10-17T15:00"},
+		{name: "german next week", input: "Bericht nächste Woche", opts: Options{Now: fixedNow, Language: "de"}, wantTitle: "Bericht", wantStart: "2025-10-20T00:00"},
+		{name: "german day after tomorrow", input: "übermorgen Müll rausbringen", opts: Options{Now: fixedNow, Language: "de"}, wantTitle: "Müll rausbringen", wantStart: "2025-10-17T00:00"},
+		{name: "german words ignored in english", input: "morgen meeting", opts: Options{Now: fixedNow}, wantTitle: "morgen meeting"},
+		{name: "o'clock", input: "call Ana at 3 o'clock", opts: Options{Now: fixedNow}, wantTitle: "call Ana", wantStart: "2025-10-15T15:00"},
+		{name: "next week from sunday start", input: "report next week", opts: Options{Now: fixedNow, WeekStart: &sunday}, wantTitle: "report", wantStart: "2025-10-19T00:00"},
+		{name: "end of week from sunday start", input: "report end of week", opts: Options{Now: fixedNow, WeekStart: &sunday}, wantTitle: "report", wantStart: "2025-10-18T00:00"},
+		{name: "weekend on closing sunday", input: "hike this weekend", opts: Options{Now: sundayNow, WeekStart: &monday}, wantTitle: "hike", wantStart: "2025-10-19T00:00"},
+		{name: "weekend on opening sunday", input: "hike this weekend", opts: Options{Now: sundayNow, WeekStart: &sunday}, wantTitle: "hike", wantStart: "2025-10-25T00:00"},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := Parse(tc.input, tc.opts)
+			if got.Title != tc.wantTitle {
+				t.Errorf("title mismatch: got %q want %q", got.Title, tc.wantTitle)
+			}
+			switch {
+			case tc.wantStart == "" && got.Date != nil:
+				t.Fatalf("expected no date, got %s", got.Date.Format(layout))
+			case tc.wantStart != "" && (got.Date == nil || got.Date.Format(layout) != tc.wantStart):
+				t.Fatalf("start mismatch: got %v want %s (phrase %q)", got.Date, tc.wantStart, got.Phrase)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package dateparse
+
+import "strings"
+
+// DateOrder is the order of day, month and year in numeric dates such as
+// 03/04/2025.
+type DateOrder string
+
+const (
+	MDY DateOrder = "MDY"
+	DMY DateOrder = "DMY"
+	YMD DateOrder = "YMD"
+)
+
+// vocabularies translate date words of other input languages into the
+// English words the recognisers understand. Translation is word for word, so
+// only phrases with the same shape as their English counterpart resolve.
+var vocabularies = map[string]map[string]string{
+	"de": {
+		"heute": "today", "heut": "today",
+		"morgen": "tomorrow", "übermorgen": "overmorrow", "uebermorgen": "overmorrow",
+		"nächste": "next", "nächsten": "next", "nächster": "next", "nächstes": "next",
+		"naechste": "next", "naechsten": "next", "naechster": "next", "naechstes": "next",
+		"kommende": "next", "kommenden": "next", "kommender": "next", "kommendes": "next",
+		"diese": "this", "diesen": "this", "dieser": "this", "dieses": "this",
+		"letzte": "last", "letzten": "last", "letzter": "last", "letztes": "last",
+		"woche": "week", "wochen": "weeks", "monat": "month", "monate": "months", "monaten": "months",
+		"jahr": "year", "jahre": "years", "jahren": "years", "tag": "day", "tage": "days", "tagen": "days",
+		"wochenende": "weekend", "am": "on", "bis": "by", "um": "at", "ab": "from", "uhr": "o'clock",
+		"montag": "monday", "dienstag": "tuesday", "mittwoch": "wednesday", "donnerstag": "thursday",
+		"freitag": "friday", "samstag": "saturday", "sonnabend": "saturday", "sonntag": "sunday",
+		"mo": "mon", "di": "tue", "mi": "wed", "do": "thu", "fr": "fri", "sa": "sat", "so": "sun",
+		"januar": "january", "jänner": "january", "februar": "february", "märz": "march", "maerz": "march",
+		"mai": "may", "juni": "june", "juli": "july", "oktober": "october", "dezember": "december",
+	},
+}
+
+// translate rewrites token words from language into English in place. Token
+// positions are unchanged, so titles keep the original wording.
+func translate(toks []token, language string) []token {
+	base, _, _ := strings.Cut(strings.ToLower(language), "-")
+	vocab, ok := vocabularies[base]
+	if !ok {
+		return toks
+	}
+	for i, tok := range toks {
+		if english, ok := vocab[tok.text]; ok {
+			toks[i].text = english
+		}
+	}
+	return toks
+}
+
+// numericParts orders the parts of a numeric date as year, month and day.
+// The year is 0 when the date has only two parts. A four-digit first part is
+// always a year, whatever the configured order.
+func numericParts(order DateOrder, parts []string, nums []int) (year, month, day int) {
+	if len(nums) == 3 && (order == YMD || len(parts[0]) == 4) {
+		return nums[0], nums[1], nums[2]
+	}
+
+	switch order {
+	case DMY:
+		day, month = nums[0], nums[1]
+	default:
+		month, day = nums[0], nums[1]
+	}
+	if len(nums) == 3 {
+		year = nums[2]
+	}
+	return year, month, day
+}
@@ -0,0 +1,285 @@
+package dateparse
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// rangeWords join the two ends of a date or time range.
+var rangeWords = map[string]bool{
+	"-":       true,
+	"to":      true,
+	"through": true,
+	"thru":    true,
+	"until":   true,
+	"till":    true,
+}
+
+// timeLeads introduce a time of day and are removed along with it.
+var timeLeads = map[string]bool{
+	"at":   true,
+	"@":    true,
+	"from": true,
+	"by":   true,
+	"due":  true,
+}
+
+var clockPattern = regexp.MustCompile(`^(\d{1,2})(?::(\d{2}))?(am|pm|a|p)?$`)
+
+// clock is a wall-clock time. meridiem is "am", "pm" or empty when the input
+// gave none.
+type clock struct {
+	hour     int
+	minute   int
+	meridiem string
+	explicit bool // written with a colon or a meridiem, not a bare number
+}
+
+// hour24 resolves the clock to a 24-hour value. Bare hours from 1 to 7 are
+// read as afternoon times, since "at 3" rarely means three in the morning.
+func (c clock) hour24() int {
+	switch c.meridiem {
+	case "am":
+		if c.hour == 12 {
+			return 0
+		}
+		return c.hour
+	case "pm":
+		if c.hour == 12 {
+			return 12
+		}
+		return c.hour + 12
+	}
+	if !c.explicit && c.hour >= 1 && c.hour <= 7 {
+		return c.hour + 12
+	}
+	return c.hour
+}
+
+// parseClock reads a time of day at toks[i]: "3pm", "3:30 pm", "15:00",
+// "noon" or, when allowBare is set, a bare hour such as the 3 in "at 3".
+func parseClock(toks []token, i int, allowBare bool) (clock, int, bool) {
+	word := strings.ReplaceAll(at(toks, i), ".", "")
+	switch word {
+	case "noon", "midday":
+		return clock{hour: 12, explicit: true}, 1, true
+	case "midnight":
+		return clock{hour: 0, explicit: true}, 1, true
+	}
+
+	m := clockPattern.FindStringSubmatch(word)
+	if m == nil {
+		return clock{}, 0, false
+	}
+	c := clock{}
+	c.hour, _ = strconv.Atoi(m[1])
+	if m[2] != "" {
+		c.minute, _ = strconv.Atoi(m[2])
+		c.explicit = true
+	}
+
+	n := 1
+	meridiem := m[3]
+	if meridiem == "" {
+		switch strings.ReplaceAll(at(toks, i+1), ".", "") {
+		case "am", "pm":
+			meridiem = strings.ReplaceAll(at(toks, i+1), ".", "")
+			n = 2
+		}
+	}
+	switch meridiem {
+	case "a", "am":
+		c.meridiem = "am"
+	case "p", "pm":
+		c.meridiem = "pm"
+	}
+	if c.meridiem != "" {
+		c.explicit = true
+		if c.hour < 1 || c.hour > 12 {
+			return clock{}, 0, false
+		}
+	}
+
+	if c.hour > 23 || c.minute > 59 {
+		return clock{}, 0, false
+	}
+	if !c.explicit && !allowBare {
+		return clock{}, 0, false
+	}
+	return c, n, true
+}
+
+// timeMatch is a time of day (or range of times) found in the input.
+type timeMatch struct {
+	start, end *clock
+	zone       *time.Location
+	zoneName   string
+	from, to   int // token span, including lead-in words
+}
+
+// findTime returns the first time-of-day phrase outside the taken token spans.
+func findTime(text string, toks []token, taken []span) (timeMatch, bool) {
+	for i := range toks {
+		if covered(taken, i) {
+			continue
+		}
+
+		afterLead := at(toks, i-1) == "at" || at(toks, i-1) == "@"
+		start, n, ok := parseClock(toks, i, true)
+		if !ok {
+			continue
+		}
+
+		m := timeMatch{start: &start, from: i, to: i + n}
+		if rangeWords[at(toks, m.to)] && !covered(taken, m.to+1) {
+			if end, n, ok := parseClock(toks, m.to+1, true); ok && end.explicit {
+				if start.meridiem == "" && end.meridiem != "" {
+					start.meridiem = end.meridiem
+					// "11-1pm" starts in the morning.
+					if end.meridiem == "pm" && start.hour != 12 && start.hour > end.hour && end.hour != 12 {
+						start.meridiem = "am"
+					}
+					start.explicit = true
+				}
+				m.end = &end
+				m.to += 1 + n
+			}
+		}
+		oclock := at(toks, m.to) == "o'clock"
+		if oclock {
+			m.to++
+		}
+		if !start.explicit && !afterLead && !oclock {
+			continue
+		}
+
+		if loc, name, ok := parseZone(text, toks, m.to); ok {
+			m.zone, m.zoneName = loc, name
+			m.to++
+		}
+
+		for m.from > 0 && timeLeads[at(toks, m.from-1)] && !covered(taken, m.from-1) {
+			m.from--
+		}
+		return m, true
+	}
+	return timeMatch{}, false
+}
+
+// zoneAbbreviations maps common abbreviations to IANA zones. Daylight and
+// standard variants share a zone, so "PST" in July still means Pacific time.
+var zoneAbbreviations = map[string]string{
+	"utc": "UTC", "gmt": "UTC", "z": "UTC",
+	"pt": "America/Los_Angeles", "pst": "America/Los_Angeles", "pdt": "America/Los_Angeles",
+	"mt": "America/Denver", "mst": "America/Denver", "mdt": "America/Denver",
+	"ct": "America/Chicago", "cst": "America/Chicago", "cdt": "America/Chicago",
+	"et": "America/New_York", "est": "America/New_York", "edt": "America/New_York",
+	"bst": "Europe/London", "cet": "Europe/Paris", "cest": "Europe/Paris",
+	"ist": "Asia/Kolkata", "jst": "Asia/Tokyo", "aest": "Australia/Sydney", "aedt": "Australia/Sydney",
+}
+
+// parseZone reads a zone abbreviation or IANA name at toks[i]. Zones are only
+// looked for straight after a time so words like "et" stay in titles.
+func parseZone(text string, toks []token, i int) (*time.Location, string, bool) {
+	if i >= len(toks) {
+		return nil, "", false
+	}
+
+	name, ok := zoneAbbreviations[toks[i].text]
+	if !ok {
+		raw := text[toks[i].start:toks[i].end]
+		if !strings.Contains(raw, "/") {
+			return nil, "", false
+		}
+		name = raw
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, "", false
+	}
+	return loc, name, true
+}
+
+// matchRangeEnd reads the end of a date range ("Mon–Wed", "Oct 3 to Oct 5",
+// "Oct 3-5") starting at the range word toks[i]. The end is resolved relative
+// to start and must not precede it.
+func (p parser) matchRangeEnd(toks []token, i int, start time.Time) (int, time.Time, bool) {
+	if !rangeWords[at(toks, i)] {
+		return 0, time.Time{}, false
+	}
+	q := p
+	q.today = start
+
+	var n int
+	var end time.Time
+	var ok bool
+	if day, isDay := parseWeekday(at(toks, i+1), true); isDay {
+		n, end, ok = 1, q.upcoming(day), true
+	} else if n, end, ok = q.match(toks, i+1); !ok {
+		day, isNum := parseDayNumber(at(toks, i+1))
+		if !isNum || isMeridiem(at(toks, i+2)) {
+			return 0, time.Time{}, false
+		}
+		n = 1
+		end, ok = q.exactDate(start.Year(), start.Month(), day)
+		if ok && end.Before(start) {
+			end, ok = q.exactDate(start.Year(), start.Month()+1, day)
+		}
+	}
+	if !ok || end.Before(start) {
+		return 0, time.Time{}, false
+	}
+	return n + 1, end, true
+}
+
+func isMeridiem(word string) bool {
+	switch strings.ReplaceAll(word, ".", "") {
+	case "am", "pm":
+		return true
+	}
+	return false
+}
+
+// splitRange breaks "mon–wed" or "3-5pm" into its two ends around the dash.
+// ASCII hyphens only split when both sides look like days or times, so words
+// such as "follow-up" and ISO dates stay whole.
+func splitRange(word string) (string, string, string, bool) {
+	for _, dash := range []string{"–", "—"} {
+		if left, right, found := strings.Cut(word, dash); found && left != "" && right != "" {
+			return left, dash, right, true
+		}
+	}
+
+	left, right, found := strings.Cut(word, "-")
+	if !found || strings.Count(word, "-") != 1 {
+		return "", "", "", false
+	}
+	if rangePart(strings.ToLower(left)) && rangePart(strings.ToLower(right)) {
+		return left, "-", right, true
+	}
+	return "", "", "", false
+}
+
+func rangePart(s string) bool {
+	if _, ok := parseWeekday(s, true); ok {
+		return true
+	}
+	return clockPattern.MatchString(strings.ReplaceAll(s, ".", ""))
+}
+
+// span is a half-open range of token indexes removed from the title.
+type span struct {
+	from, to int
+}
+
+func covered(spans []span, i int) bool {
+	for _, s := range spans {
+		if i >= s.from && i < s.to {
+			return true
+		}
+	}
+	return false
+}
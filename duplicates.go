@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/imjamesonzeller/tasklight-v3/notionapi"
+	"github.com/imjamesonzeller/tasklight-v3/settingsservice"
+)
+
+const (
+	// duplicateThreshold is the title similarity from which two tasks count
+	// as the same.
+	duplicateThreshold = 0.8
+	// duplicateDateSlack is how far apart the dates of duplicates may be.
+	duplicateDateSlack = 24 * time.Hour
+	// duplicateLookback bounds the search for undated tasks to pages created
+	// this recently.
+	duplicateLookback = 7 * 24 * time.Hour
+	// maxDuplicatePages bounds how many candidate pages are compared.
+	maxDuplicatePages = 500
+)
+
+// DuplicateMatch is an open page that looks like the captured task.
+type DuplicateMatch struct {
+	PageID string `json:"page_id"`
+	URL    string `json:"url,omitempty"`
+	Title  string `json:"title"`
+	Date   string `json:"date,omitempty"`
+	// Action is what the capture did about it.
+	Action settingsservice.DuplicateAction `json:"action"`
+}
+
+// completedNames are status options and checkbox properties that mark a task done.
+var completedNames = map[string]bool{"done": true, "complete": true, "completed": true, "finished": true}
+
+// pageProperty is the part of a page property value Tasklight reads.
+type pageProperty struct {
+	Type     string `json:"type"`
+	Checkbox bool   `json:"checkbox"`
+	Status   *struct {
+		Name string `json:"name"`
+	} `json:"status"`
+	Title []RichTextObj `json:"title"`
+	Date  *struct {
+		Start string `json:"start"`
+	} `json:"date"`
+}
+
+// propertiesCompleted reports whether a page's properties mark it done.
+func propertiesCompleted(props map[string]pageProperty) bool {
+	for name, prop := range props {
+		switch {
+		case prop.Type == "checkbox" && prop.Checkbox && completedNames[strings.ToLower(name)]:
+			return true
+		case prop.Type == "status" && prop.Status != nil && completedNames[strings.ToLower(prop.Status.Name)]:
+			return true
+		}
+	}
+	return false
+}
+
+// findDuplicate looks in the target data source for an open page whose title
+// is similar to the task's and whose date is the same or a day apart.
+// Undated tasks are compared with pages created in the last week. At most
+// maxDuplicatePages pages are compared.
+func (ts *TaskService) findDuplicate(ctx context.Context, target *pageTarget, task TaskInformation) (*DuplicateMatch, error) {
+	var properties map[string]PropertyObj
+	if target.dataSource != nil {
+		properties = target.dataSource.Properties
+	}
+	filter := duplicateFilter(task, target.dateProp, properties, time.Now())
+	url := notionapi.URL("/data_sources/" + target.dataSourceID + "/query")
+
+	var best *DuplicateMatch
+	bestScore := duplicateThreshold
+	cursor := ""
+	for seen := 0; seen < maxDuplicatePages; {
+		query := map[string]any{"page_size": 100, "filter": filter}
+		if cursor != "" {
+			query["start_cursor"] = cursor
+		}
+		req, err := notionapi.NewJSONRequest(ctx, http.MethodPost, url, target.token, query)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		var result struct {
+			Results []struct {
+				ID         string                  `json:"id"`
+				URL        string                  `json:"url"`
+				Properties map[string]pageProperty `json:"properties"`
+			} `json:"results"`
+			HasMore    bool   `json:"has_more"`
+			NextCursor string `json:"next_cursor"`
+		}
+		if err := notionapi.ParseResponse(resp, &result, ErrNotionTokenMissing); err != nil {
+			return nil, err
+		}
+
+		seen += len(result.Results)
+		for _, page := range result.Results {
+			if propertiesCompleted(page.Properties) {
+				continue
+			}
+			title := richTextPlainText(page.Properties[target.titleProp].Title)
+			score := titleSimilarity(task.Title, title)
+			if score < bestScore {
+				continue
+			}
+			bestScore = score
+			best = &DuplicateMatch{PageID: page.ID, URL: page.URL, Title: title}
+			if date := page.Properties[target.dateProp].Date; date != nil {
+				best.Date = date.Start
+			}
+		}
+		if !result.HasMore || result.NextCursor == "" {
+			break
+		}
+		cursor = result.NextCursor
+	}
+	return best, nil
+}
+
+// duplicateFilter selects the pages findDuplicate compares titles with,
+// leaving out pages that properties mark done.
+func duplicateFilter(task TaskInformation, dateProp string, properties map[string]PropertyObj, now time.Time) map[string]any {
+	window := duplicateWindow(task, dateProp, now)
+	names := make([]string, 0, len(properties))
+	for name := range properties {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	var open []any
+	for _, name := range names {
+		prop := properties[name]
+		switch {
+		case prop.Type == "checkbox" && completedNames[strings.ToLower(name)]:
+			open = append(open, map[string]any{"property": name, "checkbox": map[string]any{"equals": false}})
+		case prop.Type == "status":
+			for _, opt := range prop.Options() {
+				if completedNames[strings.ToLower(opt.Name)] {
+					open = append(open, map[string]any{"property": name, "status": map[string]any{"does_not_equal": opt.Name}})
+				}
+			}
+		}
+	}
+	if len(open) == 0 {
+		return window
+	}
+	return map[string]any{"and": append([]any{window}, open...)}
+}
+
+// duplicateWindow selects pages dated near the task, or created recently for
+// an undated task.
+func duplicateWindow(task TaskInformation, dateProp string, now time.Time) map[string]any {
+	if task.Date != nil && dateProp != "" && len(*task.Date) >= len(time.DateOnly) {
+		if day, err := time.Parse(time.DateOnly, (*task.Date)[:len(time.DateOnly)]); err == nil {
+			return map[string]any{
+				"property": dateProp,
+				"date": map[string]any{
+					"on_or_after":  day.Add(-duplicateDateSlack).Format(time.DateOnly),
+					"on_or_before": day.Add(duplicateDateSlack).Format(time.DateOnly),
+				},
+			}
+		}
+	}
+	return map[string]any{
+		"timestamp":    "created_time",
+		"created_time": map[string]any{"on_or_after": now.Add(-duplicateLookback).UTC().Format(time.RFC3339)},
+	}
+}
+
+// updateNotionPage writes properties to an existing page.
+func (ts *TaskService) updateNotionPage(ctx context.Context, token, pageID string, properties any) error {
+	req, err := notionapi.NewJSONRequest(ctx, http.MethodPatch, notionapi.URL("/pages/"+pageID), token, map[string]any{"properties": properties})
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	return notionapi.ParseResponse(resp, nil, ErrNotionTokenMissing)
+}
+
+// normalizeTitle lowercases a title and reduces it to its words.
+func normalizeTitle(title string) string {
+	fields := strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(fields, " ")
+}
+
+// titleSimilarity scores two titles from 0 (unrelated) to 1 (the same once
+// normalised) by their edit distance.
+func titleSimilarity(a, b string) float64 {
+	ra, rb := []rune(normalizeTitle(a)), []rune(normalizeTitle(b))
+	longest := max(len(ra), len(rb))
+	if longest == 0 {
+		return 0
+	}
+	return 1 - float64(editDistance(ra, rb))/float64(longest)
+}
+
+// editDistance is the Levenshtein distance between a and b.
+func editDistance(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	c "github.com/imjamesonzeller/tasklight-v3/config"
+	"github.com/imjamesonzeller/tasklight-v3/notionapi"
+	"github.com/imjamesonzeller/tasklight-v3/settingsservice"
+)
+
+func TestTitleSimilarity(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		a, b string
+		same bool
+	}{
+		{"renew passport", "Renew passport!", true},
+		{"renew passport", "renew my passport", true},
+		{"Call mom", "call  mom.", true},
+		{"renew passport", "renew car insurance", false},
+		{"buy milk", "buy eggs", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		if got := titleSimilarity(tt.a, tt.b) >= duplicateThreshold; got != tt.same {
+			t.Errorf("titleSimilarity(%q, %q) = %.2f, want duplicate %v", tt.a, tt.b, titleSimilarity(tt.a, tt.b), tt.same)
+		}
+	}
+}
+
+func TestDuplicateFilter(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, time.October, 15, 9, 0, 0, 0, time.UTC)
+	date := "2025-10-20T09:00:00"
+
+	dated := duplicateFilter(TaskInformation{Date: &date}, "Due", nil, now)
+	window, _ := dated["date"].(map[string]any)
+	if dated["property"] != "Due" || window["on_or_after"] != "2025-10-19" || window["on_or_before"] != "2025-10-21" {
+		t.Fatalf("unexpected dated filter: %v", dated)
+	}
+
+	undated := duplicateFilter(TaskInformation{}, "Due", nil, now)
+	created, _ := undated["created_time"].(map[string]any)
+	if undated["timestamp"] != "created_time" || created["on_or_after"] != "2025-10-08T09:00:00Z" {
+		t.Fatalf("unexpected undated filter: %v", undated)
+	}
+
+	// Done pages are left out when the schema can tell.
+	properties := map[string]PropertyObj{
+		"Done":   {Name: "Done", Type: "checkbox"},
+		"Status": {Name: "Status", Type: "status", Status: &SelectConfig{Options: []SelectOption{{Name: "Not started"}, {Name: "Done"}}}},
+		"Urgent": {Name: "Urgent", Type: "checkbox"},
+	}
+	open, _ := json.Marshal(duplicateFilter(TaskInformation{}, "Due", properties, now))
+	want := `{"and":[{"created_time":{"on_or_after":"2025-10-08T09:00:00Z"},"timestamp":"created_time"},` +
+		`{"checkbox":{"equals":false},"property":"Done"},{"property":"Status","status":{"does_not_equal":"Done"}}]}`
+	if string(open) != want {
+		t.Fatalf("unexpected filter for open pages:\n got %s\nwant %s", open, want)
+	}
+}
+
+// duplicateNotion serves a data source holding a completed and an open
+// "Renew passport" page on two result pages, counting page creations and
+// updates.
+func duplicateNotion(t *testing.T, creates, updates *atomic.Int32) {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/data_sources/ds-1":
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"id": "ds-1",
+				"properties": map[string]any{
+					"Name": map[string]any{"id": "title", "type": "title"},
+					"Done": map[string]any{"id": "done", "type": "checkbox"},
+				},
+			})
+		case r.Method == http.MethodPost && r.URL.Path == "/data_sources/ds-1/query":
+			page := func(id string, done bool) map[string]any {
+				return map[string]any{
+					"id":  id,
+					"url": "https://www.notion.so/" + id,
+					"properties": map[string]any{
+						"Name": map[string]any{"type": "title", "title": []map[string]any{{"plain_text": "Renew passport"}}},
+						"Done": map[string]any{"type": "checkbox", "checkbox": done},
+					},
+				}
+			}
+			var query struct {
+				StartCursor string         `json:"start_cursor"`
+				Filter      map[string]any `json:"filter"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&query)
+			if _, ok := query.Filter["and"]; !ok {
+				t.Errorf("expected done pages to be filtered out, got %v", query.Filter)
+			}
+			if query.StartCursor == "" {
+				_ = json.NewEncoder(w).Encode(map[string]any{"results": []any{page("page-done", true)}, "has_more": true, "next_cursor": "cursor-2"})
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"results": []any{page("page-open", false)}})
+		case r.Method == http.MethodPost && r.URL.Path == "/pages":
+			creates.Add(1)
+			_ = json.NewEncoder(w).Encode(map[string]any{"id": "page-new", "url": "https://www.notion.so/page-new"})
+		case r.Method == http.MethodPatch && r.URL.Path == "/pages/page-open":
+			updates.Add(1)
+			_ = json.NewEncoder(w).Encode(map[string]any{"id": "page-open"})
+		default:
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	originalBase := notionapi.BaseURL
+	t.Cleanup(func() { notionapi.BaseURL = originalBase })
+	notionapi.BaseURL = srv.URL
+}
+
+func TestCaptureHandlesDuplicates(t *testing.T) {
+	originalConfig := c.AppConfig
+	t.Cleanup(func() { c.AppConfig = originalConfig })
+
+	tests := []struct {
+		action           settingsservice.DuplicateAction
+		page             string
+		creates, updates int32
+	}{
+		{settingsservice.DuplicatesOff, "page-new", 1, 0},
+		{settingsservice.DuplicatesWarn, "page-new", 1, 0},
+		{settingsservice.DuplicatesSkip, "page-open", 0, 0},
+		{settingsservice.DuplicatesUpdate, "page-open", 0, 1},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.action), func(t *testing.T) {
+			var creates, updates atomic.Int32
+			duplicateNotion(t, &creates, &updates)
+
+			settings := &settingsservice.SettingsService{
+				AppSettings: settingsservice.ApplicationSettings{
+					NotionAccessToken:  "secret",
+					NotionDataSourceID: "ds-1",
+					DuplicateAction:    tt.action,
+				},
+			}
+			c.AppConfig = &settings.AppSettings
+			ts := NewTaskService(nil, settings)
+
+			result := ts.commitTask(context.Background(), "renew passport", TaskInformation{Title: "renew passport"})
+			if !result.OK || result.PageID != tt.page {
+				t.Fatalf("unexpected result: %+v", result)
+			}
+			if creates.Load() != tt.creates || updates.Load() != tt.updates {
+				t.Fatalf("expected %d creates and %d updates, got %d and %d", tt.creates, tt.updates, creates.Load(), updates.Load())
+			}
+
+			if tt.action == settingsservice.DuplicatesOff {
+				if result.Duplicate != nil {
+					t.Fatalf("duplicates must not be looked up when off, got %+v", result.Duplicate)
+				}
+			} else if result.Duplicate == nil || result.Duplicate.PageID != "page-open" || result.Duplicate.Action != tt.action {
+				t.Fatalf("expected the open page to be reported, got %+v", result.Duplicate)
+			}
+
+			// Undo must never trash a page from an earlier capture.
+			if _, ok := ts.lastCreated(); ok != result.Created() {
+				t.Fatalf("created page recorded for undo: %v, want %v", ok, result.Created())
+			}
+		})
+	}
+}
@@ -1,19 +1,125 @@
 import { useCallback, useEffect, useRef, useState, KeyboardEvent, ChangeEvent } from "react";
 // import "./App.css";
-import { WindowService as ws, TaskService as ts } from "../bindings/github.com/imjamesonzeller/tasklight-v3"
+import { WindowService as ws, TaskService as ts, HistoryService as hs, OutboxService as ob } from "../bindings/github.com/imjamesonzeller/tasklight-v3"
 import { SettingsService as settingsService } from "../bindings/github.com/imjamesonzeller/tasklight-v3/settingsservice"
-import { Events } from '@wailsio/runtime';
+import { Browser, Events } from '@wailsio/runtime';
 // @ts-ignore
 import { WailsEvent } from "@wailsio/runtime/types/events";
 
+type TaskResult = {
+    input: string;
+    title: string;
+    ok: boolean;
+    error?: string;
+    error_category?: "auth" | "not_found" | "validation" | "rate_limited" | "network" | "config";
+    queued?: boolean;
+    parked?: boolean;
+    page_id?: string;
+    url?: string;
+    duplicate?: { page_id: string; url?: string; title: string; date?: string; action: "warn" | "skip" | "update" };
+    warnings?: string[];
+    destination?: string;
+    routed_by?: string;
+    options?: OptionMatch[];
+}
+
+type OptionMatch = {
+    field: string;
+    property: string;
+    value: string;
+    option?: string;
+    outcome: "matched" | "created" | "dropped" | "fallback" | "ambiguous";
+    url?: string;
+    candidates?: { page_id: string; title: string; url?: string }[];
+}
+
+const optionText = (m: OptionMatch) => {
+    switch (m.outcome) {
+        case "created":
+            return `new ${m.property} option “${m.option}”`;
+        case "dropped":
+            return `left out “${m.value}”`;
+        case "ambiguous":
+            return `“${m.value}” matches ${m.candidates?.length ?? 0} projects, none linked`;
+        default:
+            return `“${m.value}” → “${m.option}”`;
+    }
+}
+
+const duplicateText = (d: NonNullable<TaskResult["duplicate"]>) => {
+    switch (d.action) {
+        case "skip":
+            return `⏭️ “${d.title}” is already in Notion; nothing added.`;
+        case "update":
+            return `🔄 Updated the existing “${d.title}”.`;
+        default:
+            return `⚠️ Added, but “${d.title}” is already in Notion.`;
+    }
+}
+
+// QueuedTask is a submission still in the outbox; parked ones wait for the user.
+type QueuedTask = {
+    id: string;
+    title: string;
+    last_error?: string;
+    error_category?: TaskResult["error_category"];
+    parked?: boolean;
+}
+
+type TaskCreated = {
+    title: string;
+    page_id: string;
+    url: string;
+    destination?: string;
+}
+
+type TaskPreview = {
+    input: string;
+    task: { title: string; date: string | null; [field: string]: unknown };
+    destination: { data_source_id: string; name: string; destination?: string; routed_by?: string };
+    properties: Record<string, string>;
+    error?: string;
+    warnings?: string[];
+    options?: OptionMatch[];
+}
+
 function Input() {
     const [resultText, setResultText] = useState<string>("");
     const [name, setName] = useState<string>("");
     const [theme, setTheme] = useState<"light" | "dark">("light");
+    const [previewMode, setPreviewMode] = useState<boolean>(false);
+    const [previews, setPreviews] = useState<TaskPreview[]>([]);
+    const [queued, setQueued] = useState<number>(0);
+    const [parked, setParked] = useState<QueuedTask[]>([]);
+    const [lastCreated, setLastCreated] = useState<TaskCreated | null>(null);
+    // clipboard is attached to the capture when the clipboard hotkey opened the window.
+    const [clipboard, setClipboard] = useState<string>("");
+    // historyIndex is the recalled capture, counting back from the newest; -1 is the user's own text.
+    const [historyIndex, setHistoryIndex] = useState<number>(-1);
     const inputRef = useRef<HTMLInputElement>(null);
     const window: string = "main"
 
-    const updateName = (e: ChangeEvent<HTMLInputElement>) => setName(e.target.value);
+    const updateName = (e: ChangeEvent<HTMLInputElement>) => {
+        setName(e.target.value);
+        setHistoryIndex(-1);
+    };
+
+    const recallHistory = (index: number) => {
+        if (index < 0) {
+            setHistoryIndex(-1);
+            setName("");
+            return;
+        }
+        hs.GetHistory("", 1, index)
+            .then((page) => {
+                const entry = page.entries[0];
+                if (entry) {
+                    setHistoryIndex(index);
+                    setName(entry.input);
+                }
+            })
+            .catch((err: unknown) => console.error("Failed to load history:", err));
+    };
 
     const handleKeyDown = (e: KeyboardEvent<HTMLInputElement>) => {
         if (e.key === "Enter") {
@@ -22,8 +128,30 @@ function Input() {
 
         if (e.key === "Escape") {
             e.preventDefault();
+            if (previews.length > 0) {
+                setPreviews([]);
+                return;
+            }
             ws.Hide(window);
             setName("");
+            clearClipboard();
+        }
+
+        if (e.key === "ArrowUp" && previews.length === 0 && (name === "" || historyIndex >= 0)) {
+            // Recall earlier captures, newest first
+            e.preventDefault();
+            recallHistory(historyIndex + 1);
+        }
+
+        if (e.key === "ArrowDown" && historyIndex >= 0) {
+            e.preventDefault();
+            recallHistory(historyIndex - 1);
+        }
+
+        if ((e.metaKey || e.ctrlKey) && e.key === "z" && name === "") {
+            // Undo the last captured task
+            e.preventDefault();
+            ts.UndoLast().catch((err: unknown) => setResultText(`❌ ${err}`));
         }
 
         if (e.metaKey && e.key === ",") {
@@ -35,20 +163,84 @@ function Input() {
     };
 
     const processMessage = () => {
+        if (previews.length > 0) {
+            commitPreviews();
+            return;
+        }
+
         if (!name.trim()) {
             setResultText("⚠️ Input cannot be empty.");
             return;
         }
+        setHistoryIndex(-1);
+
+        if (previewMode) {
+            ts.PreviewMessage(name)
+                .then((result: TaskPreview[]) => {
+                    setResultText("");
+                    setPreviews(result ?? []);
+                })
+                .catch(() => {
+                    setResultText("❌ An error occurred while parsing the message.");
+                });
+            return;
+        }
 
         ts.ProcessMessage(name)
             .then(() => {
                 setName("");
+                setClipboard("");
             })
             .catch(() => {
                 setResultText("❌ An error occurred while processing the message.");
             });
     };
 
+    const commitPreviews = () => {
+        Promise.all(previews.map((p) => ts.CommitTask(p.input, p.task as any)))
+            .then((results: TaskResult[]) => {
+                const failed = previews.filter((_, i) => !results[i].ok);
+                if (failed.length === 0) {
+                    setPreviews([]);
+                    setName("");
+                    setClipboard("");
+                    ws.Hide(window);
+                    return;
+                }
+                setPreviews(failed);
+                setResultText(`❌ ${results.filter((r) => !r.ok).map((r) => `${r.title} (${r.error})`).join("; ")}`);
+            })
+            .catch(() => {
+                setResultText("❌ An error occurred while sending the tasks.");
+            });
+    };
+
+    const loadParked = useCallback(() => {
+        ob.GetQueue()
+            .then((queue: QueuedTask[]) => setParked((queue ?? []).filter((q) => q.parked)))
+            .catch((err: unknown) => console.error("Failed to load the queue:", err));
+    }, []);
+
+    const retryParked = (id: string) => {
+        ob.RetryNow(id).catch((err: unknown) => setResultText(`❌ ${err}`));
+    };
+
+    const discardParked = (id: string) => {
+        ob.Discard(id).catch((err: unknown) => setResultText(`❌ ${err}`));
+    };
+
+    const clearClipboard = () => {
+        setClipboard("");
+        ts.ClearClipboardContext().catch((err: unknown) => console.error("Failed to detach clipboard:", err));
+    };
+
+    // An ambiguous project is settled by picking its page, stored as the page ID.
+    const editPreview = (index: number, field: "title" | "date" | "project", value: string) => {
+        setPreviews((prev) => prev.map((p, i) => i === index
+            ? { ...p, task: { ...p.task, [field]: field === "date" && value === "" ? null : value } }
+            : p));
+    };
+
     const applyTheme = useCallback((value?: string) => {
         const normalized = value === "dark" ? "dark" : "light"
         setTheme(normalized)
@@ -61,8 +253,14 @@ function Input() {
                 inputRef.current.focus();
                 setResultText("");
             }
+            ts.GetClipboardContext()
+                .then((text: string) => setClipboard(text ?? ""))
+                .catch(() => setClipboard(""))
             settingsService.GetSettings()
-                .then((res) => applyTheme(res.theme))
+                .then((res) => {
+                    applyTheme(res.theme)
+                    setPreviewMode(Boolean(res.preview_before_commit))
+                })
                 .catch(() => applyTheme("light"))
         };
 
@@ -100,14 +298,63 @@ function Input() {
     }, [applyTheme])
 
     useEffect(() => {
-        const off = Events.On("Backend:ErrorEvent", (ev: WailsEvent) => {
-            setResultText(`❌ Error: ${ev.data}`);
+        const off = Events.On("Backend:TaskReport", (ev: WailsEvent) => {
+            const results: TaskResult[] = ev.data ?? [];
+            const failed = results.filter((r) => !r.ok);
+            if (failed.length === 0) {
+                // Tasks sent to the same destination share its warnings, so each is reported once.
+                const warnings = [...new Set(results.flatMap((r) => r.warnings ?? []))];
+                // The created banner names the destination of a single task.
+                const routed = results.length > 1
+                    ? [...new Set(results.filter((r) => r.destination).map((r) => r.destination!))]
+                    : [];
+                setResultText([
+                    ...results.filter((r) => r.duplicate).map((r) => duplicateText(r.duplicate!)),
+                    ...routed.map((d) => `→ ${d}`),
+                    ...results.flatMap((r) => r.options ?? []).map((m) => `🏷️ ${optionText(m)}`),
+                    ...warnings.map((w) => `⚠️ ${w}`),
+                ].join(" "));
+                return;
+            }
+
+            const details = failed.map((r) => `${r.title || r.input} (${r.error})`).join("; ");
+            const retry = !failed.every((r) => r.queued)
+                ? ""
+                : failed.some((r) => r.parked) ? " (saved; retry or discard it below)" : " (saved, will retry)";
+            setResultText(results.length === 1
+                ? `❌ Error: ${failed[0].error}${retry}`
+                : `❌ ${failed.length} of ${results.length} tasks failed: ${details}${retry}`);
+        });
+        const offQueue = Events.On("Backend:QueueChanged", (ev: WailsEvent) => {
+            setQueued(ev.data ?? 0);
+            loadParked();
+        });
+        loadParked();
+        const offCreated = Events.On("Backend:TaskCreated", (ev: WailsEvent) => {
+            const created: TaskCreated = ev.data;
+            if (created?.url) {
+                setLastCreated(created);
+            }
+        });
+        const offClipboard = Events.On("Backend:ClipboardAttached", (ev: WailsEvent) => {
+            setClipboard(ev.data ?? "");
+        });
+        const offUndone = Events.On("Backend:TaskUndone", (ev: WailsEvent) => {
+            const undone: { title: string; cancelled: boolean } = ev.data;
+            setLastCreated(null);
+            setResultText(undone.cancelled
+                ? `↩️ Cancelled “${undone.title}” before it was sent`
+                : `↩️ Moved “${undone.title}” to the Notion trash`);
         });
 
         return () => {
             off(); // <-- remove listener on unmount
+            offQueue();
+            offCreated();
+            offClipboard();
+            offUndone();
         };
-    }, []);
+    }, [loadParked]);
 
     return (
         <div className={`spotlight-container undraggable theme-${theme}`}>
@@ -124,7 +371,72 @@ function Input() {
                 />
             </div>
 
+            {clipboard && (
+                <div className="spotlight-clipboard undraggable" title={clipboard}>
+                    📋 {clipboard.length > 80 ? `${clipboard.slice(0, 80)}…` : clipboard}
+                    <button type="button" className="spotlight-clipboard-clear" onClick={clearClipboard} aria-label="Detach clipboard">
+                        ×
+                    </button>
+                </div>
+            )}
+
+            {previews.length > 0 && (
+                <div className="spotlight-results undraggable">
+                    {previews.map((p, i) => (
+                        <div key={i} className="spotlight-preview">
+                            <input
+                                className="spotlight-preview-title"
+                                value={p.task.title}
+                                onChange={(e) => editPreview(i, "title", e.target.value)}
+                            />
+                            <input
+                                className="spotlight-preview-date"
+                                placeholder="No date"
+                                value={p.task.date ?? ""}
+                                onChange={(e) => editPreview(i, "date", e.target.value)}
+                            />
+                            {(p.options ?? []).filter((m) => m.outcome === "ambiguous").map((m) => (
+                                <select
+                                    key={m.value}
+                                    className="spotlight-preview-date"
+                                    value={(m.candidates ?? []).some((c) => c.page_id === p.task.project) ? String(p.task.project) : ""}
+                                    onChange={(e) => editPreview(i, "project", e.target.value)}
+                                >
+                                    <option value="" disabled>Which “{m.value}”?</option>
+                                    {(m.candidates ?? []).map((c) => (
+                                        <option key={c.page_id} value={c.page_id}>{c.title}</option>
+                                    ))}
+                                </select>
+                            ))}
+                            <div className="spotlight-preview-meta">
+                                {p.error
+                                    ? `⚠️ ${p.error}`
+                                    : `→ ${p.destination.destination ? `${p.destination.destination} (${p.destination.routed_by}) · ` : ""}${p.destination.name || "Notion"} · ${Object.entries(p.properties).map(([field, prop]) => `${field}: ${prop}`).join(", ")}${(p.options ?? []).map((m) => ` 🏷️ ${optionText(m)}`).join("")}${(p.warnings ?? []).map((w) => ` ⚠️ ${w}`).join("")}`}
+                            </div>
+                        </div>
+                    ))}
+                </div>
+            )}
+
             {resultText && <div className="spotlight-results undraggable">{resultText}</div>}
+            {lastCreated && !resultText && previews.length === 0 && (
+                <div className="spotlight-created undraggable">
+                    ✅ Added “{lastCreated.title}”{lastCreated.destination ? ` to ${lastCreated.destination}` : ""} ·{" "}
+                    <a href={lastCreated.url} onClick={(e) => { e.preventDefault(); Browser.OpenURL(lastCreated.url); }}>
+                        Open in Notion
+                    </a>
+                </div>
+            )}
+            {parked.map((q) => (
+                <div key={q.id} className="spotlight-parked undraggable">
+                    ⏸️ “{q.title}” was not sent: {q.last_error}
+                    <button className="spotlight-parked-action" onClick={() => retryParked(q.id)}>Retry</button>
+                    <button className="spotlight-parked-action" onClick={() => discardParked(q.id)}>Discard</button>
+                </div>
+            ))}
+            {queued > parked.length && (
+                <div className="spotlight-queue undraggable">{queued - parked.length} queued, waiting to reach Notion</div>
+            )}
         </div>
     );
 }
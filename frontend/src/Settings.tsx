@@ -28,12 +28,72 @@ const tabs = [
     {
         id: "notion",
         label: "Notion",
-        description: "Connect databases and pick date fields for syncing.",
+        description: "Connect databases, pick date fields and map task properties.",
     },
 ] as const
 
 type HelpView = "root" | "about" | "acknowledgements" | "resetConfirm"
 
+type PropertyFieldMapping = {
+    field: string
+    property_id: string
+    property?: string
+    compatible: { id: string; name: string; type: string }[]
+    problem?: string
+}
+
+type PropertyDefault = {
+    property_id: string
+    name: string
+    type: string
+    value: string
+    options?: string[]
+    problem?: string
+}
+
+type OptionMatching = {
+    field: string
+    property_id: string
+    property: string
+    type: string
+    options: string[]
+    action: "create" | "drop" | "fallback"
+    fallback?: string
+    problem?: string
+}
+
+type Destination = {
+    name: string
+    data_source_id: string
+    prefixes?: string[]
+    keywords?: string[]
+}
+
+type DestinationStatus = {
+    name: string
+    data_source_id: string
+    data_source?: string
+    date_property?: string
+    problems?: string[]
+}
+
+const splitList = (value: string) =>
+    value
+        .split(",")
+        .map((v) => v.trim())
+        .filter((v) => v !== "")
+
+const mappingFieldLabels: Record<string, string> = {
+    priority: "Priority",
+    tags: "Tags",
+    project: "Project",
+    notes: "Notes",
+    estimate_minutes: "Estimate",
+    recurrence: "Recurrence",
+    people: "People",
+    clipboard: "Clipboard link",
+}
+
 type Acknowledgement = {
     name: string
     description: string
@@ -69,7 +129,15 @@ export default function Settings() {
         use_open_ai: false,
         theme: "light",
         launch_on_startup: false,
+        preview_before_commit: false,
+        undo_window_seconds: 120,
+        history_retention_days: 90,
+        parse_timeout_seconds: 20,
+        notion_timeout_seconds: 15,
+        persist_schema_cache: false,
+        duplicate_action: "warn",
         hotkey: "ctrl+space",
+        clipboard_hotkey: "ctrl+shift+space",
         has_notion_secret: false,
         has_openai_key: false,
         date_property_id: "",
@@ -85,7 +153,7 @@ export default function Settings() {
     const [schemaLoading, setSchemaLoading] = useState(false)
     const [hasMultipleDateProps, setHasMultipleDateProps] = useState(false)
     const [dateValid, setDateValid] = useState(true)
-    const [recordingHotkey, setRecordingHotkey] = useState(false)
+    const [recordingHotkey, setRecordingHotkey] = useState<"hotkey" | "clipboard_hotkey" | null>(null)
     const [openAIKey, setOpenAIKey] = useState("")
     const [activeTab, setActiveTab] = useState<(typeof tabs)[number]["id"]>("general")
     const notionConnectTimeoutRef = useRef<number | null>(null)
@@ -95,6 +163,17 @@ export default function Settings() {
     const [appVersion, setAppVersion] = useState("")
     const [helpError, setHelpError] = useState<string | null>(null)
     const [clearingCache, setClearingCache] = useState(false)
+    // savedDataSourceID is the data source property mappings apply to.
+    const [savedDataSourceID, setSavedDataSourceID] = useState("")
+    const [propertyMappings, setPropertyMappings] = useState<PropertyFieldMapping[]>([])
+    const [propertyDefaults, setPropertyDefaults] = useState<PropertyDefault[]>([])
+    const [optionMatching, setOptionMatching] = useState<OptionMatching[]>([])
+    // mappingDestination is the destination whose mappings and defaults are
+    // shown; "" is the default destination.
+    const [mappingDestination, setMappingDestination] = useState("")
+    const [destinations, setDestinations] = useState<Destination[]>([])
+    const [savedDestinations, setSavedDestinations] = useState<Destination[]>([])
+    const [destinationStatuses, setDestinationStatuses] = useState<DestinationStatus[]>([])
     const helpModalRef = useRef<HTMLDivElement | null>(null)
     const helpMenuItemRefs = useRef<Array<HTMLButtonElement | null>>([])
     const helpLauncherRef = useRef<HTMLButtonElement | null>(null)
@@ -136,10 +215,154 @@ export default function Settings() {
 
     useEffect(() => {
         s.GetSettings()
-            .then((res) => setSettings(res))
+            .then((res) => {
+                setSettings(res)
+                setSavedDataSourceID(res.notion_data_source_id)
+            })
             .catch((err) => setStatus("❌ Failed to load settings: " + err.message))
     }, [])
 
+    const loadDestinations = useCallback(() => {
+        s.GetDestinations()
+            .then((res: Destination[]) => {
+                setDestinations(res ?? [])
+                setSavedDestinations(res ?? [])
+            })
+            .catch((err: any) => setStatus("⚠️ Unable to load destinations: " + (err?.message ?? String(err))))
+    }, [])
+
+    useEffect(() => {
+        loadDestinations()
+    }, [loadDestinations])
+
+    useEffect(() => {
+        if (!settings.has_notion_secret || !savedDataSourceID) {
+            setDestinationStatuses([])
+            return
+        }
+        n.CheckDestinations()
+            .then((statuses: DestinationStatus[]) => setDestinationStatuses(statuses ?? []))
+            .catch(() => setDestinationStatuses([]))
+    }, [settings.has_notion_secret, savedDataSourceID, savedDestinations])
+
+    // A destination removed since it was picked falls back to the default.
+    useEffect(() => {
+        if (mappingDestination !== "" && !savedDestinations.some((d) => d.name === mappingDestination)) {
+            setMappingDestination("")
+        }
+    }, [mappingDestination, savedDestinations])
+
+    const mappingReady =
+        mappingDestination !== ""
+            ? savedDestinations.some((d) => d.name === mappingDestination && d.data_source_id !== "")
+            : savedDataSourceID !== "" && savedDataSourceID === settings.notion_data_source_id
+
+    const loadPropertyMappings = useCallback(() => {
+        n.GetPropertyMappings(mappingDestination)
+            .then((mappings: PropertyFieldMapping[]) => setPropertyMappings(mappings ?? []))
+            .catch((err: any) => {
+                setPropertyMappings([])
+                setStatus("⚠️ Unable to load property mappings: " + (err?.message ?? String(err)))
+            })
+    }, [mappingDestination])
+
+    const loadPropertyDefaults = useCallback(() => {
+        n.GetPropertyDefaults(mappingDestination)
+            .then((defaults: PropertyDefault[]) => setPropertyDefaults(defaults ?? []))
+            .catch((err: any) => {
+                setPropertyDefaults([])
+                setStatus("⚠️ Unable to load default values: " + (err?.message ?? String(err)))
+            })
+    }, [mappingDestination])
+
+    const loadOptionMatching = useCallback(() => {
+        n.GetOptionMatching(mappingDestination)
+            .then((matching: OptionMatching[]) => setOptionMatching(matching ?? []))
+            .catch(() => setOptionMatching([]))
+    }, [mappingDestination])
+
+    useEffect(() => {
+        setPropertyMappings([])
+        setPropertyDefaults([])
+        setOptionMatching([])
+        if (!settings.has_notion_secret || !mappingReady || (mappingDestination === "" && !dataSourceDetail)) {
+            return
+        }
+        loadPropertyMappings()
+        loadPropertyDefaults()
+        loadOptionMatching()
+    }, [
+        settings.has_notion_secret,
+        mappingReady,
+        mappingDestination,
+        dataSourceDetail,
+        loadPropertyMappings,
+        loadPropertyDefaults,
+        loadOptionMatching,
+    ])
+
+    // value is "create", "drop" or "fallback:<option>".
+    const updateOptionMatching = async (propertyID: string, value: string) => {
+        const unmatched: Record<string, { action: string; fallback?: string }> = {}
+        for (const m of optionMatching) {
+            unmatched[m.property_id] = m.problem ? {action: "drop"} : {action: m.action, fallback: m.fallback}
+        }
+        const [action, ...fallback] = value.split(":")
+        unmatched[propertyID] = {action, fallback: fallback.join(":")}
+        try {
+            await n.SaveOptionMatching(mappingDestination, unmatched)
+            loadOptionMatching()
+        } catch (err: any) {
+            setStatus("❌ Failed to save option matching: " + (err?.message ?? String(err)))
+        }
+    }
+
+    const updatePropertyDefault = async (propertyID: string, value: string) => {
+        const defaults: Record<string, string> = {}
+        for (const d of propertyDefaults) {
+            defaults[d.property_id] = d.property_id === propertyID ? value : d.value
+        }
+        // A default whose property is gone cannot be saved again.
+        for (const d of propertyDefaults) {
+            if (!d.name) {
+                delete defaults[d.property_id]
+            }
+        }
+        try {
+            await n.SavePropertyDefaults(mappingDestination, defaults)
+            loadPropertyDefaults()
+        } catch (err: any) {
+            setStatus("❌ Failed to save default value: " + (err?.message ?? String(err)))
+        }
+    }
+
+    const updatePropertyMapping = async (field: string, propertyID: string) => {
+        const mappings: Record<string, string> = {}
+        for (const m of propertyMappings) {
+            mappings[m.field] = m.field === field ? propertyID : m.property_id
+        }
+        try {
+            await n.SavePropertyMappings(mappingDestination, mappings)
+            loadPropertyMappings()
+        } catch (err: any) {
+            setStatus("❌ Failed to save property mapping: " + (err?.message ?? String(err)))
+        }
+    }
+
+    const updateDestination = (index: number, change: Partial<Destination>) => {
+        setDestinations((prev) => prev.map((d, i) => (i === index ? {...d, ...change} : d)))
+    }
+
+    const saveDestinations = async () => {
+        try {
+            await s.SaveDestinations(destinations)
+            loadDestinations()
+            setStatus("✅ Destinations saved.")
+        } catch (err: any) {
+            setStatus("❌ Failed to save destinations: " + (err?.message ?? String(err)))
+        }
+    }
+
     useEffect(() => {
         if (!settings.has_notion_secret) {
             setDataSources([])
@@ -474,7 +697,9 @@ export default function Settings() {
             [name]:
                 type === "checkbox"
                     ? (e.target as HTMLInputElement).checked
-                    : value,
+                    : type === "number"
+                        ? Number(value)
+                        : value,
         }))
     }
 
@@ -520,6 +745,7 @@ export default function Settings() {
             }
 
             await s.UpdateSettingsFromFrontend(settings)
+            setSavedDataSourceID(settings.notion_data_source_id)
             setStatus("✅ Preferences saved.")
         } catch (err: any) {
             setStatus("❌ Failed to save settings: " + (err.message ?? String(err)))
@@ -581,8 +807,8 @@ export default function Settings() {
         }
     }
 
-    const startRecordingHotkey = async () => {
-        setRecordingHotkey(true)
+    const startRecordingHotkey = async (field: "hotkey" | "clipboard_hotkey") => {
+        setRecordingHotkey(field)
         setStatus("⌨️ Waiting for hotkey…")
 
         await PauseHotkey()
@@ -619,9 +845,9 @@ export default function Settings() {
 
             const combo = [...modifiers, ...pressedKeys].join("+")
 
-            setSettings((prev) => ({ ...prev, hotkey: combo }))
+            setSettings((prev) => ({ ...prev, [field]: combo }))
             setStatus(`✅ Hotkey set to ${combo}`)
-            setRecordingHotkey(false)
+            setRecordingHotkey(null)
 
             window.removeEventListener("keydown", downHandler)
             window.removeEventListener("keyup", upHandler)
@@ -743,6 +969,105 @@ s
                         <p>Your capture window is ready right after reboot.</p>
                     </div>
                 </label>
+                <label className="toggle">
+                    <input
+                        type="checkbox"
+                        name="preview_before_commit"
+                        checked={settings.preview_before_commit}
+                        onChange={handleChange}
+                    />
+                    <span className="toggle-track">
+                        <span className="toggle-thumb" />
+                    </span>
+                    <div className="toggle-copy">
+                        <span>Preview tasks before sending</span>
+                        <p>Review and edit the parsed task, then press Enter again to add it to Notion.</p>
+                    </div>
+                </label>
+                <div className="settings-field">
+                    <label className="field-label">Undo window (seconds)</label>
+                    <input
+                        type="number"
+                        name="undo_window_seconds"
+                        min={0}
+                        max={3600}
+                        value={settings.undo_window_seconds}
+                        onChange={handleChange}
+                        className="input-control"
+                    />
+                    <p className="field-helper">How long ⌘Z in the capture window or “Undo Last Task” in the menu bar can still remove a task.</p>
+                </div>
+                <div className="settings-field">
+                    <label className="field-label">Keep capture history (days)</label>
+                    <input
+                        type="number"
+                        name="history_retention_days"
+                        min={1}
+                        max={3650}
+                        value={settings.history_retention_days}
+                        onChange={handleChange}
+                        className="input-control"
+                    />
+                    <p className="field-helper">Press ↑ in the capture window to recall earlier captures.</p>
+                </div>
+                <div className="settings-field">
+                    <label className="field-label">Parsing time limit (seconds)</label>
+                    <input
+                        type="number"
+                        name="parse_timeout_seconds"
+                        min={1}
+                        max={120}
+                        value={settings.parse_timeout_seconds}
+                        onChange={handleChange}
+                        className="input-control"
+                    />
+                    <p className="field-helper">After this long the task is read locally instead of waiting for the model.</p>
+                </div>
+                <div className="settings-field">
+                    <label className="field-label">Notion time limit (seconds)</label>
+                    <input
+                        type="number"
+                        name="notion_timeout_seconds"
+                        min={1}
+                        max={120}
+                        value={settings.notion_timeout_seconds}
+                        onChange={handleChange}
+                        className="input-control"
+                    />
+                    <p className="field-helper">Requests that take longer stay queued and are retried.</p>
+                </div>
+                <label className="toggle">
+                    <input
+                        type="checkbox"
+                        name="persist_schema_cache"
+                        checked={settings.persist_schema_cache}
+                        onChange={handleChange}
+                    />
+                    <span className="toggle-track">
+                        <span className="toggle-thumb" />
+                    </span>
+                    <div className="toggle-copy">
+                        <span>Remember database layouts between launches</span>
+                        <p>Saves a request to Notion on the first capture after a restart.</p>
+                    </div>
+                </label>
+                <div className="settings-field">
+                    <label className="field-label">When a task is already in Notion</label>
+                    <div className="select-wrapper">
+                        <select
+                            name="duplicate_action"
+                            value={settings.duplicate_action}
+                            onChange={handleChange}
+                            className="input-control select-control"
+                        >
+                            <option value="warn">Add it and warn me</option>
+                            <option value="skip">Skip it</option>
+                            <option value="update">Update the existing page</option>
+                            <option value="off">Don’t check</option>
+                        </select>
+                    </div>
+                    <p className="field-helper">Open pages with a similar title and the same or a neighbouring date count as already there.</p>
+                </div>
             </section>
         </>
     )
@@ -969,12 +1294,44 @@ s
                 />
                 <button
                     type="button"
-                    onClick={startRecordingHotkey}
-                    className={`btn btn-secondary ${recordingHotkey ? "btn-recording" : ""}`}
+                    onClick={() => startRecordingHotkey("hotkey")}
+                    className={`btn btn-secondary ${recordingHotkey === "hotkey" ? "btn-recording" : ""}`}
                 >
-                    {recordingHotkey ? "Press keys…" : "Change"}
+                    {recordingHotkey === "hotkey" ? "Press keys…" : "Change"}
                 </button>
             </div>
+
+            <div className="settings-field">
+                <label className="field-label" htmlFor="clipboardHotkeyInput">Capture with clipboard</label>
+                <p className="field-helper">Opens Tasklight with whatever you just copied attached. Links go to the URL property, anything else to the page body.</p>
+                <div className="hotkey-row">
+                    <input
+                        id="clipboardHotkeyInput"
+                        type="text"
+                        name="clipboard_hotkey"
+                        value={settings.clipboard_hotkey || "Off"}
+                        disabled
+                        readOnly
+                        className="input-control input-control--readonly"
+                    />
+                    <button
+                        type="button"
+                        onClick={() => startRecordingHotkey("clipboard_hotkey")}
+                        className={`btn btn-secondary ${recordingHotkey === "clipboard_hotkey" ? "btn-recording" : ""}`}
+                    >
+                        {recordingHotkey === "clipboard_hotkey" ? "Press keys…" : "Change"}
+                    </button>
+                    {settings.clipboard_hotkey && (
+                        <button
+                            type="button"
+                            onClick={() => setSettings((prev) => ({ ...prev, clipboard_hotkey: "" }))}
+                            className="btn btn-ghost"
+                        >
+                            Turn off
+                        </button>
+                    )}
+                </div>
+            </div>
         </section>
     )
 
@@ -1186,6 +1543,260 @@ s
                     <p className="inline-warning">Select a date property before saving.</p>
                 )}
             </section>
+
+            <section className="settings-card">
+                <header className="settings-card-header">
+                    <h2>Destinations</h2>
+                    <p>
+                        Send tasks to other data sources. Start a task with a prefix such as “w:” or mention a
+                        keyword; everything else goes to the data source above.
+                    </p>
+                </header>
+                {destinations.map((d, index) => {
+                    const status = destinationStatuses.find((st) => st.name !== "" && st.name === d.name)
+                    return (
+                        <div className="settings-field" key={index}>
+                            <input
+                                className="input-control"
+                                value={d.name}
+                                placeholder="Name, e.g. Work"
+                                onChange={(e) => updateDestination(index, {name: e.target.value})}
+                            />
+                            <div className="select-wrapper">
+                                <select
+                                    value={d.data_source_id}
+                                    onChange={(e) => updateDestination(index, {data_source_id: e.target.value})}
+                                    className="input-control select-control"
+                                >
+                                    <option value="" disabled>
+                                        Select data source
+                                    </option>
+                                    {dataSources.map((source) => (
+                                        <option key={source.id} value={source.id}>
+                                            {formatDataSourceLabel(source)}
+                                        </option>
+                                    ))}
+                                </select>
+                            </div>
+                            <input
+                                // Remounts when a row above is removed.
+                                key={`${index}-prefixes-${(d.prefixes ?? []).join(",")}`}
+                                className="input-control"
+                                defaultValue={(d.prefixes ?? []).join(", ")}
+                                placeholder="Prefixes, e.g. w, job"
+                                onBlur={(e) => updateDestination(index, {prefixes: splitList(e.target.value)})}
+                            />
+                            <input
+                                // Remounts when a row above is removed.
+                                key={`${index}-keywords-${(d.keywords ?? []).join(",")}`}
+                                className="input-control"
+                                defaultValue={(d.keywords ?? []).join(", ")}
+                                placeholder="Keywords, e.g. standup, invoice"
+                                onBlur={(e) => updateDestination(index, {keywords: splitList(e.target.value)})}
+                            />
+                            <button
+                                type="button"
+                                className="btn btn-ghost"
+                                onClick={() => setDestinations((prev) => prev.filter((_, i) => i !== index))}
+                            >
+                                Remove
+                            </button>
+                            {status?.problems?.map((problem) => (
+                                <p className="inline-warning" key={problem}>
+                                    {problem}
+                                </p>
+                            ))}
+                            {status && !status.problems?.length && status.date_property === "" && (
+                                <p className="field-helper">Tasks go here without a date.</p>
+                            )}
+                        </div>
+                    )
+                })}
+                <div className="settings-field">
+                    <button
+                        type="button"
+                        className="btn btn-secondary"
+                        onClick={() => setDestinations((prev) => [...prev, {name: "", data_source_id: ""}])}
+                    >
+                        Add destination
+                    </button>
+                    <button type="button" className="btn btn-secondary" onClick={saveDestinations}>
+                        Save destinations
+                    </button>
+                </div>
+                <p className="field-helper">
+                    A one-word name works as a prefix too. Keywords match whole words; the first destination listed wins.
+                </p>
+            </section>
+
+            <section className="settings-card">
+                <header className="settings-card-header">
+                    <h2>Property Mapping</h2>
+                    <p>Choose the Notion property that receives each task field.</p>
+                </header>
+                {savedDestinations.length > 0 && (
+                    <div className="settings-field">
+                        <label className="field-label">Destination</label>
+                        <div className="select-wrapper">
+                            <select
+                                value={mappingDestination}
+                                onChange={(e) => setMappingDestination(e.target.value)}
+                                className="input-control select-control"
+                            >
+                                <option value="">Default</option>
+                                {savedDestinations.map((d) => (
+                                    <option key={d.name} value={d.name}>
+                                        {d.name}
+                                    </option>
+                                ))}
+                            </select>
+                        </div>
+                    </div>
+                )}
+                {!mappingReady ? (
+                    <div className="status-chip status-chip--neutral">
+                        Save your data source to map its properties
+                    </div>
+                ) : propertyMappings.length === 0 ? (
+                    <div className="status-chip status-chip--neutral">Loading property mappings…</div>
+                ) : (
+                    propertyMappings.map((mapping) => (
+                        <div className="settings-field" key={mapping.field}>
+                            <label className="field-label">{mappingFieldLabels[mapping.field] ?? mapping.field}</label>
+                            <div className="select-wrapper">
+                                <select
+                                    value={mapping.problem ? "" : mapping.property_id}
+                                    onChange={(e) => updatePropertyMapping(mapping.field, e.target.value)}
+                                    className="input-control select-control"
+                                >
+                                    <option value="">
+                                        {!mapping.problem && mapping.property_id === "" && mapping.property
+                                            ? `Automatic (${mapping.property})`
+                                            : "Automatic"}
+                                    </option>
+                                    <option value="off">Don’t save</option>
+                                    {mapping.compatible.map((prop) => (
+                                        <option key={prop.id} value={prop.id}>
+                                            {prop.name} ({prop.type.replace("_", " ")})
+                                        </option>
+                                    ))}
+                                </select>
+                            </div>
+                            {mapping.problem && <p className="inline-warning">{mapping.problem}</p>}
+                        </div>
+                    ))
+                )}
+                <p className="field-helper">
+                    Mappings follow a property when it is renamed in Notion. Automatic uses a property named after the field.
+                </p>
+            </section>
+
+            {mappingReady && optionMatching.length > 0 && (
+                <section className="settings-card">
+                    <header className="settings-card-header">
+                        <h2>Unknown Options</h2>
+                        <p>
+                            Tags and projects are matched to existing options, so “errand” is saved as “Errands”. A
+                            project relation links the project page with that title. Choose what happens to values
+                            that match nothing.
+                        </p>
+                    </header>
+                    {optionMatching.map((m) => (
+                        <div className="settings-field" key={m.property_id}>
+                            <label className="field-label">
+                                {mappingFieldLabels[m.field] ?? m.field} ({m.property})
+                            </label>
+                            <div className="select-wrapper">
+                                <select
+                                    value={m.problem ? "drop" : m.action === "fallback" ? `fallback:${m.fallback}` : m.action}
+                                    onChange={(e) => updateOptionMatching(m.property_id, e.target.value)}
+                                    className="input-control select-control"
+                                >
+                                    <option value="create">
+                                        {m.type === "relation" ? "Create a project page" : "Create a new option"}
+                                    </option>
+                                    <option value="drop">Leave it out</option>
+                                    {m.options.map((opt) => (
+                                        <option key={opt} value={`fallback:${opt}`}>
+                                            Use “{opt}”
+                                        </option>
+                                    ))}
+                                </select>
+                            </div>
+                            {m.problem && <p className="inline-warning">{m.problem}</p>}
+                        </div>
+                    ))}
+                </section>
+            )}
+
+            {mappingReady && propertyDefaults.length > 0 && (
+                <section className="settings-card">
+                    <header className="settings-card-header">
+                        <h2>Default Values</h2>
+                        <p>
+                            Every new task{mappingDestination ? ` in ${mappingDestination}` : ""} gets these values
+                            unless the task sets the property itself.
+                        </p>
+                    </header>
+                    {propertyDefaults.map((d) => (
+                        <div className="settings-field" key={d.property_id}>
+                            <label className="field-label">{d.name || "Deleted property"}</label>
+                            {!d.name ? (
+                                <div className="status-chip status-chip--negative">{d.value}</div>
+                            ) : (d.type === "select" || d.type === "status") && d.options ? (
+                                <div className="select-wrapper">
+                                    <select
+                                        value={d.problem ? "" : d.value}
+                                        onChange={(e) => updatePropertyDefault(d.property_id, e.target.value)}
+                                        className="input-control select-control"
+                                    >
+                                        <option value="">No default</option>
+                                        {d.options.map((opt) => (
+                                            <option key={opt} value={opt}>
+                                                {opt}
+                                            </option>
+                                        ))}
+                                    </select>
+                                </div>
+                            ) : d.type === "checkbox" ? (
+                                <div className="select-wrapper">
+                                    <select
+                                        value={d.value}
+                                        onChange={(e) => updatePropertyDefault(d.property_id, e.target.value)}
+                                        className="input-control select-control"
+                                    >
+                                        <option value="">No default</option>
+                                        <option value="true">Checked</option>
+                                        <option value="false">Unchecked</option>
+                                    </select>
+                                </div>
+                            ) : (
+                                <input
+                                    className="input-control"
+                                    defaultValue={d.value}
+                                    placeholder={
+                                        d.type === "people"
+                                            ? "@me, or names separated by commas"
+                                            : d.type === "multi_select"
+                                              ? (d.options ?? []).join(", ")
+                                              : d.type === "relation"
+                                                ? "Notion page links"
+                                                : "No default"
+                                    }
+                                    onBlur={(e) => {
+                                        if (e.target.value !== d.value) {
+                                            updatePropertyDefault(d.property_id, e.target.value)
+                                        }
+                                    }}
+                                />
+                            )}
+                            {d.problem && (
+                                <p className="inline-warning">Skipped when creating tasks: {d.problem}</p>
+                            )}
+                        </div>
+                    ))}
+                </section>
+            )}
         </>
     )
 
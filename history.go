@@ -0,0 +1,245 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	c "github.com/imjamesonzeller/tasklight-v3/config"
+	"github.com/imjamesonzeller/tasklight-v3/settingsservice"
+)
+
+const (
+	historyFileName     = "history.jsonl"
+	defaultHistoryLimit = 50
+	maxHistoryLimit     = 200
+)
+
+// HistoryEntry is one captured task. Retries of a queued task update the
+// entry instead of adding another.
+type HistoryEntry struct {
+	ID           string           `json:"id"`
+	Input        string           `json:"input"`
+	Task         *TaskInformation `json:"task,omitempty"`
+	DataSourceID string           `json:"data_source_id,omitempty"`
+	OK           bool             `json:"ok"`
+	Queued       bool             `json:"queued,omitempty"`
+	Category     ErrorCategory    `json:"error_category,omitempty"`
+	Error        string           `json:"error,omitempty"`
+	PageID       string           `json:"page_id,omitempty"`
+	URL          string           `json:"url,omitempty"`
+	SubmittedAt  time.Time        `json:"submitted_at"`
+	UpdatedAt    time.Time        `json:"updated_at"`
+}
+
+// HistoryPage is one page of GetHistory results.
+type HistoryPage struct {
+	Entries []HistoryEntry `json:"entries"`
+	// Total counts every entry matching the query.
+	Total int `json:"total"`
+}
+
+// HistoryService keeps a local record of captured tasks. The store is a JSON
+// Lines file next to the settings: records are appended, a later record
+// replaces an earlier one with the same ID, and the file is rewritten when
+// entries expire.
+type HistoryService struct {
+	settings *settingsservice.SettingsService
+	now      func() time.Time
+	path     string
+
+	mu      sync.Mutex
+	entries []HistoryEntry // oldest first
+	loaded  bool
+}
+
+func NewHistoryService(settings *settingsservice.SettingsService) *HistoryService {
+	return &HistoryService{settings: settings, now: time.Now}
+}
+
+// GetHistory returns captures whose input or title contains every word of
+// query, newest first. An empty query matches everything. Called from frontend
+func (h *HistoryService) GetHistory(query string, limit, offset int) (HistoryPage, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if err := h.load(); err != nil {
+		return HistoryPage{}, err
+	}
+
+	if limit <= 0 {
+		limit = defaultHistoryLimit
+	}
+	limit = min(limit, maxHistoryLimit)
+	offset = max(offset, 0)
+
+	words := strings.Fields(strings.ToLower(query))
+	page := HistoryPage{Entries: []HistoryEntry{}}
+	for i := len(h.entries) - 1; i >= 0; i-- {
+		e := h.entries[i]
+		if !e.matches(words) {
+			continue
+		}
+		if page.Total >= offset && len(page.Entries) < limit {
+			page.Entries = append(page.Entries, e)
+		}
+		page.Total++
+	}
+	return page, nil
+}
+
+func (e HistoryEntry) matches(words []string) bool {
+	text := strings.ToLower(e.Input)
+	if e.Task != nil {
+		text += "\n" + strings.ToLower(e.Task.Title)
+	}
+	for _, w := range words {
+		if !strings.Contains(text, w) {
+			return false
+		}
+	}
+	return true
+}
+
+// Record adds or updates the entry with e.ID.
+func (h *HistoryService) Record(e HistoryEntry) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if err := h.load(); err != nil {
+		return err
+	}
+
+	e.UpdatedAt = h.now()
+	if !h.replace(e) {
+		h.entries = append(h.entries, e)
+	}
+
+	if h.prune() {
+		return h.rewrite()
+	}
+	return h.append(e)
+}
+
+// replace swaps in e for the stored entry with the same ID.
+func (h *HistoryService) replace(e HistoryEntry) bool {
+	for i := len(h.entries) - 1; i >= 0; i-- {
+		if h.entries[i].ID == e.ID {
+			h.entries[i] = e
+			return true
+		}
+	}
+	return false
+}
+
+// prune drops entries older than the retention setting and reports whether
+// any were dropped.
+func (h *HistoryService) prune() bool {
+	cutoff := h.now().Add(-h.retention())
+	kept := h.entries[:0]
+	for _, e := range h.entries {
+		if e.UpdatedAt.After(cutoff) {
+			kept = append(kept, e)
+		}
+	}
+	pruned := len(kept) != len(h.entries)
+	h.entries = kept
+	return pruned
+}
+
+func (h *HistoryService) retention() time.Duration {
+	if c.AppConfig != nil {
+		return c.AppConfig.HistoryRetention()
+	}
+	return settingsservice.ApplicationSettings{}.HistoryRetention()
+}
+
+// ====== Store ======
+
+func (h *HistoryService) load() error {
+	if h.loaded {
+		return nil
+	}
+	if h.path == "" {
+		h.path = h.settings.DataPath(historyFileName)
+	}
+
+	f, err := os.Open(h.path)
+	if errors.Is(err, os.ErrNotExist) {
+		h.loaded = true
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	records := 0
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var e HistoryEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			// A crash can leave the last line half written.
+			log.Printf("History: skipping unreadable record in %s: %v", h.path, err)
+			continue
+		}
+		records++
+		if !h.replace(e) {
+			h.entries = append(h.entries, e)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("read history %s: %w", h.path, err)
+	}
+	h.loaded = true
+
+	// Compact superseded records and expired entries.
+	if h.prune() || records > len(h.entries) {
+		return h.rewrite()
+	}
+	return nil
+}
+
+func (h *HistoryService) append(e HistoryEntry) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(h.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// rewrite replaces the file with the current entries through a temporary
+// file so a crash never leaves it half written.
+func (h *HistoryService) rewrite() error {
+	var b strings.Builder
+	for _, e := range h.entries {
+		line, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		b.Write(line)
+		b.WriteByte('\n')
+	}
+
+	tmp := h.path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(b.String()), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, h.path)
+}
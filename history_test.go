@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	c "github.com/imjamesonzeller/tasklight-v3/config"
+	"github.com/imjamesonzeller/tasklight-v3/settingsservice"
+)
+
+func newTestHistory(t *testing.T, now *time.Time, retentionDays int) *HistoryService {
+	t.Helper()
+
+	originalConfig := c.AppConfig
+	t.Cleanup(func() { c.AppConfig = originalConfig })
+	c.AppConfig = &settingsservice.ApplicationSettings{HistoryRetentionDays: retentionDays}
+
+	h := NewHistoryService(nil)
+	h.now = func() time.Time { return *now }
+	h.path = filepath.Join(t.TempDir(), historyFileName)
+	return h
+}
+
+func reopenHistory(h *HistoryService) *HistoryService {
+	reopened := NewHistoryService(nil)
+	reopened.now = h.now
+	reopened.path = h.path
+	return reopened
+}
+
+func TestHistorySearch(t *testing.T) {
+	now := time.Date(2025, time.October, 15, 9, 0, 0, 0, time.UTC)
+	h := newTestHistory(t, &now, 0)
+
+	for _, e := range []HistoryEntry{
+		{ID: "1", Input: "buy milk tomorrow", Task: &TaskInformation{Title: "Buy milk"}, OK: true},
+		{ID: "2", Input: "call mom about the trip", Task: &TaskInformation{Title: "Call Mom"}},
+		{ID: "3", Input: "pick up almond milk #errands", Task: &TaskInformation{Title: "Pick up almond milk"}, OK: true},
+	} {
+		now = now.Add(time.Minute)
+		if err := h.Record(e); err != nil {
+			t.Fatalf("Record: %v", err)
+		}
+	}
+
+	ids := func(page HistoryPage) string {
+		var out []string
+		for _, e := range page.Entries {
+			out = append(out, e.ID)
+		}
+		return strings.Join(out, ",")
+	}
+
+	tests := []struct {
+		query         string
+		limit, offset int
+		want          string
+		total         int
+	}{
+		{"", 0, 0, "3,2,1", 3},
+		{"MILK", 0, 0, "3,1", 2},
+		{"almond milk", 0, 0, "3", 1},
+		{"call mom", 0, 0, "2", 1},
+		{"milk", 1, 1, "1", 2},
+		{"", 2, 5, "", 3},
+		{"groceries", 0, 0, "", 0},
+	}
+	for _, tt := range tests {
+		page, err := h.GetHistory(tt.query, tt.limit, tt.offset)
+		if err != nil {
+			t.Fatalf("GetHistory(%q): %v", tt.query, err)
+		}
+		if got := ids(page); got != tt.want || page.Total != tt.total {
+			t.Errorf("GetHistory(%q, %d, %d) = %q (total %d), want %q (total %d)", tt.query, tt.limit, tt.offset, got, page.Total, tt.want, tt.total)
+		}
+	}
+}
+
+func TestHistoryUpdatesAndCompacts(t *testing.T) {
+	now := time.Date(2025, time.October, 15, 9, 0, 0, 0, time.UTC)
+	h := newTestHistory(t, &now, 0)
+
+	_ = h.Record(HistoryEntry{ID: "1", Input: "buy milk", Queued: true, Error: "offline"})
+	now = now.Add(time.Minute)
+	_ = h.Record(HistoryEntry{ID: "1", Input: "buy milk", OK: true, URL: "https://www.notion.so/page-1"})
+
+	reopened := reopenHistory(h)
+	page, err := reopened.GetHistory("", 0, 0)
+	if err != nil {
+		t.Fatalf("GetHistory: %v", err)
+	}
+	if page.Total != 1 || !page.Entries[0].OK || page.Entries[0].URL == "" || !page.Entries[0].UpdatedAt.Equal(now) {
+		t.Fatalf("expected the latest record to win, got %+v", page.Entries)
+	}
+
+	data, _ := os.ReadFile(h.path)
+	if lines := strings.Count(string(data), "\n"); lines != 1 {
+		t.Fatalf("expected superseded records to be compacted, got %d lines", lines)
+	}
+}
+
+func TestHistoryRetention(t *testing.T) {
+	now := time.Date(2025, time.October, 1, 9, 0, 0, 0, time.UTC)
+	h := newTestHistory(t, &now, 7)
+
+	_ = h.Record(HistoryEntry{ID: "old", Input: "buy milk"})
+	now = now.Add(6 * 24 * time.Hour)
+	_ = h.Record(HistoryEntry{ID: "new", Input: "call mom"})
+
+	now = now.Add(2 * 24 * time.Hour)
+	page, _ := reopenHistory(h).GetHistory("", 0, 0)
+	if page.Total != 1 || page.Entries[0].ID != "new" {
+		t.Fatalf("expected only entries within the retention window, got %+v", page.Entries)
+	}
+}
+
+func TestHistorySkipsTornRecord(t *testing.T) {
+	now := time.Date(2025, time.October, 15, 9, 0, 0, 0, time.UTC)
+	h := newTestHistory(t, &now, 0)
+	_ = h.Record(HistoryEntry{ID: "1", Input: "buy milk"})
+
+	f, _ := os.OpenFile(h.path, os.O_APPEND|os.O_WRONLY, 0o644)
+	_, _ = f.WriteString(`{"id":"2","inp`)
+	_ = f.Close()
+
+	page, err := reopenHistory(h).GetHistory("", 0, 0)
+	if err != nil || page.Total != 1 {
+		t.Fatalf("expected the torn record to be skipped, got %+v (err %v)", page, err)
+	}
+}
+
+func TestOutboxRecordsHistory(t *testing.T) {
+	now := time.Date(2025, time.October, 15, 9, 0, 0, 0, time.UTC)
+	fail := true
+	o := newTestOutbox(t, &now, func(context.Context, TaskInformation) PageResult {
+		if fail {
+			return PageResult{Category: ErrorNetwork, Error: "offline", DataSourceID: "ds-1"}
+		}
+		return PageResult{PageID: "page-1", URL: "https://www.notion.so/page-1", DataSourceID: "ds-1"}
+	})
+	h := NewHistoryService(nil)
+	h.now = o.now
+	h.path = filepath.Join(t.TempDir(), historyFileName)
+	o.tasks.SetHistory(h)
+
+	entries, _ := o.enqueue([]string{"buy milk #errands"}, nil, "")
+	o.deliver(entries)
+
+	page, _ := h.GetHistory("errands", 0, 0)
+	if page.Total != 1 || !page.Entries[0].Queued || page.Entries[0].Category != ErrorNetwork {
+		t.Fatalf("expected a queued record, got %+v", page.Entries)
+	}
+
+	fail = false
+	now = now.Add(time.Minute)
+	o.deliverDue()
+
+	page, _ = h.GetHistory("buy milk", 0, 0)
+	got := page.Entries[0]
+	if page.Total != 1 || !got.OK || got.URL == "" || got.DataSourceID != "ds-1" || got.Task.Title != "buy milk" || !got.SubmittedAt.Equal(now.Add(-time.Minute)) {
+		t.Fatalf("expected the retry to update the record, got %+v", got)
+	}
+}
@@ -8,20 +8,34 @@ import (
 	"time"
 )
 
+// hotkeyBinding is one global hotkey and the listener that runs its action.
+type hotkeyBinding struct {
+	hotkey *hotkey.Hotkey
+	stop   chan struct{}
+	action func()
+}
+
 type HotkeyService struct {
 	app             *application.App
 	windowService   *WindowService
 	settingsService *settingsservice.SettingsService
+	tasks           *TaskService
 
-	currentHotkey *hotkey.Hotkey
-	listenerStop  chan struct{}
+	// toggle shows or hides the input window; clipboard opens it with the
+	// clipboard attached to the capture.
+	toggle    hotkeyBinding
+	clipboard hotkeyBinding
 }
 
-func NewHotkeyService(windowService *WindowService, settingsService *settingsservice.SettingsService) *HotkeyService {
-	return &HotkeyService{
+func NewHotkeyService(windowService *WindowService, settingsService *settingsservice.SettingsService, tasks *TaskService) *HotkeyService {
+	s := &HotkeyService{
 		windowService:   windowService,
 		settingsService: settingsService,
+		tasks:           tasks,
 	}
+	s.toggle.action = s.toggleWindow
+	s.clipboard.action = s.captureClipboard
+	return s
 }
 
 func (s *HotkeyService) SetApp(app *application.App) {
@@ -40,83 +54,126 @@ func (s *HotkeyService) StartHotkeyListener() {
 	}
 }
 
-// internal: Registers a hotkey and starts its listener goroutine
+// internal: Registers the toggle hotkey and starts its listener goroutine
 func (s *HotkeyService) RegisterHotkey(hk *hotkey.Hotkey) error {
-	if err := hk.Register(); err != nil {
+	if err := s.register(&s.toggle, hk); err != nil {
 		return err
 	}
 
-	s.currentHotkey = hk
-
-	s.startListener()
-
 	fmt.Println("✅ Hotkey registered:", s.settingsService.AppSettings.Hotkey)
 	return nil
 }
 
 func (s *HotkeyService) UpdateHotkey() error {
-	// Stop the listener before unregister the hotkey
+	// Stop the listeners before unregistering the hotkeys
 	// If this wasn't done before unregistering then there was a NASTY race condition where it would
 	// have a phantom .Keydown() for some reason
-	s.stopListener()
+	for _, b := range s.bindings() {
+		s.stopListener(b)
+		if b.hotkey != nil {
+			_ = b.hotkey.Unregister()
+			b.hotkey = nil
+		}
+	}
 
-	if s.currentHotkey != nil {
-		_ = s.currentHotkey.Unregister()
-		s.currentHotkey = nil
+	// Create new hotkeys from saved config
+	settings := s.settingsService.AppSettings
+	if err := s.RegisterHotkey(hotkey.New(settings.Hotkey.Modifiers, settings.Hotkey.Key)); err != nil {
+		return err
 	}
 
-	// Create new hotkey from saved config
-	modifiers := s.settingsService.AppSettings.Hotkey.Modifiers
-	key := s.settingsService.AppSettings.Hotkey.Key
+	clipboard, ok, err := settings.ClipboardHotkeyConfig()
+	if err != nil || !ok {
+		return err
+	}
+	if err := s.register(&s.clipboard, hotkey.New(clipboard.Modifiers, clipboard.Key)); err != nil {
+		return fmt.Errorf("clipboard hotkey: %w", err)
+	}
+	fmt.Println("✅ Clipboard hotkey registered:", settings.ClipboardHotkey)
+	return nil
+}
 
-	newHk := hotkey.New(modifiers, key)
-	return s.RegisterHotkey(newHk)
+func (s *HotkeyService) bindings() []*hotkeyBinding {
+	return []*hotkeyBinding{&s.toggle, &s.clipboard}
 }
 
-// stopListener stops the existing listener goroutine if it exists
-func (s *HotkeyService) stopListener() {
-	if stop := s.listenerStop; stop != nil {
+func (s *HotkeyService) register(b *hotkeyBinding, hk *hotkey.Hotkey) error {
+	if err := hk.Register(); err != nil {
+		return err
+	}
+
+	b.hotkey = hk
+	s.startListener(b)
+	return nil
+}
+
+// stopListener stops the binding's listener goroutine if it exists
+func (s *HotkeyService) stopListener(b *hotkeyBinding) {
+	if stop := b.stop; stop != nil {
 		close(stop)
-		s.listenerStop = nil
+		b.stop = nil
 	}
 }
 
-func (s *HotkeyService) startListener() {
-	hk := s.currentHotkey
+func (s *HotkeyService) startListener(b *hotkeyBinding) {
+	hk := b.hotkey
 	if hk == nil {
 		return
 	}
 
 	stop := make(chan struct{})
-	s.listenerStop = stop
+	b.stop = stop
 	keydown := hk.Keydown()
 
-	go func(stop <-chan struct{}, keydown <-chan hotkey.Event) {
+	go func(stop <-chan struct{}, keydown <-chan hotkey.Event, action func()) {
 		for {
 			select {
 			case <-keydown:
-				s.windowService.ToggleVisibility("main")
-				s.app.EmitEvent("Backend:GlobalHotkeyEvent", time.Now().String())
+				action()
 
 			case <-stop:
 				return
 			}
 		}
-	}(stop, keydown)
+	}(stop, keydown, b.action)
+}
+
+// toggleWindow shows or hides the input window. A plain capture carries no
+// clipboard, so any clipboard still attached is dropped.
+func (s *HotkeyService) toggleWindow() {
+	s.tasks.ClearClipboardContext()
+	s.windowService.ToggleVisibility("main")
+	s.app.EmitEvent("Backend:GlobalHotkeyEvent", time.Now().String())
+}
+
+// captureClipboard attaches the clipboard to the next capture and opens the
+// input window.
+func (s *HotkeyService) captureClipboard() {
+	text, ok := s.app.Clipboard().Text()
+	if !ok {
+		fmt.Println("⚠️ Clipboard could not be read")
+	}
+	s.tasks.attachClipboard(text)
+	s.windowService.Show("main")
+	s.app.EmitEvent("Backend:GlobalHotkeyEvent", time.Now().String())
 }
 
 func (s *HotkeyService) PauseHotkey() {
 	fmt.Println("⏸️ Pausing hotkey listener")
-	s.stopListener()
-	if s.currentHotkey != nil {
-		_ = s.currentHotkey.Unregister()
+	for _, b := range s.bindings() {
+		s.stopListener(b)
+		if b.hotkey != nil {
+			_ = b.hotkey.Unregister()
+		}
 	}
 }
 
 func (s *HotkeyService) ResumeHotkey() {
 	fmt.Println("▶️ Resuming hotkey listener")
-	if s.currentHotkey != nil {
-		_ = s.currentHotkey.Register()
-		s.startListener()
+	for _, b := range s.bindings() {
+		if b.hotkey != nil {
+			_ = b.hotkey.Register()
+			s.startListener(b)
+		}
 	}
 }
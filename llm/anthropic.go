@@ -0,0 +1,131 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const anthropicVersion = "2023-06-01"
+
+// anthropicProvider speaks the Anthropic Messages API, which is also offered
+// by several compatible gateways.
+type anthropicProvider struct {
+	baseURL string
+	model   string
+	apiKey  string
+	client  *http.Client
+}
+
+func newAnthropicProvider(cfg Config) *anthropicProvider {
+	return &anthropicProvider{
+		baseURL: strings.TrimRight(cfg.BaseURL, "/"),
+		model:   cfg.Model,
+		apiKey:  cfg.APIKey,
+		client:  &http.Client{Timeout: cfg.Timeout},
+	}
+}
+
+func (p *anthropicProvider) Name() string {
+	return KindAnthropic
+}
+
+type anthropicMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type anthropicTool struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	InputSchema map[string]any `json:"input_schema"`
+}
+
+type anthropicToolChoice struct {
+	Type string `json:"type"`
+	Name string `json:"name"`
+}
+
+type anthropicRequest struct {
+	Model      string               `json:"model"`
+	MaxTokens  int                  `json:"max_tokens"`
+	Messages   []anthropicMessage   `json:"messages"`
+	Tools      []anthropicTool      `json:"tools,omitempty"`
+	ToolChoice *anthropicToolChoice `json:"tool_choice,omitempty"`
+}
+
+type anthropicResponse struct {
+	Content []struct {
+		Type  string          `json:"type"`
+		Text  string          `json:"text"`
+		Input json.RawMessage `json:"input"`
+	} `json:"content"`
+}
+
+// Complete sends a Messages request. A schema is enforced by forcing the model
+// to call a single tool whose input schema is the requested shape; the tool
+// input is then returned as the JSON reply.
+func (p *anthropicProvider) Complete(ctx context.Context, req Request) (string, error) {
+	payload := anthropicRequest{
+		Model:     p.model,
+		MaxTokens: 512,
+		Messages:  []anthropicMessage{{Role: "user", Content: req.Prompt}},
+	}
+	if req.Schema != nil {
+		payload.Tools = []anthropicTool{{
+			Name:        req.Schema.Name,
+			Description: req.Schema.Description,
+			InputSchema: req.Schema.Definition,
+		}}
+		payload.ToolChoice = &anthropicToolChoice{Type: "tool", Name: req.Schema.Name}
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/v1/messages", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("x-api-key", p.apiKey)
+	httpReq.Header.Set("anthropic-version", anthropicVersion)
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("anthropic %d: %s", resp.StatusCode, string(b))
+	}
+
+	var parsed anthropicResponse
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return "", err
+	}
+
+	var text strings.Builder
+	for _, block := range parsed.Content {
+		switch block.Type {
+		case "tool_use":
+			if len(block.Input) > 0 {
+				return string(block.Input), nil
+			}
+		case "text":
+			text.WriteString(block.Text)
+		}
+	}
+	if text.Len() == 0 {
+		return "", fmt.Errorf("empty AI response")
+	}
+	return text.String(), nil
+}
@@ -0,0 +1,113 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+)
+
+type openAIProvider struct {
+	name       string
+	model      string
+	client     openai.Client
+	structured bool
+}
+
+func newOpenAIProvider(name string, cfg Config) *openAIProvider {
+	opts := []option.RequestOption{
+		option.WithRequestTimeout(cfg.Timeout),
+		option.WithMaxRetries(1),
+	}
+	if cfg.APIKey != "" {
+		opts = append(opts, option.WithAPIKey(cfg.APIKey))
+	}
+	if cfg.BaseURL != "" {
+		opts = append(opts, option.WithBaseURL(withTrailingSlash(cfg.BaseURL)))
+	}
+
+	return &openAIProvider{
+		name:       name,
+		model:      cfg.Model,
+		client:     openai.NewClient(opts...),
+		structured: true,
+	}
+}
+
+// newAzureProvider targets an Azure OpenAI deployment. Azure routes by
+// deployment in the path, authenticates with an Api-Key header and requires an
+// api-version query parameter. Structured output needs 2024-08-01-preview or
+// later, so older API versions fall back to plain JSON prompting.
+func newAzureProvider(cfg Config) *openAIProvider {
+	base := withTrailingSlash(cfg.BaseURL) + "openai/deployments/" + cfg.Model + "/"
+
+	return &openAIProvider{
+		name:       KindAzure,
+		model:      cfg.Model,
+		structured: azureSupportsSchema(cfg.APIVersion),
+		client: openai.NewClient(
+			option.WithBaseURL(base),
+			option.WithQuery("api-version", cfg.APIVersion),
+			option.WithHeader("Api-Key", cfg.APIKey),
+			option.WithHeaderDel("Authorization"),
+			option.WithRequestTimeout(cfg.Timeout),
+			option.WithMaxRetries(1),
+		),
+	}
+}
+
+// azureSupportsSchema reports whether an API version, a date such as
+// "2024-10-21" optionally followed by a suffix such as "-preview", is no
+// older than azureSchemaAPIVersion. Versions that are not dated are assumed
+// too old.
+func azureSupportsSchema(apiVersion string) bool {
+	date := func(version string) (time.Time, error) {
+		version = strings.TrimSpace(version)
+		return time.Parse(time.DateOnly, version[:min(len(version), len(time.DateOnly))])
+	}
+	version, err := date(apiVersion)
+	if err != nil {
+		return false
+	}
+	first, _ := date(azureSchemaAPIVersion)
+	return !version.Before(first)
+}
+
+func (p *openAIProvider) Name() string {
+	return p.name
+}
+
+func (p *openAIProvider) Complete(ctx context.Context, req Request) (string, error) {
+	params := openai.ChatCompletionNewParams{
+		Model:    openai.ChatModel(p.model),
+		Messages: []openai.ChatCompletionMessageParamUnion{openai.UserMessage(req.Prompt)},
+	}
+	if req.Schema != nil && p.structured {
+		params.ResponseFormat = openai.ChatCompletionNewParamsResponseFormatUnion{
+			OfJSONSchema: &openai.ResponseFormatJSONSchemaParam{
+				JSONSchema: openai.ResponseFormatJSONSchemaJSONSchemaParam{
+					Name:        req.Schema.Name,
+					Description: openai.String(req.Schema.Description),
+					Schema:      req.Schema.Definition,
+					Strict:      openai.Bool(true),
+				},
+			},
+		}
+	}
+
+	resp, err := p.client.Chat.Completions.New(ctx, params)
+	if err != nil {
+		return "", err
+	}
+	if len(resp.Choices) == 0 || resp.Choices[0].Message.Content == "" {
+		return "", fmt.Errorf("empty AI response")
+	}
+	return resp.Choices[0].Message.Content, nil
+}
+
+func withTrailingSlash(url string) string {
+	return strings.TrimRight(url, "/") + "/"
+}
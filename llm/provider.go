@@ -0,0 +1,120 @@
+// Package llm wraps the chat-completion backends Tasklight can use to parse
+// task input: OpenAI, Azure OpenAI, Anthropic-compatible APIs and self-hosted
+// OpenAI-compatible servers such as Ollama, vLLM or llama.cpp.
+package llm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Provider kinds as stored in settings.
+const (
+	KindOpenAI           = "openai"
+	KindAzure            = "azure"
+	KindAnthropic        = "anthropic"
+	KindOpenAICompatible = "openai_compatible"
+)
+
+const (
+	DefaultTimeout         = 20 * time.Second
+	defaultOpenAIModel     = "gpt-4o-mini"
+	defaultAnthropicModel  = "claude-3-5-haiku-latest"
+	defaultAnthropicURL    = "https://api.anthropic.com"
+	defaultAzureAPIVersion = "2024-06-01"
+	azureSchemaAPIVersion  = "2024-08-01-preview"
+	defaultLocalURL        = "http://localhost:11434/v1"
+)
+
+// Provider turns a prompt into the model's raw text reply.
+type Provider interface {
+	Name() string
+	Complete(ctx context.Context, req Request) (string, error)
+}
+
+// Request is a single-turn completion. When Schema is set, providers that
+// support structured output constrain the reply to it; others ignore it.
+type Request struct {
+	Prompt string
+	Schema *Schema
+}
+
+// Schema is a named JSON schema describing the expected reply object.
+type Schema struct {
+	Name        string
+	Description string
+	Definition  map[string]any
+}
+
+// Config describes one provider. Empty fields fall back to per-kind defaults.
+type Config struct {
+	Kind       string
+	BaseURL    string
+	Model      string
+	APIKey     string
+	APIVersion string
+	Timeout    time.Duration
+}
+
+// New builds the provider described by cfg.
+func New(cfg Config) (Provider, error) {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+
+	switch strings.ToLower(strings.TrimSpace(cfg.Kind)) {
+	case "", KindOpenAI:
+		if cfg.APIKey == "" {
+			return nil, fmt.Errorf("openai provider requires an API key")
+		}
+		if cfg.Model == "" {
+			cfg.Model = defaultOpenAIModel
+		}
+		return newOpenAIProvider(KindOpenAI, cfg), nil
+
+	case KindAzure:
+		if cfg.BaseURL == "" {
+			return nil, fmt.Errorf("azure provider requires a resource endpoint")
+		}
+		if cfg.Model == "" {
+			return nil, fmt.Errorf("azure provider requires a deployment name as the model")
+		}
+		if cfg.APIKey == "" {
+			return nil, fmt.Errorf("azure provider requires an API key")
+		}
+		if cfg.APIVersion == "" {
+			cfg.APIVersion = defaultAzureAPIVersion
+		}
+		return newAzureProvider(cfg), nil
+
+	case KindOpenAICompatible:
+		if cfg.BaseURL == "" {
+			cfg.BaseURL = defaultLocalURL
+		}
+		if cfg.Model == "" {
+			return nil, fmt.Errorf("openai-compatible provider requires a model name")
+		}
+		return newOpenAIProvider(KindOpenAICompatible, cfg), nil
+
+	case KindAnthropic:
+		if cfg.BaseURL == "" {
+			cfg.BaseURL = defaultAnthropicURL
+		}
+		if cfg.Model == "" {
+			cfg.Model = defaultAnthropicModel
+		}
+		if cfg.APIKey == "" {
+			return nil, fmt.Errorf("anthropic provider requires an API key")
+		}
+		return newAnthropicProvider(cfg), nil
+	}
+
+	return nil, fmt.Errorf("unknown parser provider %q", cfg.Kind)
+}
+
+// RequiresAPIKey reports whether a provider kind cannot run without a key.
+func RequiresAPIKey(kind string) bool {
+	return strings.ToLower(strings.TrimSpace(kind)) != KindOpenAICompatible
+}
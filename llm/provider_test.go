@@ -0,0 +1,343 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const chatCompletionReply = `{
+	"id": "chatcmpl-1",
+	"object": "chat.completion",
+	"created": 1,
+	"model": "test",
+	"choices": [{
+		"index": 0,
+		"finish_reason": "stop",
+		"message": {"role": "assistant", "content": "{\"title\":\"Plan\",\"date\":null}"}
+	}]
+}`
+
+type capturedRequest struct {
+	Path   string
+	Query  string
+	Header http.Header
+	Body   map[string]any
+}
+
+func newStandIn(t *testing.T, reply string, status int) (*httptest.Server, <-chan capturedRequest) {
+	t.Helper()
+
+	requests := make(chan capturedRequest, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, _ := io.ReadAll(r.Body)
+		var body map[string]any
+		_ = json.Unmarshal(raw, &body)
+
+		select {
+		case requests <- capturedRequest{Path: r.URL.Path, Query: r.URL.RawQuery, Header: r.Header.Clone(), Body: body}:
+		default:
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_, _ = io.WriteString(w, reply)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, requests
+}
+
+func TestOpenAIProvider(t *testing.T) {
+	t.Parallel()
+
+	srv, requests := newStandIn(t, chatCompletionReply, http.StatusOK)
+
+	p, err := New(Config{Kind: KindOpenAI, BaseURL: srv.URL + "/v1", APIKey: "sk-test"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got, err := p.Complete(context.Background(), Request{Prompt: "parse this"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != `{"title":"Plan","date":null}` {
+		t.Fatalf("unexpected content: %q", got)
+	}
+
+	req := <-requests
+	if req.Path != "/v1/chat/completions" {
+		t.Fatalf("unexpected path: %s", req.Path)
+	}
+	if req.Header.Get("Authorization") != "Bearer sk-test" {
+		t.Fatalf("unexpected auth header: %q", req.Header.Get("Authorization"))
+	}
+	if req.Body["model"] != defaultOpenAIModel {
+		t.Fatalf("expected default model, got %v", req.Body["model"])
+	}
+}
+
+func TestOpenAICompatibleProviderWithoutKey(t *testing.T) {
+	t.Parallel()
+
+	srv, requests := newStandIn(t, chatCompletionReply, http.StatusOK)
+
+	p, err := New(Config{Kind: KindOpenAICompatible, BaseURL: srv.URL + "/v1/", Model: "llama3.1"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p.Name() != KindOpenAICompatible {
+		t.Fatalf("unexpected name: %s", p.Name())
+	}
+
+	if _, err := p.Complete(context.Background(), Request{Prompt: "parse this"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	req := <-requests
+	if req.Path != "/v1/chat/completions" {
+		t.Fatalf("unexpected path: %s", req.Path)
+	}
+	if req.Body["model"] != "llama3.1" {
+		t.Fatalf("unexpected model: %v", req.Body["model"])
+	}
+}
+
+func TestAzureProvider(t *testing.T) {
+	t.Parallel()
+
+	srv, requests := newStandIn(t, chatCompletionReply, http.StatusOK)
+
+	p, err := New(Config{Kind: KindAzure, BaseURL: srv.URL, Model: "tasklight-mini", APIKey: "azure-key"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := p.Complete(context.Background(), Request{Prompt: "parse this"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	req := <-requests
+	if req.Path != "/openai/deployments/tasklight-mini/chat/completions" {
+		t.Fatalf("unexpected path: %s", req.Path)
+	}
+	if req.Query != "api-version="+defaultAzureAPIVersion {
+		t.Fatalf("unexpected query: %s", req.Query)
+	}
+	if req.Header.Get("Api-Key") != "azure-key" {
+		t.Fatalf("unexpected api key header: %q", req.Header.Get("Api-Key"))
+	}
+	if req.Header.Get("Authorization") != "" {
+		t.Fatalf("azure requests must not carry a bearer token")
+	}
+}
+
+func TestAnthropicProvider(t *testing.T) {
+	t.Parallel()
+
+	reply := `{"content":[{"type":"text","text":"{\"title\":\"Plan\","},{"type":"text","text":"\"date\":null}"}]}`
+	srv, requests := newStandIn(t, reply, http.StatusOK)
+
+	p, err := New(Config{Kind: KindAnthropic, BaseURL: srv.URL, APIKey: "ant-key", Model: "claude-test"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got, err := p.Complete(context.Background(), Request{Prompt: "parse this"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != `{"title":"Plan","date":null}` {
+		t.Fatalf("unexpected content: %q", got)
+	}
+
+	req := <-requests
+	if req.Path != "/v1/messages" {
+		t.Fatalf("unexpected path: %s", req.Path)
+	}
+	if req.Header.Get("x-api-key") != "ant-key" || req.Header.Get("anthropic-version") != anthropicVersion {
+		t.Fatalf("unexpected headers: %v", req.Header)
+	}
+	if req.Body["model"] != "claude-test" {
+		t.Fatalf("unexpected model: %v", req.Body["model"])
+	}
+}
+
+var testSchema = &Schema{
+	Name: "task",
+	Definition: map[string]any{
+		"type":       "object",
+		"properties": map[string]any{"title": map[string]any{"type": "string"}},
+		"required":   []string{"title"},
+	},
+}
+
+func TestOpenAIProviderRequestsSchema(t *testing.T) {
+	t.Parallel()
+
+	srv, requests := newStandIn(t, chatCompletionReply, http.StatusOK)
+
+	p, err := New(Config{Kind: KindOpenAI, BaseURL: srv.URL, APIKey: "sk-test"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := p.Complete(context.Background(), Request{Prompt: "parse this", Schema: testSchema}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	req := <-requests
+	format, ok := req.Body["response_format"].(map[string]any)
+	if !ok || format["type"] != "json_schema" {
+		t.Fatalf("expected json_schema response format, got %v", req.Body["response_format"])
+	}
+	schema, _ := format["json_schema"].(map[string]any)
+	if schema["name"] != "task" || schema["strict"] != true {
+		t.Fatalf("unexpected json_schema payload: %v", schema)
+	}
+}
+
+func TestAzureProviderSchemaDependsOnAPIVersion(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		version    string
+		wantFormat bool
+	}{
+		{version: "", wantFormat: false},
+		{version: "2024-10-21", wantFormat: true},
+	} {
+		srv, requests := newStandIn(t, chatCompletionReply, http.StatusOK)
+
+		p, err := New(Config{Kind: KindAzure, BaseURL: srv.URL, Model: "dep", APIKey: "k", APIVersion: tc.version})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := p.Complete(context.Background(), Request{Prompt: "parse this", Schema: testSchema}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		req := <-requests
+		if _, got := req.Body["response_format"]; got != tc.wantFormat {
+			t.Fatalf("api version %q: response_format present = %v, want %v", tc.version, got, tc.wantFormat)
+		}
+	}
+}
+
+func TestAzureSupportsSchema(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		version string
+		want    bool
+	}{
+		{"2024-08-01-preview", true},
+		{"2024-08-01", true},
+		{"2024-10-21", true},
+		{"2025-01-01-preview", true},
+		{"2024-07-01-preview", false},
+		{"2024-06-01", false},
+		{"2024-6-1", false},
+		{"latest", false},
+		{"", false},
+	} {
+		if got := azureSupportsSchema(tc.version); got != tc.want {
+			t.Errorf("azureSupportsSchema(%q) = %v, want %v", tc.version, got, tc.want)
+		}
+	}
+}
+
+func TestAnthropicProviderForcesSchemaTool(t *testing.T) {
+	t.Parallel()
+
+	reply := `{"content":[{"type":"tool_use","name":"task","input":{"title":"Plan","date":null}}]}`
+	srv, requests := newStandIn(t, reply, http.StatusOK)
+
+	p, err := New(Config{Kind: KindAnthropic, BaseURL: srv.URL, APIKey: "ant-key"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got, err := p.Complete(context.Background(), Request{Prompt: "parse this", Schema: testSchema})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != `{"title":"Plan","date":null}` {
+		t.Fatalf("unexpected content: %q", got)
+	}
+
+	req := <-requests
+	choice, _ := req.Body["tool_choice"].(map[string]any)
+	if choice["type"] != "tool" || choice["name"] != "task" {
+		t.Fatalf("unexpected tool_choice: %v", req.Body["tool_choice"])
+	}
+	tools, _ := req.Body["tools"].([]any)
+	if len(tools) != 1 {
+		t.Fatalf("expected one tool, got %v", req.Body["tools"])
+	}
+}
+
+func TestAnthropicProviderError(t *testing.T) {
+	t.Parallel()
+
+	srv, _ := newStandIn(t, `{"error":{"type":"authentication_error"}}`, http.StatusUnauthorized)
+
+	p, err := New(Config{Kind: KindAnthropic, BaseURL: srv.URL, APIKey: "bad"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := p.Complete(context.Background(), Request{Prompt: "parse this"}); err == nil {
+		t.Fatal("expected error for unauthorized response")
+	}
+}
+
+func TestProviderTimeout(t *testing.T) {
+	t.Parallel()
+
+	block := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-block
+	}))
+	t.Cleanup(func() {
+		close(block)
+		srv.Close()
+	})
+
+	p, err := New(Config{Kind: KindAnthropic, BaseURL: srv.URL, APIKey: "key", Timeout: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	start := time.Now()
+	if _, err := p.Complete(context.Background(), Request{Prompt: "parse this"}); err == nil {
+		t.Fatal("expected timeout error")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("timeout not honoured, took %v", elapsed)
+	}
+}
+
+func TestNewValidatesConfig(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		cfg  Config
+	}{
+		{name: "openai without key", cfg: Config{Kind: KindOpenAI}},
+		{name: "azure without endpoint", cfg: Config{Kind: KindAzure, Model: "dep", APIKey: "k"}},
+		{name: "azure without deployment", cfg: Config{Kind: KindAzure, BaseURL: "https://x", APIKey: "k"}},
+		{name: "compatible without model", cfg: Config{Kind: KindOpenAICompatible}},
+		{name: "anthropic without key", cfg: Config{Kind: KindAnthropic}},
+		{name: "unknown kind", cfg: Config{Kind: "carrier-pigeon"}},
+	}
+
+	for _, tc := range tests {
+		if _, err := New(tc.cfg); err == nil {
+			t.Errorf("%s: expected error", tc.name)
+		}
+	}
+}
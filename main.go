@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"embed"
 	_ "embed"
 	"errors"
@@ -35,9 +36,17 @@ func main() {
 	windowService := NewWindowService()
 	startupService := startupservice.NewStartupService()
 	settingsService := settingsservice.NewSettingsService(startupService)
+	schemaCache := NewSchemaCacheService(settingsService)
 	taskService := NewTaskService(windowService, settingsService)
-	hotkeyService := NewHotkeyService(windowService, settingsService)
-	notionService := NewNotionService(settingsService)
+	taskService.SetSchemaCache(schemaCache)
+	hotkeyService := NewHotkeyService(windowService, settingsService, taskService)
+	notionService := NewNotionService(settingsService, schemaCache)
+	recurrenceService := NewRecurrenceService(taskService, settingsService)
+	taskService.SetRecurrenceService(recurrenceService)
+	outboxService := NewOutboxService(taskService, settingsService)
+	taskService.SetOutbox(outboxService)
+	historyService := NewHistoryService(settingsService)
+	taskService.SetHistory(historyService)
 
 	// Create a new Wails application by providing the necessary options.
 	// Variables 'Name' and 'Description' are for application metadata.
@@ -54,6 +63,10 @@ func main() {
 			application.NewService(settingsService),
 			application.NewService(notionService),
 			application.NewService(startupService),
+			application.NewService(recurrenceService),
+			application.NewService(outboxService),
+			application.NewService(historyService),
+			application.NewService(schemaCache),
 		},
 		Assets: application.AssetOptions{
 			Handler: application.AssetFileServerFS(assets),
@@ -71,6 +84,8 @@ func main() {
 	hotkeyService.SetApp(app)
 	taskService.SetApp(app)
 	settingsService.SetApp(app)
+	outboxService.SetApp(app)
+	schemaCache.SetApp(app)
 
 	// Run Hotkey Service in go-func
 	go func() {
@@ -122,8 +137,11 @@ func main() {
 	})
 
 	app.OnApplicationEvent(events.Common.ApplicationStarted, func(e *application.ApplicationEvent) {
-		OnStartup(windowService, settingsService, notionService)
+		OnStartup(windowService, settingsService, notionService, recurrenceService, outboxService)
 	})
+	app.OnShutdown(taskService.Shutdown)
+	app.OnShutdown(recurrenceService.Stop)
+	app.OnShutdown(outboxService.Stop)
 
 	// Register settings window factory
 	windowService.RegisterWindow("settings", func() *application.WebviewWindow {
@@ -139,7 +157,11 @@ func main() {
 	})
 
 	// Creation of Tray Menu
-	tray.Setup(app, windowService, trayIcon)
+	tray.Setup(app, windowService, func() {
+		if _, err := taskService.UndoLast(); err != nil {
+			log.Println("Undo:", err)
+		}
+	}, trayIcon)
 
 	// Run the application. This blocks until the application has been exited.
 	err := app.Run()
@@ -150,12 +172,14 @@ func main() {
 	}
 }
 
-func OnStartup(ws *WindowService, ss *settingsservice.SettingsService, ns *NotionService) {
+func OnStartup(ws *WindowService, ss *settingsservice.SettingsService, ns *NotionService, rs *RecurrenceService, ob *OutboxService) {
 	ws.Show("main")
 	ss.LoadSettings()
 	config.Init(&ss.AppSettings)
+	rs.Start()
+	ob.Start()
 
-	currentUserId, err := ns.GetNotionWorkspaceId()
+	currentUserId, err := ns.GetNotionWorkspaceId(context.Background())
 	if err != nil {
 		if errors.Is(err, ErrNotionTokenMissing) {
 			// Missing or invalid Notion credentials; surface the settings window once.
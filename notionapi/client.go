@@ -2,6 +2,7 @@ package notionapi
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -10,7 +11,17 @@ import (
 
 const Version = "2025-09-03"
 
-func NewJSONRequest(method, url, token string, payload any) (*http.Request, error) {
+// BaseURL is the root of the Notion API. Tests point it at a local server.
+var BaseURL = "https://api.notion.com/v1"
+
+// URL returns the API endpoint for path, e.g. URL("/pages").
+func URL(path string) string {
+	return BaseURL + path
+}
+
+// NewJSONRequest builds a request carrying payload as JSON. The request is
+// bound to ctx, so its deadline and cancellation apply to the whole call.
+func NewJSONRequest(ctx context.Context, method, url, token string, payload any) (*http.Request, error) {
 	var body io.Reader
 
 	if payload != nil {
@@ -21,7 +32,7 @@ func NewJSONRequest(method, url, token string, payload any) (*http.Request, erro
 		body = bytes.NewBuffer(raw)
 	}
 
-	req, err := http.NewRequest(method, url, body)
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
 	if err != nil {
 		return nil, err
 	}
@@ -35,8 +46,8 @@ func NewJSONRequest(method, url, token string, payload any) (*http.Request, erro
 	return req, nil
 }
 
-func NewRequest(method, url, token string) (*http.Request, error) {
-	req, err := http.NewRequest(method, url, nil)
+func NewRequest(ctx context.Context, method, url, token string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -45,6 +56,18 @@ func NewRequest(method, url, token string) (*http.Request, error) {
 	return req, nil
 }
 
+// APIError is a non-2xx response other than 401 and 403. Code is Notion's
+// error code, e.g. "validation_error" or "object_not_found".
+type APIError struct {
+	Status int
+	Code   string
+	Body   string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("notion api error: status %d, body: %s", e.Status, e.Body)
+}
+
 func ParseResponse(resp *http.Response, target any, tokenMissingErr error) error {
 	defer resp.Body.Close()
 
@@ -55,7 +78,14 @@ func ParseResponse(resp *http.Response, target any, tokenMissingErr error) error
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("notion api error: status %d, body: %s", resp.StatusCode, body)
+		apiErr := &APIError{Status: resp.StatusCode, Body: string(body)}
+		var parsed struct {
+			Code string `json:"code"`
+		}
+		if json.Unmarshal(body, &parsed) == nil {
+			apiErr.Code = parsed.Code
+		}
+		return apiErr
 	}
 
 	if target == nil {
@@ -267,7 +267,7 @@ func completeHandoff(apiBase, handoff, clientHeader string) (*NotionOAuthRespons
 }
 
 func fetchNotionBotID(accessToken string) (string, error) {
-	req, err := notionapi.NewRequest(http.MethodGet, "https://api.notion.com/v1/users/me", accessToken)
+	req, err := notionapi.NewRequest(context.Background(), http.MethodGet, notionapi.URL("/users/me"), accessToken)
 	if err != nil {
 		return "", err
 	}
@@ -0,0 +1,364 @@
+// Package notionblocks converts task notes written in a small markdown subset
+// into Notion block children and annotated rich text. Supported are
+// paragraphs, "- [ ]" and "- [x]" checklists, "-" and "*" bullets, "1."
+// numbered lists, fenced code, [links](https://…), **bold**, *italic* or
+// _italic_ and `code`. Anything else is kept as plain text.
+//
+// Text longer than Notion's per-object limit is split across several rich
+// text objects, and blocks holding more objects than Notion accepts are split
+// into several blocks of the same type.
+package notionblocks
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// MaxTextLength is the most characters Notion accepts in one rich text
+	// object.
+	MaxTextLength = 2000
+	// MaxRichText is the most rich text objects Notion accepts in one block.
+	MaxRichText = 100
+	// MaxChildren is the most blocks Notion accepts in one request.
+	MaxChildren = 100
+)
+
+// Block is a Notion block object ready to be sent as a page child.
+type Block = map[string]any
+
+// RichText is a Notion rich text object.
+type RichText = map[string]any
+
+var (
+	checklistRe = regexp.MustCompile(`^[-*]\s+\[( |x|X)\]\s*(.*)$`)
+	bulletRe    = regexp.MustCompile(`^[-*]\s+(.*)$`)
+	numberedRe  = regexp.MustCompile(`^\d+[.)]\s+(.*)$`)
+	// inlineItemRe finds further checklist items on one line, as typed in the
+	// single-line input: "- [ ] passport - [ ] charger".
+	inlineItemRe = regexp.MustCompile(`\s+[-*]\s+\[(?: |x|X)\]\s`)
+)
+
+// FromMarkdown converts text into page blocks.
+func FromMarkdown(text string) []Block {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+
+	var blocks []Block
+	var paragraph []string
+	flush := func() {
+		if len(paragraph) > 0 {
+			blocks = append(blocks, textBlocks("paragraph", Inline(strings.Join(paragraph, "\n")), nil)...)
+			paragraph = nil
+		}
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+
+		if strings.HasPrefix(line, "```") {
+			flush()
+			language := strings.TrimSpace(strings.TrimPrefix(line, "```"))
+			var code []string
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), "```"); i++ {
+				code = append(code, lines[i])
+			}
+			blocks = append(blocks, textBlocks("code", Plain(strings.Join(code, "\n")), map[string]any{"language": codeLanguage(language)})...)
+			continue
+		}
+
+		switch {
+		case line == "":
+			flush()
+		case checklistRe.MatchString(line):
+			flush()
+			for _, item := range splitInlineItems(line) {
+				m := checklistRe.FindStringSubmatch(item)
+				blocks = append(blocks, textBlocks("to_do", Inline(m[2]), map[string]any{"checked": m[1] != " "})...)
+			}
+		case bulletRe.MatchString(line):
+			flush()
+			blocks = append(blocks, textBlocks("bulleted_list_item", Inline(bulletRe.FindStringSubmatch(line)[1]), nil)...)
+		case numberedRe.MatchString(line):
+			flush()
+			blocks = append(blocks, textBlocks("numbered_list_item", Inline(numberedRe.FindStringSubmatch(line)[1]), nil)...)
+		default:
+			paragraph = append(paragraph, line)
+		}
+	}
+	flush()
+	return blocks
+}
+
+// IsListItem reports whether line starts a checklist item, bullet or
+// numbered list item.
+func IsListItem(line string) bool {
+	line = strings.TrimSpace(line)
+	return checklistRe.MatchString(line) || bulletRe.MatchString(line) || numberedRe.MatchString(line)
+}
+
+// ListItemText returns the text of a list item line without its marker, or
+// the trimmed line when it is not a list item.
+func ListItemText(line string) string {
+	line = strings.TrimSpace(line)
+	if m := checklistRe.FindStringSubmatch(line); m != nil {
+		return m[2]
+	}
+	for _, re := range []*regexp.Regexp{bulletRe, numberedRe} {
+		if m := re.FindStringSubmatch(line); m != nil {
+			return m[1]
+		}
+	}
+	return line
+}
+
+// IsFence reports whether line opens or closes fenced code.
+func IsFence(line string) bool {
+	return strings.HasPrefix(strings.TrimSpace(line), "```")
+}
+
+// CutInlineItems splits a line typed with checklist items after its text,
+// "pack - [ ] passport - [ ] charger", into the text and the items. It
+// reports false when the line holds no such items.
+func CutInlineItems(line string) (before, items string, found bool) {
+	loc := inlineItemRe.FindStringIndex(line)
+	if loc == nil {
+		return line, "", false
+	}
+	return strings.TrimSpace(line[:loc[0]]), strings.TrimSpace(line[loc[0]:]), true
+}
+
+// IsInline reports whether text converts to a single paragraph, so it fits a
+// rich text property without losing structure.
+func IsInline(text string) bool {
+	blocks := FromMarkdown(text)
+	return len(blocks) == 1 && blocks[0]["type"] == "paragraph"
+}
+
+// Paragraphs turns text into plain paragraph blocks, one per paragraph,
+// without reading any markdown.
+func Paragraphs(text string) []Block {
+	var blocks []Block
+	for _, p := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n\n") {
+		if p = strings.TrimSpace(p); p != "" {
+			blocks = append(blocks, textBlocks("paragraph", Plain(p), nil)...)
+		}
+	}
+	return blocks
+}
+
+// Plain returns text as unannotated rich text.
+func Plain(text string) []RichText {
+	return span{text: text}.richText()
+}
+
+// Link returns text as rich text linking to url.
+func Link(text, url string) []RichText {
+	return span{text: text, link: url}.richText()
+}
+
+// Inline converts the inline markdown of text into annotated rich text.
+func Inline(text string) []RichText {
+	var out []RichText
+	for _, s := range parseInline(text) {
+		out = append(out, s.richText()...)
+	}
+	return out
+}
+
+// span is a run of text with one set of annotations.
+type span struct {
+	text               string
+	bold, italic, code bool
+	link               string
+}
+
+// richText splits the span at MaxTextLength.
+func (s span) richText() []RichText {
+	if s.text == "" {
+		return nil
+	}
+	var out []RichText
+	for _, chunk := range chunk(s.text, MaxTextLength) {
+		content := map[string]any{"content": chunk}
+		if s.link != "" {
+			content["link"] = map[string]any{"url": s.link}
+		}
+		rt := RichText{"type": "text", "text": content}
+		if s.bold || s.italic || s.code {
+			rt["annotations"] = map[string]any{"bold": s.bold, "italic": s.italic, "code": s.code}
+		}
+		out = append(out, rt)
+	}
+	return out
+}
+
+// parseInline reads bold, italic, code and links. A marker without a
+// matching closing marker is kept as text.
+func parseInline(text string) []span {
+	var spans []span
+	var buf strings.Builder
+	var bold, italic bool
+	italicMarker := ""
+
+	emit := func() {
+		if buf.Len() > 0 {
+			spans = append(spans, span{text: buf.String(), bold: bold, italic: italic})
+			buf.Reset()
+		}
+	}
+
+	for i := 0; i < len(text); {
+		rest := text[i:]
+		switch {
+		case rest[0] == '`':
+			if end := strings.IndexByte(rest[1:], '`'); end > 0 {
+				emit()
+				spans = append(spans, span{text: rest[1 : end+1], bold: bold, italic: italic, code: true})
+				i += end + 2
+				continue
+			}
+
+		case rest[0] == '[':
+			if label, url, n, ok := parseLink(rest); ok {
+				emit()
+				spans = append(spans, span{text: label, bold: bold, italic: italic, link: url})
+				i += n
+				continue
+			}
+
+		case strings.HasPrefix(rest, "**"):
+			if bold || strings.Contains(rest[2:], "**") {
+				emit()
+				bold = !bold
+				i += 2
+				continue
+			}
+
+		case rest[0] == '*' || rest[0] == '_':
+			marker := rest[:1]
+			if italic && marker == italicMarker && (marker == "*" || !wordAfter(text, i+1)) {
+				emit()
+				italic, italicMarker = false, ""
+				i++
+				continue
+			}
+			if !italic && (marker == "*" || !wordBefore(text, i)) && closes(rest[1:], marker) {
+				emit()
+				italic, italicMarker = true, marker
+				i++
+				continue
+			}
+		}
+
+		r, size := utf8.DecodeRuneInString(rest)
+		buf.WriteRune(r)
+		i += size
+	}
+	emit()
+	return spans
+}
+
+// parseLink reads "[label](url)" at the start of s.
+func parseLink(s string) (label, url string, n int, ok bool) {
+	closeLabel := strings.Index(s, "](")
+	if closeLabel < 1 {
+		return "", "", 0, false
+	}
+	closeURL := strings.IndexByte(s[closeLabel+2:], ')')
+	if closeURL < 1 {
+		return "", "", 0, false
+	}
+	url = s[closeLabel+2 : closeLabel+2+closeURL]
+	if strings.ContainsAny(url, " \t\n") || !(strings.HasPrefix(url, "http://") || strings.HasPrefix(url, "https://") || strings.HasPrefix(url, "mailto:")) {
+		return "", "", 0, false
+	}
+	return s[1:closeLabel], url, closeLabel + 3 + closeURL, true
+}
+
+// closes reports whether marker appears later in s where it can close an
+// italic run.
+func closes(s, marker string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] != marker[0] || (marker == "*" && strings.HasPrefix(s[i:], "**")) {
+			continue
+		}
+		if i > 0 && (marker == "*" || !wordAfter(s, i+1)) {
+			return true
+		}
+	}
+	return false
+}
+
+func wordBefore(s string, i int) bool {
+	r, _ := utf8.DecodeLastRuneInString(s[:i])
+	return i > 0 && (unicode.IsLetter(r) || unicode.IsDigit(r))
+}
+
+func wordAfter(s string, i int) bool {
+	r, _ := utf8.DecodeRuneInString(s[i:])
+	return i < len(s) && (unicode.IsLetter(r) || unicode.IsDigit(r))
+}
+
+// textBlocks builds blocks of typ holding richText, starting a new block
+// whenever one would hold more than MaxRichText objects.
+func textBlocks(typ string, richText []RichText, extra map[string]any) []Block {
+	var blocks []Block
+	for len(blocks) == 0 || len(richText) > 0 {
+		n := min(len(richText), MaxRichText)
+		// Notion rejects a null rich_text, so an empty block gets an empty list.
+		body := map[string]any{"rich_text": append([]RichText{}, richText[:n]...)}
+		for k, v := range extra {
+			body[k] = v
+		}
+		blocks = append(blocks, Block{"object": "block", "type": typ, typ: body})
+		richText = richText[n:]
+	}
+	return blocks
+}
+
+// splitInlineItems splits a line holding several checklist items.
+func splitInlineItems(line string) []string {
+	var items []string
+	for {
+		loc := inlineItemRe.FindStringIndex(line[1:])
+		if loc == nil {
+			return append(items, line)
+		}
+		items = append(items, strings.TrimSpace(line[:loc[0]+1]))
+		line = strings.TrimSpace(line[loc[0]+1:])
+	}
+}
+
+// codeLanguage maps a fence's info string to a Notion code language.
+func codeLanguage(info string) string {
+	switch strings.ToLower(info) {
+	case "":
+		return "plain text"
+	case "js":
+		return "javascript"
+	case "ts":
+		return "typescript"
+	case "py":
+		return "python"
+	case "sh", "bash", "zsh":
+		return "shell"
+	case "go", "golang":
+		return "go"
+	case "json", "sql", "yaml", "html", "css", "java", "rust", "ruby", "swift", "kotlin", "markdown", "javascript", "typescript", "python", "shell":
+		return strings.ToLower(info)
+	}
+	return "plain text"
+}
+
+// chunk splits s into pieces of at most limit runes.
+func chunk(s string, limit int) []string {
+	runes := []rune(s)
+	var chunks []string
+	for len(runes) > limit {
+		chunks = append(chunks, string(runes[:limit]))
+		runes = runes[limit:]
+	}
+	return append(chunks, string(runes))
+}
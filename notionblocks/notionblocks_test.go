@@ -0,0 +1,138 @@
+package notionblocks
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// texts returns the content of each rich text object in a block.
+func texts(b Block) []string {
+	var out []string
+	for _, rt := range b[b["type"].(string)].(map[string]any)["rich_text"].([]RichText) {
+		out = append(out, rt["text"].(map[string]any)["content"].(string))
+	}
+	return out
+}
+
+func TestFromMarkdown(t *testing.T) {
+	t.Parallel()
+
+	blocks := FromMarkdown("Packing list for **Berlin**\nsee notes\n\n- [ ] passport\n- [x] charger\n- socks\n1. check in\n2) board\n\n```sh\nls -la\n```")
+
+	var types []string
+	for _, b := range blocks {
+		types = append(types, b["type"].(string))
+	}
+	if strings.Join(types, ",") != "paragraph,to_do,to_do,bulleted_list_item,numbered_list_item,numbered_list_item,code" {
+		t.Fatalf("unexpected blocks: %v", types)
+	}
+	if got := strings.Join(texts(blocks[0]), "|"); got != "Packing list for |Berlin|\nsee notes" {
+		t.Fatalf("unexpected paragraph: %q", got)
+	}
+	if blocks[1]["to_do"].(map[string]any)["checked"] != false || blocks[2]["to_do"].(map[string]any)["checked"] != true {
+		t.Fatalf("unexpected checklist: %v", blocks[1:3])
+	}
+	code := blocks[len(blocks)-1]["code"].(map[string]any)
+	if code["language"] != "shell" || texts(blocks[len(blocks)-1])[0] != "ls -la" {
+		t.Fatalf("unexpected code block: %v", code)
+	}
+}
+
+func TestFromMarkdownSplitsInlineChecklist(t *testing.T) {
+	t.Parallel()
+
+	blocks := FromMarkdown("- [ ] passport - [x] charger - [ ] socks - two pairs")
+	if len(blocks) != 3 {
+		t.Fatalf("expected three items, got %d", len(blocks))
+	}
+	if got := texts(blocks[2])[0]; got != "socks - two pairs" {
+		t.Fatalf("unexpected last item: %q", got)
+	}
+	if blocks[1]["to_do"].(map[string]any)["checked"] != true {
+		t.Fatal("expected the second item to be checked")
+	}
+}
+
+func TestCutInlineItems(t *testing.T) {
+	t.Parallel()
+
+	before, items, found := CutInlineItems("pack for Berlin - [ ] passport - [x] charger")
+	if !found || before != "pack for Berlin" || items != "- [ ] passport - [x] charger" {
+		t.Fatalf("unexpected cut: %q, %q, %v", before, items, found)
+	}
+	if before, _, found := CutInlineItems("pick up keys - then the car"); found || before != "pick up keys - then the car" {
+		t.Fatalf("a line without items must stay whole, got %q, %v", before, found)
+	}
+}
+
+func TestListItemText(t *testing.T) {
+	t.Parallel()
+
+	for line, want := range map[string]string{
+		"- [x] passport": "passport",
+		"  * charger":    "charger",
+		"2) check in":    "check in",
+		" buy milk ":     "buy milk",
+	} {
+		if got := ListItemText(line); got != want {
+			t.Errorf("ListItemText(%q) = %q, want %q", line, got, want)
+		}
+	}
+}
+
+func TestInline(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"plain text", `[{"text":{"content":"plain text"},"type":"text"}]`},
+		{"a **b** c", `[{"text":{"content":"a "},"type":"text"},{"annotations":{"bold":true,"code":false,"italic":false},"text":{"content":"b"},"type":"text"},{"text":{"content":" c"},"type":"text"}]`},
+		{"*it* and _it_", `[{"annotations":{"bold":false,"code":false,"italic":true},"text":{"content":"it"},"type":"text"},{"text":{"content":" and "},"type":"text"},{"annotations":{"bold":false,"code":false,"italic":true},"text":{"content":"it"},"type":"text"}]`},
+		{"run `go test`", `[{"text":{"content":"run "},"type":"text"},{"annotations":{"bold":false,"code":true,"italic":false},"text":{"content":"go test"},"type":"text"}]`},
+		{"see [docs](https://example.com)", `[{"text":{"content":"see "},"type":"text"},{"text":{"content":"docs","link":{"url":"https://example.com"}},"type":"text"}]`},
+		{"snake_case_name and 2*3", `[{"text":{"content":"snake_case_name and 2*3"},"type":"text"}]`},
+		{"[not a link](ftp://x) **open", `[{"text":{"content":"[not a link](ftp://x) **open"},"type":"text"}]`},
+	}
+	for _, tt := range tests {
+		got, _ := json.Marshal(Inline(tt.in))
+		if string(got) != tt.want {
+			t.Errorf("Inline(%q) =\n%s\nwant\n%s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestLongTextIsSplit(t *testing.T) {
+	t.Parallel()
+
+	long := strings.Repeat("ä", MaxTextLength*MaxRichText+5)
+	blocks := FromMarkdown(long)
+	if len(blocks) != 2 {
+		t.Fatalf("expected the paragraph to continue in a second block, got %d", len(blocks))
+	}
+	first := texts(blocks[0])
+	if len(first) != MaxRichText || len([]rune(first[0])) != MaxTextLength {
+		t.Fatalf("unexpected first block: %d objects", len(first))
+	}
+	if got := texts(blocks[1]); len(got) != 1 || len([]rune(got[0])) != 5 {
+		t.Fatalf("unexpected second block: %v", got)
+	}
+
+	empty, _ := json.Marshal(FromMarkdown("```\n```"))
+	if !strings.Contains(string(empty), `"rich_text":[]`) {
+		t.Fatalf("an empty block must send an empty rich_text list, got %s", empty)
+	}
+}
+
+func TestIsInline(t *testing.T) {
+	t.Parallel()
+
+	if !IsInline("call **Sam** back") {
+		t.Fatal("one paragraph is inline")
+	}
+	if IsInline("first\n\nsecond") || IsInline("- [ ] one") {
+		t.Fatal("paragraphs and lists are not inline")
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log"
@@ -19,14 +20,15 @@ import (
 
 type NotionService struct {
 	settingsservice *settingsservice.SettingsService
+	schemas         *SchemaCacheService
 	oauthMu         sync.Mutex
 	oauthInProgress bool
 }
 
 var ErrNotionTokenMissing = errors.New("notion access token unavailable")
 
-func NewNotionService(settingsservice *settingsservice.SettingsService) *NotionService {
-	return &NotionService{settingsservice: settingsservice}
+func NewNotionService(settingsservice *settingsservice.SettingsService, schemas *SchemaCacheService) *NotionService {
+	return &NotionService{settingsservice: settingsservice, schemas: schemas}
 }
 
 func openBrowser(url string) {
@@ -122,9 +124,42 @@ type TextContent struct {
 }
 
 type PropertyObj struct {
-	ID   string `json:"id"`
-	Name string `json:"name"`
-	Type string `json:"type"`
+	ID          string          `json:"id"`
+	Name        string          `json:"name"`
+	Type        string          `json:"type"`
+	Select      *SelectConfig   `json:"select,omitempty"`
+	MultiSelect *SelectConfig   `json:"multi_select,omitempty"`
+	Status      *SelectConfig   `json:"status,omitempty"`
+	Relation    *RelationConfig `json:"relation,omitempty"`
+}
+
+// RelationConfig names the data source a relation property links to.
+type RelationConfig struct {
+	DataSourceID string `json:"data_source_id"`
+}
+
+// SelectConfig lists the options of a select, multi_select or status property.
+type SelectConfig struct {
+	Options []SelectOption `json:"options"`
+}
+
+type SelectOption struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Color string `json:"color,omitempty"`
+}
+
+// Options returns the select-like options defined on the property, if any.
+func (p PropertyObj) Options() []SelectOption {
+	switch {
+	case p.Select != nil:
+		return p.Select.Options
+	case p.MultiSelect != nil:
+		return p.MultiSelect.Options
+	case p.Status != nil:
+		return p.Status.Options
+	}
+	return nil
 }
 
 type NotionDataSourceSummary struct {
@@ -179,7 +214,9 @@ func richTextPlainText(blocks []RichTextObj) string {
 	return strings.TrimSpace(b.String())
 }
 
-func (n *NotionService) GetNotionDatabases() (*NotionDataSourceList, error) {
+// GetNotionDatabases lists the data sources shared with the integration.
+// ctx is cancelled when the frontend abandons the call.
+func (n *NotionService) GetNotionDatabases(ctx context.Context) (*NotionDataSourceList, error) {
 	notionSecret, err := n.settingsservice.GetNotionToken(false)
 	if err != nil {
 		if errors.Is(err, keychain.ErrorItemNotFound) {
@@ -191,7 +228,7 @@ func (n *NotionService) GetNotionDatabases() (*NotionDataSourceList, error) {
 	if notionSecret == "" {
 		return nil, ErrNotionTokenMissing
 	}
-	NotionSearchURL := "https://api.notion.com/v1/search"
+	NotionSearchURL := notionapi.URL("/search")
 
 	seen := make(map[string]struct{})
 	var results []NotionDataSourceSummary
@@ -209,18 +246,8 @@ func (n *NotionService) GetNotionDatabases() (*NotionDataSourceList, error) {
 			payload["start_cursor"] = *cursor
 		}
 
-		req, err := notionapi.NewJSONRequest(http.MethodPost, NotionSearchURL, notionSecret, payload)
-		if err != nil {
-			return nil, err
-		}
-
-		resp, err := http.DefaultClient.Do(req)
-		if err != nil {
-			return nil, err
-		}
-
 		var search dataSourceSearchResponse
-		if err := notionapi.ParseResponse(resp, &search, ErrNotionTokenMissing); err != nil {
+		if err := n.do(ctx, http.MethodPost, NotionSearchURL, notionSecret, payload, &search); err != nil {
 			return nil, err
 		}
 
@@ -264,7 +291,7 @@ func (n *NotionService) GetNotionDatabases() (*NotionDataSourceList, error) {
 	return &NotionDataSourceList{Results: results}, nil
 }
 
-func (n *NotionService) GetDataSourceDetail(dataSourceID string) (*NotionDataSourceDetail, error) {
+func (n *NotionService) GetDataSourceDetail(ctx context.Context, dataSourceID string) (*NotionDataSourceDetail, error) {
 	if dataSourceID == "" {
 		return nil, fmt.Errorf("data source id is required")
 	}
@@ -280,10 +307,15 @@ func (n *NotionService) GetDataSourceDetail(dataSourceID string) (*NotionDataSou
 		return nil, ErrNotionTokenMissing
 	}
 
-	return n.fetchDataSourceDetail(token, dataSourceID)
+	detail, err := n.schemas.Detail(ctx, token, dataSourceID)
+	if err != nil {
+		return nil, err
+	}
+	n.detectDateProperty(detail)
+	return detail, nil
 }
 
-func (n *NotionService) GetNotionWorkspaceId() (string, error) {
+func (n *NotionService) GetNotionWorkspaceId(ctx context.Context) (string, error) {
 	notionToken, err := n.settingsservice.GetNotionToken(false)
 	if err != nil {
 		if errors.Is(err, keychain.ErrorItemNotFound) {
@@ -296,20 +328,10 @@ func (n *NotionService) GetNotionWorkspaceId() (string, error) {
 		return "", ErrNotionTokenMissing
 	}
 
-	req, err := notionapi.NewRequest(http.MethodGet, "https://api.notion.com/v1/users/me", notionToken)
-	if err != nil {
-		return "", err
-	}
-
-	resp, err := (&http.Client{}).Do(req)
-	if err != nil {
-		return "", err
-	}
-
 	var payload struct {
 		ID string `json:"id"`
 	}
-	if err := notionapi.ParseResponse(resp, &payload, ErrNotionTokenMissing); err != nil {
+	if err := n.do(ctx, http.MethodGet, notionapi.URL("/users/me"), notionToken, nil, &payload); err != nil {
 		return "", err
 	}
 
@@ -320,43 +342,9 @@ func (n *NotionService) GetNotionWorkspaceId() (string, error) {
 	return payload.ID, nil
 }
 
-func (n *NotionService) fetchDataSourceDetail(token, dataSourceID string) (*NotionDataSourceDetail, error) {
-	req, err := notionapi.NewRequest(http.MethodGet, fmt.Sprintf("https://api.notion.com/v1/data_sources/%s", dataSourceID), token)
-	if err != nil {
-		return nil, err
-	}
-
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-
-	var payload struct {
-		ID         string                 `json:"id"`
-		Name       []RichTextObj          `json:"name"`
-		Properties map[string]PropertyObj `json:"properties"`
-	}
-	if err := notionapi.ParseResponse(resp, &payload, ErrNotionTokenMissing); err != nil {
-		return nil, err
-	}
-
-	detail := NotionDataSourceDetail{
-		ID:         payload.ID,
-		Name:       richTextPlainText(payload.Name),
-		Properties: payload.Properties,
-	}
-
-	if detail.Properties == nil {
-		detail.Properties = map[string]PropertyObj{}
-	}
-
-	for key, prop := range detail.Properties {
-		if prop.Name == "" {
-			prop.Name = key
-			detail.Properties[key] = prop
-		}
-	}
-
+// detectDateProperty picks the date property of the selected data source
+// when it has exactly one and none is chosen yet.
+func (n *NotionService) detectDateProperty(detail *NotionDataSourceDetail) {
 	if detail.ID == n.settingsservice.AppSettings.NotionDataSourceID &&
 		n.settingsservice.AppSettings.DatePropertyID == "" {
 		var dateProps []PropertyObj
@@ -372,6 +360,21 @@ func (n *NotionService) fetchDataSourceDetail(token, dataSourceID string) (*Noti
 			n.settingsservice.SaveSettings()
 		}
 	}
+}
 
-	return &detail, nil
+// do sends one request to Notion within the Notion timeout and decodes the
+// response into target.
+func (n *NotionService) do(ctx context.Context, method, url, token string, payload, target any) error {
+	ctx, cancel := notionStage(ctx)
+	defer cancel()
+
+	req, err := notionapi.NewJSONRequest(ctx, method, url, token, payload)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	return notionapi.ParseResponse(resp, target, ErrNotionTokenMissing)
 }
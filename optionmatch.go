@@ -0,0 +1,298 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/imjamesonzeller/tasklight-v3/settingsservice"
+)
+
+// optionMatchThreshold is the titleSimilarity from which a value is taken
+// for a misspelling of an option, e.g. "meetng" for "Meeting".
+const optionMatchThreshold = 0.8
+
+// What became of a parsed value written to a select or multi-select
+// property.
+const (
+	// OptionMatched means the value was written as a differently spelled
+	// option, e.g. "errand" as "Errands".
+	OptionMatched = "matched"
+	// OptionCreated means Notion creates a new option for the value.
+	OptionCreated = "created"
+	// OptionDropped means the value was left out of the page.
+	OptionDropped = "dropped"
+	// OptionFallback means the fallback option was written instead.
+	OptionFallback = "fallback"
+	// OptionAmbiguous means several project pages match, so none was linked.
+	OptionAmbiguous = "ambiguous"
+)
+
+// OptionMatch reports how a parsed tag or project was fitted to the options
+// of its property, or a project to the pages its relation links to. Values
+// matching an option or page title up to case are not reported.
+type OptionMatch struct {
+	Field    string `json:"field"`
+	Property string `json:"property"`
+	Value    string `json:"value"`
+	// Option is the option or page title written, empty when the value was
+	// dropped.
+	Option  string `json:"option,omitempty"`
+	Outcome string `json:"outcome"`
+	// URL is the linked project page.
+	URL string `json:"url,omitempty"`
+	// Candidates are the project pages an ambiguous project could mean.
+	Candidates []ProjectPage `json:"candidates,omitempty"`
+}
+
+// optionKey normalises an option for matching: lower case, punctuation
+// dropped and each word made singular.
+func optionKey(value string) string {
+	words := strings.Fields(normalizeTitle(value))
+	for i, w := range words {
+		words[i] = singular(w)
+	}
+	return strings.Join(words, " ")
+}
+
+// singular strips the English plural endings that tags most often carry.
+func singular(word string) string {
+	switch {
+	case len(word) <= 3 || strings.HasSuffix(word, "ss") || strings.HasSuffix(word, "us") || strings.HasSuffix(word, "is"):
+		return word
+	case strings.HasSuffix(word, "ies"):
+		return strings.TrimSuffix(word, "ies") + "y"
+	case strings.HasSuffix(word, "sses"), strings.HasSuffix(word, "xes"), strings.HasSuffix(word, "ches"), strings.HasSuffix(word, "shes"):
+		return strings.TrimSuffix(word, "es")
+	case strings.HasSuffix(word, "s"):
+		return strings.TrimSuffix(word, "s")
+	}
+	return word
+}
+
+// closestOption returns the option of prop that value stands for: the same
+// option up to case, punctuation or plural, or failing that the one option
+// spelled most like it above optionMatchThreshold.
+func closestOption(prop PropertyObj, value string) (string, bool) {
+	if name, ok := existingOption(prop, value); ok {
+		return name, true
+	}
+	key := optionKey(value)
+	if key == "" {
+		return "", false
+	}
+
+	best, bestScore, tied := "", 0.0, false
+	for _, opt := range prop.Options() {
+		other := optionKey(opt.Name)
+		if other == key {
+			return opt.Name, true
+		}
+		switch score := titleSimilarity(key, other); {
+		case score > bestScore:
+			best, bestScore, tied = opt.Name, score, false
+		case score == bestScore:
+			tied = true
+		}
+	}
+	// Two options equally close to a typo are both guesses.
+	if bestScore < optionMatchThreshold || tied {
+		return "", false
+	}
+	return best, true
+}
+
+// fitOption decides what to write for value in prop. It reports false when
+// the value is dropped, and a match when the outcome is worth telling the
+// user about.
+func fitOption(prop PropertyObj, field, value string, unmatched map[string]settingsservice.UnmatchedOption) (string, bool, *OptionMatch) {
+	match := &OptionMatch{Field: field, Property: prop.Name, Value: value}
+	if name, ok := closestOption(prop, value); ok {
+		if strings.EqualFold(name, value) {
+			return name, true, nil
+		}
+		match.Option, match.Outcome = name, OptionMatched
+		return name, true, match
+	}
+
+	policy := unmatched[prop.ID]
+	switch unmatchedAction(prop, policy) {
+	case settingsservice.UnmatchedDrop:
+		match.Outcome = OptionDropped
+		return "", false, match
+	case settingsservice.UnmatchedFallback:
+		// A fallback deleted in Notion must not be recreated.
+		name, ok := existingOption(prop, policy.Fallback)
+		if !ok {
+			match.Outcome = OptionDropped
+			return "", false, match
+		}
+		match.Option, match.Outcome = name, OptionFallback
+		return name, true, match
+	}
+	match.Option, match.Outcome = matchOption(prop, value), OptionCreated
+	return match.Option, true, match
+}
+
+// unmatchedAction is what happens to values matching nothing in prop.
+// Options are created unless configured otherwise, project pages only when
+// configured.
+func unmatchedAction(prop PropertyObj, policy settingsservice.UnmatchedOption) string {
+	switch {
+	case policy.Action != "":
+		return policy.Action
+	case prop.Type == "relation":
+		return settingsservice.UnmatchedDrop
+	}
+	return settingsservice.UnmatchedCreate
+}
+
+// isOptionProperty reports whether prop takes values from an option list
+// Notion can grow.
+func isOptionProperty(prop *PropertyObj) bool {
+	return prop != nil && (prop.Type == "select" || prop.Type == "multi_select")
+}
+
+// matchTaskOptions fits the tags and project of task to the options of the
+// properties receiving them, so near-duplicates of an option are not
+// created. The returned task carries the option names to write.
+func matchTaskOptions(task TaskInformation, fields taskFieldProperties) (TaskInformation, []OptionMatch) {
+	var matches []OptionMatch
+	report := func(m *OptionMatch) {
+		if m != nil {
+			matches = append(matches, *m)
+		}
+	}
+
+	if isOptionProperty(fields.Tags) && len(task.Tags) > 0 {
+		var tags []string
+		for _, tag := range task.Tags {
+			name, keep, m := fitOption(*fields.Tags, "tags", tag, fields.Unmatched)
+			report(m)
+			// "errand" and "errands" both become "Errands".
+			if keep && !slices.ContainsFunc(tags, func(t string) bool { return strings.EqualFold(t, name) }) {
+				tags = append(tags, name)
+			}
+		}
+		task.Tags = tags
+	}
+
+	if isOptionProperty(fields.Project) && task.Project != "" {
+		name, _, m := fitOption(*fields.Project, "project", task.Project, fields.Unmatched)
+		report(m)
+		task.Project = name
+	}
+	return task, matches
+}
+
+// OptionMatching is what happens to unknown tags or projects in the property
+// receiving them.
+type OptionMatching struct {
+	Field      string   `json:"field"`
+	PropertyID string   `json:"property_id"`
+	Property   string   `json:"property"`
+	Type       string   `json:"type"`
+	Options    []string `json:"options"`
+	Action     string   `json:"action"`
+	Fallback   string   `json:"fallback,omitempty"`
+	// Problem explains why the saved setting no longer works.
+	Problem string `json:"problem,omitempty"`
+}
+
+// describeOptionMatching lists the option properties that receive tags or
+// the project, and a relation receiving the project.
+func describeOptionMatching(fields taskFieldProperties) []OptionMatching {
+	var described []OptionMatching
+	for _, f := range []struct {
+		field string
+		prop  *PropertyObj
+	}{{"tags", fields.Tags}, {"project", fields.Project}} {
+		if !isOptionProperty(f.prop) && !(f.field == "project" && f.prop != nil && f.prop.Type == "relation") {
+			continue
+		}
+		policy := fields.Unmatched[f.prop.ID]
+		m := OptionMatching{
+			Field:      f.field,
+			PropertyID: f.prop.ID,
+			Property:   f.prop.Name,
+			Type:       f.prop.Type,
+			Options:    []string{},
+			Action:     unmatchedAction(*f.prop, policy),
+			Fallback:   policy.Fallback,
+		}
+		for _, opt := range f.prop.Options() {
+			m.Options = append(m.Options, opt.Name)
+		}
+		if err := checkUnmatched(*f.prop, policy); err != nil {
+			m.Problem = err.Error() + "; unknown values are dropped"
+		}
+		described = append(described, m)
+	}
+	return described
+}
+
+func checkUnmatched(prop PropertyObj, policy settingsservice.UnmatchedOption) error {
+	if policy.Action != settingsservice.UnmatchedFallback {
+		return nil
+	}
+	if prop.Type == "relation" {
+		return fmt.Errorf("%q can only create project pages or leave unknown projects out", prop.Name)
+	}
+	if _, ok := existingOption(prop, policy.Fallback); !ok {
+		return fmt.Errorf("%q is not an option of %q", policy.Fallback, prop.Name)
+	}
+	return nil
+}
+
+// destinationFields loads the properties that receive each task field in a
+// destination.
+func (n *NotionService) destinationFields(ctx context.Context, destination string) (settingsservice.Destination, *NotionDataSourceDetail, taskFieldProperties, error) {
+	dest, detail, err := n.destinationDetail(ctx, destination)
+	if err != nil {
+		return dest, nil, taskFieldProperties{}, err
+	}
+	fields := detectTaskFieldProperties(detail, n.settingsservice.AppSettings.RecurrencePropertyName)
+	applyPropertyMappings(&fields, detail, dest.PropertyMappings())
+	fields.Unmatched = dest.UnmatchedOptions()
+	return dest, detail, fields, nil
+}
+
+// GetOptionMatching lists what happens to tags and projects that match no
+// option in the destination's data source. Called from frontend
+func (n *NotionService) GetOptionMatching(ctx context.Context, destination string) ([]OptionMatching, error) {
+	_, _, fields, err := n.destinationFields(ctx, destination)
+	if err != nil {
+		return nil, err
+	}
+	return describeOptionMatching(fields), nil
+}
+
+// SaveOptionMatching sets what happens to unknown tags and projects, keyed
+// by property ID. Called from frontend
+func (n *NotionService) SaveOptionMatching(ctx context.Context, destination string, unmatched map[string]settingsservice.UnmatchedOption) error {
+	_, detail, _, err := n.destinationFields(ctx, destination)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for id, policy := range unmatched {
+		prop := propertyByID(detail, id)
+		switch {
+		case prop == nil:
+			errs = append(errs, fmt.Errorf("property %q does not exist on %q", id, detail.Name))
+		case !isOptionProperty(prop) && prop.Type != "relation":
+			errs = append(errs, fmt.Errorf("%q has no options to match", prop.Name))
+		default:
+			if err := checkUnmatched(*prop, policy); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+	return n.settingsservice.SetUnmatchedOptions(destination, unmatched)
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/imjamesonzeller/tasklight-v3/settingsservice"
+)
+
+func optionTestProperty(id, typ string, options ...string) *PropertyObj {
+	config := &SelectConfig{}
+	for _, name := range options {
+		config.Options = append(config.Options, SelectOption{Name: name})
+	}
+	prop := &PropertyObj{ID: id, Name: id, Type: typ}
+	if typ == "select" {
+		prop.Select = config
+	} else {
+		prop.MultiSelect = config
+	}
+	return prop
+}
+
+func TestClosestOption(t *testing.T) {
+	t.Parallel()
+
+	prop := *optionTestProperty("Tags", "multi_select", "Errands", "Meeting", "Categories", "Follow-up", "Focus", "Paint", "Faint")
+	tests := []struct {
+		value, want string
+		ok          bool
+	}{
+		{"errands", "Errands", true},
+		{"errand", "Errands", true},
+		{"category", "Categories", true},
+		{"follow up", "Follow-up", true},
+		{"meetng", "Meeting", true},
+		{"meetings", "Meeting", true},
+		{"focus", "Focus", true},
+		// Too far from any option, or as close to two of them.
+		{"errands list", "", false},
+		{"saint", "", false},
+		{"cap", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := closestOption(prop, tt.value)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("closestOption(%q) = %q, %v; want %q, %v", tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestMatchTaskOptions(t *testing.T) {
+	t.Parallel()
+
+	fields := taskFieldProperties{
+		Tags:    optionTestProperty("t1", "multi_select", "Errands", "Home"),
+		Project: optionTestProperty("p1", "select", "Launch", "Inbox"),
+	}
+	task := TaskInformation{Title: "Buy milk", Tags: []string{"errand", "Errands", "home", "garden"}, Project: "launch"}
+
+	// Unknown values create options unless configured otherwise.
+	matched, matches := matchTaskOptions(task, fields)
+	if !slices.Equal(matched.Tags, []string{"Errands", "Home", "garden"}) || matched.Project != "Launch" {
+		t.Fatalf("unexpected options: %v, %q", matched.Tags, matched.Project)
+	}
+	want := []OptionMatch{
+		{Field: "tags", Property: "t1", Value: "errand", Option: "Errands", Outcome: OptionMatched},
+		{Field: "tags", Property: "t1", Value: "garden", Option: "garden", Outcome: OptionCreated},
+	}
+	if !reflect.DeepEqual(matches, want) {
+		t.Fatalf("unexpected matches: %+v", matches)
+	}
+	if task.Tags[0] != "errand" {
+		t.Fatal("matching must not change the caller's tags")
+	}
+
+	fields.Unmatched = map[string]settingsservice.UnmatchedOption{
+		"t1": {Action: settingsservice.UnmatchedDrop},
+		"p1": {Action: settingsservice.UnmatchedFallback, Fallback: "inbox"},
+	}
+	matched, matches = matchTaskOptions(TaskInformation{Tags: []string{"garden"}, Project: "Renovation"}, fields)
+	if len(matched.Tags) != 0 || matched.Project != "Inbox" {
+		t.Fatalf("expected the tag dropped and the project sent to Inbox, got %v, %q", matched.Tags, matched.Project)
+	}
+	if len(matches) != 2 || matches[0].Outcome != OptionDropped || matches[1].Outcome != OptionFallback {
+		t.Fatalf("unexpected matches: %+v", matches)
+	}
+
+	// A fallback deleted in Notion drops the value rather than recreating it.
+	fields.Unmatched["p1"] = settingsservice.UnmatchedOption{Action: settingsservice.UnmatchedFallback, Fallback: "Someday"}
+	if matched, _ := matchTaskOptions(TaskInformation{Project: "Renovation"}, fields); matched.Project != "" {
+		t.Fatalf("expected the project dropped, got %q", matched.Project)
+	}
+
+	// Text properties take any value.
+	fields.Project = &PropertyObj{ID: "p2", Name: "Project", Type: "rich_text"}
+	if matched, matches := matchTaskOptions(TaskInformation{Project: "Renovation"}, fields); matched.Project != "Renovation" || len(matches) != 0 {
+		t.Fatalf("expected a text project untouched, got %q, %+v", matched.Project, matches)
+	}
+}
+
+func TestDescribeOptionMatching(t *testing.T) {
+	t.Parallel()
+
+	fields := taskFieldProperties{
+		Tags:    optionTestProperty("t1", "multi_select", "Errands"),
+		Project: &PropertyObj{ID: "p2", Name: "Project", Type: "rich_text"},
+		Unmatched: map[string]settingsservice.UnmatchedOption{
+			"t1": {Action: settingsservice.UnmatchedFallback, Fallback: "Gone"},
+		},
+	}
+	described := describeOptionMatching(fields)
+	if len(described) != 1 {
+		t.Fatalf("expected only the tags property, got %+v", described)
+	}
+	if d := described[0]; d.Field != "tags" || d.Action != settingsservice.UnmatchedFallback || d.Problem == "" || !slices.Equal(d.Options, []string{"Errands"}) {
+		t.Fatalf("unexpected description: %+v", d)
+	}
+
+	// A project relation leaves unknown projects out unless told to create them.
+	fields.Project = &PropertyObj{ID: "r1", Name: "Project", Type: "relation"}
+	described = describeOptionMatching(fields)
+	if len(described) != 2 || described[1].Action != settingsservice.UnmatchedDrop || described[1].Type != "relation" {
+		t.Fatalf("unexpected description: %+v", described)
+	}
+}
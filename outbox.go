@@ -0,0 +1,525 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/imjamesonzeller/tasklight-v3/settingsservice"
+	"github.com/wailsapp/wails/v3/pkg/application"
+)
+
+const (
+	outboxFileName = "outbox.json"
+	// Failed deliveries are retried after outboxBaseDelay, doubling on every
+	// further failure up to outboxMaxDelay.
+	outboxBaseDelay = 30 * time.Second
+	outboxMaxDelay  = time.Hour
+)
+
+// outboxEntry is one submission that has not reached Notion yet. Task is nil
+// until the input has been parsed.
+type outboxEntry struct {
+	ID    string           `json:"id"`
+	Input string           `json:"input"`
+	Task  *TaskInformation `json:"task,omitempty"`
+	// ParsedLocally is set when the local parser stood in for a model or
+	// server that failed; the input is parsed again before the next attempt.
+	ParsedLocally bool `json:"parsed_locally,omitempty"`
+	// Clipboard is attached to the task once it is parsed.
+	Clipboard   string    `json:"clipboard,omitempty"`
+	SubmittedAt time.Time `json:"submitted_at"`
+	Attempts    int       `json:"attempts"`
+	NextAttempt time.Time `json:"next_attempt"`
+	LastError   string    `json:"last_error,omitempty"`
+	// Category classifies the last failure. Parked entries failed for a
+	// reason retrying will not fix, so they wait for the user to retry or
+	// discard them.
+	Category ErrorCategory `json:"error_category,omitempty"`
+	Parked   bool          `json:"parked,omitempty"`
+}
+
+// title is the parsed title, or the raw input before parsing.
+func (e outboxEntry) title() string {
+	if e.Task != nil {
+		return e.Task.Title
+	}
+	return e.Input
+}
+
+// QueuedTask describes an outbox entry for the frontend.
+type QueuedTask struct {
+	ID          string        `json:"id"`
+	Input       string        `json:"input"`
+	Title       string        `json:"title"`
+	SubmittedAt time.Time     `json:"submitted_at"`
+	Attempts    int           `json:"attempts"`
+	NextAttempt time.Time     `json:"next_attempt"`
+	LastError   string        `json:"last_error,omitempty"`
+	Category    ErrorCategory `json:"error_category,omitempty"`
+	// Parked is set when the submission waits for the user to retry or
+	// discard it.
+	Parked bool `json:"parked,omitempty"`
+}
+
+// OutboxService journals every submission to disk before it is parsed or
+// sent. Submissions failing for a passing reason, such as a network outage,
+// are retried until they reach Notion; the others are parked until the user
+// retries or discards them.
+type OutboxService struct {
+	app      *application.App
+	tasks    *TaskService
+	settings *settingsservice.SettingsService
+	now      func() time.Time
+	path     string
+
+	mu       sync.Mutex
+	entries  []outboxEntry
+	inFlight map[string]context.CancelFunc
+	loaded   bool
+	seq      int
+	wake     chan struct{}
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+func NewOutboxService(tasks *TaskService, settings *settingsservice.SettingsService) *OutboxService {
+	return &OutboxService{
+		tasks:    tasks,
+		settings: settings,
+		now:      time.Now,
+		inFlight: map[string]context.CancelFunc{},
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+func (o *OutboxService) SetApp(app *application.App) {
+	o.app = app
+}
+
+// Start retries queued submissions whenever one is due until Stop.
+func (o *OutboxService) Start() {
+	o.mu.Lock()
+	if o.stop != nil {
+		o.mu.Unlock()
+		return
+	}
+	stop, done := make(chan struct{}), make(chan struct{})
+	o.stop, o.done = stop, done
+	o.mu.Unlock()
+
+	go func() {
+		defer close(done)
+
+		for {
+			o.deliverDue()
+
+			timer := time.NewTimer(o.untilNextAttempt())
+			select {
+			case <-stop:
+				timer.Stop()
+				return
+			case <-o.wake:
+				timer.Stop()
+			case <-timer.C:
+			}
+		}
+	}()
+}
+
+func (o *OutboxService) Stop() {
+	o.mu.Lock()
+	stop, done := o.stop, o.done
+	o.stop = nil
+	o.mu.Unlock()
+
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-done
+}
+
+// GetQueue lists the submissions still waiting to reach Notion. Called from frontend
+func (o *OutboxService) GetQueue() ([]QueuedTask, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if err := o.load(); err != nil {
+		return nil, err
+	}
+
+	queue := make([]QueuedTask, 0, len(o.entries))
+	for _, e := range o.entries {
+		queue = append(queue, QueuedTask{
+			ID:          e.ID,
+			Input:       e.Input,
+			Title:       e.title(),
+			SubmittedAt: e.SubmittedAt,
+			Attempts:    e.Attempts,
+			NextAttempt: e.NextAttempt,
+			LastError:   e.LastError,
+			Category:    e.Category,
+			Parked:      e.Parked,
+		})
+	}
+	return queue, nil
+}
+
+// RetryNow makes the queued submission id due immediately, parked or not.
+// An empty id retries every queued submission. Called from frontend
+func (o *OutboxService) RetryNow(id string) error {
+	o.mu.Lock()
+	if err := o.load(); err != nil {
+		o.mu.Unlock()
+		return err
+	}
+	found := false
+	for i := range o.entries {
+		if id == "" || o.entries[i].ID == id {
+			o.entries[i].NextAttempt = o.now()
+			o.entries[i].Parked = false
+			found = true
+		}
+	}
+	if !found && id != "" {
+		o.mu.Unlock()
+		return fmt.Errorf("queued task %q not found", id)
+	}
+	err := o.save()
+	o.queueChanged()
+	o.mu.Unlock()
+
+	o.signal()
+	return err
+}
+
+// Discard drops a queued submission without sending it, abandoning any
+// request in flight. Called from frontend
+func (o *OutboxService) Discard(id string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if err := o.load(); err != nil {
+		return err
+	}
+	for i, e := range o.entries {
+		if e.ID == id {
+			if cancel, ok := o.inFlight[id]; ok {
+				cancel()
+			}
+			o.entries = append(o.entries[:i], o.entries[i+1:]...)
+			o.queueChanged()
+			return o.save()
+		}
+	}
+	return fmt.Errorf("queued task %q not found", id)
+}
+
+// latest returns the most recently submitted entry.
+func (o *OutboxService) latest() (e outboxEntry, ok bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if err := o.load(); err != nil {
+		log.Println("Outbox: failed to load queue:", err)
+		return e, false
+	}
+	for _, entry := range o.entries {
+		if !ok || entry.SubmittedAt.After(e.SubmittedAt) {
+			e, ok = entry, true
+		}
+	}
+	return e, ok
+}
+
+// claimedEntry is an entry being delivered. Its context is cancelled when
+// the entry is discarded or the app quits.
+type claimedEntry struct {
+	outboxEntry
+	ctx context.Context
+}
+
+// claim marks e as being delivered. The caller holds o.mu.
+func (o *OutboxService) claim(e outboxEntry) claimedEntry {
+	ctx, cancel := context.WithCancel(o.tasks.ctx)
+	o.inFlight[e.ID] = cancel
+	return claimedEntry{outboxEntry: e, ctx: ctx}
+}
+
+// enqueue journals inputs, or already parsed tasks when tasks is non-nil, and
+// claims the new entries for the caller, which must pass them to deliver.
+// clipboard is attached to every input once it is parsed.
+func (o *OutboxService) enqueue(inputs []string, tasks []TaskInformation, clipboard string) ([]claimedEntry, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if err := o.load(); err != nil {
+		return nil, err
+	}
+
+	now := o.now()
+	added := make([]outboxEntry, len(inputs))
+	for i, input := range inputs {
+		o.seq++
+		added[i] = outboxEntry{
+			ID:          fmt.Sprintf("%d-%d", now.UnixNano(), o.seq),
+			Input:       input,
+			Clipboard:   clipboard,
+			SubmittedAt: now,
+			NextAttempt: now,
+		}
+		if tasks != nil {
+			added[i].Task = &tasks[i]
+		}
+	}
+
+	o.entries = append(o.entries, added...)
+	if err := o.save(); err != nil {
+		o.entries = o.entries[:len(o.entries)-len(added)]
+		return nil, err
+	}
+	claimed := make([]claimedEntry, len(added))
+	for i, e := range added {
+		claimed[i] = o.claim(e)
+	}
+	o.queueChanged()
+	return claimed, nil
+}
+
+// deliverDue sends every entry whose next attempt has come.
+func (o *OutboxService) deliverDue() {
+	o.mu.Lock()
+	if err := o.load(); err != nil {
+		o.mu.Unlock()
+		log.Println("Outbox: failed to load queue:", err)
+		return
+	}
+	now := o.now()
+	var due []claimedEntry
+	for _, e := range o.entries {
+		if _, sending := o.inFlight[e.ID]; !sending && !e.Parked && !e.NextAttempt.After(now) {
+			due = append(due, o.claim(e))
+		}
+	}
+	o.mu.Unlock()
+
+	for _, result := range o.deliver(due) {
+		switch {
+		case result.Parked:
+			log.Printf("Outbox: %q is parked until retried: %s", result.Input, result.Error)
+		case !result.OK:
+			log.Printf("Outbox: %q is still queued: %s", result.Input, result.Error)
+		}
+	}
+}
+
+// deliver sends claimed entries and returns one result per entry. Failed
+// entries stay queued, with a later next attempt or parked.
+func (o *OutboxService) deliver(entries []claimedEntry) []TaskResult {
+	results := make([]TaskResult, len(entries))
+	runPool(len(entries), func(i int) {
+		results[i] = o.deliverOne(entries[i])
+	})
+	return results
+}
+
+func (o *OutboxService) deliverOne(e claimedEntry) TaskResult {
+	if e.Task == nil || e.ParsedLocally {
+		task, fallback := o.tasks.parseSubmission(e.ctx, e.Input, e.SubmittedAt)
+		task.Clipboard = e.Clipboard
+		e.Task, e.ParsedLocally = &task, fallback
+		o.recordParse(e.outboxEntry)
+	}
+
+	result := o.tasks.commitTask(e.ctx, e.Input, *e.Task)
+	queued, parked, retracted := o.finish(e.ID, result)
+	result.Queued, result.Parked = queued, parked
+	if retracted {
+		result = o.retracted(result)
+	}
+	o.tasks.recordHistory(e.ID, e.Input, e.SubmittedAt, *e.Task, result)
+	return result
+}
+
+// retracted handles an entry discarded while it was being sent. If Notion
+// created the page before the request was abandoned, the page is trashed,
+// with the project page created for it.
+func (o *OutboxService) retracted(result TaskResult) TaskResult {
+	if result.Created() {
+		if err := o.tasks.trashPage(o.tasks.ctx, result.PageID); err != nil {
+			log.Printf("Outbox: %q was retracted but its page could not be removed: %v", result.Input, err)
+			return result
+		}
+		o.tasks.forgetCreated(result.PageID)
+	}
+	if result.CreatedProject != "" {
+		if err := o.tasks.trashPage(o.tasks.ctx, result.CreatedProject); err != nil {
+			log.Printf("Outbox: %q was retracted but its project page could not be removed: %v", result.Input, err)
+		} else {
+			o.tasks.forgetProjectPage(result.CreatedProject)
+		}
+	}
+	result.OK = false
+	result.PageResult = PageResult{Error: "cancelled before it reached Notion"}
+	return result
+}
+
+// recordParse stores the parsed task of e, if it is still queued, so a retry
+// sends the same task instead of parsing again unless the local parser made
+// it.
+func (o *OutboxService) recordParse(e outboxEntry) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for i := range o.entries {
+		if o.entries[i].ID == e.ID {
+			o.entries[i].Task, o.entries[i].ParsedLocally = e.Task, e.ParsedLocally
+			if err := o.save(); err != nil {
+				log.Printf("Outbox: failed to save %q: %v", e.Input, err)
+			}
+			return
+		}
+	}
+}
+
+// finish releases the claim on id, removing the entry when it was delivered,
+// scheduling a retry when it failed for a passing reason and parking it
+// otherwise. It reports whether the entry is still queued, whether it is
+// parked, and whether it was discarded while being sent.
+func (o *OutboxService) finish(id string, result TaskResult) (queued, parked, retracted bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if cancel, ok := o.inFlight[id]; ok {
+		cancel()
+		delete(o.inFlight, id)
+	}
+	for i := range o.entries {
+		e := &o.entries[i]
+		if e.ID != id {
+			continue
+		}
+
+		switch {
+		case result.OK:
+			o.entries = append(o.entries[:i], o.entries[i+1:]...)
+			o.queueChanged()
+		case o.tasks.ctx.Err() != nil:
+			// Abandoned because the app is quitting; retry on next launch
+			// without counting it as a failure.
+		default:
+			e.Attempts++
+			e.LastError, e.Category = result.Error, result.Category
+			if transientFailure(result.Category) {
+				e.NextAttempt = o.now().Add(outboxBackoff(e.Attempts))
+			} else {
+				e.Parked = true
+				o.queueChanged()
+			}
+		}
+		if err := o.save(); err != nil {
+			log.Printf("Outbox: failed to save %q: %v", result.Input, err)
+		}
+		return !result.OK, !result.OK && e.Parked, false
+	}
+	return false, false, true
+}
+
+// transientFailure reports whether a failure of the given category may pass
+// by itself: a network outage, Notion being unavailable or rate limiting.
+// A missing token or data source, or a page Notion rejects, needs the user.
+func transientFailure(category ErrorCategory) bool {
+	return category == ErrorNetwork || category == ErrorRateLimited
+}
+
+// untilNextAttempt is how long the worker may sleep before an entry is due.
+func (o *OutboxService) untilNextAttempt() time.Duration {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	wait := outboxMaxDelay
+	now := o.now()
+	for _, e := range o.entries {
+		if _, sending := o.inFlight[e.ID]; !sending && !e.Parked {
+			wait = min(wait, max(e.NextAttempt.Sub(now), 0))
+		}
+	}
+	return wait
+}
+
+func (o *OutboxService) signal() {
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+}
+
+// queueChanged tells the frontend how many submissions are queued. The caller
+// holds o.mu.
+func (o *OutboxService) queueChanged() {
+	if o.app != nil {
+		o.app.EmitEvent("Backend:QueueChanged", len(o.entries))
+	}
+}
+
+// outboxBackoff is the wait after the given number of failed attempts.
+func outboxBackoff(attempts int) time.Duration {
+	delay := outboxBaseDelay
+	for i := 1; i < attempts && delay < outboxMaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, outboxMaxDelay)
+}
+
+// ====== Journal ======
+
+type outboxJournal struct {
+	Entries []outboxEntry `json:"entries"`
+}
+
+func (o *OutboxService) load() error {
+	if o.loaded {
+		return nil
+	}
+	if o.path == "" {
+		o.path = o.settings.DataPath(outboxFileName)
+	}
+
+	data, err := os.ReadFile(o.path)
+	if errors.Is(err, os.ErrNotExist) {
+		o.loaded = true
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var journal outboxJournal
+	if err := json.Unmarshal(data, &journal); err != nil {
+		return fmt.Errorf("corrupt outbox %s: %w", o.path, err)
+	}
+	o.entries = journal.Entries
+	o.loaded = true
+	return nil
+}
+
+// save writes the journal through a temporary file so a crash never leaves
+// it half written.
+func (o *OutboxService) save() error {
+	data, err := json.MarshalIndent(outboxJournal{Entries: o.entries}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := o.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, o.path)
+}
@@ -0,0 +1,351 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	c "github.com/imjamesonzeller/tasklight-v3/config"
+	"github.com/imjamesonzeller/tasklight-v3/llm"
+	"github.com/imjamesonzeller/tasklight-v3/settingsservice"
+)
+
+// newTestOutbox returns an outbox whose pages are created by createPage.
+// Parsing runs locally because no user is signed in.
+func newTestOutbox(t *testing.T, now *time.Time, createPage func(context.Context, TaskInformation) PageResult) *OutboxService {
+	t.Helper()
+
+	originalConfig := c.AppConfig
+	originalUser := c.GetCurrentUserId()
+	t.Cleanup(func() {
+		c.AppConfig = originalConfig
+		c.SetCurrentUserId(originalUser)
+	})
+	c.AppConfig = &settingsservice.ApplicationSettings{TimeZone: "UTC"}
+	c.SetCurrentUserId("")
+
+	ts := NewTaskService(nil, nil)
+	ts.createPage = createPage
+	o := NewOutboxService(ts, nil)
+	o.now = func() time.Time { return *now }
+	o.path = filepath.Join(t.TempDir(), outboxFileName)
+	ts.SetOutbox(o)
+	return o
+}
+
+func TestOutboxJournalsBeforeSending(t *testing.T) {
+	now := time.Date(2025, time.October, 15, 9, 0, 0, 0, time.UTC)
+	var o *OutboxService
+	o = newTestOutbox(t, &now, func(_ context.Context, task TaskInformation) PageResult {
+		data, err := os.ReadFile(o.path)
+		if err != nil || !strings.Contains(string(data), "call mom tomorrow") {
+			t.Errorf("submission must be on disk before it is sent, got %q (err %v)", data, err)
+		}
+		return PageResult{PageID: "page-1"}
+	})
+
+	entries, err := o.enqueue([]string{"call mom tomorrow"}, nil, "")
+	if err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	results := o.deliver(entries)
+
+	if !results[0].OK || results[0].Queued {
+		t.Fatalf("unexpected result: %+v", results[0])
+	}
+	if queue, _ := o.GetQueue(); len(queue) != 0 {
+		t.Fatalf("delivered tasks must leave the queue, got %+v", queue)
+	}
+}
+
+func TestOutboxRetriesWithBackoff(t *testing.T) {
+	now := time.Date(2025, time.October, 15, 9, 0, 0, 0, time.UTC)
+	status := "notion api error: status 503, body: "
+	var sent []TaskInformation
+	o := newTestOutbox(t, &now, func(_ context.Context, task TaskInformation) PageResult {
+		sent = append(sent, task)
+		if status != "" {
+			return PageResult{Category: ErrorNetwork, Error: status}
+		}
+		return PageResult{PageID: "page-1"}
+	})
+
+	entries, err := o.enqueue([]string{"call mom tomorrow"}, nil, "")
+	if err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	if result := o.deliver(entries)[0]; result.OK || !result.Queued || result.Error != status {
+		t.Fatalf("expected the failure to be queued, got %+v", result)
+	}
+
+	queue, _ := o.GetQueue()
+	if len(queue) != 1 || queue[0].Attempts != 1 || queue[0].Title != "call mom" || !queue[0].NextAttempt.Equal(now.Add(outboxBaseDelay)) {
+		t.Fatalf("unexpected queue: %+v", queue)
+	}
+
+	o.deliverDue()
+	if len(sent) != 1 {
+		t.Fatalf("nothing is due before the backoff expires, sent %d", len(sent))
+	}
+
+	now = now.Add(outboxBaseDelay)
+	o.deliverDue()
+	if queue, _ := o.GetQueue(); len(sent) != 2 || !queue[0].NextAttempt.Equal(now.Add(2*outboxBaseDelay)) {
+		t.Fatalf("expected a second attempt with a longer backoff, got %+v", queue)
+	}
+
+	// A day later the task still keeps the date it was submitted with.
+	now = now.Add(24 * time.Hour)
+	status = ""
+	o.deliverDue()
+	if queue, _ := o.GetQueue(); len(queue) != 0 {
+		t.Fatalf("expected the queue to drain, got %+v", queue)
+	}
+	if *sent[2].Date != "2025-10-16" {
+		t.Fatalf("relative dates must resolve against the submission time, got %s", *sent[2].Date)
+	}
+}
+
+func TestOutboxParsesAgainAfterFallback(t *testing.T) {
+	now := time.Date(2025, time.October, 15, 9, 0, 0, 0, time.UTC)
+	var sent []string
+	status := "notion api error: status 503, body: "
+	o := newTestOutbox(t, &now, func(_ context.Context, task TaskInformation) PageResult {
+		sent = append(sent, task.Title)
+		if status != "" {
+			return PageResult{Category: ErrorNetwork, Error: status}
+		}
+		return PageResult{PageID: "page-1"}
+	})
+
+	// Nobody is signed in, so the local parser stands in for the server.
+	entries, err := o.enqueue([]string{"pay rent friday"}, nil, "")
+	if err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	o.deliver(entries)
+	if len(o.entries) != 1 || !o.entries[0].ParsedLocally {
+		t.Fatalf("expected the fallback parse to be marked, got %+v", o.entries)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"choices":[{"index":0,"message":{"role":"assistant","content":"{\"title\":\"Pay rent\",\"date\":\"2025-10-17\"}"}}]}`)
+	}))
+	t.Cleanup(srv.Close)
+	settings := &settingsservice.SettingsService{
+		AppSettings: settingsservice.ApplicationSettings{
+			TimeZone:       "UTC",
+			UseOpenAI:      true,
+			ParserProvider: llm.KindOpenAICompatible,
+			ParserProviders: map[string]settingsservice.ParserProviderSettings{
+				llm.KindOpenAICompatible: {BaseURL: srv.URL + "/v1", Model: "llama3.1"},
+			},
+		},
+	}
+	c.AppConfig = &settings.AppSettings
+	o.tasks.settings = settings
+
+	now = now.Add(outboxBaseDelay)
+	status = ""
+	o.deliverDue()
+	if strings.Join(sent, ",") != "pay rent,Pay rent" {
+		t.Fatalf("expected the retry to use the model's parse, sent %v", sent)
+	}
+	if queue, _ := o.GetQueue(); len(queue) != 0 {
+		t.Fatalf("expected the queue to drain, got %+v", queue)
+	}
+}
+
+func TestOutboxSurvivesRestart(t *testing.T) {
+	now := time.Date(2025, time.October, 15, 9, 0, 0, 0, time.UTC)
+	o := newTestOutbox(t, &now, func(context.Context, TaskInformation) PageResult {
+		return PageResult{Category: ErrorNetwork, Error: "notion api error: status 502, body: "}
+	})
+	if _, err := o.enqueue([]string{"buy milk", "water plants"}, nil, ""); err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	// The app quits before either task is sent.
+
+	var sent []string
+	restarted := NewOutboxService(o.tasks, nil)
+	restarted.now = o.now
+	restarted.path = o.path
+	o.tasks.createPage = func(_ context.Context, task TaskInformation) PageResult {
+		sent = append(sent, task.Title)
+		return PageResult{PageID: "page-" + task.Title}
+	}
+
+	queue, err := restarted.GetQueue()
+	if err != nil || len(queue) != 2 {
+		t.Fatalf("expected both tasks after a restart, got %+v (err %v)", queue, err)
+	}
+	if err := restarted.Discard(queue[1].ID); err != nil {
+		t.Fatalf("Discard: %v", err)
+	}
+	if err := restarted.Discard(queue[1].ID); err == nil {
+		t.Fatal("expected an error for an unknown task")
+	}
+
+	restarted.deliverDue()
+	if strings.Join(sent, ",") != "buy milk" {
+		t.Fatalf("expected only the kept task to be sent, got %v", sent)
+	}
+	if queue, _ := restarted.GetQueue(); len(queue) != 0 {
+		t.Fatalf("expected an empty queue, got %+v", queue)
+	}
+}
+
+func TestOutboxRetryNow(t *testing.T) {
+	now := time.Date(2025, time.October, 15, 9, 0, 0, 0, time.UTC)
+	calls := 0
+	o := newTestOutbox(t, &now, func(context.Context, TaskInformation) PageResult {
+		calls++
+		return PageResult{Category: ErrorRateLimited, Error: "notion api error: status 429, body: "}
+	})
+
+	entries, _ := o.enqueue([]string{"buy milk"}, nil, "")
+	o.deliver(entries)
+
+	if err := o.RetryNow("missing"); err == nil {
+		t.Fatal("expected an error for an unknown task")
+	}
+	if err := o.RetryNow(entries[0].ID); err != nil {
+		t.Fatalf("RetryNow: %v", err)
+	}
+	o.deliverDue()
+	if calls != 2 {
+		t.Fatalf("expected an immediate retry, got %d calls", calls)
+	}
+}
+
+func TestOutboxParksPermanentFailures(t *testing.T) {
+	now := time.Date(2025, time.October, 15, 9, 0, 0, 0, time.UTC)
+	result := PageResult{Category: ErrorValidation, Error: "notion api error: status 400, body: "}
+	calls := 0
+	o := newTestOutbox(t, &now, func(context.Context, TaskInformation) PageResult {
+		calls++
+		return result
+	})
+
+	entries, _ := o.enqueue([]string{"buy milk"}, nil, "")
+	if got := o.deliver(entries)[0]; !got.Queued || !got.Parked {
+		t.Fatalf("expected a rejected task to be parked, got %+v", got)
+	}
+	queue, _ := o.GetQueue()
+	if len(queue) != 1 || !queue[0].Parked || queue[0].Category != ErrorValidation {
+		t.Fatalf("unexpected queue: %+v", queue)
+	}
+
+	now = now.Add(24 * time.Hour)
+	o.deliverDue()
+	if calls != 1 {
+		t.Fatalf("a parked task must wait for the user, got %d calls", calls)
+	}
+
+	// Retried by the user, it fails for a passing reason and is retried.
+	result = PageResult{Category: ErrorNetwork, Error: "notion api error: status 503, body: "}
+	if err := o.RetryNow(""); err != nil {
+		t.Fatalf("RetryNow: %v", err)
+	}
+	o.deliverDue()
+	if queue, _ := o.GetQueue(); calls != 2 || queue[0].Parked || !queue[0].NextAttempt.After(now) {
+		t.Fatalf("expected the task unparked and scheduled, got %+v after %d calls", queue, calls)
+	}
+}
+
+func TestOutboxBackoff(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{4, 4 * time.Minute},
+		{8, outboxMaxDelay},
+		{100, outboxMaxDelay},
+	}
+	for _, tt := range tests {
+		if got := outboxBackoff(tt.attempts); got != tt.want {
+			t.Errorf("outboxBackoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+// blockingCreate returns a createPage that signals started and then waits for
+// its context to end.
+func blockingCreate(started chan<- struct{}) func(context.Context, TaskInformation) PageResult {
+	return func(ctx context.Context, task TaskInformation) PageResult {
+		started <- struct{}{}
+		<-ctx.Done()
+		return failedPage(ctx.Err())
+	}
+}
+
+func TestOutboxDiscardCancelsInFlight(t *testing.T) {
+	now := time.Date(2025, time.October, 15, 9, 0, 0, 0, time.UTC)
+	started := make(chan struct{}, 1)
+	o := newTestOutbox(t, &now, blockingCreate(started))
+
+	entries, err := o.enqueue([]string{"buy milk"}, nil, "")
+	if err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	done := make(chan []TaskResult)
+	go func() { done <- o.deliver(entries) }()
+
+	<-started
+	if err := o.Discard(entries[0].ID); err != nil {
+		t.Fatalf("Discard: %v", err)
+	}
+
+	select {
+	case results := <-done:
+		if results[0].OK || results[0].Queued || !strings.Contains(results[0].Error, "cancelled") {
+			t.Fatalf("unexpected result: %+v", results[0])
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Discard did not cancel the request in flight")
+	}
+	if queue, _ := o.GetQueue(); len(queue) != 0 {
+		t.Fatalf("expected the queue to be empty, got %+v", queue)
+	}
+}
+
+func TestOutboxShutdownKeepsAttempts(t *testing.T) {
+	now := time.Date(2025, time.October, 15, 9, 0, 0, 0, time.UTC)
+	started := make(chan struct{}, 1)
+	o := newTestOutbox(t, &now, blockingCreate(started))
+
+	entries, err := o.enqueue([]string{"buy milk"}, nil, "")
+	if err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	done := make(chan []TaskResult)
+	go func() { done <- o.deliver(entries) }()
+
+	<-started
+	o.tasks.Shutdown()
+
+	select {
+	case results := <-done:
+		if !results[0].Queued {
+			t.Fatalf("an interrupted task must stay queued, got %+v", results[0])
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Shutdown did not cancel the request in flight")
+	}
+
+	queue, _ := o.GetQueue()
+	if len(queue) != 1 || queue[0].Attempts != 0 {
+		t.Fatalf("quitting must not count as a failed attempt, got %+v", queue)
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/imjamesonzeller/tasklight-v3/notionapi"
+	"github.com/imjamesonzeller/tasklight-v3/notionblocks"
+)
+
+// pageBody returns the blocks written below a new page's properties: notes
+// that did not fit the notes property, read as markdown, then the clipboard.
+// A clipboard link goes to the property receiving the clipboard instead when
+// the data source has one.
+func pageBody(task TaskInformation, fields taskFieldProperties, properties map[string]any) []notionblocks.Block {
+	var body []notionblocks.Block
+	if task.Notes != "" && (fields.Notes == nil || !notionblocks.IsInline(task.Notes)) {
+		body = append(body, notionblocks.FromMarkdown(task.Notes)...)
+	}
+
+	if task.Clipboard != "" {
+		written := false
+		if link, ok := clipboardLink(task.Clipboard); ok && fields.URL != nil {
+			var value map[string]any
+			if value, written = propertyValue(*fields.URL, []string{link}, nil); written {
+				properties[fields.URL.Name] = value
+			}
+		}
+		if !written {
+			body = append(body, clipboardBlocks(task.Clipboard)...)
+		}
+	}
+	return body
+}
+
+// splitPageBody keeps the blocks Notion accepts with a new page in payload
+// and returns the rest, which must be appended once the page exists.
+func splitPageBody(payload map[string]any) []notionblocks.Block {
+	body, _ := payload["children"].([]notionblocks.Block)
+	if len(body) <= notionblocks.MaxChildren {
+		return nil
+	}
+	payload["children"] = body[:notionblocks.MaxChildren]
+	return body[notionblocks.MaxChildren:]
+}
+
+// appendBlocks adds blocks to the end of a page or block, as many per request
+// as Notion allows.
+func (ts *TaskService) appendBlocks(ctx context.Context, token, blockID string, blocks []notionblocks.Block) error {
+	for len(blocks) > 0 {
+		n := min(len(blocks), notionblocks.MaxChildren)
+		req, err := notionapi.NewJSONRequest(ctx, http.MethodPatch, notionapi.URL("/blocks/"+blockID+"/children"), token, map[string]any{"children": blocks[:n]})
+		if err != nil {
+			return err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		if err := notionapi.ParseResponse(resp, nil, ErrNotionTokenMissing); err != nil {
+			return err
+		}
+		blocks = blocks[n:]
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	c "github.com/imjamesonzeller/tasklight-v3/config"
+	"github.com/imjamesonzeller/tasklight-v3/notionapi"
+	"github.com/imjamesonzeller/tasklight-v3/notionblocks"
+	"github.com/imjamesonzeller/tasklight-v3/settingsservice"
+)
+
+func TestBuildNotionPagePayloadWithMarkdownNotes(t *testing.T) {
+	t.Parallel()
+
+	withNotes := taskFieldProperties{Notes: &PropertyObj{Name: "Notes", Type: "rich_text"}}
+
+	inline := TaskInformation{Title: "Call Sam", Notes: "ask about **Friday**"}
+	payload := buildNotionPagePayload(inline, "ds-1", "Name", "", withNotes)
+	notes := payload["properties"].(map[string]any)["Notes"].(map[string]any)["rich_text"].([]notionblocks.RichText)
+	if len(notes) != 2 || notes[1]["annotations"] == nil {
+		t.Fatalf("expected annotated notes in the property, got %v", notes)
+	}
+	if _, ok := payload["children"]; ok {
+		t.Fatal("notes kept in the property must not also fill the body")
+	}
+
+	list := TaskInformation{Title: "Pack", Notes: "- [ ] passport - [ ] charger"}
+	payload = buildNotionPagePayload(list, "ds-1", "Name", "", withNotes)
+	if _, ok := payload["properties"].(map[string]any)["Notes"]; ok {
+		t.Fatal("a checklist must not be flattened into the notes property")
+	}
+	body := payload["children"].([]notionblocks.Block)
+	if len(body) != 2 || body[0]["type"] != "to_do" {
+		t.Fatalf("expected a checklist in the body, got %v", body)
+	}
+
+	// Without a notes property even a single paragraph goes to the body.
+	payload = buildNotionPagePayload(inline, "ds-1", "Name", "", taskFieldProperties{})
+	if body := payload["children"].([]notionblocks.Block); len(body) != 1 || body[0]["type"] != "paragraph" {
+		t.Fatalf("expected the notes in the body, got %v", body)
+	}
+}
+
+func TestLongBodyIsAppendedAfterCreate(t *testing.T) {
+	var mu sync.Mutex
+	var requests []string
+	var sent []int
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			Children []json.RawMessage `json:"children"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+
+		mu.Lock()
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		sent = append(sent, len(payload.Children))
+		mu.Unlock()
+
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/data_sources/ds-1":
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"id":         "ds-1",
+				"properties": map[string]any{"Name": map[string]any{"id": "title", "type": "title"}},
+			})
+		case r.Method == http.MethodPost && r.URL.Path == "/pages":
+			_ = json.NewEncoder(w).Encode(map[string]any{"id": "page-1", "url": "https://www.notion.so/page-1"})
+		case r.Method == http.MethodPatch && r.URL.Path == "/blocks/page-1/children":
+			_ = json.NewEncoder(w).Encode(map[string]any{"results": []any{}})
+		default:
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	originalBase := notionapi.BaseURL
+	originalConfig := c.AppConfig
+	t.Cleanup(func() {
+		notionapi.BaseURL = originalBase
+		c.AppConfig = originalConfig
+	})
+	notionapi.BaseURL = srv.URL
+	settings := &settingsservice.SettingsService{
+		AppSettings: settingsservice.ApplicationSettings{
+			NotionAccessToken:  "secret",
+			NotionDataSourceID: "ds-1",
+		},
+	}
+	c.AppConfig = &settings.AppSettings
+	ts := NewTaskService(nil, settings)
+
+	var notes strings.Builder
+	for i := 1; i <= 250; i++ {
+		fmt.Fprintf(&notes, "- [ ] item %d\n", i)
+	}
+	result := ts.createNotionPage(context.Background(), TaskInformation{Title: "Inventory", Notes: notes.String()})
+	if !result.OK() || result.PageID != "page-1" {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+
+	want := "GET /data_sources/ds-1,POST /pages,PATCH /blocks/page-1/children,PATCH /blocks/page-1/children"
+	if strings.Join(requests, ",") != want {
+		t.Fatalf("unexpected requests: %v", requests)
+	}
+	if sent[1] != 100 || sent[2] != 100 || sent[3] != 50 {
+		t.Fatalf("expected 100 blocks with the page and the rest in batches, got %v", sent)
+	}
+}
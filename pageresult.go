@@ -0,0 +1,114 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/imjamesonzeller/tasklight-v3/notionapi"
+	"github.com/imjamesonzeller/tasklight-v3/settingsservice"
+)
+
+// ErrorCategory says why a page could not be created, so the UI can react
+// differently to, say, a revoked token and a network outage.
+type ErrorCategory string
+
+const (
+	// ErrorAuth means the Notion token is missing, expired or revoked.
+	ErrorAuth ErrorCategory = "auth"
+	// ErrorNotFound means the data source is gone or not shared with the
+	// integration.
+	ErrorNotFound ErrorCategory = "not_found"
+	// ErrorValidation means Notion rejected the page itself.
+	ErrorValidation  ErrorCategory = "validation"
+	ErrorRateLimited ErrorCategory = "rate_limited"
+	// ErrorNetwork covers transport failures and Notion being unavailable.
+	ErrorNetwork ErrorCategory = "network"
+	// ErrorConfig means Tasklight is not set up to create pages yet.
+	ErrorConfig ErrorCategory = "config"
+)
+
+// PageResult is the outcome of creating a Notion page. Error is empty on
+// success.
+type PageResult struct {
+	PageID string `json:"page_id,omitempty"`
+	URL    string `json:"url,omitempty"`
+	// DataSourceID is the data source the page was, or would have been,
+	// created in.
+	DataSourceID string        `json:"data_source_id,omitempty"`
+	LatencyMS    int64         `json:"latency_ms"`
+	Category     ErrorCategory `json:"error_category,omitempty"`
+	Error        string        `json:"error,omitempty"`
+	// Duplicate is the existing page the task looked like, if any.
+	Duplicate *DuplicateMatch `json:"duplicate,omitempty"`
+	// Destination is the destination the task was routed to, empty for the
+	// default destination, and RoutedBy what chose it.
+	Destination string `json:"destination,omitempty"`
+	RoutedBy    string `json:"routed_by,omitempty"`
+	// Warnings describe what was left out of the page without failing it,
+	// such as a default value whose option was deleted.
+	Warnings []string `json:"warnings,omitempty"`
+	// Options reports tags and projects written as an existing option,
+	// created, dropped or replaced by a fallback.
+	Options []OptionMatch `json:"options,omitempty"`
+	// CreatedProject is the project page created for the task.
+	CreatedProject string `json:"created_project,omitempty"`
+}
+
+func (r PageResult) OK() bool {
+	return r.Error == ""
+}
+
+// Created reports whether a new page was made, as opposed to an existing
+// page being kept or updated in its place.
+func (r PageResult) Created() bool {
+	if !r.OK() {
+		return false
+	}
+	return r.Duplicate == nil || r.Duplicate.Action == settingsservice.DuplicatesWarn
+}
+
+// failedPage returns the result for err, classified by errorCategory.
+func failedPage(err error) PageResult {
+	return PageResult{Category: errorCategory(err), Error: err.Error()}
+}
+
+// categorizedError carries a category for errors that are not Notion
+// responses, such as a missing data source.
+type categorizedError struct {
+	category ErrorCategory
+	err      error
+}
+
+func (e *categorizedError) Error() string { return e.err.Error() }
+func (e *categorizedError) Unwrap() error { return e.err }
+
+func withCategory(category ErrorCategory, err error) error {
+	return &categorizedError{category: category, err: err}
+}
+
+// errorCategory classifies an error from resolving the target or calling
+// Notion. Unrecognised errors count as network failures, which are retried.
+func errorCategory(err error) ErrorCategory {
+	var categorized *categorizedError
+	if errors.As(err, &categorized) {
+		return categorized.category
+	}
+	if errors.Is(err, ErrNotionTokenMissing) {
+		return ErrorAuth
+	}
+
+	var apiErr *notionapi.APIError
+	if errors.As(err, &apiErr) {
+		switch {
+		case apiErr.Status == http.StatusTooManyRequests || apiErr.Code == "rate_limited":
+			return ErrorRateLimited
+		case apiErr.Status == http.StatusNotFound || apiErr.Code == "object_not_found" || apiErr.Code == "restricted_resource":
+			return ErrorNotFound
+		case apiErr.Status >= 500:
+			return ErrorNetwork
+		default:
+			return ErrorValidation
+		}
+	}
+	return ErrorNetwork
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"testing"
+
+	"github.com/imjamesonzeller/tasklight-v3/notionapi"
+)
+
+func TestErrorCategory(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		err  error
+		want ErrorCategory
+	}{
+		{"revoked token", fmt.Errorf("%w: status 401, body: {}", ErrNotionTokenMissing), ErrorAuth},
+		{"missing token", withCategory(ErrorAuth, errors.New("Notion token unavailable")), ErrorAuth},
+		{"no data source", withCategory(ErrorConfig, errors.New("Data source not selected")), ErrorConfig},
+		{"unshared data source", fmt.Errorf("Failed to load Notion data source: %w", &notionapi.APIError{Status: 404, Code: "object_not_found"}), ErrorNotFound},
+		{"restricted", &notionapi.APIError{Status: 403, Code: "restricted_resource"}, ErrorNotFound},
+		{"bad property", &notionapi.APIError{Status: 400, Code: "validation_error"}, ErrorValidation},
+		{"rate limited", &notionapi.APIError{Status: 429, Code: "rate_limited"}, ErrorRateLimited},
+		{"notion down", &notionapi.APIError{Status: 503}, ErrorNetwork},
+		{"offline", &url.Error{Op: "Post", URL: "https://api.notion.com/v1/pages", Err: errors.New("no such host")}, ErrorNetwork},
+	}
+
+	for _, tt := range tests {
+		if got := errorCategory(tt.err); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFailedPage(t *testing.T) {
+	t.Parallel()
+
+	result := failedPage(withCategory(ErrorConfig, errors.New("Data source not selected")))
+	if result.OK() || result.Category != ErrorConfig || result.Error != "Data source not selected" {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	if !(PageResult{PageID: "page-1"}).OK() {
+		t.Fatal("a result without an error is a success")
+	}
+}
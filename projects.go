@@ -0,0 +1,282 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/imjamesonzeller/tasklight-v3/notionapi"
+	"github.com/imjamesonzeller/tasklight-v3/settingsservice"
+)
+
+const (
+	// projectPagesTTL is how long the page list of a related data source is
+	// trusted.
+	projectPagesTTL = 10 * time.Minute
+	// maxProjectPages bounds how many pages of a related data source are
+	// listed.
+	maxProjectPages = 1000
+	// maxProjectCandidates bounds the pages offered for an ambiguous project.
+	maxProjectCandidates = 5
+)
+
+// ProjectPage is a page of the data source a project relation links to.
+type ProjectPage struct {
+	PageID string `json:"page_id"`
+	Title  string `json:"title"`
+	URL    string `json:"url,omitempty"`
+}
+
+type projectList struct {
+	pages   []ProjectPage
+	fetched time.Time
+}
+
+// relatedSource is the data source a relation links to and its title
+// property.
+type relatedSource struct {
+	dataSourceID string
+	titleProp    string
+}
+
+// relatedSource loads the target of the relation prop.
+func (ts *TaskService) relatedSource(ctx context.Context, token string, prop PropertyObj) (relatedSource, error) {
+	if prop.Relation == nil || prop.Relation.DataSourceID == "" {
+		return relatedSource{}, fmt.Errorf("%q does not say which data source it links to", prop.Name)
+	}
+	detail, err := ts.loadDataSourceDetail(ctx, token, prop.Relation.DataSourceID)
+	if err != nil {
+		return relatedSource{}, err
+	}
+	title, err := detectTitleProperty(detail)
+	if err != nil {
+		return relatedSource{}, err
+	}
+	return relatedSource{dataSourceID: prop.Relation.DataSourceID, titleProp: title}, nil
+}
+
+// projectPages lists the pages of a related data source, cached for
+// projectPagesTTL.
+func (ts *TaskService) projectPages(ctx context.Context, token string, source relatedSource) ([]ProjectPage, error) {
+	ts.projectsMu.Lock()
+	defer ts.projectsMu.Unlock()
+	if cached, ok := ts.projects[source.dataSourceID]; ok && time.Since(cached.fetched) < projectPagesTTL {
+		return cached.pages, nil
+	}
+
+	pages, err := queryProjectPages(ctx, token, source, nil, maxProjectPages)
+	if err != nil {
+		return nil, err
+	}
+	if ts.projects == nil {
+		ts.projects = map[string]projectList{}
+	}
+	ts.projects[source.dataSourceID] = projectList{pages: pages, fetched: time.Now()}
+	return pages, nil
+}
+
+// rememberProjectPage adds a page created since the list was cached.
+func (ts *TaskService) rememberProjectPage(dataSourceID string, page ProjectPage) {
+	ts.projectsMu.Lock()
+	defer ts.projectsMu.Unlock()
+	if cached, ok := ts.projects[dataSourceID]; ok {
+		cached.pages = append(slices.Clip(cached.pages), page)
+		ts.projects[dataSourceID] = cached
+	}
+}
+
+// forgetProjectPage drops a trashed page from the cached lists.
+func (ts *TaskService) forgetProjectPage(pageID string) {
+	ts.projectsMu.Lock()
+	defer ts.projectsMu.Unlock()
+	for id, cached := range ts.projects {
+		cached.pages = slices.DeleteFunc(slices.Clone(cached.pages), func(p ProjectPage) bool { return p.PageID == pageID })
+		ts.projects[id] = cached
+	}
+}
+
+// queryProjectPages lists up to limit pages of source matching filter, or
+// all of them for a nil filter.
+func queryProjectPages(ctx context.Context, token string, source relatedSource, filter map[string]any, limit int) ([]ProjectPage, error) {
+	var pages []ProjectPage
+	cursor := ""
+	for len(pages) < limit {
+		query := map[string]any{"page_size": 100}
+		if filter != nil {
+			query["filter"] = filter
+		}
+		if cursor != "" {
+			query["start_cursor"] = cursor
+		}
+		req, err := notionapi.NewJSONRequest(ctx, http.MethodPost, notionapi.URL("/data_sources/"+source.dataSourceID+"/query"), token, query)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		var result struct {
+			Results []struct {
+				ID         string                  `json:"id"`
+				URL        string                  `json:"url"`
+				Properties map[string]pageProperty `json:"properties"`
+			} `json:"results"`
+			HasMore    bool   `json:"has_more"`
+			NextCursor string `json:"next_cursor"`
+		}
+		if err := notionapi.ParseResponse(resp, &result, ErrNotionTokenMissing); err != nil {
+			return nil, err
+		}
+		for _, page := range result.Results {
+			title := richTextPlainText(page.Properties[source.titleProp].Title)
+			if strings.TrimSpace(title) != "" {
+				pages = append(pages, ProjectPage{PageID: page.ID, Title: title, URL: page.URL})
+			}
+		}
+		if !result.HasMore || result.NextCursor == "" {
+			break
+		}
+		cursor = result.NextCursor
+	}
+	return pages, nil
+}
+
+// findProjectPages returns the pages value names: those with the same title
+// up to case, punctuation or plural, else the closest titles above
+// optionMatchThreshold, else those whose title contains value as whole
+// words.
+func findProjectPages(pages []ProjectPage, value string) []ProjectPage {
+	key := optionKey(value)
+	if key == "" {
+		return nil
+	}
+
+	var same, contains, closest []ProjectPage
+	bestScore := optionMatchThreshold
+	for _, page := range pages {
+		title := optionKey(page.Title)
+		if title == key {
+			same = append(same, page)
+			continue
+		}
+		if strings.Contains(" "+title+" ", " "+key+" ") {
+			contains = append(contains, page)
+		}
+		switch score := titleSimilarity(key, title); {
+		case score > bestScore:
+			closest, bestScore = []ProjectPage{page}, score
+		case score == bestScore:
+			closest = append(closest, page)
+		}
+	}
+	switch {
+	case len(same) > 0:
+		return same
+	case len(closest) > 0:
+		return closest
+	}
+	return contains
+}
+
+// linkProject resolves the project of task to a page of the data source its
+// relation property links to, and sets task.Project to the page ID. A link
+// or page ID typed by the user is kept as is. A project no page matches is
+// left out unless the destination creates project pages; with create false,
+// as in a preview, no page is created. The match is nil when the project
+// needs no report.
+func (ts *TaskService) linkProject(ctx context.Context, target *pageTarget, task TaskInformation, create bool) (TaskInformation, *OptionMatch, error) {
+	prop := target.fields.Project
+	if prop == nil || prop.Type != "relation" || strings.TrimSpace(task.Project) == "" {
+		return task, nil, nil
+	}
+	if _, ok := notionPageID(task.Project); ok {
+		return task, nil, nil
+	}
+
+	match := &OptionMatch{Field: "project", Property: prop.Name, Value: task.Project}
+	source, err := ts.relatedSource(ctx, target.token, *prop)
+	if err != nil {
+		return task, nil, err
+	}
+	pages, err := ts.projectPages(ctx, target.token, source)
+	if err != nil {
+		return task, nil, err
+	}
+	found := findProjectPages(pages, task.Project)
+	if len(found) == 0 {
+		// The project may have been added since the list was cached.
+		filter := map[string]any{"property": source.titleProp, "title": map[string]any{"contains": task.Project}}
+		recent, err := queryProjectPages(ctx, target.token, source, filter, 100)
+		if err != nil {
+			return task, nil, err
+		}
+		found = findProjectPages(recent, task.Project)
+	}
+
+	switch {
+	case len(found) == 1:
+		task.Project = found[0].PageID
+		if strings.EqualFold(found[0].Title, match.Value) {
+			return task, nil, nil
+		}
+		match.Option, match.URL, match.Outcome = found[0].Title, found[0].URL, OptionMatched
+		return task, match, nil
+
+	case len(found) > 1:
+		match.Outcome = OptionAmbiguous
+		match.Candidates = found[:min(len(found), maxProjectCandidates)]
+		return task, match, nil
+	}
+
+	if unmatchedAction(*prop, target.fields.Unmatched[prop.ID]) != settingsservice.UnmatchedCreate {
+		match.Outcome = OptionDropped
+		return task, match, nil
+	}
+	match.Option, match.Outcome = strings.TrimSpace(task.Project), OptionCreated
+	if !create {
+		return task, match, nil
+	}
+	page, err := ts.createProjectPage(ctx, target.token, source, match.Option)
+	if err != nil {
+		return task, nil, err
+	}
+	ts.rememberProjectPage(source.dataSourceID, page)
+	task.Project, match.URL = page.PageID, page.URL
+	return task, match, nil
+}
+
+// createProjectPage adds a page titled title to the related data source.
+func (ts *TaskService) createProjectPage(ctx context.Context, token string, source relatedSource, title string) (ProjectPage, error) {
+	payload := map[string]any{
+		"parent": map[string]any{"type": "data_source_id", "data_source_id": source.dataSourceID},
+		"properties": map[string]any{
+			source.titleProp: map[string]any{
+				"title": []map[string]any{{"type": "text", "text": map[string]any{"content": title}}},
+			},
+		},
+	}
+	req, err := notionapi.NewJSONRequest(ctx, http.MethodPost, notionapi.URL("/pages"), token, payload)
+	if err != nil {
+		return ProjectPage{}, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return ProjectPage{}, err
+	}
+	var created struct {
+		ID  string `json:"id"`
+		URL string `json:"url"`
+	}
+	if err := notionapi.ParseResponse(resp, &created, ErrNotionTokenMissing); err != nil {
+		return ProjectPage{}, err
+	}
+	if created.ID == "" {
+		return ProjectPage{}, errors.New("Notion returned no page ID")
+	}
+	return ProjectPage{PageID: created.ID, Title: title, URL: created.URL}, nil
+}
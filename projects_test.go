@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	c "github.com/imjamesonzeller/tasklight-v3/config"
+	"github.com/imjamesonzeller/tasklight-v3/notionapi"
+	"github.com/imjamesonzeller/tasklight-v3/settingsservice"
+)
+
+func TestFindProjectPages(t *testing.T) {
+	t.Parallel()
+
+	pages := []ProjectPage{
+		{PageID: "p1", Title: "Website Redesign"},
+		{PageID: "p2", Title: "Website Launch"},
+		{PageID: "p3", Title: "Errands"},
+		{PageID: "p4", Title: "Q3 Planning"},
+		{PageID: "p5", Title: "q3 planning"},
+	}
+	tests := []struct {
+		value string
+		want  []string
+	}{
+		{"errand", []string{"p3"}},
+		{"website redesing", []string{"p1"}},
+		{"website", []string{"p1", "p2"}},
+		{"Q3 planning", []string{"p4", "p5"}},
+		{"launch", []string{"p2"}},
+		{"garden", nil},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, page := range findProjectPages(pages, tt.value) {
+			got = append(got, page.PageID)
+		}
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("findProjectPages(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestLinkProject(t *testing.T) {
+	const (
+		redesign = "11111111-1111-1111-1111-111111111111"
+		launch   = "22222222-2222-2222-2222-222222222222"
+		garden   = "33333333-3333-3333-3333-333333333333"
+		kitchen  = "44444444-4444-4444-4444-444444444444"
+	)
+	var listRequests, titleQueries, projectsCreated int
+	var taskPage map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/data_sources/ds-1":
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"id": "ds-1",
+				"properties": map[string]any{
+					"Name":    map[string]any{"id": "title", "name": "Name", "type": "title"},
+					"Project": map[string]any{"id": "r1", "name": "Project", "type": "relation", "relation": map[string]any{"data_source_id": "ds-projects"}},
+				},
+			})
+		case "/data_sources/ds-projects":
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"id": "ds-projects",
+				"properties": map[string]any{
+					"Project name": map[string]any{"id": "title", "name": "Project name", "type": "title"},
+				},
+			})
+		case "/data_sources/ds-projects/query":
+			var query map[string]any
+			_ = json.NewDecoder(r.Body).Decode(&query)
+			if query["filter"] != nil {
+				titleQueries++
+			} else {
+				listRequests++
+			}
+			page := func(id, title string) map[string]any {
+				return map[string]any{"id": id, "url": "https://notion.so/" + id, "properties": map[string]any{
+					"Project name": map[string]any{"type": "title", "title": []any{map[string]any{"plain_text": title}}},
+				}}
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"results": []any{
+				page(redesign, "Website Redesign"),
+				page(launch, "Website Launch"),
+				page(garden, "Garden"),
+			}})
+		case "/pages":
+			var payload struct {
+				Parent struct {
+					DataSourceID string `json:"data_source_id"`
+				} `json:"parent"`
+				Properties map[string]any `json:"properties"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&payload)
+			if payload.Parent.DataSourceID == "ds-projects" {
+				projectsCreated++
+				if _, ok := payload.Properties["Project name"]; !ok {
+					t.Errorf("expected the project page titled, got %v", payload.Properties)
+				}
+				_ = json.NewEncoder(w).Encode(map[string]any{"id": kitchen, "url": "https://notion.so/kitchen"})
+				return
+			}
+			taskPage = payload.Properties
+			_ = json.NewEncoder(w).Encode(map[string]any{"id": "task-page"})
+		default:
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	originalBase := notionapi.BaseURL
+	originalConfig := c.AppConfig
+	t.Cleanup(func() {
+		notionapi.BaseURL = originalBase
+		c.AppConfig = originalConfig
+	})
+	notionapi.BaseURL = srv.URL
+	settings := &settingsservice.SettingsService{
+		AppSettings: settingsservice.ApplicationSettings{NotionAccessToken: "secret", NotionDataSourceID: "ds-1"},
+	}
+	c.AppConfig = &settings.AppSettings
+	ts := NewTaskService(nil, settings)
+	ctx := context.Background()
+
+	target, err := ts.resolveTarget(ctx, "")
+	if err != nil {
+		t.Fatalf("resolveTarget: %v", err)
+	}
+	if target.fields.Project == nil || target.fields.Project.Type != "relation" {
+		t.Fatalf("expected the project relation found by name, got %+v", target.fields.Project)
+	}
+
+	task, match, err := ts.linkProject(ctx, target, TaskInformation{Project: "garden"}, true)
+	if err != nil || task.Project != garden || match != nil {
+		t.Fatalf("expected garden linked without a report, got %q, %+v, %v", task.Project, match, err)
+	}
+	task, match, _ = ts.linkProject(ctx, target, TaskInformation{Project: "website redesing"}, true)
+	if task.Project != redesign || match == nil || match.Outcome != OptionMatched || match.Option != "Website Redesign" {
+		t.Fatalf("expected the misspelt project matched, got %q, %+v", task.Project, match)
+	}
+	task, match, _ = ts.linkProject(ctx, target, TaskInformation{Project: "website"}, true)
+	if task.Project != "website" || match == nil || match.Outcome != OptionAmbiguous || len(match.Candidates) != 2 {
+		t.Fatalf("expected website to be ambiguous, got %q, %+v", task.Project, match)
+	}
+
+	// Unknown projects are left out unless the destination creates them.
+	task, match, _ = ts.linkProject(ctx, target, TaskInformation{Project: "Kitchen"}, true)
+	if match == nil || match.Outcome != OptionDropped || projectsCreated != 0 {
+		t.Fatalf("expected Kitchen dropped, got %q, %+v", task.Project, match)
+	}
+	if titleQueries != 1 {
+		t.Fatalf("expected a title query before giving up on a cached list, got %d", titleQueries)
+	}
+
+	target.fields.Unmatched = map[string]settingsservice.UnmatchedOption{"r1": {Action: settingsservice.UnmatchedCreate}}
+	if _, match, _ = ts.linkProject(ctx, target, TaskInformation{Project: "Kitchen"}, false); match.Outcome != OptionCreated || projectsCreated != 0 {
+		t.Fatalf("a preview must not create the project, got %+v after %d pages", match, projectsCreated)
+	}
+	task, match, _ = ts.linkProject(ctx, target, TaskInformation{Project: "Kitchen"}, true)
+	if task.Project != kitchen || match.Outcome != OptionCreated || projectsCreated != 1 {
+		t.Fatalf("expected Kitchen created, got %q, %+v", task.Project, match)
+	}
+	if task, _, _ = ts.linkProject(ctx, target, TaskInformation{Project: "kitchen"}, true); task.Project != kitchen || projectsCreated != 1 {
+		t.Fatalf("expected the created project to be remembered, got %q after %d pages", task.Project, projectsCreated)
+	}
+	if listRequests != 1 {
+		t.Fatalf("expected the project list to be cached, got %d requests", listRequests)
+	}
+
+	// The linked page is written into the relation.
+	result := ts.postNotionPage(ctx, TaskInformation{Title: "Pick tiles", Project: "Garden"}, settingsservice.DuplicatesOff)
+	if !result.OK() {
+		t.Fatalf("postNotionPage: %+v", result)
+	}
+	relation, _ := json.Marshal(taskPage["Project"])
+	if !strings.Contains(string(relation), garden) {
+		t.Fatalf("expected the relation to link %s, got %s", garden, relation)
+	}
+}
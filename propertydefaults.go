@@ -0,0 +1,258 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/imjamesonzeller/tasklight-v3/notionapi"
+	"github.com/imjamesonzeller/tasklight-v3/settingsservice"
+)
+
+// defaultableTypes are the property types that can have a default value.
+var defaultableTypes = []string{"select", "status", "multi_select", "checkbox", "number", "url", "rich_text", "people", "relation"}
+
+// defaultValues splits a default into the values of prop.
+func defaultValues(prop PropertyObj, value string) []string {
+	switch prop.Type {
+	case "multi_select", "people", "relation":
+		var values []string
+		for _, v := range strings.Split(value, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
+		}
+		return values
+	}
+	return []string{strings.TrimSpace(value)}
+}
+
+// checkDefault validates the default of prop against the schema. People are
+// only matched to workspace members when a page is created.
+func checkDefault(prop PropertyObj, value string) error {
+	if !slices.Contains(defaultableTypes, prop.Type) {
+		return fmt.Errorf("%q is a %s property, which cannot have a default", prop.Name, prop.Type)
+	}
+
+	for _, v := range defaultValues(prop, value) {
+		switch prop.Type {
+		case "select", "status", "multi_select":
+			if _, ok := existingOption(prop, v); !ok {
+				return fmt.Errorf("%q is not an option of %q", v, prop.Name)
+			}
+		case "checkbox":
+			if _, err := strconv.ParseBool(v); err != nil {
+				return fmt.Errorf("the default of %q must be true or false", prop.Name)
+			}
+		case "number":
+			if _, err := strconv.ParseFloat(v, 64); err != nil {
+				return fmt.Errorf("the default of %q must be a number", prop.Name)
+			}
+		case "url":
+			if _, ok := clipboardLink(v); !ok {
+				return fmt.Errorf("the default of %q must be a web link", prop.Name)
+			}
+		case "relation":
+			if _, ok := notionPageID(v); !ok {
+				return fmt.Errorf("%q is not a Notion page link", v)
+			}
+		}
+	}
+	return nil
+}
+
+// resolveDefaults encodes the default values of a data source, keyed by
+// property name. A default that no longer fits the schema, such as an option
+// deleted in Notion, is left out and described in the returned warnings so
+// the page is still created.
+func (ts *TaskService) resolveDefaults(ctx context.Context, token string, detail *NotionDataSourceDetail, defaults map[string]string) (map[string]map[string]any, []string) {
+	ids := make([]string, 0, len(defaults))
+	for id := range defaults {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+
+	resolved := map[string]map[string]any{}
+	var warnings []string
+	var users map[string]string
+	for _, id := range ids {
+		value := defaults[id]
+		prop := propertyByID(detail, id)
+		if prop == nil {
+			warnings = append(warnings, fmt.Sprintf("Default %q skipped: its property no longer exists on %q.", value, detail.Name))
+			continue
+		}
+		if err := checkDefault(*prop, value); err != nil {
+			warnings = append(warnings, fmt.Sprintf("Default for %q skipped: %v.", prop.Name, err))
+			continue
+		}
+
+		if prop.Type == "people" && users == nil {
+			users = ts.defaultUsers(ctx, token, defaults)
+		}
+		encoded, ok := propertyValue(*prop, defaultValues(*prop, value), users)
+		if !ok {
+			warnings = append(warnings, fmt.Sprintf("Default for %q skipped: %q matches no workspace member.", prop.Name, value))
+			continue
+		}
+		resolved[prop.Name] = encoded
+	}
+	return resolved, warnings
+}
+
+// defaultUsers returns the workspace members for people defaults, with
+// DefaultMe standing for the connected user. Lookup failures leave the
+// people out; resolveDefaults reports them.
+func (ts *TaskService) defaultUsers(ctx context.Context, token string, defaults map[string]string) map[string]string {
+	users, err := ts.workspaceUsers(ctx, token)
+	if err != nil {
+		log.Println("SendToNotion: failed to list workspace members for defaults:", err)
+	}
+	users = maps.Clone(users)
+	if users == nil {
+		users = map[string]string{}
+	}
+	for _, value := range defaults {
+		if !strings.Contains(value, settingsservice.DefaultMe) {
+			continue
+		}
+		if me, err := ts.connectedUser(ctx, token); err != nil {
+			log.Println("SendToNotion: failed to find the connected user:", err)
+		} else {
+			users[settingsservice.DefaultMe] = me
+		}
+		break
+	}
+	return users
+}
+
+// connectedUser returns the ID of the person who connected Tasklight to
+// Notion, the owner of the integration's bot user.
+func (ts *TaskService) connectedUser(ctx context.Context, token string) (string, error) {
+	ts.usersMu.Lock()
+	defer ts.usersMu.Unlock()
+	if ts.me != "" {
+		return ts.me, nil
+	}
+
+	req, err := notionapi.NewRequest(ctx, http.MethodGet, notionapi.URL("/users/me"), token)
+	if err != nil {
+		return "", err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	var bot struct {
+		Bot struct {
+			Owner struct {
+				Type string `json:"type"`
+				User struct {
+					ID string `json:"id"`
+				} `json:"user"`
+			} `json:"owner"`
+		} `json:"bot"`
+	}
+	if err := notionapi.ParseResponse(resp, &bot, ErrNotionTokenMissing); err != nil {
+		return "", err
+	}
+	if bot.Bot.Owner.Type != "user" || bot.Bot.Owner.User.ID == "" {
+		return "", errors.New("Tasklight is connected for the whole workspace, not for one person")
+	}
+	ts.me = bot.Bot.Owner.User.ID
+	return ts.me, nil
+}
+
+// PropertyDefault is the default value of one property of the selected data
+// source.
+type PropertyDefault struct {
+	PropertyID string `json:"property_id"`
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	// Value is the saved default, or empty when the property has none.
+	Value string `json:"value"`
+	// Options are the choices of a select, multi-select or status property.
+	Options []string `json:"options,omitempty"`
+	// Problem explains why the saved default is skipped.
+	Problem string `json:"problem,omitempty"`
+}
+
+// describePropertyDefaults lists the properties of detail that can have a
+// default, followed by defaults whose property is gone.
+func describePropertyDefaults(detail *NotionDataSourceDetail, defaults map[string]string) []PropertyDefault {
+	var described []PropertyDefault
+	for name, prop := range detail.Properties {
+		if !slices.Contains(defaultableTypes, prop.Type) {
+			continue
+		}
+		if prop.Name == "" {
+			prop.Name = name
+		}
+		d := PropertyDefault{PropertyID: prop.ID, Name: prop.Name, Type: prop.Type, Value: defaults[prop.ID]}
+		for _, opt := range prop.Options() {
+			d.Options = append(d.Options, opt.Name)
+		}
+		if d.Value != "" {
+			if err := checkDefault(prop, d.Value); err != nil {
+				d.Problem = err.Error()
+			}
+		}
+		described = append(described, d)
+	}
+	slices.SortFunc(described, func(a, b PropertyDefault) int {
+		return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+	})
+
+	for id, value := range defaults {
+		if propertyByID(detail, id) == nil {
+			described = append(described, PropertyDefault{PropertyID: id, Value: value, Problem: "the property no longer exists"})
+		}
+	}
+	return described
+}
+
+// GetPropertyDefaults lists the properties of the destination's data source
+// that can have a default value, with the saved defaults. Called from
+// frontend
+func (n *NotionService) GetPropertyDefaults(ctx context.Context, destination string) ([]PropertyDefault, error) {
+	dest, detail, err := n.destinationDetail(ctx, destination)
+	if err != nil {
+		return nil, err
+	}
+	return describePropertyDefaults(detail, dest.PropertyDefaults()), nil
+}
+
+// SavePropertyDefaults sets the values new pages in the destination's data
+// source get, keyed by property ID. Use "@me" in a people property for the
+// connected user. Called from frontend
+func (n *NotionService) SavePropertyDefaults(ctx context.Context, destination string, defaults map[string]string) error {
+	_, detail, err := n.destinationDetail(ctx, destination)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for id, value := range defaults {
+		if strings.TrimSpace(value) == "" {
+			continue
+		}
+		prop := propertyByID(detail, id)
+		if prop == nil {
+			errs = append(errs, fmt.Errorf("property %q does not exist on %q", id, detail.Name))
+			continue
+		}
+		if err := checkDefault(*prop, value); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+	return n.settingsservice.SetPropertyDefaults(destination, defaults)
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	c "github.com/imjamesonzeller/tasklight-v3/config"
+	"github.com/imjamesonzeller/tasklight-v3/notionapi"
+	"github.com/imjamesonzeller/tasklight-v3/settingsservice"
+)
+
+func TestCheckDefault(t *testing.T) {
+	t.Parallel()
+
+	status := PropertyObj{Name: "Status", Type: "status", Status: &SelectConfig{Options: []SelectOption{{Name: "Inbox"}}}}
+	tags := PropertyObj{Name: "Tags", Type: "multi_select", MultiSelect: &SelectConfig{Options: []SelectOption{{Name: "Work"}, {Name: "Home"}}}}
+
+	tests := []struct {
+		prop  PropertyObj
+		value string
+		ok    bool
+	}{
+		{status, "inbox", true},
+		{status, "Archived", false},
+		{tags, "work, Home", true},
+		{tags, "work, Errands", false},
+		{PropertyObj{Name: "Flag", Type: "checkbox"}, "true", true},
+		{PropertyObj{Name: "Flag", Type: "checkbox"}, "maybe", false},
+		{PropertyObj{Name: "Points", Type: "number"}, "2.5", true},
+		{PropertyObj{Name: "Points", Type: "number"}, "two", false},
+		{PropertyObj{Name: "Owner", Type: "people"}, "@me, sam", true},
+		{PropertyObj{Name: "Link", Type: "url"}, "notion", false},
+		{PropertyObj{Name: "Due", Type: "date"}, "today", false},
+	}
+	for _, tt := range tests {
+		if err := checkDefault(tt.prop, tt.value); (err == nil) != tt.ok {
+			t.Errorf("checkDefault(%s, %q) = %v, want ok %v", tt.prop.Name, tt.value, err, tt.ok)
+		}
+	}
+}
+
+func TestDescribePropertyDefaults(t *testing.T) {
+	t.Parallel()
+
+	described := describePropertyDefaults(mappingTestDetail(), map[string]string{"s1": "Gone", "x9": "Tasklight"})
+	names := make([]string, 0, len(described))
+	for _, d := range described {
+		names = append(names, d.Name)
+	}
+	if got := strings.Join(names, ","); got != "Labels,Owner,Projects,Stage,Tags,Urgent," {
+		t.Fatalf("unexpected properties: %s", got)
+	}
+	if stage := described[3]; stage.Value != "Gone" || stage.Problem == "" || len(stage.Options) != 1 {
+		t.Fatalf("expected the deleted option to be reported, got %+v", stage)
+	}
+	if gone := described[6]; gone.PropertyID != "x9" || gone.Problem == "" {
+		t.Fatalf("expected the default of a deleted property, got %+v", gone)
+	}
+}
+
+func TestDefaultsFillWhatTheTaskLeavesEmpty(t *testing.T) {
+	var created map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/data_sources/ds-1":
+			options := func(names ...string) map[string]any {
+				var opts []map[string]any
+				for _, name := range names {
+					opts = append(opts, map[string]any{"name": name})
+				}
+				return map[string]any{"options": opts}
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"id": "ds-1",
+				"properties": map[string]any{
+					"Name":     map[string]any{"id": "title", "name": "Name", "type": "title"},
+					"Status":   map[string]any{"id": "st", "name": "Status", "type": "status", "status": options("Inbox", "Done")},
+					"Source":   map[string]any{"id": "so", "name": "Source", "type": "select", "select": options("Email")},
+					"Priority": map[string]any{"id": "pr", "name": "Priority", "type": "select", "select": options("Low", "High")},
+					"Owner":    map[string]any{"id": "ow", "name": "Owner", "type": "people"},
+				},
+			})
+		case "/users":
+			_ = json.NewEncoder(w).Encode(map[string]any{"results": []any{}})
+		case "/users/me":
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"id":   "bot-1",
+				"type": "bot",
+				"bot":  map[string]any{"owner": map[string]any{"type": "user", "user": map[string]any{"id": "user-7"}}},
+			})
+		case "/pages":
+			_ = json.NewDecoder(r.Body).Decode(&created)
+			_ = json.NewEncoder(w).Encode(map[string]any{"id": "page-1"})
+		default:
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	originalBase := notionapi.BaseURL
+	originalConfig := c.AppConfig
+	t.Cleanup(func() {
+		notionapi.BaseURL = originalBase
+		c.AppConfig = originalConfig
+	})
+	notionapi.BaseURL = srv.URL
+	settings := &settingsservice.SettingsService{
+		AppSettings: settingsservice.ApplicationSettings{
+			NotionAccessToken:  "secret",
+			NotionDataSourceID: "ds-1",
+			PropertyMapping: settingsservice.PropertyMapping{
+				DataSourceID: "ds-1",
+				Defaults: map[string]string{
+					"st": "Inbox",
+					// "Tasklight" was deleted from the options in Notion.
+					"so": "Tasklight",
+					"pr": "Low",
+					"ow": settingsservice.DefaultMe,
+				},
+			},
+		},
+	}
+	c.AppConfig = &settings.AppSettings
+	ts := NewTaskService(nil, settings)
+
+	result := ts.createNotionPage(context.Background(), TaskInformation{Title: "Ship it", Priority: "high"})
+	if !result.OK() {
+		t.Fatalf("an invalid default must not fail the page: %+v", result)
+	}
+	if len(result.Warnings) != 1 || !strings.Contains(result.Warnings[0], `"Tasklight" is not an option of "Source"`) {
+		t.Fatalf("expected the stale default to be reported, got %v", result.Warnings)
+	}
+
+	props := created["properties"].(map[string]any)
+	if status := props["Status"].(map[string]any)["status"].(map[string]any); status["name"] != "Inbox" {
+		t.Fatalf("unexpected status: %v", status)
+	}
+	if priority := props["Priority"].(map[string]any)["select"].(map[string]any); priority["name"] != "High" {
+		t.Fatalf("the parsed priority must win over the default, got %v", priority)
+	}
+	owner := props["Owner"].(map[string]any)["people"].([]any)
+	if len(owner) != 1 || owner[0].(map[string]any)["id"] != "user-7" {
+		t.Fatalf("expected the connected user as owner, got %v", owner)
+	}
+	if _, ok := props["Source"]; ok {
+		t.Fatalf("a stale default must be left out, got %v", props["Source"])
+	}
+}
@@ -0,0 +1,324 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/imjamesonzeller/tasklight-v3/settingsservice"
+)
+
+// mappableField is a task field that can be written to a property of one of
+// Types.
+type mappableField struct {
+	Field string
+	Types []string
+}
+
+// mappableFields lists the task fields a property mapping can assign, keyed
+// like the fields of TaskInformation. A checkbox receiving the priority is
+// ticked for high and urgent tasks; a relation receiving the project takes a
+// Notion page link or ID.
+var mappableFields = []mappableField{
+	{Field: "priority", Types: []string{"select", "status", "multi_select", "rich_text", "checkbox"}},
+	{Field: "tags", Types: []string{"multi_select", "select", "rich_text"}},
+	{Field: "project", Types: []string{"select", "multi_select", "status", "rich_text", "relation"}},
+	{Field: "notes", Types: []string{"rich_text"}},
+	{Field: "estimate_minutes", Types: []string{"number", "rich_text"}},
+	{Field: "recurrence", Types: []string{"rich_text"}},
+	{Field: "people", Types: []string{"people", "multi_select", "rich_text"}},
+	{Field: "clipboard", Types: []string{"url", "rich_text"}},
+}
+
+func findMappableField(field string) (mappableField, bool) {
+	for _, f := range mappableFields {
+		if f.Field == field {
+			return f, true
+		}
+	}
+	return mappableField{}, false
+}
+
+// slot returns the entry of f that receives field.
+func (f *taskFieldProperties) slot(field string) **PropertyObj {
+	switch field {
+	case "priority":
+		return &f.Priority
+	case "tags":
+		return &f.Tags
+	case "project":
+		return &f.Project
+	case "notes":
+		return &f.Notes
+	case "estimate_minutes":
+		return &f.Estimate
+	case "recurrence":
+		return &f.Recurrence
+	case "people":
+		return &f.People
+	case "clipboard":
+		return &f.URL
+	}
+	return nil
+}
+
+// propertyByID returns the property of detail with the given ID.
+func propertyByID(detail *NotionDataSourceDetail, id string) *PropertyObj {
+	for name, prop := range detail.Properties {
+		if prop.ID == id {
+			if prop.Name == "" {
+				prop.Name = name
+			}
+			return &prop
+		}
+	}
+	return nil
+}
+
+// mappedProperty checks the mapping of one field against detail.
+func mappedProperty(detail *NotionDataSourceDetail, field, id string) (*PropertyObj, error) {
+	f, ok := findMappableField(field)
+	if !ok {
+		return nil, fmt.Errorf("%q is not a task field that can be mapped", field)
+	}
+	if id == settingsservice.PropertyOff {
+		return nil, nil
+	}
+	prop := propertyByID(detail, id)
+	if prop == nil {
+		return nil, fmt.Errorf("the property mapped to %s no longer exists on %q", field, detail.Name)
+	}
+	if !slices.Contains(f.Types, prop.Type) {
+		return nil, fmt.Errorf("property %q is a %s property, which cannot hold %s", prop.Name, prop.Type, field)
+	}
+	return prop, nil
+}
+
+// applyPropertyMappings points fields at the mapped properties. A mapping
+// that no longer fits the schema leaves the property found by name in place
+// and is returned as a problem.
+func applyPropertyMappings(fields *taskFieldProperties, detail *NotionDataSourceDetail, mappings map[string]string) []error {
+	var problems []error
+	for field, id := range mappings {
+		prop, err := mappedProperty(detail, field, id)
+		if err != nil {
+			problems = append(problems, err)
+			continue
+		}
+		*fields.slot(field) = prop
+	}
+	return problems
+}
+
+// validatePropertyMappings checks mappings before they are saved. Each
+// property may receive only one field.
+func validatePropertyMappings(detail *NotionDataSourceDetail, mappings map[string]string) error {
+	mappedBy := map[string]string{}
+	var errs []error
+	for _, f := range mappableFields {
+		id, ok := mappings[f.Field]
+		if !ok || id == "" {
+			continue
+		}
+		prop, err := mappedProperty(detail, f.Field, id)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if prop == nil {
+			continue
+		}
+		if other, taken := mappedBy[prop.ID]; taken {
+			errs = append(errs, fmt.Errorf("property %q cannot receive both %s and %s", prop.Name, other, f.Field))
+			continue
+		}
+		mappedBy[prop.ID] = f.Field
+	}
+	for field := range mappings {
+		if _, ok := findMappableField(field); !ok {
+			errs = append(errs, fmt.Errorf("%q is not a task field that can be mapped", field))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// PropertyFieldMapping describes where one task field is written.
+type PropertyFieldMapping struct {
+	Field string `json:"field"`
+	// PropertyID is the mapped property, "off", or empty when the field
+	// goes to the property named after it.
+	PropertyID string `json:"property_id"`
+	// Property is the name of the property receiving the field now.
+	Property string `json:"property,omitempty"`
+	// Compatible lists the properties that can hold the field.
+	Compatible []MappableProperty `json:"compatible"`
+	// Problem explains why the saved mapping is not used.
+	Problem string `json:"problem,omitempty"`
+}
+
+type MappableProperty struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// describePropertyMappings reports, for every mappable field, the saved
+// mapping, the property it resolves to and the properties it could use.
+func describePropertyMappings(detail *NotionDataSourceDetail, mappings map[string]string, recurrenceProperty string) []PropertyFieldMapping {
+	fields := detectTaskFieldProperties(detail, recurrenceProperty)
+
+	described := make([]PropertyFieldMapping, 0, len(mappableFields))
+	for _, f := range mappableFields {
+		mapping := PropertyFieldMapping{Field: f.Field, PropertyID: mappings[f.Field], Compatible: []MappableProperty{}}
+		if mapping.PropertyID != "" {
+			if prop, err := mappedProperty(detail, f.Field, mapping.PropertyID); err != nil {
+				mapping.Problem = err.Error()
+			} else {
+				*fields.slot(f.Field) = prop
+			}
+		}
+		if prop := *fields.slot(f.Field); prop != nil {
+			mapping.Property = prop.Name
+		}
+
+		for name, prop := range detail.Properties {
+			if !slices.Contains(f.Types, prop.Type) {
+				continue
+			}
+			if prop.Name == "" {
+				prop.Name = name
+			}
+			mapping.Compatible = append(mapping.Compatible, MappableProperty{ID: prop.ID, Name: prop.Name, Type: prop.Type})
+		}
+		slices.SortFunc(mapping.Compatible, func(a, b MappableProperty) int {
+			return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+		})
+		described = append(described, mapping)
+	}
+	return described
+}
+
+// GetPropertyMappings lists where each task field is written in the data
+// source of the named destination, or of the default destination for "", and
+// which properties could receive it. Called from frontend
+func (n *NotionService) GetPropertyMappings(ctx context.Context, destination string) ([]PropertyFieldMapping, error) {
+	dest, detail, err := n.destinationDetail(ctx, destination)
+	if err != nil {
+		return nil, err
+	}
+	return describePropertyMappings(detail, dest.PropertyMappings(), n.settingsservice.AppSettings.RecurrencePropertyName), nil
+}
+
+// SavePropertyMappings maps task fields to properties of the destination's
+// data source, keyed by field with property IDs as values. An empty ID goes
+// back to matching by name and "off" stops writing the field. Called from
+// frontend
+func (n *NotionService) SavePropertyMappings(ctx context.Context, destination string, mappings map[string]string) error {
+	_, detail, err := n.destinationDetail(ctx, destination)
+	if err != nil {
+		return err
+	}
+	if err := validatePropertyMappings(detail, mappings); err != nil {
+		return err
+	}
+	return n.settingsservice.SetPropertyMappings(destination, mappings)
+}
+
+// propertyValue encodes values as the value of prop. It reports false when
+// nothing can be written: a status no option matches, since Notion cannot
+// create status options on the fly, a number that does not parse, people
+// who are not workspace members or a relation without a page link.
+func propertyValue(prop PropertyObj, values []string, users map[string]string) (map[string]any, bool) {
+	values = slices.DeleteFunc(slices.Clone(values), func(v string) bool {
+		return strings.TrimSpace(v) == ""
+	})
+	if len(values) == 0 {
+		return nil, false
+	}
+
+	switch prop.Type {
+	case "select":
+		return map[string]any{"select": map[string]any{"name": matchOption(prop, values[0])}}, true
+
+	case "status":
+		name, ok := existingOption(prop, values[0])
+		if !ok {
+			return nil, false
+		}
+		return map[string]any{"status": map[string]any{"name": name}}, true
+
+	case "multi_select":
+		options := make([]map[string]any, 0, len(values))
+		for _, v := range values {
+			options = append(options, map[string]any{"name": matchOption(prop, v)})
+		}
+		return map[string]any{"multi_select": options}, true
+
+	case "rich_text":
+		return richTextValue(strings.Join(values, ", ")), true
+
+	case "checkbox":
+		checked, _ := strconv.ParseBool(values[0])
+		return map[string]any{"checkbox": checked}, true
+
+	case "number":
+		number, err := strconv.ParseFloat(values[0], 64)
+		if err != nil {
+			return nil, false
+		}
+		return map[string]any{"number": number}, true
+
+	case "url":
+		return map[string]any{"url": values[0]}, true
+
+	case "people":
+		var people []map[string]any
+		for _, v := range values {
+			if id, ok := users[strings.ToLower(v)]; ok {
+				people = append(people, map[string]any{"object": "user", "id": id})
+			}
+		}
+		if len(people) == 0 {
+			return nil, false
+		}
+		return map[string]any{"people": people}, true
+
+	case "relation":
+		var pages []map[string]any
+		for _, v := range values {
+			if id, ok := notionPageID(v); ok {
+				pages = append(pages, map[string]any{"id": id})
+			}
+		}
+		if len(pages) == 0 {
+			return nil, false
+		}
+		return map[string]any{"relation": pages}, true
+	}
+	return nil, false
+}
+
+// notionPageID reads a page ID, with or without dashes, or the ID at the end
+// of a Notion page link.
+func notionPageID(value string) (string, bool) {
+	value = strings.TrimSpace(value)
+	if u, err := url.Parse(value); err == nil && u.Host != "" {
+		value = u.Path[strings.LastIndex(u.Path, "/")+1:]
+		value = value[strings.LastIndex(value, "-")+1:]
+	}
+	id := strings.ReplaceAll(value, "-", "")
+	if len(id) != 32 {
+		return "", false
+	}
+	for _, r := range id {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", r) {
+			return "", false
+		}
+	}
+	id = strings.ToLower(id)
+	return id[:8] + "-" + id[8:12] + "-" + id[12:16] + "-" + id[16:20] + "-" + id[20:], true
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	c "github.com/imjamesonzeller/tasklight-v3/config"
+	"github.com/imjamesonzeller/tasklight-v3/notionapi"
+	"github.com/imjamesonzeller/tasklight-v3/settingsservice"
+)
+
+func mappingTestDetail() *NotionDataSourceDetail {
+	return &NotionDataSourceDetail{Name: "Tasks", Properties: map[string]PropertyObj{
+		"Name":     {ID: "title", Name: "Name", Type: "title"},
+		"Tags":     {ID: "t1", Name: "Tags", Type: "multi_select"},
+		"Labels":   {ID: "t2", Name: "Labels", Type: "multi_select", MultiSelect: &SelectConfig{Options: []SelectOption{{Name: "Home"}}}},
+		"Stage":    {ID: "s1", Name: "Stage", Type: "status", Status: &SelectConfig{Options: []SelectOption{{Name: "High"}}}},
+		"Urgent":   {ID: "c1", Name: "Urgent", Type: "checkbox"},
+		"Owner":    {ID: "p1", Name: "Owner", Type: "people"},
+		"Projects": {ID: "r1", Name: "Projects", Type: "relation"},
+		"Due":      {ID: "d1", Name: "Due", Type: "date"},
+	}}
+}
+
+func TestApplyPropertyMappings(t *testing.T) {
+	t.Parallel()
+
+	detail := mappingTestDetail()
+	fields := detectTaskFieldProperties(detail, "")
+	if fields.Tags == nil || fields.Tags.Name != "Tags" {
+		t.Fatalf("expected the tags property found by name, got %+v", fields.Tags)
+	}
+
+	// The mapped property was renamed in Notion after it was chosen.
+	renamed := detail.Properties["Labels"]
+	renamed.Name = "Areas"
+	delete(detail.Properties, "Labels")
+	detail.Properties["Areas"] = renamed
+
+	problems := applyPropertyMappings(&fields, detail, map[string]string{
+		"tags":     "t2",
+		"priority": "c1",
+		"project":  "gone",
+		"notes":    "d1",
+		"people":   settingsservice.PropertyOff,
+	})
+	if fields.Tags == nil || fields.Tags.Name != "Areas" {
+		t.Fatalf("expected the mapped property under its new name, got %+v", fields.Tags)
+	}
+	if fields.Priority == nil || fields.Priority.Type != "checkbox" {
+		t.Fatalf("expected the checkbox to receive the priority, got %+v", fields.Priority)
+	}
+	if fields.People != nil {
+		t.Fatalf("a field mapped off must not be written, got %+v", fields.People)
+	}
+	if len(problems) != 2 {
+		t.Fatalf("expected the missing and the incompatible mapping to be reported, got %v", problems)
+	}
+}
+
+func TestValidatePropertyMappings(t *testing.T) {
+	t.Parallel()
+
+	detail := mappingTestDetail()
+	valid := map[string]string{"tags": "t2", "priority": "s1", "people": "p1", "project": "r1", "notes": ""}
+	if err := validatePropertyMappings(detail, valid); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for name, mappings := range map[string]map[string]string{
+		"unknown field":    {"colour": "t1"},
+		"unknown property": {"tags": "nope"},
+		"wrong type":       {"estimate_minutes": "t1"},
+		"shared property":  {"tags": "t1", "people": "t1"},
+	} {
+		if err := validatePropertyMappings(detail, mappings); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestDescribePropertyMappings(t *testing.T) {
+	t.Parallel()
+
+	described := describePropertyMappings(mappingTestDetail(), map[string]string{"project": "gone"}, "")
+	byField := map[string]PropertyFieldMapping{}
+	for _, m := range described {
+		byField[m.Field] = m
+	}
+	if len(described) != len(mappableFields) {
+		t.Fatalf("expected every mappable field, got %d", len(described))
+	}
+
+	tags := byField["tags"]
+	if tags.Property != "Tags" || len(tags.Compatible) != 2 || tags.Compatible[0].Name != "Labels" {
+		t.Fatalf("unexpected tags mapping: %+v", tags)
+	}
+	if people := byField["people"]; len(people.Compatible) != 3 || people.Compatible[1].ID != "p1" {
+		t.Fatalf("expected the people property to be offered, got %+v", people)
+	}
+	if project := byField["project"]; project.Problem == "" {
+		t.Fatalf("expected the broken mapping to be reported, got %+v", project)
+	}
+}
+
+func TestBuildNotionPagePayloadWithMappedTypes(t *testing.T) {
+	t.Parallel()
+
+	detail := mappingTestDetail()
+	fields := taskFieldProperties{
+		Priority: propertyByID(detail, "s1"),
+		Project:  propertyByID(detail, "r1"),
+		People:   propertyByID(detail, "p1"),
+		Users:    map[string]string{"sam": "user-1"},
+	}
+	task := TaskInformation{
+		Title:    "Review",
+		Priority: "high",
+		Project:  "https://www.notion.so/acme/Launch-0123456789abcdef0123456789ABCDEF",
+		People:   []string{"Sam", "nobody"},
+	}
+	props := buildNotionPagePayload(task, "ds-1", "Name", "", fields)["properties"].(map[string]any)
+
+	if status := props["Stage"].(map[string]any)["status"].(map[string]any); status["name"] != "High" {
+		t.Fatalf("unexpected status: %v", status)
+	}
+	relation := props["Projects"].(map[string]any)["relation"].([]map[string]any)
+	if len(relation) != 1 || relation[0]["id"] != "01234567-89ab-cdef-0123-456789abcdef" {
+		t.Fatalf("unexpected relation: %v", relation)
+	}
+	people := props["Owner"].(map[string]any)["people"].([]map[string]any)
+	if len(people) != 1 || people[0]["id"] != "user-1" {
+		t.Fatalf("expected only workspace members, got %v", people)
+	}
+
+	// A status without a matching option and a project that is not a page
+	// link are left out rather than rejected by Notion.
+	task.Priority, task.Project = "low", "Launch"
+	props = buildNotionPagePayload(task, "ds-1", "Name", "", fields)["properties"].(map[string]any)
+	for _, name := range []string{"Stage", "Projects"} {
+		if _, ok := props[name]; ok {
+			t.Fatalf("expected %s to be left out, got %v", name, props[name])
+		}
+	}
+
+	fields = taskFieldProperties{Priority: propertyByID(detail, "c1")}
+	props = buildNotionPagePayload(TaskInformation{Title: "Fix", Priority: "urgent"}, "ds-1", "Name", "", fields)["properties"].(map[string]any)
+	if props["Urgent"].(map[string]any)["checkbox"] != true {
+		t.Fatalf("expected an urgent task to tick the checkbox, got %v", props["Urgent"])
+	}
+}
+
+func TestNotionPageID(t *testing.T) {
+	t.Parallel()
+
+	want := "01234567-89ab-cdef-0123-456789abcdef"
+	for _, value := range []string{
+		"0123456789abcdef0123456789abcdef",
+		"01234567-89ab-cdef-0123-456789abcdef",
+		"https://www.notion.so/acme/Launch-0123456789abcdef0123456789abcdef?pvs=4",
+	} {
+		if got, ok := notionPageID(value); !ok || got != want {
+			t.Errorf("notionPageID(%q) = %q, %v", value, got, ok)
+		}
+	}
+	for _, value := range []string{"Launch", "0123456789abcdef", "https://example.com/"} {
+		if _, ok := notionPageID(value); ok {
+			t.Errorf("notionPageID(%q) should not find an ID", value)
+		}
+	}
+}
+
+func TestResolveTargetUsesMappings(t *testing.T) {
+	var userRequests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/data_sources/ds-1":
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"id": "ds-1",
+				"properties": map[string]any{
+					"Name":     map[string]any{"id": "title", "name": "Name", "type": "title"},
+					"Deadline": map[string]any{"id": "d1", "name": "Deadline", "type": "date"},
+					"Owner":    map[string]any{"id": "p1", "name": "Owner", "type": "people"},
+				},
+			})
+		case "/users":
+			userRequests++
+			_ = json.NewEncoder(w).Encode(map[string]any{"results": []any{
+				map[string]any{"id": "user-1", "type": "person", "name": "Sam Lee", "person": map[string]any{"email": "sam@example.com"}},
+				map[string]any{"id": "user-2", "type": "person", "name": "Sam Roe"},
+				map[string]any{"id": "bot-1", "type": "bot", "name": "Tasklight"},
+			}})
+		default:
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	originalBase := notionapi.BaseURL
+	originalConfig := c.AppConfig
+	t.Cleanup(func() {
+		notionapi.BaseURL = originalBase
+		c.AppConfig = originalConfig
+	})
+	notionapi.BaseURL = srv.URL
+	settings := &settingsservice.SettingsService{
+		AppSettings: settingsservice.ApplicationSettings{
+			NotionAccessToken:  "secret",
+			NotionDataSourceID: "ds-1",
+			// The date property was renamed from "Due" since it was chosen.
+			DatePropertyID:   "d1",
+			DatePropertyName: "Due",
+			PropertyMapping: settingsservice.PropertyMapping{
+				DataSourceID: "ds-1",
+				Properties:   map[string]string{"people": "p1"},
+			},
+		},
+	}
+	c.AppConfig = &settings.AppSettings
+	ts := NewTaskService(nil, settings)
+
+	for range 2 {
+		target, err := ts.resolveTarget(context.Background(), "")
+		if err != nil {
+			t.Fatalf("resolveTarget: %v", err)
+		}
+		if target.dateProp != "Deadline" {
+			t.Fatalf("expected the date property under its new name, got %q", target.dateProp)
+		}
+		if target.fields.People == nil || target.fields.Users["sam@example.com"] != "user-1" {
+			t.Fatalf("expected the mapped people property with members, got %+v", target.fields)
+		}
+		if _, ok := target.fields.Users["sam"]; ok {
+			t.Fatal("a first name shared by two members must not pick either")
+		}
+		if _, ok := target.fields.Users["tasklight"]; ok {
+			t.Fatal("bots must not be offered as people")
+		}
+	}
+	if userRequests != 1 {
+		t.Fatalf("expected the member list to be cached, got %d requests", userRequests)
+	}
+}
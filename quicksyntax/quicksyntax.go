@@ -0,0 +1,238 @@
+// Package quicksyntax reads inline task tokens such as "#errands",
+// "+website", "!1", "^fri" and "@sam". Tokens are whole words that start with
+// a configurable prefix; a backslash before the prefix keeps the word as text.
+package quicksyntax
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Prefixes are the symbols that start each kind of token. Empty fields use
+// the defaults.
+type Prefixes struct {
+	Tag      string `json:"tag,omitempty"`
+	Project  string `json:"project,omitempty"`
+	Priority string `json:"priority,omitempty"`
+	Due      string `json:"due,omitempty"`
+	Person   string `json:"person,omitempty"`
+}
+
+func DefaultPrefixes() Prefixes {
+	return Prefixes{Tag: "#", Project: "+", Priority: "!", Due: "^", Person: "@"}
+}
+
+// WithDefaults fills empty prefixes from DefaultPrefixes.
+func (p Prefixes) WithDefaults() Prefixes {
+	d := DefaultPrefixes()
+	for _, f := range []struct{ value, fallback *string }{
+		{&p.Tag, &d.Tag}, {&p.Project, &d.Project}, {&p.Priority, &d.Priority}, {&p.Due, &d.Due}, {&p.Person, &d.Person},
+	} {
+		*f.value = strings.TrimSpace(*f.value)
+		if *f.value == "" {
+			*f.value = *f.fallback
+		}
+	}
+	return p
+}
+
+// Validate checks that every prefix is a single symbol and that no two kinds
+// share one.
+func (p Prefixes) Validate() error {
+	p = p.WithDefaults()
+	seen := map[string]string{}
+	for _, f := range p.kinds() {
+		r, size := utf8.DecodeRuneInString(f.prefix)
+		if size != len(f.prefix) || unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsSpace(r) || r == '\\' {
+			return fmt.Errorf("%s prefix %q must be a single symbol", f.kind, f.prefix)
+		}
+		if other, ok := seen[f.prefix]; ok {
+			return fmt.Errorf("%s and %s tokens cannot both use %q", other, f.kind, f.prefix)
+		}
+		seen[f.prefix] = f.kind
+	}
+	return nil
+}
+
+type kind struct {
+	kind   string
+	prefix string
+}
+
+func (p Prefixes) kinds() []kind {
+	return []kind{
+		{"tag", p.Tag}, {"project", p.Project}, {"priority", p.Priority}, {"due", p.Due}, {"person", p.Person},
+	}
+}
+
+// Tokens are the values found in the input. The first project, priority and
+// due date win; tags and people accumulate.
+type Tokens struct {
+	Tags    []string
+	Project string
+	// Priority runs from 1 (highest) to 4, and is 0 when absent.
+	Priority int
+	// Due is the text after the due prefix with underscores read as spaces,
+	// e.g. "2025-11-03", "fri" or "next friday".
+	Due    string
+	People []string
+	// Rest is the input with the tokens removed and escapes resolved.
+	Rest string
+}
+
+// priorityLevels maps the text after the priority prefix to a level.
+var priorityLevels = map[string]int{
+	"1": 1, "2": 2, "3": 3, "4": 4,
+	"urgent": 1, "high": 2, "medium": 3, "med": 3, "low": 4,
+}
+
+// trailingPunct may follow a token without being part of it.
+const trailingPunct = ",.;:!?)"
+
+// separators are the trailing punctuation that means nothing once the token
+// before it ends the text.
+const separators = ",;:"
+
+// Parse reads the tokens in input.
+func Parse(input string, prefixes Prefixes) Tokens {
+	p := prefixes.WithDefaults()
+
+	var t Tokens
+	var rest []string
+	// dangling is set while rest ends with punctuation left by a token.
+	dangling := false
+	for _, word := range strings.Fields(input) {
+		if literal, ok := unescape(word, p); ok {
+			rest, dangling = append(rest, literal), false
+			continue
+		}
+		if isURL(word) {
+			rest, dangling = append(rest, word), false
+			continue
+		}
+
+		body := strings.TrimRight(word, trailingPunct)
+		punct := word[len(body):]
+		if !t.take(body, p) {
+			rest, dangling = append(rest, word), false
+			continue
+		}
+		if punct != "" {
+			rest, dangling = attachPunct(rest, punct), true
+		}
+	}
+
+	t.Rest = strings.Join(rest, " ")
+	if dangling {
+		t.Rest = strings.TrimRight(t.Rest, separators)
+	}
+	return t
+}
+
+// attachPunct adds the punctuation after a removed token to the word before
+// it, so "call @sam, then" reads "call, then".
+func attachPunct(rest []string, punct string) []string {
+	if len(rest) == 0 {
+		// Nothing before the token for a separator to follow.
+		if punct = strings.TrimLeft(punct, separators); punct != "" {
+			rest = append(rest, punct)
+		}
+		return rest
+	}
+	last := rest[len(rest)-1]
+	if strings.TrimRight(last, separators) != last {
+		// "notes, @sam, then" keeps a single comma.
+		punct = strings.TrimLeft(punct, separators)
+	}
+	rest[len(rest)-1] = last + punct
+	return rest
+}
+
+// take records word as a token and reports whether it was one.
+func (t *Tokens) take(word string, p Prefixes) bool {
+	for _, k := range p.kinds() {
+		value, ok := strings.CutPrefix(word, k.prefix)
+		if !ok || value == "" {
+			continue
+		}
+
+		switch k.kind {
+		case "tag":
+			if !isName(value) {
+				return false
+			}
+			t.Tags = appendUnique(t.Tags, value)
+		case "project":
+			if !isName(value) {
+				return false
+			}
+			if t.Project == "" {
+				t.Project = value
+			}
+		case "priority":
+			level, ok := priorityLevels[strings.ToLower(value)]
+			if !ok {
+				return false
+			}
+			if t.Priority == 0 {
+				t.Priority = level
+			}
+		case "due":
+			if t.Due == "" {
+				t.Due = strings.ReplaceAll(value, "_", " ")
+			}
+		case "person":
+			if !isName(value) {
+				return false
+			}
+			t.People = appendUnique(t.People, value)
+		}
+		return true
+	}
+	return false
+}
+
+// unescape turns "\#1" into the literal "#1".
+func unescape(word string, p Prefixes) (string, bool) {
+	after, ok := strings.CutPrefix(word, `\`)
+	if !ok {
+		return "", false
+	}
+	for _, k := range p.kinds() {
+		if strings.HasPrefix(after, k.prefix) {
+			return after, true
+		}
+	}
+	return "", false
+}
+
+func isURL(word string) bool {
+	return strings.Contains(word, "://") || strings.HasPrefix(strings.ToLower(word), "www.")
+}
+
+// isName accepts tag, project and person names: a letter followed by
+// letters, digits, "_", "-", "." or "/".
+func isName(value string) bool {
+	for i, r := range value {
+		switch {
+		case unicode.IsLetter(r):
+		case i == 0:
+			return false
+		case unicode.IsDigit(r), strings.ContainsRune("_-./", r):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func appendUnique(values []string, value string) []string {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return values
+		}
+	}
+	return append(values, value)
+}
@@ -0,0 +1,116 @@
+package quicksyntax
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		input string
+		want  Tokens
+	}{
+		{
+			name:  "all tokens",
+			input: "buy milk #errands +home !2 ^fri @sam",
+			want:  Tokens{Tags: []string{"errands"}, Project: "home", Priority: 2, Due: "fri", People: []string{"sam"}, Rest: "buy milk"},
+		},
+		{
+			name:  "iso due date and named priority",
+			input: "file taxes ^2025-11-03 !urgent",
+			want:  Tokens{Priority: 1, Due: "2025-11-03", Rest: "file taxes"},
+		},
+		{
+			name:  "underscores in due dates",
+			input: "renew passport ^next_friday",
+			want:  Tokens{Due: "next friday", Rest: "renew passport"},
+		},
+		{
+			name:  "escaped prefixes stay in the text",
+			input: `reply to \#42 and \@channel #work`,
+			want:  Tokens{Tags: []string{"work"}, Rest: "reply to #42 and @channel"},
+		},
+		{
+			name:  "hashtags inside urls",
+			input: "read https://example.com/docs#install #reading www.example.com/#top",
+			want:  Tokens{Tags: []string{"reading"}, Rest: "read https://example.com/docs#install www.example.com/#top"},
+		},
+		{
+			name:  "symbols inside words",
+			input: "learn C# with sam@example.com and C++",
+			want:  Tokens{Rest: "learn C# with sam@example.com and C++"},
+		},
+		{
+			name:  "values that are not tokens",
+			input: "score +1 !5 #42 @3pm ! #",
+			want:  Tokens{Rest: "score +1 !5 #42 @3pm ! #"},
+		},
+		{
+			name:  "trailing punctuation is kept",
+			input: "call @sam, then email +website.",
+			want:  Tokens{Project: "website", People: []string{"sam"}, Rest: "call, then email."},
+		},
+		{
+			name:  "separators left dangling are dropped",
+			input: "#work: review notes, @sam, @kim; buy milk #errands,",
+			want:  Tokens{Tags: []string{"work", "errands"}, People: []string{"sam", "kim"}, Rest: "review notes, buy milk"},
+		},
+		{
+			name:  "first single value wins and lists dedupe",
+			input: "plan +alpha +beta !1 !4 #Work #work",
+			want:  Tokens{Tags: []string{"Work"}, Project: "alpha", Priority: 1, Rest: "plan"},
+		},
+	}
+
+	for _, tt := range tests {
+		got := Parse(tt.input, DefaultPrefixes())
+		if got.Rest != tt.want.Rest {
+			t.Errorf("%s: rest = %q, want %q", tt.name, got.Rest, tt.want.Rest)
+		}
+		if strings.Join(got.Tags, ",") != strings.Join(tt.want.Tags, ",") ||
+			strings.Join(got.People, ",") != strings.Join(tt.want.People, ",") ||
+			got.Project != tt.want.Project || got.Priority != tt.want.Priority || got.Due != tt.want.Due {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseCustomPrefixes(t *testing.T) {
+	t.Parallel()
+
+	prefixes := Prefixes{Tag: "%", Project: "&"}
+	got := Parse("ship it %release &web #notatag +notaproject !3", prefixes)
+
+	if strings.Join(got.Tags, ",") != "release" || got.Project != "web" || got.Priority != 3 {
+		t.Fatalf("unexpected tokens: %+v", got)
+	}
+	if got.Rest != "ship it #notatag +notaproject" {
+		t.Fatalf("unexpected rest: %q", got.Rest)
+	}
+}
+
+func TestPrefixesValidate(t *testing.T) {
+	t.Parallel()
+
+	if err := (Prefixes{}).Validate(); err != nil {
+		t.Fatalf("defaults should be valid: %v", err)
+	}
+	if err := (Prefixes{Tag: "%"}).Validate(); err != nil {
+		t.Fatalf("custom prefix should be valid: %v", err)
+	}
+
+	for _, p := range []Prefixes{
+		{Tag: "t"},
+		{Tag: "##"},
+		{Due: "7"},
+		{Person: `\`},
+		{Project: "#"},
+	} {
+		if err := p.Validate(); err == nil {
+			t.Errorf("expected %+v to be rejected", p)
+		}
+	}
+}
@@ -0,0 +1,279 @@
+package recurrence
+
+import (
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Match is a recurrence phrase found in task input.
+type Match struct {
+	Rule Rule
+	// Rest is the input with the phrase removed.
+	Rest   string
+	Phrase string
+}
+
+type word struct {
+	text       string // lower-cased, surrounding punctuation trimmed
+	start, end int
+}
+
+// Extract finds the first recurrence phrase in input, e.g. "every other
+// Tuesday", "daily", "every weekday", "every 3 months" or "on the 1st of each
+// month".
+func Extract(input string) (Match, bool) {
+	words := split(input)
+	for i := range words {
+		rule, n, ok := matchAt(words, i)
+		if !ok {
+			continue
+		}
+
+		from, to := i, i+n
+		switch at(words, from-1) {
+		case "repeat", "repeats", "repeating":
+			from--
+		}
+		start, end := words[from].start, words[to-1].end
+		rest := strings.Join(strings.Fields(input[:start]+" "+input[end:]), " ")
+		return Match{Rule: rule, Rest: rest, Phrase: input[start:end]}, true
+	}
+	return Match{}, false
+}
+
+func split(s string) []word {
+	var words []word
+	inWord := false
+	start := 0
+	flush := func(end int) {
+		raw := s[start:end]
+		lead := len(raw) - len(strings.TrimLeft(raw, ",.;:!?()"))
+		core := strings.Trim(raw, ",.;:!?()")
+		if core != "" {
+			words = append(words, word{text: strings.ToLower(core), start: start + lead, end: start + lead + len(core)})
+		}
+	}
+	for i, r := range s {
+		if unicode.IsSpace(r) {
+			if inWord {
+				flush(i)
+				inWord = false
+			}
+			continue
+		}
+		if !inWord {
+			start = i
+			inWord = true
+		}
+	}
+	if inWord {
+		flush(len(s))
+	}
+	return words
+}
+
+func at(words []word, i int) string {
+	if i < 0 || i >= len(words) {
+		return ""
+	}
+	return words[i].text
+}
+
+// matchAt reports the rule starting at words[i] and how many words it spans.
+func matchAt(words []word, i int) (Rule, int, bool) {
+	switch at(words, i) {
+	case "daily":
+		return Rule{Freq: Daily, Interval: 1}, 1, true
+	case "weekly":
+		return withWeekdays(Rule{Freq: Weekly, Interval: 1}, words, i, 1)
+	case "biweekly", "fortnightly":
+		return withWeekdays(Rule{Freq: Weekly, Interval: 2}, words, i, 1)
+	case "monthly":
+		return withMonthDay(Rule{Freq: Monthly, Interval: 1}, words, i, 1)
+	case "yearly", "annually":
+		return Rule{Freq: Yearly, Interval: 1}, 1, true
+	case "every", "each":
+		return matchEvery(words, i)
+	case "on":
+		if at(words, i+1) == "weekdays" {
+			return Rule{Freq: Weekly, Interval: 1, ByDay: workWeek}, 2, true
+		}
+		return matchMonthDayOf(words, i)
+	case "the":
+		return matchMonthDayOf(words, i)
+	}
+	return Rule{}, 0, false
+}
+
+var workWeek = []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}
+
+var ordinalWords = map[string]int{"other": 2, "second": 2, "third": 3, "fourth": 4}
+
+// matchEvery handles "every [other|N] day/week/month/year", "every weekday",
+// "every weekend" and "every Monday and Thursday".
+func matchEvery(words []word, i int) (Rule, int, bool) {
+	j := i + 1
+	interval := 1
+	if n, ok := ordinalWords[at(words, j)]; ok {
+		interval = n
+		j++
+	} else if n, err := strconv.Atoi(at(words, j)); err == nil && n > 0 && n < 1000 {
+		interval = n
+		j++
+	}
+
+	r := Rule{Interval: interval}
+	switch strings.TrimSuffix(at(words, j), "s") {
+	case "day":
+		r.Freq = Daily
+		return r, j - i + 1, true
+	case "week":
+		r.Freq = Weekly
+		return withWeekdays(r, words, i, j-i+1)
+	case "month":
+		r.Freq = Monthly
+		return withMonthDay(r, words, i, j-i+1)
+	case "year":
+		r.Freq = Yearly
+		return r, j - i + 1, true
+	case "weekday", "workday":
+		if interval != 1 {
+			return Rule{}, 0, false
+		}
+		return Rule{Freq: Weekly, Interval: 1, ByDay: workWeek}, j - i + 1, true
+	case "weekend":
+		r.Freq = Weekly
+		r.ByDay = []time.Weekday{time.Saturday, time.Sunday}
+		return r, j - i + 1, true
+	}
+
+	days, n := weekdayList(words, j)
+	if n == 0 {
+		return Rule{}, 0, false
+	}
+	r.Freq = Weekly
+	r.ByDay = days
+	return r, j - i + n, true
+}
+
+// withWeekdays extends a weekly rule with a trailing "on Monday and Friday".
+func withWeekdays(r Rule, words []word, i, n int) (Rule, int, bool) {
+	if at(words, i+n) == "on" {
+		if days, m := weekdayList(words, i+n+1); m > 0 {
+			r.ByDay = days
+			n += 1 + m
+		}
+	}
+	return r, n, true
+}
+
+// withMonthDay extends a monthly rule with a trailing "on the 15th" or "on
+// the last day".
+func withMonthDay(r Rule, words []word, i, n int) (Rule, int, bool) {
+	if at(words, i+n) != "on" || at(words, i+n+1) != "the" {
+		return r, n, true
+	}
+	if day, ok := parseMonthDay(at(words, i+n+2)); ok {
+		r.ByMonthDay = day
+		return r, n + 3, true
+	}
+	if at(words, i+n+2) == "last" && at(words, i+n+3) == "day" {
+		r.ByMonthDay = LastDay
+		return r, n + 4, true
+	}
+	return r, n, true
+}
+
+// matchMonthDayOf handles "(on) the 1st of each month" and "(on) the last day
+// of every month".
+func matchMonthDayOf(words []word, i int) (Rule, int, bool) {
+	j := i
+	if at(words, j) == "on" {
+		j++
+	}
+	if at(words, j) != "the" {
+		return Rule{}, 0, false
+	}
+	j++
+
+	var day int
+	if d, ok := parseMonthDay(at(words, j)); ok {
+		day = d
+		j++
+	} else if at(words, j) == "last" && at(words, j+1) == "day" {
+		day = LastDay
+		j += 2
+	} else {
+		return Rule{}, 0, false
+	}
+
+	if at(words, j) != "of" {
+		return Rule{}, 0, false
+	}
+	switch at(words, j+1) {
+	case "each", "every":
+	default:
+		return Rule{}, 0, false
+	}
+	if at(words, j+2) != "month" {
+		return Rule{}, 0, false
+	}
+	return Rule{Freq: Monthly, Interval: 1, ByMonthDay: day}, j + 3 - i, true
+}
+
+// weekdayList reads "monday", "mon, wed and fri" or "tuesdays & thursdays"
+// starting at words[i] and reports how many words it used.
+func weekdayList(words []word, i int) ([]time.Weekday, int) {
+	var days []time.Weekday
+	n := 0
+	for {
+		text := at(words, i+n)
+		if len(days) > 0 && (text == "and" || text == "&") {
+			if _, ok := parseWeekday(at(words, i+n+1)); ok {
+				n++
+				continue
+			}
+			break
+		}
+		day, ok := parseWeekday(text)
+		if !ok {
+			break
+		}
+		if !containsDay(days, day) {
+			days = append(days, day)
+		}
+		n++
+	}
+	return days, n
+}
+
+var weekdayNames = map[string]time.Weekday{
+	"monday": time.Monday, "mon": time.Monday,
+	"tuesday": time.Tuesday, "tue": time.Tuesday, "tues": time.Tuesday,
+	"wednesday": time.Wednesday, "wed": time.Wednesday, "weds": time.Wednesday,
+	"thursday": time.Thursday, "thu": time.Thursday, "thur": time.Thursday, "thurs": time.Thursday,
+	"friday": time.Friday, "fri": time.Friday,
+	"saturday": time.Saturday, "sat": time.Saturday,
+	"sunday": time.Sunday, "sun": time.Sunday,
+}
+
+func parseWeekday(text string) (time.Weekday, bool) {
+	if day, ok := weekdayNames[text]; ok {
+		return day, true
+	}
+	day, ok := weekdayNames[strings.TrimSuffix(text, "s")]
+	return day, ok
+}
+
+func parseMonthDay(text string) (int, bool) {
+	for _, suffix := range []string{"st", "nd", "rd", "th"} {
+		text = strings.TrimSuffix(text, suffix)
+	}
+	n, err := strconv.Atoi(text)
+	if err != nil || n < 1 || n > 31 {
+		return 0, false
+	}
+	return n, true
+}
@@ -0,0 +1,161 @@
+package recurrence
+
+import (
+	"testing"
+	"time"
+)
+
+func date(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 9, 0, 0, 0, time.UTC)
+}
+
+func TestExtract(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		input    string
+		wantRule string
+		wantRest string
+	}{
+		{input: "water plants every other Tuesday", wantRule: "FREQ=WEEKLY;INTERVAL=2;BYDAY=TU", wantRest: "water plants"},
+		{input: "invoice on the 1st of each month", wantRule: "FREQ=MONTHLY;BYMONTHDAY=1", wantRest: "invoice"},
+		{input: "pay rent on the last day of every month", wantRule: "FREQ=MONTHLY;BYMONTHDAY=-1", wantRest: "pay rent"},
+		{input: "standup every weekday at 9am", wantRule: "FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR", wantRest: "standup at 9am"},
+		{input: "gym every mon, wed and fri", wantRule: "FREQ=WEEKLY;BYDAY=MO,WE,FR", wantRest: "gym"},
+		{input: "take vitamins daily", wantRule: "FREQ=DAILY", wantRest: "take vitamins"},
+		{input: "backup every 3 days", wantRule: "FREQ=DAILY;INTERVAL=3", wantRest: "backup"},
+		{input: "review budget monthly on the 15th", wantRule: "FREQ=MONTHLY;BYMONTHDAY=15", wantRest: "review budget"},
+		{input: "clean gutters every 6 months", wantRule: "FREQ=MONTHLY;INTERVAL=6", wantRest: "clean gutters"},
+		{input: "renew domain yearly", wantRule: "FREQ=YEARLY", wantRest: "renew domain"},
+		{input: "team sync biweekly on thursday", wantRule: "FREQ=WEEKLY;INTERVAL=2;BYDAY=TH", wantRest: "team sync"},
+		{input: "mow lawn every weekend", wantRule: "FREQ=WEEKLY;BYDAY=SA,SU", wantRest: "mow lawn"},
+		{input: "call grandma on weekdays", wantRule: "FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR", wantRest: "call grandma"},
+		{input: "stretch every day", wantRule: "FREQ=DAILY", wantRest: "stretch"},
+		{input: "read every page of the report"},
+		{input: "buy milk friday"},
+	}
+
+	for _, tc := range tests {
+		m, ok := Extract(tc.input)
+		if tc.wantRule == "" {
+			if ok {
+				t.Errorf("%q: unexpected rule %s", tc.input, m.Rule)
+			}
+			continue
+		}
+		if !ok {
+			t.Errorf("%q: expected rule %s", tc.input, tc.wantRule)
+			continue
+		}
+		if got := m.Rule.String(); got != tc.wantRule {
+			t.Errorf("%q: rule = %s, want %s", tc.input, got, tc.wantRule)
+		}
+		if m.Rest != tc.wantRest {
+			t.Errorf("%q: rest = %q, want %q", tc.input, m.Rest, tc.wantRest)
+		}
+	}
+}
+
+func TestParseRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	for _, value := range []string{
+		"FREQ=DAILY",
+		"FREQ=WEEKLY;INTERVAL=2;BYDAY=TU",
+		"FREQ=WEEKLY;BYDAY=MO,WE,FR",
+		"FREQ=MONTHLY;BYMONTHDAY=-1",
+		"FREQ=YEARLY;INTERVAL=3",
+	} {
+		r, err := Parse(value)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", value, err)
+		}
+		if r.String() != value {
+			t.Errorf("round trip: got %s, want %s", r.String(), value)
+		}
+	}
+
+	if r, err := Parse("RRULE:freq=weekly;byday=fr,mo"); err != nil || r.String() != "FREQ=WEEKLY;BYDAY=MO,FR" {
+		t.Fatalf("prefixed rule: got %v (err %v)", r, err)
+	}
+
+	for _, bad := range []string{"", "FREQ=HOURLY", "INTERVAL=2", "FREQ=DAILY;COUNT=3", "FREQ=WEEKLY;BYDAY=XX", "FREQ=DAILY;BYDAY=MO", "FREQ=MONTHLY;BYMONTHDAY=40"} {
+		if _, err := Parse(bad); err == nil {
+			t.Errorf("%q: expected error", bad)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	t.Parallel()
+
+	mustParse := func(v string) Rule {
+		r, err := Parse(v)
+		if err != nil {
+			t.Fatalf("parse %s: %v", v, err)
+		}
+		return r
+	}
+
+	tests := []struct {
+		rule    string
+		current time.Time
+		want    time.Time
+	}{
+		{rule: "FREQ=DAILY", current: date(2025, 10, 15), want: date(2025, 10, 16)},
+		{rule: "FREQ=DAILY;INTERVAL=3", current: date(2025, 10, 30), want: date(2025, 11, 2)},
+		{rule: "FREQ=WEEKLY", current: date(2025, 10, 15), want: date(2025, 10, 22)},
+		{rule: "FREQ=WEEKLY;INTERVAL=2;BYDAY=TU", current: date(2025, 10, 21), want: date(2025, 11, 4)},
+		{rule: "FREQ=WEEKLY;BYDAY=MO,WE,FR", current: date(2025, 10, 15), want: date(2025, 10, 17)},
+		{rule: "FREQ=WEEKLY;BYDAY=MO,WE,FR", current: date(2025, 10, 17), want: date(2025, 10, 20)},
+		{rule: "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR", current: date(2025, 10, 17), want: date(2025, 10, 27)},
+		{rule: "FREQ=MONTHLY;BYMONTHDAY=1", current: date(2025, 10, 1), want: date(2025, 11, 1)},
+		{rule: "FREQ=MONTHLY;BYMONTHDAY=31", current: date(2025, 1, 31), want: date(2025, 2, 28)},
+		{rule: "FREQ=MONTHLY;BYMONTHDAY=31", current: date(2025, 2, 28), want: date(2025, 3, 31)},
+		{rule: "FREQ=MONTHLY;BYMONTHDAY=-1", current: date(2025, 4, 30), want: date(2025, 5, 31)},
+		{rule: "FREQ=MONTHLY;INTERVAL=6", current: date(2025, 10, 15), want: date(2026, 4, 15)},
+		{rule: "FREQ=YEARLY", current: date(2024, 2, 29), want: date(2025, 2, 28)},
+	}
+
+	for _, tc := range tests {
+		got := mustParse(tc.rule).Next(tc.current)
+		if !got.Equal(tc.want) {
+			t.Errorf("%s after %s: got %s, want %s", tc.rule, tc.current.Format("2006-01-02"), got.Format("2006-01-02"), tc.want.Format("2006-01-02"))
+		}
+	}
+}
+
+func TestFirst(t *testing.T) {
+	t.Parallel()
+
+	wed := date(2025, 10, 15)
+	tests := []struct {
+		rule string
+		want time.Time
+	}{
+		{rule: "FREQ=WEEKLY;INTERVAL=2;BYDAY=TU", want: date(2025, 10, 21)},
+		{rule: "FREQ=WEEKLY;BYDAY=WE", want: wed},
+		{rule: "FREQ=MONTHLY;BYMONTHDAY=1", want: date(2025, 11, 1)},
+		{rule: "FREQ=DAILY;INTERVAL=5", want: wed},
+	}
+	for _, tc := range tests {
+		r, _ := Parse(tc.rule)
+		if got := r.First(wed); !got.Equal(tc.want) {
+			t.Errorf("%s: got %s, want %s", tc.rule, got.Format("2006-01-02"), tc.want.Format("2006-01-02"))
+		}
+	}
+}
+
+func TestNextKeepsWallClockAcrossDST(t *testing.T) {
+	t.Parallel()
+
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("zone data unavailable")
+	}
+	current := time.Date(2025, time.November, 1, 9, 30, 0, 0, loc)
+	got := Rule{Freq: Daily, Interval: 1}.Next(current)
+	if got.Hour() != 9 || got.Minute() != 30 || got.Day() != 2 {
+		t.Fatalf("expected 09:30 on Nov 2, got %s", got)
+	}
+}
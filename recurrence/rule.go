@@ -0,0 +1,258 @@
+// Package recurrence describes repeating tasks with a subset of iCalendar
+// RRULEs (FREQ, INTERVAL, BYDAY and BYMONTHDAY), finds their occurrences and
+// recognises recurrence phrases such as "every other Tuesday".
+package recurrence
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Freq string
+
+const (
+	Daily   Freq = "DAILY"
+	Weekly  Freq = "WEEKLY"
+	Monthly Freq = "MONTHLY"
+	Yearly  Freq = "YEARLY"
+)
+
+// LastDay as ByMonthDay means the last day of each month.
+const LastDay = -1
+
+// searchLimit bounds the day-by-day occurrence search; no supported rule
+// leaves a gap this long unless its interval is absurd.
+const searchLimit = 20 * 366
+
+// Rule is a recurrence rule. Occurrences keep the time of day of the date
+// they are computed from.
+type Rule struct {
+	Freq     Freq
+	Interval int
+	// ByDay lists the weekdays of a weekly rule. Empty means the weekday of
+	// the current occurrence.
+	ByDay []time.Weekday
+	// ByMonthDay is the day of a monthly rule, or LastDay. Zero means the
+	// day of the current occurrence.
+	ByMonthDay int
+}
+
+var dayCodes = map[time.Weekday]string{
+	time.Monday: "MO", time.Tuesday: "TU", time.Wednesday: "WE", time.Thursday: "TH",
+	time.Friday: "FR", time.Saturday: "SA", time.Sunday: "SU",
+}
+
+// String renders the rule as an RRULE value, e.g. "FREQ=WEEKLY;INTERVAL=2;BYDAY=TU".
+func (r Rule) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, 0, len(r.ByDay))
+		for _, day := range r.sortedDays() {
+			days = append(days, dayCodes[day])
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if r.ByMonthDay != 0 {
+		parts = append(parts, "BYMONTHDAY="+strconv.Itoa(r.ByMonthDay))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Parse reads an RRULE value, with or without the "RRULE:" prefix.
+func Parse(value string) (Rule, error) {
+	value = strings.TrimSpace(value)
+	value = strings.TrimPrefix(strings.TrimPrefix(value, "RRULE:"), "rrule:")
+	if value == "" {
+		return Rule{}, fmt.Errorf("empty recurrence rule")
+	}
+
+	r := Rule{Interval: 1}
+	for _, part := range strings.Split(value, ";") {
+		key, val, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return Rule{}, fmt.Errorf("malformed rule part %q", part)
+		}
+		val = strings.ToUpper(strings.TrimSpace(val))
+
+		switch strings.ToUpper(strings.TrimSpace(key)) {
+		case "FREQ":
+			switch Freq(val) {
+			case Daily, Weekly, Monthly, Yearly:
+				r.Freq = Freq(val)
+			default:
+				return Rule{}, fmt.Errorf("unsupported frequency %q", val)
+			}
+		case "INTERVAL":
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 1 || n > 999 {
+				return Rule{}, fmt.Errorf("invalid interval %q", val)
+			}
+			r.Interval = n
+		case "BYDAY":
+			for _, code := range strings.Split(val, ",") {
+				day, ok := parseDayCode(code)
+				if !ok {
+					return Rule{}, fmt.Errorf("invalid weekday %q", code)
+				}
+				r.ByDay = append(r.ByDay, day)
+			}
+		case "BYMONTHDAY":
+			n, err := strconv.Atoi(val)
+			if err != nil || n == 0 || n < LastDay || n > 31 {
+				return Rule{}, fmt.Errorf("invalid month day %q", val)
+			}
+			r.ByMonthDay = n
+		case "WKST":
+			// Weeks are always counted from Monday.
+		default:
+			return Rule{}, fmt.Errorf("unsupported rule part %q", key)
+		}
+	}
+
+	if r.Freq == "" {
+		return Rule{}, fmt.Errorf("rule has no FREQ")
+	}
+	if len(r.ByDay) > 0 && r.Freq != Weekly {
+		return Rule{}, fmt.Errorf("BYDAY is only supported for weekly rules")
+	}
+	if r.ByMonthDay != 0 && r.Freq != Monthly {
+		return Rule{}, fmt.Errorf("BYMONTHDAY is only supported for monthly rules")
+	}
+	return r, nil
+}
+
+func parseDayCode(code string) (time.Weekday, bool) {
+	code = strings.TrimSpace(code)
+	for day, c := range dayCodes {
+		if c == code {
+			return day, true
+		}
+	}
+	return 0, false
+}
+
+// Next returns the first occurrence after current, which is taken to be an
+// occurrence of the rule itself, so intervals count from it.
+func (r Rule) Next(current time.Time) time.Time {
+	for i := 1; i <= searchLimit; i++ {
+		day := addDays(current, i)
+		if r.matches(day, current, true) {
+			return day
+		}
+	}
+	return time.Time{}
+}
+
+// First returns the first occurrence on or after from, ignoring the interval:
+// "every other Tuesday" starts on the coming Tuesday.
+func (r Rule) First(from time.Time) time.Time {
+	for i := 0; i <= searchLimit; i++ {
+		day := addDays(from, i)
+		if r.matches(day, from, false) {
+			return day
+		}
+	}
+	return time.Time{}
+}
+
+func (r Rule) matches(day, anchor time.Time, useInterval bool) bool {
+	interval := r.Interval
+	if interval < 1 || !useInterval {
+		interval = 1
+	}
+
+	switch r.Freq {
+	case Daily:
+		return daysBetween(anchor, day)%interval == 0
+	case Weekly:
+		days := r.ByDay
+		if len(days) == 0 {
+			days = []time.Weekday{anchor.Weekday()}
+		}
+		if !containsDay(days, day.Weekday()) {
+			return false
+		}
+		return (daysBetween(mondayOf(anchor), mondayOf(day))/7)%interval == 0
+	case Monthly:
+		months := (day.Year()-anchor.Year())*12 + int(day.Month()-anchor.Month())
+		if months%interval != 0 {
+			return false
+		}
+		want := r.ByMonthDay
+		if want == 0 {
+			want = anchor.Day()
+		}
+		return day.Day() == clampDay(day, want)
+	case Yearly:
+		if (day.Year()-anchor.Year())%interval != 0 || day.Month() != anchor.Month() {
+			return false
+		}
+		return day.Day() == clampDay(day, anchor.Day())
+	}
+	return false
+}
+
+func (r Rule) sortedDays() []time.Weekday {
+	days := append([]time.Weekday(nil), r.ByDay...)
+	sort.Slice(days, func(a, b int) bool { return mondayIndex(days[a]) < mondayIndex(days[b]) })
+	return days
+}
+
+func containsDay(days []time.Weekday, day time.Weekday) bool {
+	for _, d := range days {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
+
+// clampDay resolves want (or LastDay) to a real day of day's month, so the
+// 31st falls on the 30th in a 30-day month.
+func clampDay(day time.Time, want int) int {
+	last := time.Date(day.Year(), day.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	if want == LastDay || want > last {
+		return last
+	}
+	return want
+}
+
+// addDays moves by calendar days, keeping the wall-clock time across DST.
+func addDays(t time.Time, n int) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day()+n, t.Hour(), t.Minute(), t.Second(), 0, t.Location())
+}
+
+func daysBetween(a, b time.Time) int {
+	da := time.Date(a.Year(), a.Month(), a.Day(), 0, 0, 0, 0, time.UTC)
+	db := time.Date(b.Year(), b.Month(), b.Day(), 0, 0, 0, 0, time.UTC)
+	return int(db.Sub(da).Hours() / 24)
+}
+
+func mondayOf(t time.Time) time.Time {
+	return addDays(t, -mondayIndex(t.Weekday()))
+}
+
+func mondayIndex(day time.Weekday) int {
+	return (int(day) + 6) % 7
+}
+
+// Anchored pins the parts a rule left implicit to first, so a weekly rule
+// keeps its weekday and a monthly rule its day even after a short month.
+func (r Rule) Anchored(first time.Time) Rule {
+	switch {
+	case r.Freq == Weekly && len(r.ByDay) == 0:
+		r.ByDay = []time.Weekday{first.Weekday()}
+	case r.Freq == Monthly && r.ByMonthDay == 0:
+		r.ByMonthDay = first.Day()
+	}
+	if r.Interval < 1 {
+		r.Interval = 1
+	}
+	return r
+}
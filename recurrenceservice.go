@@ -0,0 +1,474 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	c "github.com/imjamesonzeller/tasklight-v3/config"
+	"github.com/imjamesonzeller/tasklight-v3/notionapi"
+	"github.com/imjamesonzeller/tasklight-v3/recurrence"
+	"github.com/imjamesonzeller/tasklight-v3/settingsservice"
+)
+
+const (
+	recurrenceFileName      = "recurring.json"
+	recurrenceCheckInterval = 15 * time.Minute
+)
+
+// occurrenceStore is the part of Notion the recurrence service needs.
+type occurrenceStore interface {
+	PageState(ctx context.Context, pageID string) (pageState, error)
+	// FindOccurrence returns a page titled like task, in the data source of
+	// its destination, created at or after since, or "" when there is none.
+	FindOccurrence(ctx context.Context, task TaskInformation, since time.Time) (string, error)
+	CreateOccurrence(ctx context.Context, task TaskInformation) (string, error)
+}
+
+type pageState struct {
+	Completed bool
+	Archived  bool
+}
+
+// recurringSeries is one repeating task. Task holds the current occurrence.
+type recurringSeries struct {
+	ID     string          `json:"id"`
+	Task   TaskInformation `json:"task"`
+	PageID string          `json:"page_id"`
+	// Pending is written before the next occurrence is created, so a restart
+	// finishes that occurrence instead of creating another one.
+	Pending *pendingOccurrence `json:"pending,omitempty"`
+}
+
+type pendingOccurrence struct {
+	Task  TaskInformation `json:"task"`
+	Since time.Time       `json:"since"`
+}
+
+// RecurringTask describes a series for the frontend.
+type RecurringTask struct {
+	ID         string `json:"id"`
+	Title      string `json:"title"`
+	Recurrence string `json:"recurrence"`
+	Date       string `json:"date"`
+}
+
+// RecurrenceService creates the next occurrence of a recurring task once the
+// current one is completed or its date has passed. Series live in a local
+// registry next to the settings file.
+type RecurrenceService struct {
+	store    occurrenceStore
+	settings *settingsservice.SettingsService
+	now      func() time.Time
+	interval time.Duration
+	path     string
+
+	mu     sync.Mutex
+	series []recurringSeries
+	loaded bool
+
+	// runMu guards the background loop apart from mu, which CheckDue holds
+	// while it talks to Notion.
+	runMu  sync.Mutex
+	stop   chan struct{}
+	done   chan struct{}
+	cancel context.CancelFunc
+}
+
+func NewRecurrenceService(tasks *TaskService, settings *settingsservice.SettingsService) *RecurrenceService {
+	return &RecurrenceService{
+		store:    &notionOccurrences{tasks: tasks},
+		settings: settings,
+		now:      time.Now,
+		interval: recurrenceCheckInterval,
+	}
+}
+
+// Start loads the registry and checks for due series now and then on every
+// interval until Stop.
+func (rs *RecurrenceService) Start() {
+	rs.runMu.Lock()
+	if rs.stop != nil {
+		rs.runMu.Unlock()
+		return
+	}
+	stop, done := make(chan struct{}), make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
+	rs.stop, rs.done, rs.cancel = stop, done, cancel
+	rs.runMu.Unlock()
+
+	go func() {
+		defer close(done)
+
+		ticker := time.NewTicker(rs.interval)
+		defer ticker.Stop()
+
+		for {
+			rs.CheckDue(ctx)
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (rs *RecurrenceService) Stop() {
+	rs.runMu.Lock()
+	stop, done, cancel := rs.stop, rs.done, rs.cancel
+	rs.stop = nil
+	rs.runMu.Unlock()
+
+	if stop == nil {
+		return
+	}
+	cancel()
+	close(stop)
+	<-done
+}
+
+// Register adds a newly created recurring task whose first occurrence is pageID.
+func (rs *RecurrenceService) Register(task TaskInformation, pageID string) error {
+	if task.Recurrence == "" || pageID == "" {
+		return fmt.Errorf("task %q is not a created recurring task", task.Title)
+	}
+
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	if err := rs.load(); err != nil {
+		return err
+	}
+	for _, s := range rs.series {
+		if s.ID == pageID {
+			return nil
+		}
+	}
+
+	rs.series = append(rs.series, recurringSeries{ID: pageID, Task: task, PageID: pageID})
+	return rs.save()
+}
+
+// GetRecurringTasks lists the registered series. Called from frontend
+func (rs *RecurrenceService) GetRecurringTasks() ([]RecurringTask, error) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	if err := rs.load(); err != nil {
+		return nil, err
+	}
+
+	tasks := make([]RecurringTask, 0, len(rs.series))
+	for _, s := range rs.series {
+		item := RecurringTask{ID: s.ID, Title: s.Task.Title, Recurrence: s.Task.Recurrence}
+		if s.Task.Date != nil {
+			item.Date = *s.Task.Date
+		}
+		tasks = append(tasks, item)
+	}
+	return tasks, nil
+}
+
+// StopRecurring removes a series; pages already created are left alone.
+func (rs *RecurrenceService) StopRecurring(id string) error {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	if err := rs.load(); err != nil {
+		return err
+	}
+	for i, s := range rs.series {
+		if s.ID == id {
+			rs.series = append(rs.series[:i], rs.series[i+1:]...)
+			return rs.save()
+		}
+	}
+	return fmt.Errorf("recurring task %q not found", id)
+}
+
+// CheckDue creates the next occurrence for every series that is due, and
+// finishes any occurrence a previous run left pending.
+func (rs *RecurrenceService) CheckDue(ctx context.Context) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	if err := rs.load(); err != nil {
+		log.Println("CheckDue: failed to load recurring tasks:", err)
+		return
+	}
+
+	// advance saves rs.series as it goes, so it must stay whole until the
+	// loop is done.
+	kept := make([]recurringSeries, 0, len(rs.series))
+	for i := range rs.series {
+		if rs.advance(ctx, &rs.series[i]) {
+			kept = append(kept, rs.series[i])
+		}
+	}
+	rs.series = kept
+
+	if err := rs.save(); err != nil {
+		log.Println("CheckDue: failed to save recurring tasks:", err)
+	}
+}
+
+// advance moves one series forward and reports whether it should be kept.
+func (rs *RecurrenceService) advance(ctx context.Context, s *recurringSeries) bool {
+	if s.Pending == nil {
+		state, err := rs.store.PageState(ctx, s.PageID)
+		if err != nil {
+			log.Printf("CheckDue: %q: failed to read page state: %v", s.Task.Title, err)
+			return true
+		}
+		if state.Archived {
+			log.Printf("CheckDue: %q was deleted in Notion; stopping the series", s.Task.Title)
+			return false
+		}
+
+		next, ok := rs.nextOccurrence(s.Task, state.Completed)
+		if !ok {
+			return true
+		}
+		s.Pending = &pendingOccurrence{Task: next, Since: rs.now()}
+		if err := rs.save(); err != nil {
+			log.Printf("CheckDue: %q: failed to record pending occurrence: %v", s.Task.Title, err)
+			s.Pending = nil
+			return true
+		}
+	}
+
+	pageID, err := rs.store.FindOccurrence(ctx, s.Pending.Task, s.Pending.Since)
+	if err != nil {
+		log.Printf("CheckDue: %q: failed to look for an existing occurrence: %v", s.Task.Title, err)
+		return true
+	}
+	if pageID == "" {
+		pageID, err = rs.store.CreateOccurrence(ctx, s.Pending.Task)
+		if err != nil {
+			log.Printf("CheckDue: %q: failed to create the next occurrence: %v", s.Task.Title, err)
+			return true
+		}
+	}
+
+	s.Task, s.PageID, s.Pending = s.Pending.Task, pageID, nil
+	return true
+}
+
+// nextOccurrence returns the task for the next occurrence when the current
+// one is completed or past. Occurrences missed while the app was closed are
+// skipped rather than created.
+func (rs *RecurrenceService) nextOccurrence(task TaskInformation, completed bool) (TaskInformation, bool) {
+	if task.Date == nil {
+		return TaskInformation{}, false
+	}
+	rule, err := recurrence.Parse(task.Recurrence)
+	if err != nil {
+		log.Printf("CheckDue: %q: %v", task.Title, err)
+		return TaskInformation{}, false
+	}
+
+	loc := rs.location(task)
+	current, ok := normalizeTaskDate(task.Date, loc)
+	if !ok {
+		return TaskInformation{}, false
+	}
+	end, _ := normalizeTaskDate(task.EndDate, loc)
+
+	now := rs.now().In(loc)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	passed := func(t time.Time, timed bool) bool {
+		if timed {
+			return t.Before(now)
+		}
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc).Before(today)
+	}
+
+	if !completed && !passed(current.time, current.timed) {
+		return TaskInformation{}, false
+	}
+
+	next := rule.Next(current.time)
+	for !next.IsZero() && passed(next, current.timed) {
+		next = rule.Next(next)
+	}
+	if next.IsZero() {
+		return TaskInformation{}, false
+	}
+
+	date := formatLike(next, *task.Date)
+	task.Date = &date
+	if task.EndDate != nil {
+		shifted := formatLike(end.time.Add(next.Sub(current.time)), *task.EndDate)
+		task.EndDate = &shifted
+	}
+	return task, true
+}
+
+func (rs *RecurrenceService) location(task TaskInformation) *time.Location {
+	if task.TimeZone != "" {
+		if loc, err := time.LoadLocation(task.TimeZone); err == nil {
+			return loc
+		}
+	}
+	if c.AppConfig != nil {
+		loc, _ := c.AppConfig.Location()
+		return loc
+	}
+	return time.Local
+}
+
+// ====== Registry ======
+
+type recurrenceRegistry struct {
+	Series []recurringSeries `json:"series"`
+}
+
+func (rs *RecurrenceService) load() error {
+	if rs.loaded {
+		return nil
+	}
+	if rs.path == "" {
+		rs.path = rs.settings.DataPath(recurrenceFileName)
+	}
+
+	data, err := os.ReadFile(rs.path)
+	if errors.Is(err, os.ErrNotExist) {
+		rs.loaded = true
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var registry recurrenceRegistry
+	if err := json.Unmarshal(data, &registry); err != nil {
+		return fmt.Errorf("corrupt recurrence registry %s: %w", rs.path, err)
+	}
+	rs.series = registry.Series
+	rs.loaded = true
+	return nil
+}
+
+// save writes the registry through a temporary file so a crash never leaves
+// it half written.
+func (rs *RecurrenceService) save() error {
+	data, err := json.MarshalIndent(recurrenceRegistry{Series: rs.series}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := rs.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, rs.path)
+}
+
+// ====== Notion ======
+
+type notionOccurrences struct {
+	tasks *TaskService
+}
+
+func (n *notionOccurrences) token() (string, error) {
+	token, err := n.tasks.settings.GetNotionToken(true)
+	if err != nil {
+		return "", err
+	}
+	if token == "" {
+		return "", ErrNotionTokenMissing
+	}
+	return token, nil
+}
+
+func (n *notionOccurrences) PageState(ctx context.Context, pageID string) (pageState, error) {
+	ctx, cancel := notionStage(ctx)
+	defer cancel()
+
+	token, err := n.token()
+	if err != nil {
+		return pageState{}, err
+	}
+
+	req, err := notionapi.NewRequest(ctx, http.MethodGet, notionapi.URL("/pages/"+pageID), token)
+	if err != nil {
+		return pageState{}, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return pageState{}, err
+	}
+
+	var page struct {
+		Archived   bool                    `json:"archived"`
+		InTrash    bool                    `json:"in_trash"`
+		Properties map[string]pageProperty `json:"properties"`
+	}
+	if err := notionapi.ParseResponse(resp, &page, ErrNotionTokenMissing); err != nil {
+		return pageState{}, err
+	}
+
+	return pageState{
+		Archived:  page.Archived || page.InTrash,
+		Completed: propertiesCompleted(page.Properties),
+	}, nil
+}
+
+func (n *notionOccurrences) FindOccurrence(ctx context.Context, task TaskInformation, since time.Time) (string, error) {
+	ctx, cancel := notionStage(ctx)
+	defer cancel()
+
+	// CreateOccurrence routes by destination, so the lookup must too.
+	target, err := n.tasks.resolveTarget(ctx, task.Destination)
+	if err != nil {
+		return "", err
+	}
+
+	// Allow for clock skew between this machine and Notion.
+	query := map[string]any{
+		"page_size": 1,
+		"filter": map[string]any{
+			"and": []map[string]any{
+				{"property": target.titleProp, "title": map[string]any{"equals": task.Title}},
+				{"timestamp": "created_time", "created_time": map[string]any{"on_or_after": since.Add(-time.Minute).UTC().Format(time.RFC3339)}},
+			},
+		},
+	}
+	url := notionapi.URL("/data_sources/" + target.dataSourceID + "/query")
+	req, err := notionapi.NewJSONRequest(ctx, http.MethodPost, url, target.token, query)
+	if err != nil {
+		return "", err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+
+	var result struct {
+		Results []struct {
+			ID string `json:"id"`
+		} `json:"results"`
+	}
+	if err := notionapi.ParseResponse(resp, &result, ErrNotionTokenMissing); err != nil {
+		return "", err
+	}
+	if len(result.Results) == 0 {
+		return "", nil
+	}
+	return result.Results[0].ID, nil
+}
+
+func (n *notionOccurrences) CreateOccurrence(ctx context.Context, task TaskInformation) (string, error) {
+	result := n.tasks.createNotionPage(ctx, task)
+	if !result.OK() {
+		return "", errors.New(result.Error)
+	}
+	return result.PageID, nil
+}
@@ -0,0 +1,332 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	c "github.com/imjamesonzeller/tasklight-v3/config"
+	"github.com/imjamesonzeller/tasklight-v3/notionapi"
+	"github.com/imjamesonzeller/tasklight-v3/settingsservice"
+)
+
+type fakeOccurrences struct {
+	states  map[string]pageState
+	pages   map[string]TaskInformation
+	created []TaskInformation
+	failing bool
+}
+
+func newFakeOccurrences() *fakeOccurrences {
+	return &fakeOccurrences{states: map[string]pageState{}, pages: map[string]TaskInformation{}}
+}
+
+func (f *fakeOccurrences) PageState(_ context.Context, pageID string) (pageState, error) {
+	return f.states[pageID], nil
+}
+
+func (f *fakeOccurrences) FindOccurrence(_ context.Context, want TaskInformation, _ time.Time) (string, error) {
+	for id, task := range f.pages {
+		if task.Title == want.Title && task.Destination == want.Destination {
+			return id, nil
+		}
+	}
+	return "", nil
+}
+
+func (f *fakeOccurrences) CreateOccurrence(_ context.Context, task TaskInformation) (string, error) {
+	if f.failing {
+		return "", errors.New("503 Service Unavailable")
+	}
+	f.created = append(f.created, task)
+	id := fmt.Sprintf("page-%d", len(f.created))
+	f.pages[id] = task
+	return id, nil
+}
+
+func newTestRecurrenceService(t *testing.T, store occurrenceStore, now *time.Time) *RecurrenceService {
+	t.Helper()
+
+	return &RecurrenceService{
+		store:    store,
+		now:      func() time.Time { return *now },
+		interval: time.Hour,
+		path:     filepath.Join(t.TempDir(), recurrenceFileName),
+	}
+}
+
+func weeklyTask(date string) TaskInformation {
+	return TaskInformation{Title: "Water plants", Date: ptr(date), TimeZone: "UTC", Recurrence: "FREQ=WEEKLY;BYDAY=TU"}
+}
+
+func TestRecurrenceWaitsForCurrentOccurrence(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, time.October, 21, 9, 0, 0, 0, time.UTC)
+	store := newFakeOccurrences()
+	rs := newTestRecurrenceService(t, store, &now)
+
+	if err := rs.Register(weeklyTask("2025-10-21"), "first"); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	rs.CheckDue(context.Background())
+
+	if len(store.created) != 0 {
+		t.Fatalf("today's occurrence is not due yet, created %+v", store.created)
+	}
+}
+
+func TestRecurrenceCreatesNextOnceWhenCompleted(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, time.October, 21, 9, 0, 0, 0, time.UTC)
+	store := newFakeOccurrences()
+	store.states["first"] = pageState{Completed: true}
+	rs := newTestRecurrenceService(t, store, &now)
+
+	if err := rs.Register(weeklyTask("2025-10-21"), "first"); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	rs.CheckDue(context.Background())
+	rs.CheckDue(context.Background())
+
+	if len(store.created) != 1 {
+		t.Fatalf("expected one new occurrence, got %d", len(store.created))
+	}
+	if *store.created[0].Date != "2025-10-28" {
+		t.Fatalf("unexpected next date: %s", *store.created[0].Date)
+	}
+
+	series, err := rs.GetRecurringTasks()
+	if err != nil || len(series) != 1 || series[0].Date != "2025-10-28" {
+		t.Fatalf("unexpected series: %+v (err %v)", series, err)
+	}
+}
+
+func TestRecurrenceSkipsMissedOccurrences(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, time.November, 6, 9, 0, 0, 0, time.UTC)
+	store := newFakeOccurrences()
+	rs := newTestRecurrenceService(t, store, &now)
+
+	task := weeklyTask("2025-10-21")
+	task.EndDate = ptr("2025-10-22")
+	if err := rs.Register(task, "first"); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	rs.CheckDue(context.Background())
+
+	if len(store.created) != 1 {
+		t.Fatalf("expected one new occurrence, got %d", len(store.created))
+	}
+	got := store.created[0]
+	if *got.Date != "2025-11-11" || *got.EndDate != "2025-11-12" {
+		t.Fatalf("expected the next upcoming occurrence, got %s..%s", *got.Date, *got.EndDate)
+	}
+}
+
+func TestRecurrenceTimedOccurrence(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, time.October, 21, 9, 30, 0, 0, time.UTC)
+	store := newFakeOccurrences()
+	rs := newTestRecurrenceService(t, store, &now)
+
+	task := TaskInformation{Title: "Standup", Date: ptr("2025-10-21T09:00:00"), TimeZone: "UTC", Recurrence: "FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR"}
+	if err := rs.Register(task, "first"); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	rs.CheckDue(context.Background())
+
+	if len(store.created) != 1 || *store.created[0].Date != "2025-10-22T09:00:00" {
+		t.Fatalf("unexpected occurrences: %+v", store.created)
+	}
+}
+
+func TestRecurrenceResumesPendingAfterRestart(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, time.October, 22, 9, 0, 0, 0, time.UTC)
+	store := newFakeOccurrences()
+	store.failing = true
+	rs := newTestRecurrenceService(t, store, &now)
+
+	if err := rs.Register(weeklyTask("2025-10-21"), "first"); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	rs.CheckDue(context.Background())
+
+	// The page was created but the app quit before recording it.
+	store.failing = false
+	store.pages["made-before-crash"] = TaskInformation{Title: "Water plants", Date: ptr("2025-10-28")}
+
+	restarted := newTestRecurrenceService(t, store, &now)
+	restarted.path = rs.path
+	restarted.CheckDue(context.Background())
+	restarted.CheckDue(context.Background())
+
+	if len(store.created) != 0 {
+		t.Fatalf("restart must not duplicate the occurrence, created %+v", store.created)
+	}
+	series, err := restarted.GetRecurringTasks()
+	if err != nil || len(series) != 1 || series[0].Date != "2025-10-28" {
+		t.Fatalf("unexpected series: %+v (err %v)", series, err)
+	}
+	if restarted.series[0].PageID != "made-before-crash" || restarted.series[0].Pending != nil {
+		t.Fatalf("expected the existing page to be adopted: %+v", restarted.series[0])
+	}
+}
+
+func TestRecurrenceStopsWhenPageDeleted(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, time.October, 22, 9, 0, 0, 0, time.UTC)
+	store := newFakeOccurrences()
+	store.states["first"] = pageState{Archived: true}
+	rs := newTestRecurrenceService(t, store, &now)
+
+	if err := rs.Register(weeklyTask("2025-10-21"), "first"); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	rs.CheckDue(context.Background())
+
+	if len(store.created) != 0 {
+		t.Fatalf("deleted series must not continue, created %+v", store.created)
+	}
+	if series, _ := rs.GetRecurringTasks(); len(series) != 0 {
+		t.Fatalf("expected the series to be dropped, got %+v", series)
+	}
+}
+
+// snapshotOccurrences records the saved registry when an occurrence is
+// created, in the middle of CheckDue.
+type snapshotOccurrences struct {
+	*fakeOccurrences
+	path      string
+	snapshots []recurrenceRegistry
+}
+
+func (s *snapshotOccurrences) CreateOccurrence(ctx context.Context, task TaskInformation) (string, error) {
+	var saved recurrenceRegistry
+	if data, err := os.ReadFile(s.path); err == nil {
+		_ = json.Unmarshal(data, &saved)
+	}
+	s.snapshots = append(s.snapshots, saved)
+	return s.fakeOccurrences.CreateOccurrence(ctx, task)
+}
+
+func TestCheckDueSavesWholeRegistryMidway(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, time.October, 22, 9, 0, 0, 0, time.UTC)
+	fake := newFakeOccurrences()
+	fake.states["a"] = pageState{Archived: true}
+	fake.states["b"] = pageState{Completed: true}
+	fake.states["c"] = pageState{Completed: true}
+	store := &snapshotOccurrences{fakeOccurrences: fake}
+	rs := newTestRecurrenceService(t, store, &now)
+	store.path = rs.path
+
+	for _, id := range []string{"a", "b", "c"} {
+		task := weeklyTask("2025-10-21")
+		task.Title = "Task " + id
+		if err := rs.Register(task, id); err != nil {
+			t.Fatalf("Register: %v", err)
+		}
+	}
+	rs.CheckDue(context.Background())
+
+	if len(store.snapshots) != 2 {
+		t.Fatalf("expected two occurrences, got %d", len(store.snapshots))
+	}
+	for _, saved := range store.snapshots {
+		seen := map[string]bool{}
+		for _, s := range saved.Series {
+			if seen[s.PageID] {
+				t.Fatalf("registry saved midway repeats %q: %+v", s.PageID, saved.Series)
+			}
+			seen[s.PageID] = true
+		}
+	}
+	if series, _ := rs.GetRecurringTasks(); len(series) != 2 {
+		t.Fatalf("expected the deleted series dropped, got %+v", series)
+	}
+}
+
+func TestStopRecurring(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, time.October, 22, 9, 0, 0, 0, time.UTC)
+	rs := newTestRecurrenceService(t, newFakeOccurrences(), &now)
+
+	if err := rs.Register(weeklyTask("2025-10-21"), "first"); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if err := rs.StopRecurring("first"); err != nil {
+		t.Fatalf("StopRecurring: %v", err)
+	}
+	if err := rs.StopRecurring("first"); err == nil {
+		t.Fatalf("expected an error for an unknown series")
+	}
+}
+
+func TestFindOccurrenceUsesDestination(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/data_sources/ds-2":
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"id": "ds-2",
+				"properties": map[string]any{
+					"Task": map[string]any{"id": "title", "name": "Task", "type": "title"},
+				},
+			})
+		case "/data_sources/ds-2/query":
+			var query struct {
+				Filter struct {
+					And []map[string]any `json:"and"`
+				} `json:"filter"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&query)
+			if len(query.Filter.And) == 0 || query.Filter.And[0]["property"] != "Task" {
+				t.Errorf("expected a query on the destination's title property, got %+v", query)
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"results": []any{map[string]any{"id": "occurrence"}}})
+		default:
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	originalBase := notionapi.BaseURL
+	originalConfig := c.AppConfig
+	t.Cleanup(func() {
+		notionapi.BaseURL = originalBase
+		c.AppConfig = originalConfig
+	})
+	notionapi.BaseURL = srv.URL
+	settings := &settingsservice.SettingsService{
+		AppSettings: settingsservice.ApplicationSettings{
+			NotionAccessToken:  "secret",
+			NotionDataSourceID: "ds-1",
+			Destinations:       []settingsservice.Destination{{Name: "Work", DataSourceID: "ds-2"}},
+		},
+	}
+	c.AppConfig = &settings.AppSettings
+	store := &notionOccurrences{tasks: NewTaskService(nil, settings)}
+
+	task := weeklyTask("2025-10-28")
+	task.Destination = "Work"
+	id, err := store.FindOccurrence(context.Background(), task, time.Now())
+	if err != nil || id != "occurrence" {
+		t.Fatalf("FindOccurrence = %q, %v", id, err)
+	}
+}
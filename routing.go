@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/imjamesonzeller/tasklight-v3/settingsservice"
+)
+
+// routePrefixRe reads a leading "prefix: rest" from task input, whose rest
+// may carry notes on further lines.
+var routePrefixRe = regexp.MustCompile(`(?s)^\s*([^\s:]+):\s*(.*)$`)
+
+// taskRoute is the destination chosen for a capture.
+type taskRoute struct {
+	// destination is the name of the destination; empty is the default
+	// destination.
+	destination string
+	// reason says what chose the destination, e.g. `prefix "w:"`.
+	reason string
+	// input is the capture without its routing prefix.
+	input string
+}
+
+// routeInput picks the destination of input. A leading "prefix:" naming a
+// one-word destination or one of its prefixes wins, then the first destination with
+// a keyword in the input. Anything else goes to the default destination.
+func routeInput(input string, destinations []settingsservice.Destination) taskRoute {
+	if m := routePrefixRe.FindStringSubmatch(input); m != nil && strings.TrimSpace(m[2]) != "" {
+		for _, d := range destinations {
+			for _, prefix := range d.RoutePrefixes() {
+				if strings.EqualFold(prefix, m[1]) {
+					return taskRoute{destination: d.Name, reason: fmt.Sprintf("prefix %q", m[1]+":"), input: strings.TrimSpace(m[2])}
+				}
+			}
+		}
+	}
+
+	for _, d := range destinations {
+		for _, keyword := range d.Keywords {
+			if containsWord(input, keyword) {
+				return taskRoute{destination: d.Name, reason: fmt.Sprintf("keyword %q", keyword), input: input}
+			}
+		}
+	}
+	return taskRoute{input: input}
+}
+
+// containsWord reports whether text contains phrase case-insensitively and
+// not as part of a longer word.
+func containsWord(text, phrase string) bool {
+	if strings.TrimSpace(phrase) == "" {
+		return false
+	}
+	re, err := regexp.Compile(`(?i)(?:^|[^\p{L}\p{N}])` + regexp.QuoteMeta(phrase) + `(?:$|[^\p{L}\p{N}])`)
+	return err == nil && re.MatchString(text)
+}
+
+// destinationDateProperty returns the date property of dest. A named
+// destination without one configured uses the only date property of its
+// data source, or one named Due or Date.
+func destinationDateProperty(dest settingsservice.Destination, detail *NotionDataSourceDetail) (string, error) {
+	if dest.Name == "" || dest.DatePropertyID != "" || dest.DatePropertyName != "" {
+		return dateProperty(detail, dest.DatePropertyID, dest.DatePropertyName)
+	}
+
+	if prop := findProperty(detail, []string{"due", "due date", "date", "deadline"}, "date"); prop != nil {
+		return prop.Name, nil
+	}
+	var only string
+	for name, prop := range detail.Properties {
+		if prop.Type != "date" {
+			continue
+		}
+		if only != "" {
+			return "", nil
+		}
+		only = name
+		if prop.Name != "" {
+			only = prop.Name
+		}
+	}
+	return only, nil
+}
+
+// destinationDetail loads the data source of the named destination, or of
+// the default destination for "".
+func (n *NotionService) destinationDetail(ctx context.Context, destination string) (settingsservice.Destination, *NotionDataSourceDetail, error) {
+	dest, ok := n.settingsservice.AppSettings.FindDestination(destination)
+	if !ok {
+		return dest, nil, fmt.Errorf("destination %q does not exist", destination)
+	}
+	detail, err := n.GetDataSourceDetail(ctx, dest.DataSourceID)
+	return dest, detail, err
+}
+
+// DestinationStatus describes whether captures routed to a destination can
+// be written.
+type DestinationStatus struct {
+	// Name is empty for the default destination.
+	Name         string `json:"name"`
+	DataSourceID string `json:"data_source_id"`
+	DataSource   string `json:"data_source,omitempty"`
+	DateProperty string `json:"date_property,omitempty"`
+	// Problems keep captures from the destination or leave fields out.
+	Problems []string `json:"problems,omitempty"`
+}
+
+// CheckDestinations validates the default destination and each named one
+// against the schema of its own data source. Called from frontend
+func (n *NotionService) CheckDestinations(ctx context.Context) []DestinationStatus {
+	settings := n.settingsservice.AppSettings
+	destinations := append([]settingsservice.Destination{settings.DefaultDestination()}, settings.Destinations...)
+
+	statuses := make([]DestinationStatus, 0, len(destinations))
+	for _, dest := range destinations {
+		statuses = append(statuses, n.checkDestination(ctx, dest))
+	}
+	return statuses
+}
+
+func (n *NotionService) checkDestination(ctx context.Context, dest settingsservice.Destination) DestinationStatus {
+	status := DestinationStatus{Name: dest.Name, DataSourceID: dest.DataSourceID}
+	problem := func(err error) {
+		status.Problems = append(status.Problems, err.Error())
+	}
+
+	if dest.DataSourceID == "" {
+		problem(errors.New("no data source selected"))
+		return status
+	}
+	detail, err := n.GetDataSourceDetail(ctx, dest.DataSourceID)
+	if err != nil {
+		problem(err)
+		return status
+	}
+	status.DataSource = detail.Name
+
+	if _, err := detectTitleProperty(detail); err != nil {
+		problem(err)
+	}
+	if status.DateProperty, err = destinationDateProperty(dest, detail); err != nil {
+		problem(err)
+	}
+	if err := validatePropertyMappings(detail, dest.PropertyMappings()); err != nil {
+		problem(err)
+	}
+	for id, value := range dest.PropertyDefaults() {
+		if prop := propertyByID(detail, id); prop == nil {
+			problem(fmt.Errorf("default %q is for a property that no longer exists", value))
+		} else if err := checkDefault(*prop, value); err != nil {
+			problem(err)
+		}
+	}
+	for id, policy := range dest.UnmatchedOptions() {
+		if prop := propertyByID(detail, id); prop != nil {
+			if err := checkUnmatched(*prop, policy); err != nil {
+				problem(err)
+			}
+		}
+	}
+	return status
+}
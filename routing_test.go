@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	c "github.com/imjamesonzeller/tasklight-v3/config"
+	"github.com/imjamesonzeller/tasklight-v3/notionapi"
+	"github.com/imjamesonzeller/tasklight-v3/settingsservice"
+)
+
+func TestRouteInput(t *testing.T) {
+	t.Parallel()
+
+	destinations := []settingsservice.Destination{
+		{Name: "Work", Prefixes: []string{"w"}, Keywords: []string{"standup", "sprint review"}},
+		{Name: "Home", Keywords: []string{"groceries", "standup"}},
+		{Name: "Team Backlog", Prefixes: []string{"tb"}},
+	}
+	tests := []struct {
+		input, destination, reason, rest string
+	}{
+		{"w: call Sam tomorrow", "Work", `prefix "w:"`, "call Sam tomorrow"},
+		{"HOME:  fix the sink", "Home", `prefix "HOME:"`, "fix the sink"},
+		{"prep for Standup notes", "Work", `keyword "standup"`, "prep for Standup notes"},
+		{"book room for sprint review", "Work", `keyword "sprint review"`, "book room for sprint review"},
+		{"order groceries", "Home", `keyword "groceries"`, "order groceries"},
+		{"read standups digest", "", "", "read standups digest"},
+		{"x: unknown prefix", "", "", "x: unknown prefix"},
+		{"project: Launch", "", "", "project: Launch"},
+		{"w:", "", "", "w:"},
+		{"tb: triage bugs", "Team Backlog", `prefix "tb:"`, "triage bugs"},
+		{"Backlog: triage bugs", "", "", "Backlog: triage bugs"},
+		{"w: plan sprint\n- [ ] agenda", "Work", `prefix "w:"`, "plan sprint\n- [ ] agenda"},
+	}
+	for _, tt := range tests {
+		route := routeInput(tt.input, destinations)
+		if route.destination != tt.destination || route.reason != tt.reason || route.input != tt.rest {
+			t.Errorf("routeInput(%q) = %+v, want %q by %q with %q", tt.input, route, tt.destination, tt.reason, tt.rest)
+		}
+	}
+}
+
+func TestProcessTasksRoutesToDestinations(t *testing.T) {
+	var mu sync.Mutex
+	parents := map[string]string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/data_sources/ds-1":
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"id": "ds-1",
+				"properties": map[string]any{
+					"Name": map[string]any{"id": "title", "name": "Name", "type": "title"},
+				},
+			})
+		case r.URL.Path == "/data_sources/ds-2":
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"id": "ds-2",
+				"properties": map[string]any{
+					"Task":    map[string]any{"id": "title", "name": "Task", "type": "title"},
+					"Due":     map[string]any{"id": "d1", "name": "Due", "type": "date"},
+					"Created": map[string]any{"id": "d2", "name": "Created", "type": "date"},
+				},
+			})
+		case strings.HasSuffix(r.URL.Path, "/query"):
+			_ = json.NewEncoder(w).Encode(map[string]any{"results": []any{}})
+		case r.Method == http.MethodPost && r.URL.Path == "/pages":
+			var payload struct {
+				Parent struct {
+					DataSourceID string `json:"data_source_id"`
+				} `json:"parent"`
+				Properties map[string]json.RawMessage `json:"properties"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&payload)
+			names := make([]string, 0, len(payload.Properties))
+			for name := range payload.Properties {
+				names = append(names, name)
+			}
+			mu.Lock()
+			parents[payload.Parent.DataSourceID] = strings.Join(names, ",")
+			mu.Unlock()
+			_ = json.NewEncoder(w).Encode(map[string]any{"id": "page-" + payload.Parent.DataSourceID})
+		default:
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	originalBase := notionapi.BaseURL
+	originalConfig := c.AppConfig
+	originalUser := c.GetCurrentUserId()
+	t.Cleanup(func() {
+		notionapi.BaseURL = originalBase
+		c.AppConfig = originalConfig
+		c.SetCurrentUserId(originalUser)
+	})
+	notionapi.BaseURL = srv.URL
+	c.SetCurrentUserId("")
+	settings := &settingsservice.SettingsService{
+		AppSettings: settingsservice.ApplicationSettings{
+			NotionAccessToken:  "secret",
+			NotionDataSourceID: "ds-1",
+			Destinations: []settingsservice.Destination{
+				{Name: "Work", DataSourceID: "ds-2", Prefixes: []string{"w"}},
+			},
+		},
+	}
+	c.AppConfig = &settings.AppSettings
+	ts := NewTaskService(nil, settings)
+
+	results := ts.processTasks(context.Background(), []string{"w: call Sam tomorrow", "buy milk"}, "")
+	work, home := results[0], results[1]
+	if !work.OK || work.DataSourceID != "ds-2" || work.Destination != "Work" || work.RoutedBy != `prefix "w:"` || work.Title != "call Sam" {
+		t.Fatalf("expected the prefixed task in Work, got %+v", work)
+	}
+	if !home.OK || home.DataSourceID != "ds-1" || home.Destination != "" || home.RoutedBy != "" {
+		t.Fatalf("expected the other task in the default destination, got %+v", home)
+	}
+	// The destination's own schema decides the title and date properties.
+	if props := parents["ds-2"]; !strings.Contains(props, "Task") || !strings.Contains(props, "Due") {
+		t.Fatalf("expected Task and Due to be written in ds-2, got %q", props)
+	}
+
+	// A task queued for a destination deleted since goes to the default.
+	result := ts.postNotionPage(context.Background(), TaskInformation{Title: "late", Destination: "Gone"}, settingsservice.DuplicatesOff)
+	if !result.OK() || result.DataSourceID != "ds-1" || len(result.Warnings) != 1 || !strings.Contains(result.Warnings[0], "Gone") {
+		t.Fatalf("expected a fallback to the default destination with a warning, got %+v", result)
+	}
+}
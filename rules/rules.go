@@ -0,0 +1,409 @@
+// Package rules applies declarative capture rules to parsed tasks. A rule
+// file lists rules in order; each rule has conditions that must all hold and
+// actions that run when they do, so a later rule sees the changes of an
+// earlier one:
+//
+//	{"rules": [
+//	  {"name": "Invoices", "when": {"keywords": ["invoice"]},
+//	   "then": [{"add_tag": "Finance"}, {"set": {"field": "priority", "value": "High"}}]},
+//	  {"name": "Evening captures", "when": {"captured_after": "18:00", "missing": ["date"]},
+//	   "then": [{"set_date": "tomorrow"}]}
+//	]}
+package rules
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/imjamesonzeller/tasklight-v3/dateparse"
+)
+
+// Task is the parsed task rules read and change. Field names are the
+// lower-case task fields listed in MissingFields.
+type Task interface {
+	// Get returns the field as text, or "" when it is empty. Tags are
+	// joined with ", ".
+	Get(field string) string
+	// Set replaces a text field.
+	Set(field, value string)
+	AddTag(tag string)
+	// SetDate moves the task to the day named by a phrase such as
+	// "tomorrow" or "next friday".
+	SetDate(phrase string)
+}
+
+var (
+	// TextFields can be matched, replaced and set.
+	TextFields = []string{"title", "notes", "project", "priority"}
+	// MissingFields can be required to be empty.
+	MissingFields = append(slices.Clone(TextFields), "date", "tags", "people", "recurrence", "estimate")
+)
+
+// File is the JSON form of a rule file.
+type File struct {
+	Rules []Rule `json:"rules"`
+}
+
+type Rule struct {
+	Name string    `json:"name"`
+	When Condition `json:"when"`
+	Then []Action  `json:"then"`
+}
+
+// Condition holds the tests of a rule. Every test that is set must pass; a
+// rule without tests always fires.
+type Condition struct {
+	// Field is the text field Keywords and Regex look at; empty means title.
+	Field string `json:"field,omitempty"`
+	// Keywords match when any of them appears in the field, ignoring case.
+	Keywords []string `json:"keywords,omitempty"`
+	Regex    string   `json:"regex,omitempty"`
+	// Missing lists fields that must be empty.
+	Missing []string `json:"missing,omitempty"`
+	// CapturedAfter and CapturedBefore bound the local time of capture as
+	// "15:04". A window whose start is later than its end spans midnight.
+	CapturedAfter  string `json:"captured_after,omitempty"`
+	CapturedBefore string `json:"captured_before,omitempty"`
+}
+
+// Action is one change. Exactly one of its fields is set.
+type Action struct {
+	Set     *SetAction     `json:"set,omitempty"`
+	Replace *ReplaceAction `json:"replace,omitempty"`
+	AddTag  string         `json:"add_tag,omitempty"`
+	SetDate string         `json:"set_date,omitempty"`
+	// Case restyles the title: "sentence", "lower" or "upper".
+	Case string `json:"case,omitempty"`
+}
+
+type SetAction struct {
+	Field string `json:"field"`
+	Value string `json:"value"`
+}
+
+// ReplaceAction rewrites every match of Pattern, a regular expression, in a
+// text field. With may refer to groups as $1.
+type ReplaceAction struct {
+	// Field is the text field to rewrite; empty means title.
+	Field   string `json:"field,omitempty"`
+	Pattern string `json:"pattern"`
+	With    string `json:"with"`
+}
+
+// Fired reports a rule that matched and the actions it ran.
+type Fired struct {
+	Rule    string   `json:"rule"`
+	Actions []string `json:"actions"`
+}
+
+// Set is a validated rule file.
+type Set struct {
+	rules []compiled
+}
+
+type compiled struct {
+	Rule
+	field         string
+	regex         *regexp.Regexp
+	after, before int // minutes since midnight, or -1
+	replacements  []*regexp.Regexp
+}
+
+// Parse reads and validates a rule file. dates holds the locale set_date
+// phrases are read in.
+func Parse(data []byte, dates dateparse.Options) (*Set, error) {
+	var file File
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid rule file: %w", err)
+	}
+
+	set := &Set{}
+	var errs []error
+	for i, rule := range file.Rules {
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("rule %d", i+1)
+		}
+		c, err := compile(rule, dates)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", rule.Name, err))
+			continue
+		}
+		set.rules = append(set.rules, c)
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return set, nil
+}
+
+// Len is the number of rules.
+func (s *Set) Len() int {
+	if s == nil {
+		return 0
+	}
+	return len(s.rules)
+}
+
+func compile(rule Rule, dates dateparse.Options) (compiled, error) {
+	c := compiled{Rule: rule, after: -1, before: -1}
+	when := rule.When
+
+	var err error
+	if c.field, err = textField(when.Field); err != nil {
+		return c, err
+	}
+	if when.Regex != "" {
+		if c.regex, err = regexp.Compile(when.Regex); err != nil {
+			return c, fmt.Errorf("invalid regex: %w", err)
+		}
+	}
+	for _, field := range when.Missing {
+		if !slices.Contains(MissingFields, field) {
+			return c, fmt.Errorf("unknown field %q in missing; expected one of %s", field, strings.Join(MissingFields, ", "))
+		}
+	}
+	if c.after, err = clock(when.CapturedAfter); err != nil {
+		return c, fmt.Errorf("captured_after: %w", err)
+	}
+	if c.before, err = clock(when.CapturedBefore); err != nil {
+		return c, fmt.Errorf("captured_before: %w", err)
+	}
+
+	if len(rule.Then) == 0 {
+		return c, errors.New("no actions")
+	}
+	c.replacements = make([]*regexp.Regexp, len(rule.Then))
+	for i, action := range rule.Then {
+		if err := validateAction(action, dates); err != nil {
+			return c, fmt.Errorf("action %d: %w", i+1, err)
+		}
+		if action.Replace != nil {
+			if c.replacements[i], err = regexp.Compile(action.Replace.Pattern); err != nil {
+				return c, fmt.Errorf("action %d: invalid pattern: %w", i+1, err)
+			}
+		}
+	}
+	return c, nil
+}
+
+func validateAction(a Action, dates dateparse.Options) error {
+	kinds := 0
+	for _, set := range []bool{a.Set != nil, a.Replace != nil, a.AddTag != "", a.SetDate != "", a.Case != ""} {
+		if set {
+			kinds++
+		}
+	}
+	if kinds != 1 {
+		return errors.New("set exactly one of set, replace, add_tag, set_date or case")
+	}
+
+	switch {
+	case a.Set != nil:
+		if _, err := textField(a.Set.Field); err != nil || a.Set.Field == "" {
+			return fmt.Errorf("set: unknown field %q; expected one of %s", a.Set.Field, strings.Join(TextFields, ", "))
+		}
+	case a.Replace != nil:
+		if _, err := textField(a.Replace.Field); err != nil {
+			return fmt.Errorf("replace: %w", err)
+		}
+		if a.Replace.Pattern == "" {
+			return errors.New("replace: pattern is required")
+		}
+	case a.SetDate != "":
+		if _, ok := ParseDate(a.SetDate, dates); !ok {
+			return fmt.Errorf("set_date: unrecognised date %q", a.SetDate)
+		}
+	case a.Case != "":
+		if a.Case != "sentence" && a.Case != "lower" && a.Case != "upper" {
+			return fmt.Errorf("case: expected sentence, lower or upper, got %q", a.Case)
+		}
+	}
+	return nil
+}
+
+// textField resolves the field of a condition or action.
+func textField(field string) (string, error) {
+	if field == "" {
+		return "title", nil
+	}
+	if !slices.Contains(TextFields, field) {
+		return "", fmt.Errorf("unknown field %q; expected one of %s", field, strings.Join(TextFields, ", "))
+	}
+	return field, nil
+}
+
+// clock reads "15:04" as minutes since midnight; empty is -1.
+func clock(value string) (int, error) {
+	if value == "" {
+		return -1, nil
+	}
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("expected a time such as 18:00, got %q", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// ParseDate resolves a set_date phrase.
+func ParseDate(phrase string, opts dateparse.Options) (dateparse.Result, bool) {
+	result := dateparse.Parse(phrase, opts)
+	if result.Date == nil {
+		// Short weekday names are only read after a connector.
+		result = dateparse.Parse("on "+phrase, opts)
+	}
+	return result, result.Date != nil
+}
+
+// Apply runs every matching rule against task in order. captured is the
+// local time the task was typed.
+func (s *Set) Apply(task Task, captured time.Time) []Fired {
+	if s == nil {
+		return nil
+	}
+	var fired []Fired
+	for _, rule := range s.rules {
+		if !rule.matches(task, captured) {
+			continue
+		}
+		f := Fired{Rule: rule.Name}
+		for i, action := range rule.Then {
+			if rule.apply(task, action, rule.replacements[i]) {
+				f.Actions = append(f.Actions, action.String())
+			}
+		}
+		fired = append(fired, f)
+	}
+	return fired
+}
+
+func (c compiled) matches(task Task, captured time.Time) bool {
+	text := task.Get(c.field)
+	when := c.When
+	if len(when.Keywords) > 0 && !containsAny(text, when.Keywords) {
+		return false
+	}
+	if c.regex != nil && !c.regex.MatchString(text) {
+		return false
+	}
+	for _, field := range when.Missing {
+		if task.Get(field) != "" {
+			return false
+		}
+	}
+	return inWindow(captured.Hour()*60+captured.Minute(), c.after, c.before)
+}
+
+func containsAny(text string, keywords []string) bool {
+	text = strings.ToLower(text)
+	for _, k := range keywords {
+		if k != "" && strings.Contains(text, strings.ToLower(k)) {
+			return true
+		}
+	}
+	return false
+}
+
+// inWindow reports whether minute lies in [after, before), either bound
+// being -1 for open.
+func inWindow(minute, after, before int) bool {
+	switch {
+	case after < 0 && before < 0:
+		return true
+	case after < 0:
+		return minute < before
+	case before < 0:
+		return minute >= after
+	case after <= before:
+		return minute >= after && minute < before
+	default:
+		return minute >= after || minute < before
+	}
+}
+
+// apply runs one action and reports whether it changed anything.
+func (c compiled) apply(task Task, a Action, pattern *regexp.Regexp) bool {
+	switch {
+	case a.Set != nil:
+		if task.Get(a.Set.Field) == a.Set.Value {
+			return false
+		}
+		task.Set(a.Set.Field, a.Set.Value)
+	case a.Replace != nil:
+		field, _ := textField(a.Replace.Field)
+		before := task.Get(field)
+		after := pattern.ReplaceAllString(before, a.Replace.With)
+		if after == before {
+			return false
+		}
+		task.Set(field, after)
+	case a.AddTag != "":
+		tags := strings.Split(task.Get("tags"), ", ")
+		for _, tag := range tags {
+			if strings.EqualFold(tag, a.AddTag) {
+				return false
+			}
+		}
+		task.AddTag(a.AddTag)
+	case a.SetDate != "":
+		before := task.Get("date")
+		task.SetDate(a.SetDate)
+		return task.Get("date") != before
+	case a.Case != "":
+		before := task.Get("title")
+		after := restyle(before, a.Case)
+		if after == before {
+			return false
+		}
+		task.Set("title", after)
+	}
+	return true
+}
+
+// String describes the action for dry runs.
+func (a Action) String() string {
+	switch {
+	case a.Set != nil:
+		return fmt.Sprintf("set %s to %q", a.Set.Field, a.Set.Value)
+	case a.Replace != nil:
+		field, _ := textField(a.Replace.Field)
+		return fmt.Sprintf("replace /%s/ with %q in %s", a.Replace.Pattern, a.Replace.With, field)
+	case a.AddTag != "":
+		return fmt.Sprintf("add tag %q", a.AddTag)
+	case a.SetDate != "":
+		return fmt.Sprintf("set date to %q", a.SetDate)
+	case a.Case != "":
+		return a.Case + " case title"
+	}
+	return "no-op"
+}
+
+// restyle changes the case of a title. Sentence case lowercases capitalised
+// words after the first, leaving acronyms such as "PR" alone.
+func restyle(title, style string) string {
+	switch style {
+	case "lower":
+		return strings.ToLower(title)
+	case "upper":
+		return strings.ToUpper(title)
+	}
+
+	words := strings.Fields(title)
+	for i, w := range words {
+		first, size := utf8.DecodeRuneInString(w)
+		if i == 0 {
+			words[i] = string(unicode.ToUpper(first)) + w[size:]
+			continue
+		}
+		if unicode.IsUpper(first) && strings.ToLower(w[size:]) == w[size:] {
+			words[i] = strings.ToLower(w)
+		}
+	}
+	return strings.Join(words, " ")
+}
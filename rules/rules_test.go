@@ -0,0 +1,159 @@
+package rules
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/imjamesonzeller/tasklight-v3/dateparse"
+)
+
+// fakeTask keeps fields as text; SetDate records the phrase it was given.
+type fakeTask map[string]string
+
+func (f fakeTask) Get(field string) string { return f[field] }
+func (f fakeTask) Set(field, value string) { f[field] = value }
+func (f fakeTask) SetDate(phrase string)   { f["date"] = "resolved:" + phrase }
+func (f fakeTask) AddTag(tag string) {
+	if f["tags"] == "" {
+		f["tags"] = tag
+		return
+	}
+	f["tags"] += ", " + tag
+}
+
+const teamRules = `{"rules": [
+	{"name": "Invoices", "when": {"keywords": ["invoice"]},
+	 "then": [{"add_tag": "Finance"}, {"set": {"field": "priority", "value": "High"}}]},
+	{"name": "Expand PR", "when": {"regex": "\\bPR\\b"},
+	 "then": [{"replace": {"pattern": "\\bPR\\b", "with": "pull request"}}]},
+	{"name": "Sentence case", "then": [{"case": "sentence"}]},
+	{"name": "Evening", "when": {"captured_after": "18:00", "missing": ["date"]},
+	 "then": [{"set_date": "tomorrow"}]}
+]}`
+
+func TestApply(t *testing.T) {
+	t.Parallel()
+
+	set, err := Parse([]byte(teamRules), dateparse.Options{})
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if set.Len() != 4 {
+		t.Fatalf("expected 4 rules, got %d", set.Len())
+	}
+
+	evening := time.Date(2025, time.October, 15, 19, 30, 0, 0, time.UTC)
+	task := fakeTask{"title": "Pay Invoice From ACME", "tags": "Work"}
+	fired := set.Apply(task, evening)
+
+	if task["title"] != "Pay invoice from ACME" || task["tags"] != "Work, Finance" || task["priority"] != "High" {
+		t.Fatalf("unexpected task: %v", task)
+	}
+	if task["date"] != "resolved:tomorrow" {
+		t.Fatalf("expected the evening rule to set a date, got %v", task)
+	}
+
+	var names []string
+	for _, f := range fired {
+		names = append(names, f.Rule)
+	}
+	if strings.Join(names, ",") != "Invoices,Sentence case,Evening" {
+		t.Fatalf("unexpected rules fired: %v", fired)
+	}
+	if len(fired[0].Actions) != 2 || fired[0].Actions[0] != `add tag "Finance"` {
+		t.Fatalf("unexpected actions: %v", fired[0].Actions)
+	}
+
+	morning := time.Date(2025, time.October, 15, 9, 0, 0, 0, time.UTC)
+	task = fakeTask{"title": "review PR for billing"}
+	set.Apply(task, morning)
+	if task["title"] != "Review pull request for billing" || task["date"] != "" {
+		t.Fatalf("unexpected task: %v", task)
+	}
+}
+
+func TestApplyReportsOnlyChanges(t *testing.T) {
+	t.Parallel()
+
+	set, err := Parse([]byte(teamRules), dateparse.Options{})
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	task := fakeTask{"title": "Send invoice", "tags": "finance", "priority": "High", "date": "2025-10-16"}
+	fired := set.Apply(task, time.Date(2025, time.October, 15, 20, 0, 0, 0, time.UTC))
+
+	if len(fired) != 2 || fired[0].Rule != "Invoices" || len(fired[0].Actions) != 0 {
+		t.Fatalf("unexpected rules fired: %+v", fired)
+	}
+	if task["tags"] != "finance" {
+		t.Fatalf("tags must not be duplicated, got %q", task["tags"])
+	}
+}
+
+func TestInWindow(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		minute, after, before int
+		want                  bool
+	}{
+		{600, -1, -1, true},
+		{1080, 1080, -1, true},
+		{1079, 1080, -1, false},
+		{540, -1, 600, true},
+		{600, -1, 600, false},
+		{1380, 1320, 360, true},
+		{120, 1320, 360, true},
+		{720, 1320, 360, false},
+	}
+	for _, tt := range tests {
+		if got := inWindow(tt.minute, tt.after, tt.before); got != tt.want {
+			t.Errorf("inWindow(%d, %d, %d) = %v, want %v", tt.minute, tt.after, tt.before, got, tt.want)
+		}
+	}
+}
+
+func TestParseRejectsInvalidRules(t *testing.T) {
+	t.Parallel()
+
+	invalid := map[string]string{
+		"json":           `{"rules": [`,
+		"regex":          `{"rules": [{"when": {"regex": "("}, "then": [{"case": "lower"}]}]}`,
+		"missing field":  `{"rules": [{"when": {"missing": ["colour"]}, "then": [{"case": "lower"}]}]}`,
+		"time":           `{"rules": [{"when": {"captured_after": "6pm"}, "then": [{"case": "lower"}]}]}`,
+		"no actions":     `{"rules": [{"when": {"keywords": ["x"]}}]}`,
+		"two kinds":      `{"rules": [{"then": [{"add_tag": "x", "case": "lower"}]}]}`,
+		"set field":      `{"rules": [{"then": [{"set": {"field": "date", "value": "x"}}]}]}`,
+		"replace":        `{"rules": [{"then": [{"replace": {"pattern": "[", "with": "x"}}]}]}`,
+		"date":           `{"rules": [{"then": [{"set_date": "whenever"}]}]}`,
+		"case":           `{"rules": [{"then": [{"case": "title"}]}]}`,
+		"condition text": `{"rules": [{"when": {"field": "tags", "keywords": ["x"]}, "then": [{"case": "lower"}]}]}`,
+	}
+	for name, data := range invalid {
+		if _, err := Parse([]byte(data), dateparse.Options{}); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+
+	_, err := Parse([]byte(`{"rules": [{"name": "Broken", "when": {"regex": "("}, "then": [{"case": "lower"}]}]}`), dateparse.Options{})
+	if err == nil || !strings.Contains(err.Error(), "Broken") {
+		t.Fatalf("expected the error to name the rule, got %v", err)
+	}
+}
+
+func TestParseDateUsesLocale(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, time.October, 15, 9, 0, 0, 0, time.UTC)
+	if _, ok := ParseDate("morgen", dateparse.Options{Now: now}); ok {
+		t.Fatal("German phrases must not be read in English")
+	}
+	result, ok := ParseDate("morgen", dateparse.Options{Now: now, Language: "de"})
+	if !ok || result.Date.Day() != 16 {
+		t.Fatalf("unexpected date: %+v", result)
+	}
+	if result, ok := ParseDate("fri", dateparse.Options{Now: now}); !ok || result.Date.Weekday() != time.Friday {
+		t.Fatalf("expected a short weekday to resolve, got %+v", result)
+	}
+}
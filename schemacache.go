@@ -0,0 +1,263 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/imjamesonzeller/tasklight-v3/notionapi"
+	"github.com/imjamesonzeller/tasklight-v3/settingsservice"
+	"github.com/wailsapp/wails/v3/pkg/application"
+)
+
+const (
+	schemaCacheFileName = "schema-cache.json"
+	// schemaCacheTTL is how long a data source schema is trusted before it
+	// is fetched again.
+	schemaCacheTTL = 10 * time.Minute
+)
+
+// cachedSchema is a fetched data source schema.
+type cachedSchema struct {
+	Detail    *NotionDataSourceDetail `json:"detail"`
+	FetchedAt time.Time               `json:"fetched_at"`
+}
+
+// schemaFetch is a fetch in progress that concurrent lookups wait for.
+type schemaFetch struct {
+	done   chan struct{}
+	detail *NotionDataSourceDetail
+	err    error
+	// generation is the cache generation the fetch started in.
+	generation int
+}
+
+// SchemaCacheStats reports how well the schema cache is doing.
+type SchemaCacheStats struct {
+	Hits    int     `json:"hits"`
+	Misses  int     `json:"misses"`
+	HitRate float64 `json:"hit_rate"`
+	Entries int     `json:"entries"`
+}
+
+// SchemaCacheService keeps data source schemas in memory so a capture needs
+// a single request to Notion. When the persist_schema_cache setting is on,
+// the schemas also survive a restart.
+type SchemaCacheService struct {
+	settings *settingsservice.SettingsService
+	now      func() time.Time
+	fetch    func(ctx context.Context, token, dataSourceID string) (*NotionDataSourceDetail, error)
+	path     string
+
+	mu      sync.Mutex
+	entries map[string]cachedSchema
+	pending map[string]*schemaFetch
+	// generation counts invalidations; a fetch started before the latest
+	// one may have read the old schema and is not stored.
+	generation int
+	loaded     bool
+	hits       int
+	misses     int
+}
+
+func NewSchemaCacheService(settings *settingsservice.SettingsService) *SchemaCacheService {
+	return &SchemaCacheService{
+		settings: settings,
+		now:      time.Now,
+		fetch:    fetchDataSourceDetail,
+		entries:  map[string]cachedSchema{},
+		pending:  map[string]*schemaFetch{},
+	}
+}
+
+// SetApp drops every schema when the settings are saved, since a new data
+// source or property mapping should be checked against a fresh schema.
+func (sc *SchemaCacheService) SetApp(app *application.App) {
+	app.OnEvent("Backend:SettingsUpdated", func(_ *application.CustomEvent) {
+		sc.Invalidate("")
+	})
+}
+
+// GetCacheStats reports the hit rate since launch. Called from frontend
+func (sc *SchemaCacheService) GetCacheStats() SchemaCacheStats {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	stats := SchemaCacheStats{Hits: sc.hits, Misses: sc.misses, Entries: len(sc.entries)}
+	if total := sc.hits + sc.misses; total > 0 {
+		stats.HitRate = float64(sc.hits) / float64(total)
+	}
+	return stats
+}
+
+// Detail returns the schema of dataSourceID, fetching it when it is missing
+// or older than schemaCacheTTL. Callers share the result and must not
+// modify it.
+func (sc *SchemaCacheService) Detail(ctx context.Context, token, dataSourceID string) (*NotionDataSourceDetail, error) {
+	sc.mu.Lock()
+	sc.load()
+	if entry, ok := sc.entries[dataSourceID]; ok && sc.now().Sub(entry.FetchedAt) < schemaCacheTTL {
+		sc.hits++
+		sc.mu.Unlock()
+		return entry.Detail, nil
+	}
+
+	f, ok := sc.pending[dataSourceID]
+	if ok {
+		// Another capture is already fetching it; no extra request is made.
+		sc.hits++
+	} else {
+		sc.misses++
+		f = &schemaFetch{done: make(chan struct{}), generation: sc.generation}
+		sc.pending[dataSourceID] = f
+		// The fetch is shared, so it must not end when the capture that
+		// started it gives up.
+		go sc.run(context.WithoutCancel(ctx), token, dataSourceID, f)
+	}
+	sc.mu.Unlock()
+
+	select {
+	case <-f.done:
+		return f.detail, f.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// run performs f and stores the schema unless the cache was invalidated
+// since f started.
+func (sc *SchemaCacheService) run(ctx context.Context, token, dataSourceID string, f *schemaFetch) {
+	f.detail, f.err = sc.fetch(ctx, token, dataSourceID)
+
+	sc.mu.Lock()
+	if sc.pending[dataSourceID] == f {
+		delete(sc.pending, dataSourceID)
+	}
+	if f.err == nil && f.generation == sc.generation {
+		sc.entries[dataSourceID] = cachedSchema{Detail: f.detail, FetchedAt: sc.now()}
+		sc.save()
+	}
+	sc.mu.Unlock()
+	close(f.done)
+}
+
+// Invalidate forgets the schema of dataSourceID, or every schema when it is
+// empty. Fetches already under way are not cached, and later lookups fetch
+// again rather than wait for them.
+func (sc *SchemaCacheService) Invalidate(dataSourceID string) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	sc.load()
+	sc.generation++
+	if dataSourceID == "" {
+		clear(sc.entries)
+		clear(sc.pending)
+	} else {
+		delete(sc.entries, dataSourceID)
+		delete(sc.pending, dataSourceID)
+	}
+	sc.save()
+}
+
+// ====== Store ======
+
+func (sc *SchemaCacheService) persistent() bool {
+	return sc.settings != nil && sc.settings.AppSettings.PersistSchemaCache
+}
+
+// load reads the persisted schemas once. The caller holds sc.mu.
+func (sc *SchemaCacheService) load() {
+	if sc.loaded || !sc.persistent() {
+		return
+	}
+	sc.loaded = true
+	if sc.path == "" {
+		sc.path = sc.settings.DataPath(schemaCacheFileName)
+	}
+
+	data, err := os.ReadFile(sc.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return
+	}
+	var stored map[string]cachedSchema
+	if err == nil {
+		err = json.Unmarshal(data, &stored)
+	}
+	if err != nil {
+		// The cache can always be rebuilt from Notion.
+		log.Printf("SchemaCache: ignoring %s: %v", sc.path, err)
+		return
+	}
+	for id, entry := range stored {
+		if _, ok := sc.entries[id]; !ok && entry.Detail != nil {
+			sc.entries[id] = entry
+		}
+	}
+}
+
+// save persists the schemas when persistence is on. The caller holds sc.mu.
+func (sc *SchemaCacheService) save() {
+	if !sc.persistent() {
+		return
+	}
+	if sc.path == "" {
+		sc.path = sc.settings.DataPath(schemaCacheFileName)
+	}
+
+	data, err := json.Marshal(sc.entries)
+	if err == nil {
+		tmp := sc.path + ".tmp"
+		if err = os.WriteFile(tmp, data, 0o644); err == nil {
+			err = os.Rename(tmp, sc.path)
+		}
+	}
+	if err != nil {
+		log.Printf("SchemaCache: failed to save %s: %v", sc.path, err)
+	}
+}
+
+// fetchDataSourceDetail loads the schema of a data source from Notion.
+func fetchDataSourceDetail(ctx context.Context, token, dataSourceID string) (*NotionDataSourceDetail, error) {
+	ctx, cancel := notionStage(ctx)
+	defer cancel()
+
+	req, err := notionapi.NewRequest(ctx, http.MethodGet, notionapi.URL("/data_sources/"+dataSourceID), token)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var payload struct {
+		ID         string                 `json:"id"`
+		Name       []RichTextObj          `json:"name"`
+		Properties map[string]PropertyObj `json:"properties"`
+	}
+	if err := notionapi.ParseResponse(resp, &payload, ErrNotionTokenMissing); err != nil {
+		return nil, err
+	}
+
+	detail := NotionDataSourceDetail{
+		ID:         payload.ID,
+		Name:       richTextPlainText(payload.Name),
+		Properties: payload.Properties,
+	}
+	if detail.Properties == nil {
+		detail.Properties = map[string]PropertyObj{}
+	}
+	for key, prop := range detail.Properties {
+		if prop.Name == "" {
+			prop.Name = key
+			detail.Properties[key] = prop
+		}
+	}
+	return &detail, nil
+}
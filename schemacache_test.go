@@ -0,0 +1,275 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	c "github.com/imjamesonzeller/tasklight-v3/config"
+	"github.com/imjamesonzeller/tasklight-v3/notionapi"
+	"github.com/imjamesonzeller/tasklight-v3/settingsservice"
+)
+
+// newTestSchemaCache returns a cache whose fetches are counted in fetches.
+func newTestSchemaCache(settings *settingsservice.SettingsService, now *time.Time, fetches *atomic.Int32) *SchemaCacheService {
+	sc := NewSchemaCacheService(settings)
+	sc.now = func() time.Time { return *now }
+	sc.fetch = func(_ context.Context, _, id string) (*NotionDataSourceDetail, error) {
+		fetches.Add(1)
+		return &NotionDataSourceDetail{ID: id, Properties: map[string]PropertyObj{}}, nil
+	}
+	return sc
+}
+
+func TestSchemaCacheExpires(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, time.October, 15, 9, 0, 0, 0, time.UTC)
+	var fetches atomic.Int32
+	sc := newTestSchemaCache(nil, &now, &fetches)
+
+	for range 3 {
+		if _, err := sc.Detail(context.Background(), "secret", "ds-1"); err != nil {
+			t.Fatalf("Detail: %v", err)
+		}
+	}
+	if fetches.Load() != 1 {
+		t.Fatalf("expected one fetch, got %d", fetches.Load())
+	}
+
+	now = now.Add(schemaCacheTTL)
+	if _, err := sc.Detail(context.Background(), "secret", "ds-1"); err != nil {
+		t.Fatalf("Detail: %v", err)
+	}
+	if fetches.Load() != 2 {
+		t.Fatalf("expected an expired schema to be fetched again, got %d fetches", fetches.Load())
+	}
+
+	stats := sc.GetCacheStats()
+	if stats.Hits != 2 || stats.Misses != 2 || stats.HitRate != 0.5 || stats.Entries != 1 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestSchemaCacheInvalidate(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	var fetches atomic.Int32
+	sc := newTestSchemaCache(nil, &now, &fetches)
+
+	for _, id := range []string{"ds-1", "ds-2"} {
+		_, _ = sc.Detail(context.Background(), "secret", id)
+	}
+	sc.Invalidate("ds-1")
+	_, _ = sc.Detail(context.Background(), "secret", "ds-1")
+	_, _ = sc.Detail(context.Background(), "secret", "ds-2")
+	if fetches.Load() != 3 {
+		t.Fatalf("expected only ds-1 to be fetched again, got %d fetches", fetches.Load())
+	}
+
+	sc.Invalidate("")
+	if stats := sc.GetCacheStats(); stats.Entries != 0 {
+		t.Fatalf("expected an empty cache, got %+v", stats)
+	}
+}
+
+func TestSchemaCacheSharesFetch(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	release := make(chan struct{})
+	var fetches atomic.Int32
+	sc := NewSchemaCacheService(nil)
+	sc.now = func() time.Time { return now }
+	sc.fetch = func(_ context.Context, _, id string) (*NotionDataSourceDetail, error) {
+		fetches.Add(1)
+		<-release
+		return &NotionDataSourceDetail{ID: id}, nil
+	}
+
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if detail, err := sc.Detail(context.Background(), "secret", "ds-1"); err != nil || detail.ID != "ds-1" {
+				t.Errorf("unexpected detail %+v (err %v)", detail, err)
+			}
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if fetches.Load() != 1 {
+		t.Fatalf("expected concurrent lookups to share one fetch, got %d", fetches.Load())
+	}
+}
+
+func TestSchemaCacheDropsFetchStartedBeforeInvalidate(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	started, release := make(chan struct{}, 2), make(chan struct{})
+	var fetches atomic.Int32
+	sc := NewSchemaCacheService(nil)
+	sc.now = func() time.Time { return now }
+	sc.fetch = func(_ context.Context, _, id string) (*NotionDataSourceDetail, error) {
+		name := "new"
+		if fetches.Add(1) == 1 {
+			name = "old"
+			started <- struct{}{}
+			<-release
+		}
+		return &NotionDataSourceDetail{ID: id, Name: name}, nil
+	}
+
+	stale := make(chan *NotionDataSourceDetail)
+	go func() {
+		detail, _ := sc.Detail(context.Background(), "secret", "ds-1")
+		stale <- detail
+	}()
+	<-started
+	sc.Invalidate("")
+
+	// A lookup after the invalidation does not wait for the old fetch.
+	if detail, err := sc.Detail(context.Background(), "secret", "ds-1"); err != nil || detail.Name != "new" {
+		t.Fatalf("expected a fresh fetch, got %+v (err %v)", detail, err)
+	}
+	close(release)
+	if detail := <-stale; detail.Name != "old" {
+		t.Fatalf("the first lookup still gets its own result, got %+v", detail)
+	}
+	if detail, _ := sc.Detail(context.Background(), "secret", "ds-1"); detail.Name != "new" || fetches.Load() != 2 {
+		t.Fatalf("the stale schema must not be cached, got %+v after %d fetches", detail, fetches.Load())
+	}
+}
+
+func TestSchemaCacheWaitersOutliveCaller(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	started, release := make(chan struct{}, 1), make(chan struct{})
+	sc := NewSchemaCacheService(nil)
+	sc.now = func() time.Time { return now }
+	sc.fetch = func(ctx context.Context, _, id string) (*NotionDataSourceDetail, error) {
+		started <- struct{}{}
+		<-release
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return &NotionDataSourceDetail{ID: id}, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error)
+	go func() {
+		_, err := sc.Detail(ctx, "secret", "ds-1")
+		first <- err
+	}()
+	<-started
+	waiter := make(chan error)
+	go func() {
+		_, err := sc.Detail(context.Background(), "secret", "ds-1")
+		waiter <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	cancel()
+	if err := <-first; err != context.Canceled {
+		t.Fatalf("expected the cancelled caller to give up, got %v", err)
+	}
+	close(release)
+	if err := <-waiter; err != nil {
+		t.Fatalf("the waiter must not get the caller's cancellation, got %v", err)
+	}
+	if stats := sc.GetCacheStats(); stats.Entries != 1 {
+		t.Fatalf("expected the schema cached, got %+v", stats)
+	}
+}
+
+func TestSchemaCachePersists(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	path := filepath.Join(t.TempDir(), schemaCacheFileName)
+	settings := &settingsservice.SettingsService{
+		AppSettings: settingsservice.ApplicationSettings{PersistSchemaCache: true},
+	}
+
+	var fetches atomic.Int32
+	first := newTestSchemaCache(settings, &now, &fetches)
+	first.path = path
+	if _, err := first.Detail(context.Background(), "secret", "ds-1"); err != nil {
+		t.Fatalf("Detail: %v", err)
+	}
+
+	second := newTestSchemaCache(settings, &now, &fetches)
+	second.path = path
+	detail, err := second.Detail(context.Background(), "secret", "ds-1")
+	if err != nil || detail.ID != "ds-1" {
+		t.Fatalf("unexpected detail %+v (err %v)", detail, err)
+	}
+	if fetches.Load() != 1 {
+		t.Fatalf("expected the persisted schema to be reused, got %d fetches", fetches.Load())
+	}
+}
+
+func TestValidationErrorInvalidatesSchema(t *testing.T) {
+	var schemaRequests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/data_sources/ds-1":
+			schemaRequests.Add(1)
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"id": "ds-1",
+				"properties": map[string]any{
+					"Name": map[string]any{"id": "title", "type": "title"},
+				},
+			})
+		case "/pages":
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = io.WriteString(w, `{"object":"error","status":400,"code":"validation_error","message":"Tags is not a property"}`)
+		default:
+			t.Errorf("unexpected request: %s", r.URL.Path)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	originalBase := notionapi.BaseURL
+	originalConfig := c.AppConfig
+	t.Cleanup(func() {
+		notionapi.BaseURL = originalBase
+		c.AppConfig = originalConfig
+	})
+	notionapi.BaseURL = srv.URL
+
+	settings := &settingsservice.SettingsService{
+		AppSettings: settingsservice.ApplicationSettings{
+			NotionAccessToken:  "secret",
+			NotionDataSourceID: "ds-1",
+		},
+	}
+	c.AppConfig = &settings.AppSettings
+
+	ts := NewTaskService(nil, settings)
+	ts.SetSchemaCache(NewSchemaCacheService(settings))
+
+	for range 2 {
+		result := ts.createNotionPage(context.Background(), TaskInformation{Title: "pay rent"})
+		if result.Category != ErrorValidation {
+			t.Fatalf("expected a validation error, got %+v", result)
+		}
+	}
+	if schemaRequests.Load() != 2 {
+		t.Fatalf("expected the schema to be fetched again after a validation error, got %d requests", schemaRequests.Load())
+	}
+}
@@ -0,0 +1,147 @@
+package settingsservice
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// reservedPrefixes read as fields by the local parser, so no destination may
+// claim them.
+var reservedPrefixes = []string{"project", "notes", "note"}
+
+// Destination is a named data source that captures are routed to by a
+// leading prefix or a keyword. Captures no destination claims go to the
+// default destination, the data source chosen in the settings form.
+type Destination struct {
+	Name         string `json:"name"`
+	DataSourceID string `json:"data_source_id"`
+	// DatePropertyID and DatePropertyName pick the date property. When both
+	// are empty the only date property, or one named Due or Date, is used.
+	DatePropertyID   string `json:"date_property_id,omitempty"`
+	DatePropertyName string `json:"date_property_name,omitempty"`
+	// Prefixes route input such as "w: call Sam" here. A one-word name works
+	// as a prefix too.
+	Prefixes []string `json:"prefixes,omitempty"`
+	// Keywords route input containing one of them as a whole word.
+	Keywords []string `json:"keywords,omitempty"`
+	// PropertyMapping is edited with SetPropertyMappings and
+	// SetPropertyDefaults.
+	PropertyMapping PropertyMapping `json:"property_mapping"`
+}
+
+// DefaultDestination is the data source chosen in the settings form.
+func (a ApplicationSettings) DefaultDestination() Destination {
+	return Destination{
+		DataSourceID:     a.NotionDataSourceID,
+		DatePropertyID:   a.DatePropertyID,
+		DatePropertyName: a.DatePropertyName,
+		PropertyMapping:  a.PropertyMapping,
+	}
+}
+
+// FindDestination returns the named destination, or the default destination
+// for "".
+func (a ApplicationSettings) FindDestination(name string) (Destination, bool) {
+	if name == "" {
+		return a.DefaultDestination(), true
+	}
+	if i := a.destinationIndex(name); i >= 0 {
+		return a.Destinations[i], true
+	}
+	return Destination{}, false
+}
+
+// RoutePrefixes lists the prefixes that route input here: the configured
+// ones, and the name unless it is several words such as "Team Backlog".
+func (d Destination) RoutePrefixes() []string {
+	if !isPrefixWord(d.Name) {
+		return d.Prefixes
+	}
+	return append([]string{d.Name}, d.Prefixes...)
+}
+
+func isPrefixWord(word string) bool {
+	return word != "" && !strings.ContainsAny(word, ": \t")
+}
+
+func (a ApplicationSettings) destinationIndex(name string) int {
+	return slices.IndexFunc(a.Destinations, func(d Destination) bool {
+		return strings.EqualFold(d.Name, name)
+	})
+}
+
+// GetDestinations returns the named destinations. Called from frontend
+func (s *SettingsService) GetDestinations() []Destination {
+	if s.AppSettings.Destinations == nil {
+		return []Destination{}
+	}
+	return s.AppSettings.Destinations
+}
+
+// SaveDestinations replaces the named destinations. Property mappings are
+// kept for destinations whose name is unchanged. Called from frontend
+func (s *SettingsService) SaveDestinations(destinations []Destination) error {
+	cleaned := make([]Destination, 0, len(destinations))
+	names := map[string]bool{}
+	prefixes := map[string]string{}
+	var errs []error
+
+	for _, d := range destinations {
+		d.Name = strings.TrimSpace(d.Name)
+		d.DataSourceID = strings.TrimSpace(d.DataSourceID)
+		d.Prefixes = trimmedList(d.Prefixes)
+		d.Keywords = trimmedList(d.Keywords)
+
+		key := strings.ToLower(d.Name)
+		switch {
+		case d.Name == "":
+			errs = append(errs, errors.New("every destination needs a name"))
+			continue
+		case names[key]:
+			errs = append(errs, fmt.Errorf("destination name %q is used twice", d.Name))
+			continue
+		case d.DataSourceID == "":
+			errs = append(errs, fmt.Errorf("destination %q needs a data source", d.Name))
+		}
+		names[key] = true
+
+		for _, prefix := range d.RoutePrefixes() {
+			lower := strings.ToLower(prefix)
+			if !isPrefixWord(prefix) {
+				errs = append(errs, fmt.Errorf("prefix %q of %q must be one word without a colon", prefix, d.Name))
+			} else if slices.Contains(reservedPrefixes, lower) {
+				errs = append(errs, fmt.Errorf("prefix %q of %q is reserved for task fields", prefix, d.Name))
+			} else if other, taken := prefixes[lower]; taken && other != d.Name {
+				errs = append(errs, fmt.Errorf("prefix %q is used by both %q and %q", prefix, other, d.Name))
+			}
+			prefixes[lower] = d.Name
+		}
+
+		if i := s.AppSettings.destinationIndex(d.Name); i >= 0 {
+			d.PropertyMapping = s.AppSettings.Destinations[i].PropertyMapping
+		} else {
+			d.PropertyMapping = PropertyMapping{}
+		}
+		cleaned = append(cleaned, d)
+	}
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+
+	s.AppSettings.Destinations = cleaned
+	s.SaveSettings()
+	s.notifySettingsUpdated()
+	return nil
+}
+
+func trimmedList(values []string) []string {
+	var kept []string
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			kept = append(kept, v)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,166 @@
+package settingsservice
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PropertyOff maps a task field to no property, keeping it out of Notion even
+// when the data source has a property named after it.
+const PropertyOff = "off"
+
+// DefaultMe as the default of a people property stands for the Notion user
+// who connected Tasklight.
+const DefaultMe = "@me"
+
+// What to do with a parsed tag or project that matches no option of a
+// select or multi-select property.
+const (
+	// UnmatchedCreate lets Notion create the option, or Tasklight create the
+	// page a project relation links to. It is the default for options.
+	UnmatchedCreate = "create"
+	// UnmatchedDrop leaves the value out of the page.
+	UnmatchedDrop = "drop"
+	// UnmatchedFallback writes UnmatchedOption.Fallback instead.
+	UnmatchedFallback = "fallback"
+)
+
+// UnmatchedOption says what happens to values that match no option of a
+// property.
+type UnmatchedOption struct {
+	Action   string `json:"action"`
+	Fallback string `json:"fallback,omitempty"`
+}
+
+// PropertyMapping assigns task fields to the properties of one data source
+// and holds the default values of its properties.
+type PropertyMapping struct {
+	DataSourceID string `json:"data_source_id"`
+	// Properties maps a task field, e.g. "tags", to the ID of the property
+	// receiving it, or to PropertyOff. IDs survive renames in Notion.
+	// Unmapped fields go to the property named after them.
+	Properties map[string]string `json:"properties,omitempty"`
+	// Defaults maps a property ID to the value every new page gets unless a
+	// task field fills the property. Multi-select, people and relation
+	// defaults list their values separated by commas.
+	Defaults map[string]string `json:"defaults,omitempty"`
+	// Unmatched maps a property ID to what happens to values matching none
+	// of its options or, for a relation, none of the pages it can link to.
+	// Properties without an entry create options and leave relations empty.
+	Unmatched map[string]UnmatchedOption `json:"unmatched,omitempty"`
+}
+
+// PropertyMappings returns the mapped properties of d. A mapping made for
+// another data source does not apply.
+func (d Destination) PropertyMappings() map[string]string {
+	if d.DataSourceID == "" || d.PropertyMapping.DataSourceID != d.DataSourceID {
+		return nil
+	}
+	return d.PropertyMapping.Properties
+}
+
+// PropertyDefaults returns the default property values of d.
+func (d Destination) PropertyDefaults() map[string]string {
+	if d.DataSourceID == "" || d.PropertyMapping.DataSourceID != d.DataSourceID {
+		return nil
+	}
+	return d.PropertyMapping.Defaults
+}
+
+// UnmatchedOptions returns what happens to unknown options of d's properties.
+func (d Destination) UnmatchedOptions() map[string]UnmatchedOption {
+	if d.DataSourceID == "" || d.PropertyMapping.DataSourceID != d.DataSourceID {
+		return nil
+	}
+	return d.PropertyMapping.Unmatched
+}
+
+// SetPropertyMappings replaces the mapping of the named destination, or of
+// the default destination for "", and saves it. Empty property IDs are
+// dropped so those fields go back to name matching.
+func (s *SettingsService) SetPropertyMappings(destination string, mappings map[string]string) error {
+	return s.updatePropertyMapping(destination, func(m *PropertyMapping) {
+		m.Properties = nonEmpty(mappings)
+	})
+}
+
+// SetPropertyDefaults replaces the default values of the named destination
+// and saves them. Empty values are dropped.
+func (s *SettingsService) SetPropertyDefaults(destination string, defaults map[string]string) error {
+	return s.updatePropertyMapping(destination, func(m *PropertyMapping) {
+		m.Defaults = nonEmpty(defaults)
+	})
+}
+
+// SetUnmatchedOptions replaces what happens to unknown options in the named
+// destination and saves it. Properties without an action are dropped.
+func (s *SettingsService) SetUnmatchedOptions(destination string, unmatched map[string]UnmatchedOption) error {
+	kept := make(map[string]UnmatchedOption, len(unmatched))
+	for id, u := range unmatched {
+		u.Fallback = strings.TrimSpace(u.Fallback)
+		switch u.Action {
+		case "":
+			continue
+		case UnmatchedCreate, UnmatchedDrop:
+			u.Fallback = ""
+		case UnmatchedFallback:
+			if u.Fallback == "" {
+				return fmt.Errorf("choose the option unknown values of %q go to", id)
+			}
+		default:
+			return fmt.Errorf("unknown action %q for unmatched options", u.Action)
+		}
+		kept[id] = u
+	}
+	return s.updatePropertyMapping(destination, func(m *PropertyMapping) {
+		m.Unmatched = kept
+	})
+}
+
+// updatePropertyMapping applies update to the mapping of a destination. A
+// mapping made for a data source the destination no longer uses starts over.
+func (s *SettingsService) updatePropertyMapping(destination string, update func(*PropertyMapping)) error {
+	current := func(dataSourceID string, mapping PropertyMapping) PropertyMapping {
+		if mapping.DataSourceID != dataSourceID {
+			return PropertyMapping{DataSourceID: dataSourceID}
+		}
+		return mapping
+	}
+
+	if destination == "" {
+		mapping := current(s.AppSettings.NotionDataSourceID, s.AppSettings.PropertyMapping)
+		update(&mapping)
+		s.AppSettings.PropertyMapping = mapping
+	} else {
+		i := s.AppSettings.destinationIndex(destination)
+		if i < 0 {
+			return fmt.Errorf("destination %q does not exist", destination)
+		}
+		d := &s.AppSettings.Destinations[i]
+		mapping := current(d.DataSourceID, d.PropertyMapping)
+		update(&mapping)
+		d.PropertyMapping = mapping
+	}
+
+	s.SaveSettings()
+	s.notifySettingsUpdated()
+	return nil
+}
+
+func (s *SettingsService) notifySettingsUpdated() {
+	if s.App != nil {
+		s.App.EmitEvent("Backend:SettingsUpdated", map[string]any{
+			"theme": s.AppSettings.Theme,
+		})
+	}
+}
+
+func nonEmpty(values map[string]string) map[string]string {
+	kept := make(map[string]string, len(values))
+	for key, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			kept[key] = value
+		}
+	}
+	return kept
+}
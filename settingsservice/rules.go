@@ -0,0 +1,79 @@
+package settingsservice
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/imjamesonzeller/tasklight-v3/dateparse"
+	"github.com/imjamesonzeller/tasklight-v3/rules"
+)
+
+const rulesFileName = "rules.json"
+
+// RulesStatus describes the rule file.
+type RulesStatus struct {
+	Path  string `json:"path"`
+	Count int    `json:"count"`
+	// Error explains why the file was rejected; no rules apply until it is
+	// fixed.
+	Error string `json:"error,omitempty"`
+}
+
+// loadedRules is the rule file as last read.
+type loadedRules struct {
+	modTime  time.Time
+	size     int64
+	language string
+	set      *rules.Set
+	err      error
+}
+
+// Rules returns the capture rules in rules.json next to the settings file,
+// reading the file again whenever it changes. A missing file means no rules.
+func (s *SettingsService) Rules() (*rules.Set, error) {
+	s.rulesMu.Lock()
+	defer s.rulesMu.Unlock()
+
+	path := s.DataPath(rulesFileName)
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		s.rules = loadedRules{}
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	locale := s.AppSettings.Locale()
+	if s.rules.modTime.Equal(info.ModTime()) && s.rules.size == info.Size() && s.rules.language == locale.Language {
+		return s.rules.set, s.rules.err
+	}
+
+	loaded := loadedRules{modTime: info.ModTime(), size: info.Size(), language: locale.Language}
+	data, err := os.ReadFile(path)
+	if err == nil {
+		loaded.set, err = rules.Parse(data, dateparse.Options{
+			Language:  locale.Language,
+			Order:     dateparse.DateOrder(locale.DateOrder),
+			WeekStart: &locale.WeekStart,
+		})
+	}
+	if err != nil {
+		loaded.err = fmt.Errorf("rules %s: %w", path, err)
+	}
+	s.rules = loaded
+	return loaded.set, loaded.err
+}
+
+// GetRulesStatus validates the rule file and reports how many rules it
+// holds. Called from frontend
+func (s *SettingsService) GetRulesStatus() RulesStatus {
+	set, err := s.Rules()
+	status := RulesStatus{Path: s.DataPath(rulesFileName), Count: set.Len()}
+	if err != nil {
+		status.Error = err.Error()
+	}
+	return status
+}
@@ -7,10 +7,14 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"regexp"
 	"runtime/debug"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/imjamesonzeller/tasklight-v3/quicksyntax"
 	"github.com/imjamesonzeller/tasklight-v3/startupservice"
 	"github.com/keybase/go-keychain"
 	"github.com/wailsapp/wails/v3/pkg/application"
@@ -23,7 +27,21 @@ const (
 	keychainService     = "com.tasklight.app"
 	keychainNotionToken = "NotionAccessToken"
 	keychainOpenAIKey   = "OpenAIAPISecret"
+	keychainProviderKey = "ParserProviderKey:"
 	settingsFileName    = "settings.json"
+
+	defaultParserProvider = "openai"
+	defaultLanguage       = "en"
+	defaultDateOrder      = "MDY"
+	defaultWeekStart      = "monday"
+	defaultUndoWindow     = 2 * time.Minute
+	maxUndoWindowSeconds  = 3600
+	defaultHistoryDays    = 90
+	defaultParseTimeout   = 20 * time.Second
+	defaultNotionTimeout  = 15 * time.Second
+	maxTimeoutSeconds     = 120
+	maxHistoryDays        = 3650
+	envKeyRefPrefix       = "env:"
 )
 
 // ====== Structs ======
@@ -35,6 +53,14 @@ type SettingsService struct {
 	StartupService    *startupservice.StartupService
 	settingsPath      string
 	appVersion        string
+
+	// providerKeysMu guards providerKeys, which parses running in parallel
+	// fill on first use.
+	providerKeysMu sync.Mutex
+	providerKeys   map[string]string
+
+	rulesMu sync.Mutex
+	rules   loadedRules
 }
 
 func keychainDisabled() bool {
@@ -46,10 +72,68 @@ type ApplicationSettings struct {
 	UseOpenAI          bool         `json:"use_open_ai"`
 	Theme              string       `json:"theme"`
 	Hotkey             hotkeyConfig `json:"hotkey"`
+	// ClipboardHotkey is the combination, e.g. "ctrl+shift+space", that opens
+	// the input window with the clipboard attached to the capture. Empty
+	// disables it.
+	ClipboardHotkey string `json:"clipboard_hotkey"`
+	// PreviewBeforeCommit shows the parsed task for review instead of sending
+	// it to Notion straight away.
+	PreviewBeforeCommit bool `json:"preview_before_commit"`
+	// UndoWindowSeconds is how long after capture UndoLast may still trash a
+	// task. Zero uses the default.
+	UndoWindowSeconds int `json:"undo_window_seconds"`
+	// HistoryRetentionDays is how long captures stay in the local history.
+	// Zero uses the default.
+	HistoryRetentionDays int `json:"history_retention_days"`
+	// ParseTimeoutSeconds and NotionTimeoutSeconds bound the parse stage and
+	// each Notion request of a capture. Zero uses the defaults.
+	ParseTimeoutSeconds  int `json:"parse_timeout_seconds"`
+	NotionTimeoutSeconds int `json:"notion_timeout_seconds"`
+	// PersistSchemaCache keeps fetched data source schemas on disk so the
+	// first capture after a restart skips the schema request.
+	PersistSchemaCache bool `json:"persist_schema_cache"`
+	// DuplicateAction is what a capture does when an open page with a
+	// similar title and date already exists. Empty means DuplicatesWarn.
+	DuplicateAction DuplicateAction `json:"duplicate_action"`
 
 	// ====== Date Property Shiznit ======
 	DatePropertyID   string `json:"date_property_id"`
 	DatePropertyName string `json:"date_property_name"`
+	// TimeZone is the IANA zone for timed tasks; empty means the system zone.
+	TimeZone string `json:"time_zone"`
+
+	// ====== Locale ======
+	// Language is the BCP 47 code of task input, e.g. "en" or "de".
+	Language string `json:"language"`
+	// DateOrder reads numeric dates: "MDY", "DMY" or "YMD".
+	DateOrder string `json:"date_order"`
+	// WeekStart is the first day of the week: "monday", "sunday" or "saturday".
+	WeekStart string `json:"week_start"`
+
+	// ====== Recurrence ======
+	// RecurrencePropertyName is the rich-text property that receives a
+	// recurring task's RRULE. Empty falls back to a property named
+	// "Recurrence" or "Repeat" when the data source has one.
+	RecurrencePropertyName string `json:"recurrence_property_name"`
+
+	// ====== Property Mapping ======
+	// PropertyMapping assigns task fields to properties of the selected data
+	// source by ID. It is edited through NotionService, not the settings form.
+	PropertyMapping PropertyMapping `json:"property_mapping"`
+
+	// ====== Destinations ======
+	// Destinations are further data sources captures can be routed to; see
+	// Destination. They are edited with SaveDestinations.
+	Destinations []Destination `json:"destinations,omitempty"`
+
+	// ====== Quick Syntax ======
+	// QuickSyntax holds the prefixes of inline tokens such as "#tag" and
+	// "+project". Empty prefixes use the defaults.
+	QuickSyntax quicksyntax.Prefixes `json:"quick_syntax"`
+
+	// ====== Parser Provider ======
+	ParserProvider  string                            `json:"parser_provider"`
+	ParserProviders map[string]ParserProviderSettings `json:"parser_providers,omitempty"`
 
 	// ====== Secrets ======
 	NotionAccessToken string `json:"notion_access_token,omitempty"`
@@ -64,11 +148,45 @@ type FrontendSettings struct {
 	Theme              string `json:"theme"`
 	LaunchOnStartup    bool   `json:"launch_on_startup"`
 	Hotkey             string `json:"hotkey"`
+	ClipboardHotkey    string `json:"clipboard_hotkey"`
 	HasConnectedNotion bool   `json:"has_notion_secret"`
 	HasOpenAIAPIKey    bool   `json:"has_openai_key"`
 
+	PreviewBeforeCommit  bool `json:"preview_before_commit"`
+	UndoWindowSeconds    int  `json:"undo_window_seconds"`
+	HistoryRetentionDays int  `json:"history_retention_days"`
+	ParseTimeoutSeconds  int  `json:"parse_timeout_seconds"`
+	NotionTimeoutSeconds int  `json:"notion_timeout_seconds"`
+	PersistSchemaCache   bool `json:"persist_schema_cache"`
+
+	DuplicateAction DuplicateAction `json:"duplicate_action"`
+
 	DatePropertyID   string `json:"date_property_id"`
 	DatePropertyName string `json:"date_property_name"`
+	TimeZone         string `json:"time_zone"`
+
+	Language  string `json:"language"`
+	DateOrder string `json:"date_order"`
+	WeekStart string `json:"week_start"`
+
+	RecurrencePropertyName string `json:"recurrence_property_name"`
+
+	QuickSyntax quicksyntax.Prefixes `json:"quick_syntax"`
+
+	ParserProvider  string                            `json:"parser_provider"`
+	ParserProviders map[string]ParserProviderSettings `json:"parser_providers"`
+}
+
+// ParserProviderSettings configures one LLM backend used to parse task input.
+// APIKeyRef names where the key lives: empty means the OpenAI key saved from
+// settings, "env:NAME" reads an environment variable, and anything else is a
+// keychain entry written by SaveProviderAPIKey.
+type ParserProviderSettings struct {
+	BaseURL        string `json:"base_url,omitempty"`
+	Model          string `json:"model,omitempty"`
+	APIKeyRef      string `json:"api_key_ref,omitempty"`
+	APIVersion     string `json:"api_version,omitempty"`
+	TimeoutSeconds int    `json:"timeout_seconds,omitempty"`
 }
 
 // ====== Initializers ======
@@ -79,6 +197,9 @@ func NewSettingsService(startup *startupservice.StartupService) *SettingsService
 	service.AppSettings = defaultApplicationSettings()
 	service.appVersion = detectAppVersion()
 	service.LoadSettings()
+	if _, err := service.Rules(); err != nil {
+		log.Printf("warning: ignoring capture rules: %v", err)
+	}
 	return service
 }
 
@@ -111,8 +232,10 @@ func defaultApplicationSettings() ApplicationSettings {
 	}
 
 	return ApplicationSettings{
-		Theme:  "light",
-		Hotkey: config,
+		Theme:           "light",
+		Hotkey:          config,
+		ClipboardHotkey: "ctrl+shift+space",
+		ParserProvider:  defaultParserProvider,
 	}
 }
 
@@ -198,6 +321,9 @@ func (s *SettingsService) ClearLocalCache() (bool, error) {
 
 	s.AppSettings = defaultApplicationSettings()
 	s.FrontendOverrides = FrontendSettings{}
+	s.providerKeysMu.Lock()
+	s.providerKeys = nil
+	s.providerKeysMu.Unlock()
 
 	if len(errs) > 0 {
 		return false, errors.Join(errs...)
@@ -226,6 +352,29 @@ func (h *hotkeyConfig) MarshalJSON() ([]byte, error) {
 	return json.Marshal(strings.Join(parts, "+"))
 }
 
+// ClipboardHotkeyConfig parses ClipboardHotkey; ok is false when it is
+// disabled.
+func (a ApplicationSettings) ClipboardHotkeyConfig() (config hotkeyConfig, ok bool, err error) {
+	if a.ClipboardHotkey == "" {
+		return hotkeyConfig{}, false, nil
+	}
+	config, err = parseHotkeyString(a.ClipboardHotkey)
+	return config, err == nil, err
+}
+
+// equal reports whether h and other are the same key combination.
+func (h hotkeyConfig) equal(other hotkeyConfig) bool {
+	if h.Key != other.Key || len(h.Modifiers) != len(other.Modifiers) {
+		return false
+	}
+	for _, mod := range h.Modifiers {
+		if !slices.Contains(other.Modifiers, mod) {
+			return false
+		}
+	}
+	return true
+}
+
 func parseHotkeyString(input string) (hotkeyConfig, error) {
 	parts := strings.Split(strings.ToLower(strings.TrimSpace(input)), "+")
 	var mods []hotkey.Modifier
@@ -266,6 +415,9 @@ func saveSecret(label, value string) error {
 	return keychain.AddItem(item)
 }
 
+// loadSecret reads the keychain; tests replace it.
+var loadSecret = LoadSecret
+
 func LoadSecret(label string) (string, error) {
 	query := keychain.NewItem()
 	query.SetSecClass(keychain.SecClassGenericPassword)
@@ -416,8 +568,223 @@ func (s *SettingsService) GetOpenAIKey() (string, error) {
 	return key, nil
 }
 
+// ====== Parser Provider ======
+
+// ActiveParserProvider returns the selected provider kind and its settings.
+func (s *SettingsService) ActiveParserProvider() (string, ParserProviderSettings) {
+	kind := strings.TrimSpace(s.AppSettings.ParserProvider)
+	if kind == "" {
+		kind = defaultParserProvider
+	}
+	return kind, s.AppSettings.ParserProviders[kind]
+}
+
+func (s *SettingsService) SaveProviderAPIKey(ref, key string) error {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return s.SaveOpenAIKey(key)
+	}
+	if strings.HasPrefix(ref, envKeyRefPrefix) {
+		return fmt.Errorf("key reference %q is read from the environment", ref)
+	}
+
+	sanitized := strings.TrimSpace(key)
+	s.providerKeysMu.Lock()
+	defer s.providerKeysMu.Unlock()
+
+	if !keychainDisabled() {
+		if err := UpdateSecret(keychainProviderKey+ref, sanitized); err != nil {
+			return err
+		}
+	}
+
+	if s.providerKeys == nil {
+		s.providerKeys = map[string]string{}
+	}
+	s.providerKeys[ref] = sanitized
+	return nil
+}
+
+func (s *SettingsService) GetProviderAPIKey(ref string) (string, error) {
+	ref = strings.TrimSpace(ref)
+	switch {
+	case ref == "":
+		return s.GetOpenAIKey()
+	case strings.HasPrefix(ref, envKeyRefPrefix):
+		return os.Getenv(strings.TrimPrefix(ref, envKeyRefPrefix)), nil
+	}
+
+	// The lock is held across the keychain read so parallel parses ask the
+	// keychain once.
+	s.providerKeysMu.Lock()
+	defer s.providerKeysMu.Unlock()
+
+	if key, ok := s.providerKeys[ref]; ok {
+		return key, nil
+	}
+	if keychainDisabled() {
+		return "", nil
+	}
+
+	key, err := loadSecret(keychainProviderKey + ref)
+	if err != nil {
+		return "", err
+	}
+
+	if s.providerKeys == nil {
+		s.providerKeys = map[string]string{}
+	}
+	s.providerKeys[ref] = key
+	return key, nil
+}
+
+// ====== Time Zone ======
+
+// Location returns the zone used for timed tasks and its IANA name. The name
+// is empty when the system zone cannot be identified.
+func (a ApplicationSettings) Location() (*time.Location, string) {
+	if name := strings.TrimSpace(a.TimeZone); name != "" {
+		if loc, err := time.LoadLocation(name); err == nil {
+			return loc, name
+		}
+		log.Printf("Location: unknown time zone %q, using system zone", name)
+	}
+	return systemTimeZone()
+}
+
+func systemTimeZone() (*time.Location, string) {
+	if name := strings.TrimPrefix(os.Getenv("TZ"), ":"); name != "" {
+		if loc, err := time.LoadLocation(name); err == nil {
+			return loc, name
+		}
+	}
+
+	// macOS and most Linux systems link /etc/localtime into the zoneinfo tree.
+	if target, err := os.Readlink("/etc/localtime"); err == nil {
+		if _, name, found := strings.Cut(target, "zoneinfo/"); found {
+			if loc, err := time.LoadLocation(name); err == nil {
+				return loc, name
+			}
+		}
+	}
+	return time.Local, ""
+}
+
+// ====== Locale ======
+
+// Locale is how task input is written.
+type Locale struct {
+	Language  string
+	DateOrder string
+	WeekStart time.Weekday
+}
+
+var (
+	languagePattern = regexp.MustCompile(`^[a-z]{2,3}(-[a-zA-Z0-9]{2,8})*$`)
+	dateOrders      = []string{"MDY", "DMY", "YMD"}
+	weekStarts      = map[string]time.Weekday{"monday": time.Monday, "sunday": time.Sunday, "saturday": time.Saturday}
+)
+
+// Locale returns the configured locale with defaults for unset fields.
+func (a ApplicationSettings) Locale() Locale {
+	locale := Locale{Language: defaultLanguage, DateOrder: defaultDateOrder, WeekStart: time.Monday}
+	if language := strings.TrimSpace(a.Language); language != "" {
+		locale.Language = language
+	}
+	if order := strings.ToUpper(strings.TrimSpace(a.DateOrder)); slices.Contains(dateOrders, order) {
+		locale.DateOrder = order
+	}
+	if day, ok := weekStarts[strings.ToLower(strings.TrimSpace(a.WeekStart))]; ok {
+		locale.WeekStart = day
+	}
+	return locale
+}
+
+// UndoWindow is how long after capture a task can still be undone.
+func (a ApplicationSettings) UndoWindow() time.Duration {
+	if a.UndoWindowSeconds <= 0 {
+		return defaultUndoWindow
+	}
+	return time.Duration(a.UndoWindowSeconds) * time.Second
+}
+
+// HistoryRetention is how long captures stay in the local history.
+func (a ApplicationSettings) HistoryRetention() time.Duration {
+	days := a.HistoryRetentionDays
+	if days <= 0 {
+		days = defaultHistoryDays
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// DuplicateAction says how a capture treats an existing open page that
+// looks the same.
+type DuplicateAction string
+
+const (
+	// DuplicatesOff creates pages without looking for duplicates.
+	DuplicatesOff DuplicateAction = "off"
+	// DuplicatesWarn creates the page and reports the duplicate.
+	DuplicatesWarn DuplicateAction = "warn"
+	// DuplicatesSkip keeps the existing page and creates nothing.
+	DuplicatesSkip DuplicateAction = "skip"
+	// DuplicatesUpdate writes the captured fields to the existing page.
+	DuplicatesUpdate DuplicateAction = "update"
+)
+
+// Duplicates is the configured DuplicateAction.
+func (a ApplicationSettings) Duplicates() DuplicateAction {
+	if a.DuplicateAction == "" {
+		return DuplicatesWarn
+	}
+	return a.DuplicateAction
+}
+
+// ParseTimeout bounds reading a task from the input, including any model
+// call. When it passes the local parser is used instead.
+func (a ApplicationSettings) ParseTimeout() time.Duration {
+	if a.ParseTimeoutSeconds <= 0 {
+		return defaultParseTimeout
+	}
+	return time.Duration(a.ParseTimeoutSeconds) * time.Second
+}
+
+// NotionTimeout bounds each request to the Notion API.
+func (a ApplicationSettings) NotionTimeout() time.Duration {
+	if a.NotionTimeoutSeconds <= 0 {
+		return defaultNotionTimeout
+	}
+	return time.Duration(a.NotionTimeoutSeconds) * time.Second
+}
+
+func validateLocale(settings *ApplicationSettings) error {
+	settings.Language = strings.TrimSpace(settings.Language)
+	if settings.Language != "" && !languagePattern.MatchString(settings.Language) {
+		return fmt.Errorf("invalid language %q", settings.Language)
+	}
+
+	settings.DateOrder = strings.ToUpper(strings.TrimSpace(settings.DateOrder))
+	if settings.DateOrder != "" && !slices.Contains(dateOrders, settings.DateOrder) {
+		return fmt.Errorf("invalid date order %q: use one of %s", settings.DateOrder, strings.Join(dateOrders, ", "))
+	}
+
+	settings.WeekStart = strings.ToLower(strings.TrimSpace(settings.WeekStart))
+	if _, ok := weekStarts[settings.WeekStart]; settings.WeekStart != "" && !ok {
+		return fmt.Errorf("invalid week start %q: use monday, sunday or saturday", settings.WeekStart)
+	}
+	return nil
+}
+
 // ====== Settings Load/Save ======
 
+// DataPath returns the path of a data file stored next to the settings file.
+func (s *SettingsService) DataPath(name string) string {
+	if s.settingsPath == "" {
+		s.settingsPath = resolveSettingsPath()
+	}
+	return filepath.Join(filepath.Dir(s.settingsPath), name)
+}
+
 func (s *SettingsService) LoadSettings() {
 	if s.settingsPath == "" {
 		s.settingsPath = resolveSettingsPath()
@@ -506,10 +873,26 @@ func (s *SettingsService) GetSettings() (FrontendSettings, error) {
 	var frontend FrontendSettings
 	frontend.UseOpenAI = s.AppSettings.UseOpenAI
 	frontend.Theme = s.AppSettings.Theme
+	frontend.PreviewBeforeCommit = s.AppSettings.PreviewBeforeCommit
+	frontend.UndoWindowSeconds = int(s.AppSettings.UndoWindow().Seconds())
+	frontend.HistoryRetentionDays = int(s.AppSettings.HistoryRetention().Hours() / 24)
+	frontend.ParseTimeoutSeconds = int(s.AppSettings.ParseTimeout().Seconds())
+	frontend.NotionTimeoutSeconds = int(s.AppSettings.NotionTimeout().Seconds())
+	frontend.PersistSchemaCache = s.AppSettings.PersistSchemaCache
+	frontend.DuplicateAction = s.AppSettings.Duplicates()
 	frontend.LaunchOnStartup = s.StartupService.IsEnabled()
 	frontend.NotionDataSourceID = s.AppSettings.NotionDataSourceID
 	frontend.DatePropertyID = s.AppSettings.DatePropertyID
 	frontend.DatePropertyName = s.AppSettings.DatePropertyName
+	frontend.TimeZone = s.AppSettings.TimeZone
+	locale := s.AppSettings.Locale()
+	frontend.Language = locale.Language
+	frontend.DateOrder = locale.DateOrder
+	frontend.WeekStart = strings.ToLower(locale.WeekStart.String())
+	frontend.RecurrencePropertyName = s.AppSettings.RecurrencePropertyName
+	frontend.QuickSyntax = s.AppSettings.QuickSyntax.WithDefaults()
+	frontend.ParserProvider, _ = s.ActiveParserProvider()
+	frontend.ParserProviders = s.AppSettings.ParserProviders
 
 	hotkeyJSON, err := s.AppSettings.Hotkey.MarshalJSON()
 	if err != nil {
@@ -517,6 +900,7 @@ func (s *SettingsService) GetSettings() (FrontendSettings, error) {
 	} else {
 		_ = json.Unmarshal(hotkeyJSON, &frontend.Hotkey)
 	}
+	frontend.ClipboardHotkey = s.AppSettings.ClipboardHotkey
 
 	frontend.HasConnectedNotion = s.AppSettings.HasNotionSecret
 	frontend.HasOpenAIAPIKey = s.AppSettings.HasOpenAIKey
@@ -536,12 +920,64 @@ func (s *SettingsService) UpdateSettingsFromFrontend(raw map[string]interface{})
 	}
 
 	data, _ := json.Marshal(raw)
-	var newSettings ApplicationSettings
+	// Start from the current settings so fields the settings form does not
+	// send survive a save.
+	newSettings := s.AppSettings
+	// Destinations and property mappings have their own bindings; decoding
+	// into them here would also change the slices and maps they share with
+	// the current settings.
+	newSettings.Destinations, newSettings.PropertyMapping = nil, PropertyMapping{}
+	// The form sends every parser provider, so decode them into a fresh map:
+	// merging into the shared one would change the live settings before
+	// validation and never drop a removed provider.
+	newSettings.ParserProviders = nil
 	_ = json.Unmarshal(data, &newSettings)
+	newSettings.Destinations, newSettings.PropertyMapping = s.AppSettings.Destinations, s.AppSettings.PropertyMapping
 	newSettings.Hotkey = hotkeyCfg
+	// An empty combination turns the clipboard hotkey off.
+	newSettings.ClipboardHotkey = strings.TrimSpace(newSettings.ClipboardHotkey)
+	if clipboardCfg, ok, err := newSettings.ClipboardHotkeyConfig(); err != nil {
+		return fmt.Errorf("invalid clipboard hotkey: %w", err)
+	} else if ok && clipboardCfg.equal(hotkeyCfg) {
+		return fmt.Errorf("clipboard hotkey must differ from the main hotkey")
+	}
 	newSettings.NotionAccessToken = s.AppSettings.NotionAccessToken
 	newSettings.OpenAIAPIKey = s.AppSettings.OpenAIAPIKey
 
+	newSettings.TimeZone = strings.TrimSpace(newSettings.TimeZone)
+	if newSettings.TimeZone != "" {
+		if _, err := time.LoadLocation(newSettings.TimeZone); err != nil {
+			return fmt.Errorf("invalid time zone %q: %w", newSettings.TimeZone, err)
+		}
+	}
+
+	if err := validateLocale(&newSettings); err != nil {
+		return err
+	}
+
+	switch newSettings.DuplicateAction {
+	case "", DuplicatesOff, DuplicatesWarn, DuplicatesSkip, DuplicatesUpdate:
+	default:
+		return fmt.Errorf("invalid duplicate action %q", newSettings.DuplicateAction)
+	}
+
+	if newSettings.UndoWindowSeconds < 0 || newSettings.UndoWindowSeconds > maxUndoWindowSeconds {
+		return fmt.Errorf("undo window must be between 0 and %d seconds", maxUndoWindowSeconds)
+	}
+	if newSettings.HistoryRetentionDays < 0 || newSettings.HistoryRetentionDays > maxHistoryDays {
+		return fmt.Errorf("history retention must be between 0 and %d days", maxHistoryDays)
+	}
+	for _, timeout := range []int{newSettings.ParseTimeoutSeconds, newSettings.NotionTimeoutSeconds} {
+		if timeout < 0 || timeout > maxTimeoutSeconds {
+			return fmt.Errorf("timeouts must be between 0 and %d seconds", maxTimeoutSeconds)
+		}
+	}
+
+	newSettings.QuickSyntax = newSettings.QuickSyntax.WithDefaults()
+	if err := newSettings.QuickSyntax.Validate(); err != nil {
+		return fmt.Errorf("invalid quick syntax: %w", err)
+	}
+
 	if launchRaw, ok := raw["launch_on_startup"].(bool); ok {
 		if launchRaw {
 			_ = s.StartupService.EnableLaunchAtLogin()
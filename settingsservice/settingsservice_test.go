@@ -5,8 +5,12 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/imjamesonzeller/tasklight-v3/quicksyntax"
 	"github.com/imjamesonzeller/tasklight-v3/startupservice"
 	"golang.design/x/hotkey"
 )
@@ -104,3 +108,408 @@ func TestLoadSettingsRecoversFromCorruptFile(t *testing.T) {
 		t.Fatalf("expected no additional corrupt backups after reload, got %d (files: %v)", len(backupsAfterReload), backupsAfterReload)
 	}
 }
+
+func TestParserProviderSettings(t *testing.T) {
+	t.Setenv("TASKLIGHT_SKIP_KEYCHAIN", "1")
+	t.Setenv("TASKLIGHT_SETTINGS_PATH", filepath.Join(t.TempDir(), "settings.json"))
+	t.Setenv("TASKLIGHT_TEST_PROVIDER_KEY", "env-secret")
+
+	svc := NewSettingsService(startupservice.NewStartupService())
+
+	kind, _ := svc.ActiveParserProvider()
+	if kind != defaultParserProvider {
+		t.Fatalf("expected default provider %q, got %q", defaultParserProvider, kind)
+	}
+
+	svc.AppSettings.ParserProvider = "anthropic"
+	svc.AppSettings.ParserProviders = map[string]ParserProviderSettings{
+		"anthropic": {Model: "claude-test", APIKeyRef: "env:TASKLIGHT_TEST_PROVIDER_KEY", TimeoutSeconds: 5},
+	}
+	svc.SaveSettings()
+
+	reloaded := NewSettingsService(startupservice.NewStartupService())
+	kind, cfg := reloaded.ActiveParserProvider()
+	if kind != "anthropic" || cfg.Model != "claude-test" || cfg.TimeoutSeconds != 5 {
+		t.Fatalf("provider settings did not persist: %s %+v", kind, cfg)
+	}
+
+	key, err := reloaded.GetProviderAPIKey(cfg.APIKeyRef)
+	if err != nil || key != "env-secret" {
+		t.Fatalf("expected env key, got %q (err %v)", key, err)
+	}
+
+	if err := reloaded.SaveProviderAPIKey("work-azure", "azure-secret"); err != nil {
+		t.Fatalf("unexpected error saving key: %v", err)
+	}
+	key, err = reloaded.GetProviderAPIKey("work-azure")
+	if err != nil || key != "azure-secret" {
+		t.Fatalf("expected stored key, got %q (err %v)", key, err)
+	}
+
+	reloaded.SaveSettings()
+	data, err := os.ReadFile(reloaded.settingsPath)
+	if err != nil {
+		t.Fatalf("failed to read settings file: %v", err)
+	}
+	if strings.Contains(string(data), "azure-secret") {
+		t.Fatalf("provider keys must not be written to the settings file")
+	}
+
+	// A rejected form must leave the saved providers as they were.
+	err = reloaded.UpdateSettingsFromFrontend(map[string]interface{}{
+		"hotkey":           "ctrl+space",
+		"time_zone":        "Mars/Olympus_Mons",
+		"parser_providers": map[string]interface{}{"anthropic": map[string]interface{}{"model": "claude-other"}, "ollama": map[string]interface{}{}},
+	})
+	if err == nil {
+		t.Fatal("expected an error for an unknown time zone")
+	}
+	if providers := reloaded.AppSettings.ParserProviders; len(providers) != 1 || providers["anthropic"].Model != "claude-test" {
+		t.Fatalf("rejected providers must not be applied, got %+v", providers)
+	}
+}
+
+func TestProviderAPIKeyConcurrentLoads(t *testing.T) {
+	t.Setenv("TASKLIGHT_SKIP_KEYCHAIN", "")
+	var loads atomic.Int32
+	original := loadSecret
+	t.Cleanup(func() { loadSecret = original })
+	loadSecret = func(label string) (string, error) {
+		loads.Add(1)
+		time.Sleep(time.Millisecond)
+		return "secret-for-" + strings.TrimPrefix(label, keychainProviderKey), nil
+	}
+
+	svc := &SettingsService{}
+	refs := []string{"work-azure", "local", "anthropic"}
+	var wg sync.WaitGroup
+	for i := range 12 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ref := refs[i%len(refs)]
+			if key, err := svc.GetProviderAPIKey(ref); err != nil || key != "secret-for-"+ref {
+				t.Errorf("GetProviderAPIKey(%q) = %q, %v", ref, key, err)
+			}
+			if i == 0 {
+				svc.providerKeysMu.Lock()
+				svc.providerKeys = nil
+				svc.providerKeysMu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if loads.Load() < int32(len(refs)) || loads.Load() > int32(2*len(refs)) {
+		t.Fatalf("expected each key read about once, got %d reads", loads.Load())
+	}
+}
+
+func TestTimeZoneSetting(t *testing.T) {
+	t.Setenv("TZ", "Asia/Tokyo")
+
+	_, name := ApplicationSettings{}.Location()
+	if name != "Asia/Tokyo" {
+		t.Fatalf("expected system zone from TZ, got %q", name)
+	}
+
+	loc, name := ApplicationSettings{TimeZone: "Europe/Berlin"}.Location()
+	if name != "Europe/Berlin" || loc.String() != "Europe/Berlin" {
+		t.Fatalf("expected configured zone, got %q (%v)", name, loc)
+	}
+
+	_, name = ApplicationSettings{TimeZone: "Mars/Olympus_Mons"}.Location()
+	if name != "Asia/Tokyo" {
+		t.Fatalf("unknown zone should fall back to the system zone, got %q", name)
+	}
+
+	t.Setenv("TASKLIGHT_SKIP_KEYCHAIN", "1")
+	t.Setenv("TASKLIGHT_SETTINGS_PATH", filepath.Join(t.TempDir(), "settings.json"))
+	svc := NewSettingsService(startupservice.NewStartupService())
+
+	err := svc.UpdateSettingsFromFrontend(map[string]interface{}{"hotkey": "ctrl+space", "time_zone": "Mars/Olympus_Mons"})
+	if err == nil {
+		t.Fatal("expected an error for an unknown time zone")
+	}
+	if svc.AppSettings.TimeZone != "" {
+		t.Fatalf("rejected time zone must not be applied, got %q", svc.AppSettings.TimeZone)
+	}
+}
+
+func TestQuickSyntaxSetting(t *testing.T) {
+	t.Setenv("TASKLIGHT_SKIP_KEYCHAIN", "1")
+	t.Setenv("TASKLIGHT_SETTINGS_PATH", filepath.Join(t.TempDir(), "settings.json"))
+	svc := NewSettingsService(startupservice.NewStartupService())
+
+	err := svc.UpdateSettingsFromFrontend(map[string]interface{}{
+		"hotkey":       "ctrl+space",
+		"quick_syntax": map[string]interface{}{"tag": "+"},
+	})
+	if err == nil {
+		t.Fatal("expected an error when tags and projects share a prefix")
+	}
+
+	if svc.AppSettings.QuickSyntax != (quicksyntax.Prefixes{}) {
+		t.Fatalf("rejected prefixes must not be applied, got %+v", svc.AppSettings.QuickSyntax)
+	}
+
+	svc.AppSettings.QuickSyntax = quicksyntax.Prefixes{Tag: "%"}
+	settings, _ := svc.GetSettings()
+	if settings.QuickSyntax.Tag != "%" || settings.QuickSyntax.Project != "+" {
+		t.Fatalf("unexpected prefixes: %+v", settings.QuickSyntax)
+	}
+}
+
+func TestLocaleSetting(t *testing.T) {
+	locale := ApplicationSettings{}.Locale()
+	if locale.Language != "en" || locale.DateOrder != "MDY" || locale.WeekStart != time.Monday {
+		t.Fatalf("unexpected default locale: %+v", locale)
+	}
+
+	locale = ApplicationSettings{Language: "de", DateOrder: "dmy", WeekStart: "Sunday"}.Locale()
+	if locale.Language != "de" || locale.DateOrder != "DMY" || locale.WeekStart != time.Sunday {
+		t.Fatalf("unexpected locale: %+v", locale)
+	}
+
+	t.Setenv("TASKLIGHT_SKIP_KEYCHAIN", "1")
+	t.Setenv("TASKLIGHT_SETTINGS_PATH", filepath.Join(t.TempDir(), "settings.json"))
+	svc := NewSettingsService(startupservice.NewStartupService())
+
+	for _, raw := range []map[string]interface{}{
+		{"hotkey": "ctrl+space", "language": "German"},
+		{"hotkey": "ctrl+space", "date_order": "DDMM"},
+		{"hotkey": "ctrl+space", "week_start": "friday"},
+	} {
+		if err := svc.UpdateSettingsFromFrontend(raw); err == nil {
+			t.Errorf("expected %v to be rejected", raw)
+		}
+	}
+}
+
+func TestCaptureLimitSettings(t *testing.T) {
+	if got := (ApplicationSettings{}).UndoWindow(); got != 2*time.Minute {
+		t.Fatalf("unexpected default undo window: %v", got)
+	}
+	if got := (ApplicationSettings{UndoWindowSeconds: 30}).UndoWindow(); got != 30*time.Second {
+		t.Fatalf("unexpected undo window: %v", got)
+	}
+	if got := (ApplicationSettings{}).HistoryRetention(); got != 90*24*time.Hour {
+		t.Fatalf("unexpected default history retention: %v", got)
+	}
+	if got := (ApplicationSettings{}).NotionTimeout(); got != 15*time.Second {
+		t.Fatalf("unexpected default Notion timeout: %v", got)
+	}
+	if got := (ApplicationSettings{ParseTimeoutSeconds: 5}).ParseTimeout(); got != 5*time.Second {
+		t.Fatalf("unexpected parse timeout: %v", got)
+	}
+	if got := (ApplicationSettings{}).Duplicates(); got != DuplicatesWarn {
+		t.Fatalf("unexpected default duplicate action: %v", got)
+	}
+
+	t.Setenv("TASKLIGHT_SKIP_KEYCHAIN", "1")
+	t.Setenv("TASKLIGHT_SETTINGS_PATH", filepath.Join(t.TempDir(), "settings.json"))
+	svc := NewSettingsService(startupservice.NewStartupService())
+
+	for _, raw := range []map[string]interface{}{
+		{"hotkey": "ctrl+space", "undo_window_seconds": -1},
+		{"hotkey": "ctrl+space", "undo_window_seconds": 86400},
+		{"hotkey": "ctrl+space", "history_retention_days": -7},
+		{"hotkey": "ctrl+space", "parse_timeout_seconds": 600},
+		{"hotkey": "ctrl+space", "notion_timeout_seconds": -1},
+		{"hotkey": "ctrl+space", "duplicate_action": "merge"},
+	} {
+		if err := svc.UpdateSettingsFromFrontend(raw); err == nil {
+			t.Errorf("expected %v to be rejected", raw)
+		}
+	}
+}
+
+func TestRulesReloadWhenFileChanges(t *testing.T) {
+	t.Setenv("TASKLIGHT_SKIP_KEYCHAIN", "1")
+	dir := t.TempDir()
+	t.Setenv("TASKLIGHT_SETTINGS_PATH", filepath.Join(dir, "settings.json"))
+	svc := NewSettingsService(startupservice.NewStartupService())
+
+	if set, err := svc.Rules(); err != nil || set.Len() != 0 {
+		t.Fatalf("expected no rules without a file, got %d (err %v)", set.Len(), err)
+	}
+
+	path := filepath.Join(dir, rulesFileName)
+	if err := os.WriteFile(path, []byte(`{"rules": [{"then": [{"case": "sentence"}]}]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if status := svc.GetRulesStatus(); status.Count != 1 || status.Error != "" || status.Path != path {
+		t.Fatalf("unexpected status: %+v", status)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"rules": [{"when": {"regex": "("}, "then": [{"case": "sentence"}]}]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if status := svc.GetRulesStatus(); status.Count != 0 || !strings.Contains(status.Error, "invalid regex") {
+		t.Fatalf("expected the broken file to be reported, got %+v", status)
+	}
+}
+
+func TestClipboardHotkeySetting(t *testing.T) {
+	t.Setenv("TASKLIGHT_SKIP_KEYCHAIN", "1")
+	t.Setenv("TASKLIGHT_SETTINGS_PATH", filepath.Join(t.TempDir(), "settings.json"))
+	svc := NewSettingsService(startupservice.NewStartupService())
+
+	if settings, _ := svc.GetSettings(); settings.ClipboardHotkey != "ctrl+shift+space" {
+		t.Fatalf("unexpected default clipboard hotkey: %q", settings.ClipboardHotkey)
+	}
+
+	for _, raw := range []map[string]interface{}{
+		{"hotkey": "ctrl+space", "clipboard_hotkey": "ctrl+banana"},
+		{"hotkey": "ctrl+space", "clipboard_hotkey": "space+ctrl"},
+	} {
+		if err := svc.UpdateSettingsFromFrontend(raw); err == nil {
+			t.Errorf("expected %v to be rejected", raw)
+		}
+	}
+
+	// A disabled clipboard hotkey stays disabled after a restart.
+	svc.AppSettings.ClipboardHotkey = ""
+	svc.SaveSettings()
+	reloaded := NewSettingsService(startupservice.NewStartupService())
+	if _, ok, err := reloaded.AppSettings.ClipboardHotkeyConfig(); ok || err != nil {
+		t.Fatalf("expected the clipboard hotkey to stay off, got %q", reloaded.AppSettings.ClipboardHotkey)
+	}
+}
+
+func TestPropertyMappingsFollowDataSource(t *testing.T) {
+	t.Setenv("TASKLIGHT_SKIP_KEYCHAIN", "1")
+	t.Setenv("TASKLIGHT_SETTINGS_PATH", filepath.Join(t.TempDir(), "settings.json"))
+	svc := NewSettingsService(startupservice.NewStartupService())
+	svc.AppSettings.NotionDataSourceID = "ds-1"
+
+	if err := svc.SetPropertyMappings("", map[string]string{"tags": "abc%3D", "notes": PropertyOff, "project": ""}); err != nil {
+		t.Fatalf("SetPropertyMappings: %v", err)
+	}
+
+	reloaded := NewSettingsService(startupservice.NewStartupService())
+	mappings := reloaded.AppSettings.DefaultDestination().PropertyMappings()
+	if len(mappings) != 2 || mappings["tags"] != "abc%3D" || mappings["notes"] != PropertyOff {
+		t.Fatalf("unexpected mappings after reload: %v", mappings)
+	}
+
+	// A mapping made for one data source does not apply to another.
+	reloaded.AppSettings.NotionDataSourceID = "ds-2"
+	if mappings := reloaded.AppSettings.DefaultDestination().PropertyMappings(); mappings != nil {
+		t.Fatalf("a mapping must not apply to another data source, got %v", mappings)
+	}
+}
+
+func TestPropertyDefaultsKeepMappings(t *testing.T) {
+	t.Setenv("TASKLIGHT_SKIP_KEYCHAIN", "1")
+	t.Setenv("TASKLIGHT_SETTINGS_PATH", filepath.Join(t.TempDir(), "settings.json"))
+	svc := NewSettingsService(startupservice.NewStartupService())
+	svc.AppSettings.NotionDataSourceID = "ds-1"
+
+	svc.SetPropertyMappings("", map[string]string{"tags": "t1"})
+	svc.SetPropertyDefaults("", map[string]string{"st": " Inbox ", "so": ""})
+	dest := svc.AppSettings.DefaultDestination()
+	if mappings := dest.PropertyMappings(); mappings["tags"] != "t1" {
+		t.Fatalf("saving defaults must keep the mapping, got %v", mappings)
+	}
+	if defaults := dest.PropertyDefaults(); len(defaults) != 1 || defaults["st"] != "Inbox" {
+		t.Fatalf("unexpected defaults: %v", defaults)
+	}
+
+	// Defaults saved after picking another data source start over.
+	svc.AppSettings.NotionDataSourceID = "ds-2"
+	svc.SetPropertyDefaults("", map[string]string{"st": "Inbox"})
+	if dest := svc.AppSettings.DefaultDestination(); dest.PropertyMappings() != nil || dest.PropertyDefaults()["st"] != "Inbox" {
+		t.Fatalf("unexpected mapping: %+v", svc.AppSettings.PropertyMapping)
+	}
+}
+
+func TestSaveDestinations(t *testing.T) {
+	t.Setenv("TASKLIGHT_SKIP_KEYCHAIN", "1")
+	t.Setenv("TASKLIGHT_SETTINGS_PATH", filepath.Join(t.TempDir(), "settings.json"))
+	svc := NewSettingsService(startupservice.NewStartupService())
+
+	for _, destinations := range [][]Destination{
+		{{Name: " ", DataSourceID: "ds-2"}},
+		{{Name: "Work"}},
+		{{Name: "Work", DataSourceID: "ds-2"}, {Name: "work", DataSourceID: "ds-3"}},
+		{{Name: "Work", DataSourceID: "ds-2", Prefixes: []string{"w:"}}},
+		{{Name: "Work", DataSourceID: "ds-2", Prefixes: []string{"project"}}},
+		{{Name: "Work", DataSourceID: "ds-2", Prefixes: []string{"h"}}, {Name: "Home", DataSourceID: "ds-3", Prefixes: []string{"h"}}},
+		{{Name: "Work", DataSourceID: "ds-2"}, {Name: "Home", DataSourceID: "ds-3", Prefixes: []string{"work"}}},
+		{{Name: "Team Backlog", DataSourceID: "ds-4", Prefixes: []string{"team backlog"}}},
+	} {
+		if err := svc.SaveDestinations(destinations); err == nil {
+			t.Errorf("expected %+v to be rejected", destinations)
+		}
+	}
+	if len(svc.GetDestinations()) != 0 {
+		t.Fatalf("rejected destinations must not be saved, got %+v", svc.GetDestinations())
+	}
+
+	work := Destination{Name: " Work ", DataSourceID: "ds-2", Prefixes: []string{" w ", ""}, Keywords: []string{"standup"}}
+	if err := svc.SaveDestinations([]Destination{work}); err != nil {
+		t.Fatalf("SaveDestinations: %v", err)
+	}
+	if err := svc.SetPropertyMappings("work", map[string]string{"tags": "t1"}); err != nil {
+		t.Fatalf("SetPropertyMappings: %v", err)
+	}
+	if err := svc.SetPropertyMappings("Gone", map[string]string{"tags": "t1"}); err == nil {
+		t.Fatal("expected a mapping for a missing destination to be rejected")
+	}
+
+	// Editing the routing keeps the mapping of a destination with the same name.
+	work.Keywords = []string{"standup", "sprint"}
+	if err := svc.SaveDestinations([]Destination{work}); err != nil {
+		t.Fatalf("SaveDestinations: %v", err)
+	}
+
+	reloaded := NewSettingsService(startupservice.NewStartupService())
+	dest, ok := reloaded.AppSettings.FindDestination("WORK")
+	if !ok || dest.Name != "Work" || len(dest.Prefixes) != 1 || dest.Prefixes[0] != "w" || len(dest.Keywords) != 2 {
+		t.Fatalf("unexpected destination after reload: %+v", dest)
+	}
+	if dest.PropertyMappings()["tags"] != "t1" {
+		t.Fatalf("expected the mapping to survive, got %+v", dest.PropertyMapping)
+	}
+
+	// A name of several words is no prefix, so it needs no prefix rules.
+	backlog := Destination{Name: "Team Backlog", DataSourceID: "ds-4", Prefixes: []string{"tb"}}
+	if err := svc.SaveDestinations([]Destination{work, backlog}); err != nil {
+		t.Fatalf("SaveDestinations: %v", err)
+	}
+	if got := backlog.RoutePrefixes(); len(got) != 1 || got[0] != "tb" {
+		t.Fatalf("unexpected prefixes: %v", got)
+	}
+}
+
+func TestSetUnmatchedOptions(t *testing.T) {
+	t.Setenv("TASKLIGHT_SKIP_KEYCHAIN", "1")
+	t.Setenv("TASKLIGHT_SETTINGS_PATH", filepath.Join(t.TempDir(), "settings.json"))
+	svc := NewSettingsService(startupservice.NewStartupService())
+	svc.AppSettings.NotionDataSourceID = "ds-1"
+
+	for _, unmatched := range []map[string]UnmatchedOption{
+		{"t1": {Action: UnmatchedFallback, Fallback: " "}},
+		{"t1": {Action: "ignore"}},
+	} {
+		if err := svc.SetUnmatchedOptions("", unmatched); err == nil {
+			t.Errorf("expected %+v to be rejected", unmatched)
+		}
+	}
+
+	err := svc.SetUnmatchedOptions("", map[string]UnmatchedOption{
+		"t1": {Action: UnmatchedDrop, Fallback: "Inbox"},
+		"p1": {Action: UnmatchedFallback, Fallback: " Inbox "},
+		"s1": {Action: UnmatchedCreate},
+		"x1": {},
+	})
+	if err != nil {
+		t.Fatalf("SetUnmatchedOptions: %v", err)
+	}
+	reloaded := NewSettingsService(startupservice.NewStartupService())
+	unmatched := reloaded.AppSettings.DefaultDestination().UnmatchedOptions()
+	if len(unmatched) != 3 || unmatched["t1"] != (UnmatchedOption{Action: UnmatchedDrop}) || unmatched["p1"].Fallback != "Inbox" {
+		t.Fatalf("unexpected settings after reload: %+v", unmatched)
+	}
+}
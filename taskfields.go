@@ -0,0 +1,137 @@
+package main
+
+import (
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	c "github.com/imjamesonzeller/tasklight-v3/config"
+	"github.com/imjamesonzeller/tasklight-v3/dateparse"
+	"github.com/imjamesonzeller/tasklight-v3/quicksyntax"
+)
+
+var (
+	priorityPhraseRe = regexp.MustCompile(`(?i)(^|\s)(urgent|high|medium|low)\s+priority\b`)
+	estimateRe       = regexp.MustCompile(`(?i)(^|\s)~\s*(?:(\d+(?:\.\d+)?)\s*h(?:ours?|rs?)?)?\s*(?:(\d+)\s*m(?:in(?:utes?|s)?)?)?\b`)
+	projectRe        = regexp.MustCompile(`(?i)(^|\s)(?:for\s+project\s+|project:\s*)([\p{L}\p{N}_-]+)`)
+	notesRe          = regexp.MustCompile(`(?i)(^|\s)notes?:\s*(.*)$`)
+)
+
+// extractLocalFields pulls phrase-style details out of input for the local
+// fallback parser: "high priority", "~30m" or "~1h30m", "for project Alpha"
+// or "project:Alpha" and a trailing "notes: ...". Prefixed tokens such as
+// "#tag" are read earlier by quicksyntax. It returns the remaining text
+// together with the fields it found; Title and Date are left empty.
+func extractLocalFields(input string) (string, TaskInformation) {
+	var task TaskInformation
+	rest := input
+
+	if m := notesRe.FindStringSubmatchIndex(rest); m != nil {
+		task.Notes = strings.TrimSpace(rest[m[4]:m[5]])
+		rest = rest[:m[0]]
+	}
+
+	if m := priorityPhraseRe.FindStringSubmatch(rest); m != nil {
+		task.Priority = normalizePriority(m[2])
+		rest = priorityPhraseRe.ReplaceAllString(rest, "$1")
+	}
+
+	for _, m := range estimateRe.FindAllStringSubmatchIndex(rest, -1) {
+		minutes := 0
+		if m[4] >= 0 {
+			hours, _ := strconv.ParseFloat(rest[m[4]:m[5]], 64)
+			minutes += int(hours * 60)
+		}
+		if m[6] >= 0 {
+			mins, _ := strconv.Atoi(rest[m[6]:m[7]])
+			minutes += mins
+		}
+		if minutes > 0 {
+			task.EstimateMinutes = minutes
+			rest = rest[:m[3]] + rest[m[1]:]
+			break
+		}
+	}
+
+	if m := projectRe.FindStringSubmatchIndex(rest); m != nil {
+		task.Project = rest[m[4]:m[5]]
+		rest = rest[:m[3]] + rest[m[1]:]
+	}
+
+	task = normalizeTaskFields(task)
+	return strings.Join(strings.Fields(rest), " "), task
+}
+
+// quickSyntaxPrefixes returns the configured token prefixes.
+func quickSyntaxPrefixes() quicksyntax.Prefixes {
+	if c.AppConfig == nil {
+		return quicksyntax.DefaultPrefixes()
+	}
+	return c.AppConfig.QuickSyntax.WithDefaults()
+}
+
+// applyQuickSyntax overrides task with the inline tokens, which always win over
+// what the model or the local parser found.
+func applyQuickSyntax(task TaskInformation, tokens quicksyntax.Tokens, now time.Time) TaskInformation {
+	if len(tokens.Tags) > 0 {
+		task.Tags = tokens.Tags
+	}
+	if tokens.Project != "" {
+		task.Project = tokens.Project
+	}
+	if tokens.Priority > 0 {
+		// Level 1 is the most urgent; priorities runs from low to urgent.
+		task.Priority = priorities[len(priorities)-tokens.Priority]
+	}
+	if len(tokens.People) > 0 {
+		task.People = tokens.People
+	}
+
+	if tokens.Due != "" {
+		due := dateparse.Parse(tokens.Due, dateOptions(now))
+		if due.Date == nil {
+			// Short weekday names are only read after a connector.
+			due = dateparse.Parse("on "+tokens.Due, dateOptions(now))
+		}
+		if due.Date == nil {
+			log.Printf("applyQuickSyntax: ignoring unrecognised due date %q", tokens.Due)
+		} else {
+			task = withDueDate(task, due, now.Location())
+		}
+	}
+
+	return normalizeTaskFields(task)
+}
+
+// withDueDate moves task to the due date, keeping a parsed time of day when the
+// token names only a day and shifting the end of a range with it.
+func withDueDate(task TaskInformation, due dateparse.Result, loc *time.Location) TaskInformation {
+	start, _ := normalizeTaskDate(task.Date, loc)
+	end, _ := normalizeTaskDate(task.EndDate, loc)
+
+	day := *due.Date
+	var value string
+	switch {
+	case due.HasTime:
+		value = day.Format(dateTimeLayout)
+		if due.TimeZone != "" {
+			task.TimeZone = due.TimeZone
+		}
+	case start.value != nil && start.timed:
+		t := start.time
+		day = time.Date(day.Year(), day.Month(), day.Day(), t.Hour(), t.Minute(), t.Second(), 0, t.Location())
+		value = formatLike(day, *start.value)
+	default:
+		value = day.Format(dateLayout)
+	}
+
+	task.EndDate = nil
+	if end.value != nil && start.value != nil && end.timed == isDateTime(&value) {
+		shifted := formatLike(end.time.Add(day.Sub(start.time)), *end.value)
+		task.EndDate = &shifted
+	}
+	task.Date = &value
+	return task
+}
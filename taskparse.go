@@ -0,0 +1,377 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/imjamesonzeller/tasklight-v3/llm"
+	"github.com/imjamesonzeller/tasklight-v3/recurrence"
+)
+
+// Layouts for TaskInformation dates: all-day and wall-clock date-time.
+const (
+	dateLayout     = "2006-01-02"
+	dateTimeLayout = "2006-01-02T15:04:05"
+)
+
+// errResponseRejected marks AI replies that were unusable and fell back to the local parser.
+var errResponseRejected = errors.New("AI response rejected")
+
+// taskResponseSchema is the structured-output schema sent to providers that support it.
+var taskResponseSchema = &llm.Schema{
+	Name:        "task",
+	Description: "A task parsed from natural-language input.",
+	Definition: map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"title": map[string]any{
+				"type":        "string",
+				"description": "The task without any date phrases.",
+			},
+			"date": map[string]any{
+				"type":        []string{"string", "null"},
+				"description": "ISO 8601 date (YYYY-MM-DD), or local date-time (YYYY-MM-DDTHH:MM) when a time is given; null when no date is mentioned.",
+			},
+			"end_date": map[string]any{
+				"type":        []string{"string", "null"},
+				"description": "End of a date or time range, in the same format as date; null when there is no range.",
+			},
+			"time_zone": map[string]any{
+				"type":        []string{"string", "null"},
+				"description": "IANA time zone named in the input, or null.",
+			},
+			"recurrence": map[string]any{
+				"type":        []string{"string", "null"},
+				"description": "RRULE for a repeating task (FREQ, INTERVAL, BYDAY, BYMONTHDAY), or null.",
+			},
+			"priority": map[string]any{
+				"type":        []string{"string", "null"},
+				"enum":        []any{"low", "medium", "high", "urgent", nil},
+				"description": "Task priority, or null when none is given.",
+			},
+			"tags": map[string]any{
+				"type":        []string{"array", "null"},
+				"items":       map[string]any{"type": "string"},
+				"description": "Short labels such as hashtags, without the leading #.",
+			},
+			"project": map[string]any{
+				"type":        []string{"string", "null"},
+				"description": "The project the task belongs to, or null.",
+			},
+			"notes": map[string]any{
+				"type":        []string{"string", "null"},
+				"description": "Extra detail that does not belong in the title, or null. May use markdown: paragraphs, - [ ] checklists, - bullets, 1. numbered lists, [links](https://...), **bold**, *italic* and `code`.",
+			},
+			"estimate_minutes": map[string]any{
+				"type":        []string{"integer", "null"},
+				"description": "Time estimate in whole minutes, or null.",
+			},
+		},
+		"required":             []string{"title", "date", "end_date", "time_zone", "recurrence", "priority", "tags", "project", "notes", "estimate_minutes"},
+		"additionalProperties": false,
+	},
+}
+
+func rejectResponse(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", errResponseRejected, fmt.Sprintf(format, args...))
+}
+
+// parseTaskFromContent converts the model's reply into TaskInformation. It
+// tolerates code fences, prose around the JSON object and single-quoted JSON.
+func parseTaskFromContent(content string) (TaskInformation, error) {
+	raw, ok := extractJSONObject(content)
+	if !ok {
+		return TaskInformation{}, rejectResponse("no JSON object found")
+	}
+
+	var task TaskInformation
+	err := json.Unmarshal([]byte(raw), &task)
+	if err != nil {
+		if retryErr := json.Unmarshal([]byte(normalizeQuotes(raw)), &task); retryErr != nil {
+			return TaskInformation{}, rejectResponse("invalid JSON: %v", err)
+		}
+	}
+	return task, nil
+}
+
+// extractJSONObject returns the first balanced {...} object in s, skipping any
+// markdown fences or surrounding prose.
+func extractJSONObject(s string) (string, bool) {
+	start := strings.IndexByte(s, '{')
+	if start < 0 {
+		return "", false
+	}
+
+	depth := 0
+	var quote byte
+	escaped := false
+	for i := start; i < len(s); i++ {
+		ch := s[i]
+		if quote != 0 {
+			switch {
+			case escaped:
+				escaped = false
+			case ch == '\\':
+				escaped = true
+			case ch == quote:
+				quote = 0
+			}
+			continue
+		}
+
+		switch ch {
+		case '"', '\'':
+			quote = ch
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return s[start : i+1], true
+			}
+		}
+	}
+	return "", false
+}
+
+// normalizeQuotes rewrites single-quoted JSON strings as double-quoted ones.
+func normalizeQuotes(s string) string {
+	var b strings.Builder
+	var quote byte
+	escaped := false
+
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		switch {
+		case quote == 0 && (ch == '"' || ch == '\''):
+			quote = ch
+			b.WriteByte('"')
+		case quote == 0:
+			b.WriteByte(ch)
+		case escaped:
+			escaped = false
+			if quote == '\'' && ch == '\'' {
+				b.WriteByte('\'')
+				continue
+			}
+			b.WriteByte('\\')
+			b.WriteByte(ch)
+		case ch == '\\':
+			escaped = true
+		case ch == quote:
+			quote = 0
+			b.WriteByte('"')
+		case quote == '\'' && ch == '"':
+			b.WriteString(`\"`)
+		default:
+			b.WriteByte(ch)
+		}
+	}
+	return b.String()
+}
+
+// priorities are the priority values Tasklight understands.
+var priorities = []string{"low", "medium", "high", "urgent"}
+
+// pastMarkers are words that make a past date a legitimate answer.
+var pastMarkers = []string{"last", "previous", "yesterday", "ago", "earlier", "past"}
+
+// validateParsedTask checks a parsed task before it is trusted: the title must
+// be non-empty, the date must be a real ISO 8601 date or date-time that is not
+// in the past unless the input asked for one, and a range must not end before
+// it starts.
+func validateParsedTask(task TaskInformation, input string, now time.Time) (TaskInformation, error) {
+	task.Title = strings.TrimSpace(task.Title)
+	if task.Title == "" {
+		return TaskInformation{}, rejectResponse("empty title")
+	}
+	task = normalizeTaskFields(task)
+
+	task.TimeZone = strings.TrimSpace(task.TimeZone)
+	loc := now.Location()
+	if task.TimeZone != "" {
+		zone, err := time.LoadLocation(task.TimeZone)
+		if err != nil {
+			// An unknown zone is not worth losing the task over; the
+			// configured zone is used instead.
+			task.TimeZone = ""
+		} else {
+			loc = zone
+		}
+	}
+
+	start, ok := normalizeTaskDate(task.Date, loc)
+	if !ok {
+		return TaskInformation{}, rejectResponse("date %q is not an ISO 8601 date", *task.Date)
+	}
+	end, ok := normalizeTaskDate(task.EndDate, loc)
+	if !ok {
+		return TaskInformation{}, rejectResponse("end date %q is not an ISO 8601 date", *task.EndDate)
+	}
+	task.Date, task.EndDate = start.value, end.value
+
+	if task.Date == nil {
+		task.EndDate = nil
+	} else {
+		today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+		startDay := time.Date(start.time.Year(), start.time.Month(), start.time.Day(), 0, 0, 0, 0, now.Location())
+		if startDay.Before(today) && !mentionsPast(input) {
+			return TaskInformation{}, rejectResponse("date %s is in the past", *task.Date)
+		}
+
+		if task.EndDate != nil {
+			if start.timed != end.timed {
+				return TaskInformation{}, rejectResponse("range mixes a date and a date-time")
+			}
+			if end.time.Before(start.time) {
+				return TaskInformation{}, rejectResponse("range ends (%s) before it starts (%s)", *task.EndDate, *task.Date)
+			}
+		}
+	}
+
+	task.Recurrence = strings.TrimSpace(task.Recurrence)
+	if task.Recurrence == "" || strings.EqualFold(task.Recurrence, "null") {
+		task.Recurrence = ""
+		return task, nil
+	}
+	return applyRecurrence(task, start, end, now)
+}
+
+// applyRecurrence normalises a task's RRULE and moves its date to the first
+// occurrence on or after the parsed date (or today), so "every other Tuesday"
+// starts on a Tuesday.
+func applyRecurrence(task TaskInformation, start, end taskDate, now time.Time) (TaskInformation, error) {
+	rule, err := recurrence.Parse(task.Recurrence)
+	if err != nil {
+		return TaskInformation{}, rejectResponse("recurrence %q: %v", task.Recurrence, err)
+	}
+
+	base, like := start.time, dateLayout
+	if task.Date == nil {
+		base = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	} else {
+		like = *task.Date
+	}
+
+	first := rule.First(base)
+	if task.Date == nil || !first.Equal(base) {
+		value := formatLike(first, like)
+		task.Date = &value
+		if task.EndDate != nil {
+			shifted := formatLike(end.time.Add(first.Sub(base)), *task.EndDate)
+			task.EndDate = &shifted
+		}
+	}
+
+	task.Recurrence = rule.Anchored(first).String()
+	return task, nil
+}
+
+// formatLike formats t the way like is written: all-day, wall-clock or with an
+// offset.
+func formatLike(t time.Time, like string) string {
+	switch {
+	case !strings.Contains(like, "T"):
+		return t.Format(dateLayout)
+	case len(like) > len(dateTimeLayout):
+		return t.Format(time.RFC3339)
+	}
+	return t.Format(dateTimeLayout)
+}
+
+type taskDate struct {
+	value *string
+	time  time.Time
+	timed bool
+}
+
+// dateTimeLayouts are the date-time forms accepted from parsers; they are
+// normalised to dateTimeLayout.
+var dateTimeLayouts = []string{"2006-01-02T15:04", dateTimeLayout}
+
+// normalizeTaskDate parses a date or wall-clock date-time. Empty and "null"
+// values become nil. An RFC 3339 value with an offset is kept as written.
+func normalizeTaskDate(raw *string, loc *time.Location) (taskDate, bool) {
+	if raw == nil {
+		return taskDate{}, true
+	}
+	value := strings.TrimSpace(*raw)
+	if value == "" || strings.EqualFold(value, "null") {
+		return taskDate{}, true
+	}
+
+	if t, err := time.ParseInLocation(dateLayout, value, loc); err == nil {
+		return taskDate{value: &value, time: t}, true
+	}
+	for _, layout := range dateTimeLayouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			normalized := t.Format(dateTimeLayout)
+			return taskDate{value: &normalized, time: t, timed: true}, true
+		}
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return taskDate{value: &value, time: t, timed: true}, true
+	}
+	return taskDate{}, false
+}
+
+// isDateTime reports whether a task date carries a time of day.
+func isDateTime(date *string) bool {
+	return date != nil && strings.Contains(*date, "T")
+}
+
+// normalizeTaskFields tidies the optional fields: unknown priorities are
+// dropped, tags lose their # and duplicates, and negative estimates are cleared.
+func normalizeTaskFields(task TaskInformation) TaskInformation {
+	task.Priority = normalizePriority(task.Priority)
+	task.Project = strings.TrimSpace(task.Project)
+	task.Notes = strings.TrimSpace(task.Notes)
+	if task.EstimateMinutes < 0 {
+		task.EstimateMinutes = 0
+	}
+
+	var tags []string
+	seen := make(map[string]bool)
+	for _, tag := range task.Tags {
+		tag = strings.TrimSpace(strings.TrimLeft(tag, "#"))
+		key := strings.ToLower(tag)
+		if tag == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		tags = append(tags, tag)
+	}
+	task.Tags = tags
+	return task
+}
+
+func normalizePriority(value string) string {
+	value = strings.ToLower(strings.TrimSpace(value))
+	switch value {
+	case "med":
+		return "medium"
+	case "asap", "critical":
+		return "urgent"
+	}
+	for _, p := range priorities {
+		if value == p {
+			return p
+		}
+	}
+	return ""
+}
+
+func mentionsPast(input string) bool {
+	for _, word := range strings.Fields(strings.ToLower(input)) {
+		word = strings.Trim(word, ",.;:!?")
+		for _, marker := range pastMarkers {
+			if word == marker {
+				return true
+			}
+		}
+	}
+	return false
+}
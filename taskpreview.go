@@ -0,0 +1,215 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	c "github.com/imjamesonzeller/tasklight-v3/config"
+	"github.com/imjamesonzeller/tasklight-v3/recurrence"
+)
+
+// TaskPreview is a parsed task together with where it would be written.
+type TaskPreview struct {
+	Input       string          `json:"input"`
+	Task        TaskInformation `json:"task"`
+	Destination TaskDestination `json:"destination"`
+	// Properties maps each task field that would be written to the Notion
+	// property receiving it, e.g. "tags" -> "Labels".
+	Properties map[string]string `json:"properties"`
+	// Error explains why the task could not be written as it stands.
+	Error string `json:"error,omitempty"`
+	// Warnings describe what would be left out of the page.
+	Warnings []string `json:"warnings,omitempty"`
+	// Options reports how tags and the project fit the property options.
+	Options []OptionMatch `json:"options,omitempty"`
+}
+
+type TaskDestination struct {
+	DataSourceID string `json:"data_source_id"`
+	Name         string `json:"name"`
+	// Destination and RoutedBy say which destination the task was routed to
+	// and why; both are empty for the default destination.
+	Destination string `json:"destination,omitempty"`
+	RoutedBy    string `json:"routed_by,omitempty"`
+}
+
+// PreviewMessage parses message the way ProcessMessage does but writes
+// nothing. Called from frontend
+func (ts *TaskService) PreviewMessage(message string) ([]TaskPreview, error) {
+	inputs := splitTasks(message)
+	if len(inputs) == 0 {
+		return nil, errors.New("nothing to preview")
+	}
+
+	clipboard := ts.GetClipboardContext()
+	previews := make([]TaskPreview, len(inputs))
+	runPool(len(inputs), func(i int) {
+		task := ts.ProcessedThroughAI(inputs[i])
+		task.Clipboard = clipboard
+		previews[i] = TaskPreview{Input: inputs[i], Task: task}
+	})
+
+	ctx, cancel := notionStage(ts.ctx)
+	defer cancel()
+	type resolved struct {
+		target *pageTarget
+		err    error
+	}
+	targets := map[string]resolved{}
+	for i := range previews {
+		task := previews[i].Task
+		r, ok := targets[task.Destination]
+		if !ok {
+			r.target, r.err = ts.resolveTarget(ctx, task.Destination)
+			targets[task.Destination] = r
+		}
+		target, err := r.target, r.err
+		if err != nil {
+			previews[i].Error = err.Error()
+			continue
+		}
+		previews[i].Destination = TaskDestination{
+			DataSourceID: target.dataSourceID,
+			Name:         target.dataSource.Name,
+			Destination:  target.destination,
+			RoutedBy:     task.RoutedBy,
+		}
+		// The task shows the options it will be written as and carries the
+		// ID of its project page.
+		previews[i].Task, previews[i].Options = matchTaskOptions(previews[i].Task, target.fields)
+		previews[i].Warnings = target.warnings
+		linked, link, err := ts.linkProject(ctx, target, previews[i].Task, false)
+		if err != nil {
+			previews[i].Warnings = append(slices.Clip(target.warnings), fmt.Sprintf("Project %q not linked: %v.", linked.Project, err))
+		}
+		if link != nil {
+			previews[i].Options = append(previews[i].Options, *link)
+		}
+		previews[i].Task = linked
+		previews[i].Properties = propertyMapping(previews[i].Task, target)
+	}
+	return previews, nil
+}
+
+// CommitTask sends a task from PreviewMessage, possibly edited by the user.
+// input is the text the task was previewed from, and is what the outbox and
+// history keep. Called from frontend
+func (ts *TaskService) CommitTask(input string, task TaskInformation) TaskResult {
+	// The previewed task carries the clipboard it was captured with.
+	ts.ClearClipboardContext()
+
+	loc, zone := c.AppConfig.Location()
+	now := time.Now().In(loc)
+
+	if strings.TrimSpace(input) == "" {
+		input = task.Title
+	}
+	task, err := validateEditedTask(task, now)
+	if err != nil {
+		return TaskResult{Input: input, Title: task.Title, PageResult: PageResult{Category: ErrorValidation, Error: err.Error()}}
+	}
+	task = withTimeZone(task, zone, now)
+
+	entries, err := ts.outbox.enqueue([]string{input}, []TaskInformation{task}, "")
+	if err != nil {
+		log.Println("CommitTask: failed to journal task, sending directly:", err)
+		submitted := time.Now()
+		result := ts.commitTask(ts.ctx, input, task)
+		ts.recordHistory(fmt.Sprintf("%d-direct-0", submitted.UnixNano()), input, submitted, task, result)
+		return result
+	}
+	return ts.outbox.deliver(entries)[0]
+}
+
+// propertyMapping lists the properties buildNotionPagePayload would write for
+// task, keyed by task field.
+func propertyMapping(task TaskInformation, target *pageTarget) map[string]string {
+	payload := buildNotionPagePayload(task, target.dataSourceID, target.titleProp, target.dateProp, target.fields)
+	written := payload["properties"].(map[string]any)
+
+	candidates := map[string]*PropertyObj{
+		"priority":         target.fields.Priority,
+		"tags":             target.fields.Tags,
+		"project":          target.fields.Project,
+		"notes":            target.fields.Notes,
+		"estimate_minutes": target.fields.Estimate,
+		"recurrence":       target.fields.Recurrence,
+		"people":           target.fields.People,
+		"clipboard":        target.fields.URL,
+	}
+
+	mapping := map[string]string{"title": target.titleProp}
+
+	if _, ok := written[target.dateProp]; ok && target.dateProp != "" {
+		mapping["date"] = target.dateProp
+	}
+	for field, prop := range candidates {
+		if prop == nil {
+			continue
+		}
+		if _, ok := written[prop.Name]; ok {
+			mapping[field] = prop.Name
+		}
+	}
+	for field, value := range map[string]string{"notes": task.Notes, "clipboard": task.Clipboard} {
+		if _, mapped := mapping[field]; value != "" && !mapped {
+			mapping[field] = "page body"
+		}
+	}
+	return mapping
+}
+
+// validateEditedTask checks a task the user edited after a preview. Unlike
+// validateParsedTask it trusts the user with past dates.
+func validateEditedTask(task TaskInformation, now time.Time) (TaskInformation, error) {
+	task.Title = strings.TrimSpace(task.Title)
+	if task.Title == "" {
+		return task, errors.New("title is required")
+	}
+	task = normalizeTaskFields(task)
+
+	loc := now.Location()
+	if task.TimeZone != "" {
+		zone, err := time.LoadLocation(task.TimeZone)
+		if err != nil {
+			return task, fmt.Errorf("unknown time zone %q", task.TimeZone)
+		}
+		loc = zone
+	}
+
+	start, ok := normalizeTaskDate(task.Date, loc)
+	if !ok {
+		return task, fmt.Errorf("invalid date %q", *task.Date)
+	}
+	end, ok := normalizeTaskDate(task.EndDate, loc)
+	if !ok {
+		return task, fmt.Errorf("invalid end date %q", *task.EndDate)
+	}
+	task.Date, task.EndDate = start.value, end.value
+
+	switch {
+	case task.EndDate == nil:
+	case task.Date == nil:
+		task.EndDate = nil
+	case start.timed != end.timed:
+		return task, errors.New("start and end must both be dates or both include a time")
+	case end.time.Before(start.time):
+		return task, errors.New("end is before start")
+	}
+
+	if task.Recurrence != "" {
+		if _, err := recurrence.Parse(task.Recurrence); err != nil {
+			return task, fmt.Errorf("invalid recurrence: %w", err)
+		}
+		recurring, err := applyRecurrence(task, start, end, now)
+		if err != nil {
+			return task, err
+		}
+		task = recurring
+	}
+	return task, nil
+}
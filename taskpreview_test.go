@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	c "github.com/imjamesonzeller/tasklight-v3/config"
+	"github.com/imjamesonzeller/tasklight-v3/settingsservice"
+)
+
+func TestPropertyMapping(t *testing.T) {
+	t.Parallel()
+
+	detail := &NotionDataSourceDetail{Properties: map[string]PropertyObj{
+		"Name":   {Name: "Name", Type: "title"},
+		"Due":    {Name: "Due", Type: "date"},
+		"Labels": {Name: "Labels", Type: "multi_select"},
+		"Notes":  {Name: "Notes", Type: "rich_text"},
+	}}
+	target := &pageTarget{
+		dataSourceID: "ds-1",
+		dataSource:   detail,
+		titleProp:    "Name",
+		dateProp:     "Due",
+		fields:       detectTaskFieldProperties(detail, ""),
+	}
+
+	got := propertyMapping(TaskInformation{Title: "Buy milk", Date: ptr("2025-10-16"), Tags: []string{"errands"}, Priority: "high"}, target)
+
+	want := map[string]string{"title": "Name", "date": "Due", "tags": "Labels"}
+	if len(got) != len(want) {
+		t.Fatalf("unexpected mapping: %v", got)
+	}
+	for field, prop := range want {
+		if got[field] != prop {
+			t.Errorf("%s: got %q, want %q", field, got[field], prop)
+		}
+	}
+}
+
+func TestValidateEditedTask(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, time.October, 15, 9, 0, 0, 0, time.UTC)
+
+	got, err := validateEditedTask(TaskInformation{Title: "  Pay rent ", Date: ptr("2025-10-01"), Tags: []string{"#bills"}}, now)
+	if err != nil {
+		t.Fatalf("past dates chosen by the user are allowed: %v", err)
+	}
+	if got.Title != "Pay rent" || *got.Date != "2025-10-01" || got.Tags[0] != "bills" {
+		t.Fatalf("unexpected task: %+v", got)
+	}
+
+	timed, err := validateEditedTask(TaskInformation{Title: "Call", Date: ptr("2025-10-16T15:00")}, now)
+	if err != nil || *timed.Date != "2025-10-16T15:00:00" {
+		t.Fatalf("expected a normalised date-time, got %+v (err %v)", timed, err)
+	}
+
+	rejects := []TaskInformation{
+		{Title: " "},
+		{Title: "Trip", Date: ptr("next week")},
+		{Title: "Trip", Date: ptr("2025-10-20"), EndDate: ptr("2025-10-18")},
+		{Title: "Trip", Date: ptr("2025-10-20"), EndDate: ptr("2025-10-21T10:00")},
+		{Title: "Trip", TimeZone: "Nowhere/Special"},
+		{Title: "Trip", Recurrence: "FREQ=HOURLY"},
+	}
+	for _, task := range rejects {
+		if _, err := validateEditedTask(task, now); err == nil {
+			t.Errorf("expected %+v to be rejected", task)
+		}
+	}
+}
+
+func TestPreviewMessageWritesNothing(t *testing.T) {
+	t.Setenv("TASKLIGHT_SKIP_KEYCHAIN", "1")
+
+	originalConfig := c.AppConfig
+	originalUser := c.GetCurrentUserId()
+	t.Cleanup(func() {
+		c.AppConfig = originalConfig
+		c.SetCurrentUserId(originalUser)
+	})
+	settings := &settingsservice.SettingsService{}
+	c.AppConfig = &settings.AppSettings
+	c.SetCurrentUserId("")
+
+	ts := NewTaskService(nil, settings)
+	ts.createPage = func(context.Context, TaskInformation) PageResult {
+		t.Fatal("preview must not create pages")
+		return PageResult{}
+	}
+
+	previews, err := ts.PreviewMessage("buy milk #errands; call mom tomorrow")
+	if err != nil {
+		t.Fatalf("PreviewMessage: %v", err)
+	}
+	if len(previews) != 2 || previews[0].Task.Title != "buy milk" || previews[1].Task.Title != "call mom" {
+		t.Fatalf("unexpected previews: %+v", previews)
+	}
+	if !strings.Contains(previews[0].Error, "Notion token unavailable") {
+		t.Fatalf("expected the missing destination to be reported, got %q", previews[0].Error)
+	}
+
+	if _, err := ts.PreviewMessage(" ; "); err == nil {
+		t.Fatal("expected an error for empty input")
+	}
+}
+
+func TestCommitTask(t *testing.T) {
+	originalConfig := c.AppConfig
+	t.Cleanup(func() { c.AppConfig = originalConfig })
+	c.AppConfig = &settingsservice.ApplicationSettings{TimeZone: "Europe/Berlin"}
+
+	var sent []TaskInformation
+	page := PageResult{PageID: "page-1"}
+	ts := NewTaskService(nil, nil)
+	outbox := NewOutboxService(ts, nil)
+	outbox.path = filepath.Join(t.TempDir(), outboxFileName)
+	ts.SetOutbox(outbox)
+	ts.createPage = func(_ context.Context, task TaskInformation) PageResult {
+		sent = append(sent, task)
+		return page
+	}
+
+	const input = "call sam friday 3pm"
+	result := ts.CommitTask(input, TaskInformation{Title: "Call Sam", Date: ptr("2030-01-04T15:00")})
+	if !result.OK || len(sent) != 1 || result.Input != input {
+		t.Fatalf("expected the task to be sent, got %+v", result)
+	}
+	if *sent[0].Date != "2030-01-04T15:00:00" || sent[0].TimeZone != "Europe/Berlin" {
+		t.Fatalf("unexpected task sent: %+v", sent[0])
+	}
+
+	result = ts.CommitTask(input, TaskInformation{Title: ""})
+	if result.OK || result.Error == "" || len(sent) != 1 {
+		t.Fatalf("invalid edits must not be sent, got %+v", result)
+	}
+
+	// The outbox keeps what the user typed, not the edited title.
+	page = PageResult{Category: ErrorNetwork, Error: "notion api error: status 503, body: "}
+	ts.CommitTask(input, TaskInformation{Title: "Call Sam"})
+	if queue, _ := outbox.GetQueue(); len(queue) != 1 || queue[0].Input != input || queue[0].Title != "Call Sam" {
+		t.Fatalf("expected the input journalled, got %+v", queue)
+	}
+}
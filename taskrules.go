@@ -0,0 +1,134 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	c "github.com/imjamesonzeller/tasklight-v3/config"
+	"github.com/imjamesonzeller/tasklight-v3/rules"
+)
+
+// RuleDryRun shows what the capture rules do to one task.
+type RuleDryRun struct {
+	Input  string          `json:"input"`
+	Before TaskInformation `json:"before"`
+	After  TaskInformation `json:"after"`
+	Fired  []rules.Fired   `json:"fired"`
+}
+
+// DryRunRules parses message and applies the capture rules without sending
+// anything, reporting which rules fired for each task. Called from frontend
+func (ts *TaskService) DryRunRules(message string) ([]RuleDryRun, error) {
+	inputs := splitTasks(message)
+	if len(inputs) == 0 {
+		return nil, errors.New("nothing to try the rules on")
+	}
+	set, err := ts.settings.Rules()
+	if err != nil {
+		return nil, err
+	}
+
+	loc, zone := c.AppConfig.Location()
+	submitted := time.Now()
+	now := submitted.In(loc)
+	runs := make([]RuleDryRun, len(inputs))
+	runPool(len(inputs), func(i int) {
+		before, _ := ts.parseWithoutRules(ts.ctx, inputs[i], submitted)
+		after := before
+		fired := applyRuleSet(set, &after, now)
+		runs[i] = RuleDryRun{Input: inputs[i], Before: before, After: withTimeZone(after, zone, now), Fired: fired}
+	})
+	return runs, nil
+}
+
+// applyRules runs the capture rules on a parsed task. A rule file that does
+// not validate is skipped so captures keep working.
+func (ts *TaskService) applyRules(task TaskInformation, now time.Time) TaskInformation {
+	if ts.settings == nil {
+		return task
+	}
+	set, err := ts.settings.Rules()
+	if err != nil {
+		log.Println("applyRules: capture rules not applied:", err)
+		return task
+	}
+	for _, f := range applyRuleSet(set, &task, now) {
+		log.Printf("applyRules: %q fired for %q: %s", f.Rule, task.Title, strings.Join(f.Actions, "; "))
+	}
+	return task
+}
+
+// applyRuleSet applies set to task as captured at now.
+func applyRuleSet(set *rules.Set, task *TaskInformation, now time.Time) []rules.Fired {
+	fired := set.Apply(ruleTask{task: task, now: now}, now)
+	if len(fired) > 0 {
+		*task = normalizeTaskFields(*task)
+	}
+	return fired
+}
+
+// ruleTask lets capture rules read and change a task.
+type ruleTask struct {
+	task *TaskInformation
+	now  time.Time
+}
+
+func (r ruleTask) Get(field string) string {
+	t := r.task
+	switch field {
+	case "title":
+		return t.Title
+	case "notes":
+		return t.Notes
+	case "project":
+		return t.Project
+	case "priority":
+		return t.Priority
+	case "date":
+		if t.Date != nil {
+			return *t.Date
+		}
+	case "tags":
+		return strings.Join(t.Tags, ", ")
+	case "people":
+		return strings.Join(t.People, ", ")
+	case "recurrence":
+		return t.Recurrence
+	case "estimate":
+		if t.EstimateMinutes > 0 {
+			return strconv.Itoa(t.EstimateMinutes)
+		}
+	}
+	return ""
+}
+
+func (r ruleTask) Set(field, value string) {
+	switch field {
+	case "title":
+		if value = strings.TrimSpace(value); value != "" {
+			r.task.Title = value
+		}
+	case "notes":
+		r.task.Notes = value
+	case "project":
+		r.task.Project = value
+	case "priority":
+		r.task.Priority = value
+	}
+}
+
+func (r ruleTask) AddTag(tag string) {
+	r.task.Tags = append(r.task.Tags, tag)
+}
+
+func (r ruleTask) SetDate(phrase string) {
+	due, ok := rules.ParseDate(phrase, dateOptions(r.now))
+	if !ok {
+		log.Printf("applyRules: ignoring unrecognised date %q", phrase)
+		return
+	}
+	*r.task = withDueDate(*r.task, due, r.now.Location())
+}
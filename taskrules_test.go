@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	c "github.com/imjamesonzeller/tasklight-v3/config"
+	"github.com/imjamesonzeller/tasklight-v3/settingsservice"
+	"github.com/imjamesonzeller/tasklight-v3/startupservice"
+)
+
+// newRulesTaskService returns a task service whose settings directory holds
+// rulesJSON. Parsing runs locally because no user is signed in.
+func newRulesTaskService(t *testing.T, rulesJSON string) *TaskService {
+	t.Helper()
+	t.Setenv("TASKLIGHT_SKIP_KEYCHAIN", "1")
+	dir := t.TempDir()
+	t.Setenv("TASKLIGHT_SETTINGS_PATH", filepath.Join(dir, "settings.json"))
+	if err := os.WriteFile(filepath.Join(dir, "rules.json"), []byte(rulesJSON), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	originalConfig := c.AppConfig
+	originalUser := c.GetCurrentUserId()
+	t.Cleanup(func() {
+		c.AppConfig = originalConfig
+		c.SetCurrentUserId(originalUser)
+	})
+	settings := settingsservice.NewSettingsService(startupservice.NewStartupService())
+	settings.AppSettings.TimeZone = "UTC"
+	c.AppConfig = &settings.AppSettings
+	c.SetCurrentUserId("")
+
+	return NewTaskService(nil, settings)
+}
+
+func TestParseSubmissionAppliesRules(t *testing.T) {
+	ts := newRulesTaskService(t, `{"rules": [
+		{"name": "Invoices", "when": {"keywords": ["invoice"]},
+		 "then": [{"add_tag": "Finance"}, {"set": {"field": "priority", "value": "High"}}]},
+		{"name": "Evening", "when": {"captured_after": "18:00", "missing": ["date"]},
+		 "then": [{"set_date": "tomorrow"}]}
+	]}`)
+
+	evening := time.Date(2025, time.October, 15, 19, 0, 0, 0, time.UTC)
+	task, _ := ts.parseSubmission(context.Background(), "pay invoice #work", evening)
+
+	if task.Priority != "high" || len(task.Tags) != 2 || task.Tags[0] != "work" || task.Tags[1] != "Finance" {
+		t.Fatalf("unexpected fields: %+v", task)
+	}
+	if task.Date == nil || *task.Date != "2025-10-16" {
+		t.Fatalf("expected the evening rule to move the task to tomorrow, got %v", task.Date)
+	}
+
+	morning := time.Date(2025, time.October, 15, 9, 0, 0, 0, time.UTC)
+	if task, _ := ts.parseSubmission(context.Background(), "pay rent", morning); task.Date != nil || len(task.Tags) != 0 {
+		t.Fatalf("no rule should fire, got %+v", task)
+	}
+}
+
+func TestParseSubmissionIgnoresInvalidRules(t *testing.T) {
+	ts := newRulesTaskService(t, `{"rules": [{"when": {"regex": "("}, "then": [{"case": "upper"}]}]}`)
+
+	task, _ := ts.parseSubmission(context.Background(), "pay rent", time.Now())
+	if task.Title != "pay rent" {
+		t.Fatalf("captures must keep working with a broken rule file, got %+v", task)
+	}
+	if _, err := ts.DryRunRules("pay rent"); err == nil {
+		t.Fatal("expected the dry run to report the broken rule file")
+	}
+}
+
+func TestDryRunRules(t *testing.T) {
+	ts := newRulesTaskService(t, `{"rules": [
+		{"name": "Expand PR", "when": {"regex": "\\bPR\\b"},
+		 "then": [{"replace": {"pattern": "\\bPR\\b", "with": "pull request"}}]},
+		{"name": "Sentence case", "then": [{"case": "sentence"}]}
+	]}`)
+
+	runs, err := ts.DryRunRules("review PR; Call Mom")
+	if err != nil {
+		t.Fatalf("DryRunRules: %v", err)
+	}
+	if len(runs) != 2 {
+		t.Fatalf("expected two runs, got %+v", runs)
+	}
+	if runs[0].Before.Title != "review PR" || runs[0].After.Title != "Review pull request" || len(runs[0].Fired) != 2 {
+		t.Fatalf("unexpected first run: %+v", runs[0])
+	}
+	if runs[1].After.Title != "Call mom" || len(runs[1].Fired) != 1 || runs[1].Fired[0].Rule != "Sentence case" {
+		t.Fatalf("unexpected second run: %+v", runs[1])
+	}
+}
@@ -4,147 +4,624 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	c "github.com/imjamesonzeller/tasklight-v3/config"
+	"github.com/imjamesonzeller/tasklight-v3/dateparse"
+	"github.com/imjamesonzeller/tasklight-v3/llm"
 	"github.com/imjamesonzeller/tasklight-v3/notionapi"
+	"github.com/imjamesonzeller/tasklight-v3/notionblocks"
+	"github.com/imjamesonzeller/tasklight-v3/quicksyntax"
+	"github.com/imjamesonzeller/tasklight-v3/recurrence"
 	"github.com/imjamesonzeller/tasklight-v3/settingsservice"
-	"github.com/openai/openai-go/option"
 	"io"
 	"log"
 	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
-	"github.com/openai/openai-go"
 	"github.com/wailsapp/wails/v3/pkg/application"
 )
 
+// TaskInformation is a parsed task. Date and EndDate are either all-day
+// dates (YYYY-MM-DD) or wall-clock date-times (YYYY-MM-DDTHH:MM:SS) in
+// TimeZone.
 type TaskInformation struct {
-	Title string  `json:"title"`
-	Date  *string `json:"date"`
+	Title           string   `json:"title"`
+	Date            *string  `json:"date"`
+	EndDate         *string  `json:"end_date,omitempty"`
+	TimeZone        string   `json:"time_zone,omitempty"`
+	Recurrence      string   `json:"recurrence,omitempty"`
+	People          []string `json:"people,omitempty"`
+	Priority        string   `json:"priority,omitempty"`
+	Tags            []string `json:"tags,omitempty"`
+	Project         string   `json:"project,omitempty"`
+	Notes           string   `json:"notes,omitempty"`
+	EstimateMinutes int      `json:"estimate_minutes,omitempty"`
+	// Clipboard is the text copied when the capture was started from the
+	// clipboard hotkey. A link goes to the URL property, anything else to
+	// the page body.
+	Clipboard string `json:"clipboard,omitempty"`
+	// Destination names the destination the task was routed to; empty is
+	// the default destination. RoutedBy says why, e.g. `prefix "w:"`.
+	Destination string `json:"destination,omitempty"`
+	RoutedBy    string `json:"routed_by,omitempty"`
 }
 
 type TaskService struct {
 	app           *application.App
 	windowService *WindowService
 	settings      *settingsservice.SettingsService
+	recurrence    *RecurrenceService
+	outbox        *OutboxService
+	history       *HistoryService
+	schemas       *SchemaCacheService
+
+	// ctx is cancelled when the app quits, abandoning in-flight requests.
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// createPage creates a Notion page for a task.
+	createPage func(context.Context, TaskInformation) PageResult
+	// trashPage moves a Notion page to the trash.
+	trashPage func(ctx context.Context, pageID string) error
+
+	undoMu  sync.Mutex
+	created []createdPage
+
+	// clipboard is attached to the next capture; see attachClipboard.
+	clipMu    sync.Mutex
+	clipboard string
+
+	// users caches workspaceUsers and me connectedUser.
+	usersMu      sync.Mutex
+	users        map[string]string
+	usersFetched time.Time
+	me           string
+
+	// projects caches projectPages by related data source.
+	projectsMu sync.Mutex
+	projects   map[string]projectList
 }
 
 func NewTaskService(windowService *WindowService, settings *settingsservice.SettingsService) *TaskService {
-	return &TaskService{
+	ts := &TaskService{
 		windowService: windowService,
 		settings:      settings,
 	}
+	ts.ctx, ts.cancel = context.WithCancel(context.Background())
+	ts.createPage = ts.captureNotionPage
+	ts.trashPage = ts.trashNotionPage
+	return ts
+}
+
+// Shutdown cancels every parse and Notion request still in flight.
+func (ts *TaskService) Shutdown() {
+	ts.cancel()
 }
 
 func (ts *TaskService) SetApp(app *application.App) {
 	ts.app = app
 }
 
+func (ts *TaskService) SetRecurrenceService(rs *RecurrenceService) {
+	ts.recurrence = rs
+}
+
+func (ts *TaskService) SetOutbox(o *OutboxService) {
+	ts.outbox = o
+}
+
+func (ts *TaskService) SetHistory(h *HistoryService) {
+	ts.history = h
+}
+
+func (ts *TaskService) SetSchemaCache(sc *SchemaCacheService) {
+	ts.schemas = sc
+}
+
+// TaskResult reports the outcome of one task from a submission.
+type TaskResult struct {
+	Input string `json:"input"`
+	Title string `json:"title"`
+	OK    bool   `json:"ok"`
+	// Queued is set when a failed task was kept in the outbox, and Parked
+	// when it waits there for the user to retry or discard it instead of
+	// being retried.
+	Queued bool `json:"queued,omitempty"`
+	Parked bool `json:"parked,omitempty"`
+	PageResult
+}
+
+// TaskCreated is the payload of the Backend:TaskCreated event.
+type TaskCreated struct {
+	Title       string `json:"title"`
+	PageID      string `json:"page_id"`
+	URL         string `json:"url"`
+	Destination string `json:"destination,omitempty"`
+}
+
+// maxConcurrentPages bounds the Notion pages created at once; Notion allows
+// about three requests per second per integration.
+const maxConcurrentPages = 3
+
+// taskSeparatorRe splits one line of a submission into several tasks.
+var taskSeparatorRe = regexp.MustCompile(`(?i)\s*(?:;|\band then\b)\s*`)
+
 // ProcessMessage Called from frontend
 func (ts *TaskService) ProcessMessage(message string) {
 	ts.windowService.Hide("main")
 
+	// The submission is journalled before anything touches the network, so
+	// it survives Notion being down or the app quitting.
+	inputs := splitTasks(message)
+	clipboard := ts.takeClipboard()
+	entries, err := ts.outbox.enqueue(inputs, nil, clipboard)
+	if err != nil {
+		log.Println("ProcessMessage: failed to journal submission, sending directly:", err)
+	}
+
 	go func() {
-		task := ts.ProcessedThroughAI(message)
-		status := ts.SendToNotion(task)
+		var results []TaskResult
+		if err == nil {
+			results = ts.outbox.deliver(entries)
+		} else {
+			results = ts.processTasks(ts.ctx, inputs, clipboard)
+		}
+		ts.app.EmitEvent("Backend:TaskReport", results)
 
-		if status != "200 OK" {
-			ts.app.EmitEvent("Backend:ErrorEvent", status)
-			ts.windowService.Show("main")
+		for _, result := range results {
+			if !result.OK {
+				ts.windowService.Show("main")
+				break
+			}
 		}
 	}()
 }
 
+// splitTasks breaks a submission on newlines, semicolons and "and then".
+// List items after a task line and fenced code are notes: they stay with the
+// task before them, separators and all. A list with no task line before it
+// is a list of tasks, one per item.
+func splitTasks(message string) []string {
+	var tasks []string
+	inFence := false
+	// hasNotes reports whether the last task takes list items as notes.
+	hasNotes := false
+	for _, line := range strings.Split(strings.ReplaceAll(message, "\r\n", "\n"), "\n") {
+		fence := notionblocks.IsFence(line)
+		listItem := notionblocks.IsListItem(line)
+		switch {
+		case len(tasks) > 0 && (inFence || fence || listItem && hasNotes):
+			tasks[len(tasks)-1] += "\n" + strings.TrimRight(line, " \t")
+		case fence:
+			// Code with no task before it is a task of its own.
+			tasks = append(tasks, strings.TrimSpace(line))
+			hasNotes = true
+		default:
+			for _, part := range taskSeparatorRe.Split(notionblocks.ListItemText(line), -1) {
+				if part = strings.TrimSpace(part); part != "" {
+					tasks = append(tasks, part)
+					hasNotes = !listItem
+				}
+			}
+		}
+		if fence {
+			inFence = !inFence
+		}
+	}
+	return tasks
+}
+
+// processTasks parses and creates each task on a bounded pool of workers,
+// attaching clipboard to every task. Results keep the order of inputs.
+func (ts *TaskService) processTasks(ctx context.Context, inputs []string, clipboard string) []TaskResult {
+	submitted := time.Now()
+	results := make([]TaskResult, len(inputs))
+	runPool(len(inputs), func(i int) {
+		task, _ := ts.parseSubmission(ctx, inputs[i], submitted)
+		task.Clipboard = clipboard
+		results[i] = ts.commitTask(ctx, inputs[i], task)
+		ts.recordHistory(fmt.Sprintf("%d-direct-%d", submitted.UnixNano(), i), inputs[i], submitted, task, results[i])
+	})
+	return results
+}
+
+// recordHistory adds the outcome of one submission to the local history.
+func (ts *TaskService) recordHistory(id, input string, submitted time.Time, task TaskInformation, result TaskResult) {
+	if ts.history == nil {
+		return
+	}
+	err := ts.history.Record(HistoryEntry{
+		ID:           id,
+		Input:        input,
+		Task:         &task,
+		DataSourceID: result.DataSourceID,
+		OK:           result.OK,
+		Queued:       result.Queued,
+		Category:     result.Category,
+		Error:        result.Error,
+		PageID:       result.PageID,
+		URL:          result.URL,
+		SubmittedAt:  submitted,
+	})
+	if err != nil {
+		log.Println("History: failed to record submission:", err)
+	}
+}
+
+// runPool calls fn for every index below n on at most maxConcurrentPages
+// goroutines and waits for them to finish.
+func runPool(n int, fn func(i int)) {
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < min(maxConcurrentPages, n); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				fn(i)
+			}
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// parseStage bounds the parse stage of a capture by the configured timeout.
+func parseStage(ctx context.Context) (context.Context, context.CancelFunc) {
+	timeout := settingsservice.ApplicationSettings{}.ParseTimeout()
+	if c.AppConfig != nil {
+		timeout = c.AppConfig.ParseTimeout()
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// notionStage bounds a call to Notion by the configured timeout.
+func notionStage(ctx context.Context) (context.Context, context.CancelFunc) {
+	timeout := settingsservice.ApplicationSettings{}.NotionTimeout()
+	if c.AppConfig != nil {
+		timeout = c.AppConfig.NotionTimeout()
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// commitTask creates the page for a parsed task and registers it when it
+// repeats.
+func (ts *TaskService) commitTask(ctx context.Context, input string, task TaskInformation) TaskResult {
+	page := ts.createPage(ctx, task)
+	result := TaskResult{Input: input, Title: task.Title, OK: page.OK(), PageResult: page}
+	if !page.Created() {
+		// A skipped or updated duplicate belongs to an earlier capture.
+		return result
+	}
+
+	ts.recordCreated(page.PageID, task.Title, page.CreatedProject)
+	if ts.app != nil {
+		ts.app.EmitEvent("Backend:TaskCreated", TaskCreated{Title: task.Title, PageID: page.PageID, URL: page.URL, Destination: task.Destination})
+	}
+	if task.Recurrence != "" && ts.recurrence != nil {
+		if err := ts.recurrence.Register(task, page.PageID); err != nil {
+			log.Println("ProcessMessage: failed to register recurring task:", err)
+		}
+	}
+	return result
+}
+
 // --- Internals ---
 
 func (ts *TaskService) ProcessedThroughAI(input string) TaskInformation {
-	key, userProvided := ts.selectOpenAIKey()
+	task, _ := ts.parseSubmission(ts.ctx, input, time.Now())
+	return task
+}
+
+// parseSubmission parses input as if it were typed at submitted, so relative
+// dates in a retried submission keep the meaning they had when typed, and
+// applies the capture rules. It reports whether the local parser stood in for
+// a model or server that failed.
+func (ts *TaskService) parseSubmission(ctx context.Context, input string, submitted time.Time) (TaskInformation, bool) {
+	loc, zone := c.AppConfig.Location()
+	now := submitted.In(loc)
+
+	// The routing prefix is not part of the task.
+	route := routeInput(input, c.AppConfig.Destinations)
+	parsed, fallback := ts.parseWithoutRules(ctx, route.input, submitted)
+	task := ts.applyRules(parsed, now)
+	task.Destination, task.RoutedBy = route.destination, route.reason
+	// A date set by a rule may carry a time of day.
+	return withTimeZone(task, zone, now), fallback
+}
+
+func (ts *TaskService) parseWithoutRules(ctx context.Context, input string, submitted time.Time) (TaskInformation, bool) {
+	loc, zone := c.AppConfig.Location()
+	now := submitted.In(loc)
+
+	// The lines splitTasks kept with the task, and checklist items typed
+	// after it, are notes as written; only the task itself is parsed.
+	line, body, _ := strings.Cut(input, "\n")
+	if before, items, ok := notionblocks.CutInlineItems(line); ok && before != "" {
+		line, body = before, items+"\n"+body
+	}
+
+	// Inline tokens are read here so the model never sees them and cannot
+	// override them.
+	tokens := quicksyntax.Parse(line, quickSyntaxPrefixes())
+	text := tokens.Rest
+	if text == "" {
+		text = line
+	}
+
+	parsed, fallback := ts.parseTask(ctx, text, now, zone)
+	task := applyQuickSyntax(parsed, tokens, now)
+	if body = strings.Trim(body, "\r\n"); strings.TrimSpace(body) != "" {
+		task.Notes = strings.TrimSpace(task.Notes + "\n\n" + body)
+	}
+	return withTimeZone(task, zone, now), fallback
+}
+
+// parseTask reads a task from input within the parse timeout, falling back to
+// the local parser when the model or server fails or is too slow. It reports
+// whether it fell back.
+func (ts *TaskService) parseTask(ctx context.Context, input string, now time.Time, zone string) (TaskInformation, bool) {
+	ctx, cancel := parseStage(ctx)
+	defer cancel()
+
+	provider, userProvided := ts.selectProvider()
 	if userProvided {
-		prompt := buildParsePrompt(input)
-		task, err := ts.callOpenAI(key, prompt)
+		prompt := buildParsePrompt(input, now, zone, currentLocale())
+		task, err := ts.callProvider(ctx, provider, prompt)
+		if err == nil {
+			task, err = validateParsedTask(task, input, now)
+		}
 		if err != nil {
-			log.Println("ProcessedThroughAI: AI call failed:", err)
-			return TaskInformation{Title: input, Date: nil}
+			log.Println("ProcessedThroughAI: AI call failed, using local parser:", err)
+			return parseLocally(input, now), true
 		}
-		return task
+		return task, false
 	}
 
 	// No user key: call server to parse (server owns key, checks & increments usage)
-	task, err := ts.callServerParse(input)
+	task, err := ts.callServerParse(ctx, input, now, zone, currentLocale())
+	if err == nil {
+		task, err = validateParsedTask(task, input, now)
+	}
 	if err != nil {
-		log.Println("ProcessedThroughAI: server parse failed:", err)
-		return TaskInformation{Title: input, Date: nil}
+		log.Println("ProcessedThroughAI: server parse failed, using local parser:", err)
+		return parseLocally(input, now), true
+	}
+	return task, false
+}
+
+// withTimeZone gives timed tasks an explicit zone. When the zone has no IANA
+// name the times are written with a UTC offset instead.
+func withTimeZone(task TaskInformation, zone string, now time.Time) TaskInformation {
+	if task.TimeZone != "" || !isDateTime(task.Date) {
+		return task
+	}
+	if zone != "" {
+		task.TimeZone = zone
+		return task
+	}
+
+	for _, date := range []*string{task.Date, task.EndDate} {
+		if date == nil {
+			continue
+		}
+		if t, err := time.ParseInLocation(dateTimeLayout, *date, now.Location()); err == nil {
+			*date = t.Format(time.RFC3339)
+		}
 	}
 	return task
 }
 
-// selectOpenAIKey decides which key to use and returns (key, userProvided)
-func (ts *TaskService) selectOpenAIKey() (string, bool) {
+// currentLocale returns the configured input locale.
+func currentLocale() settingsservice.Locale {
+	if c.AppConfig == nil {
+		return settingsservice.ApplicationSettings{}.Locale()
+	}
+	return c.AppConfig.Locale()
+}
+
+// dateOptions resolves local date phrases with the configured locale.
+func dateOptions(now time.Time) dateparse.Options {
+	locale := currentLocale()
+	return dateparse.Options{
+		Now:       now,
+		Language:  locale.Language,
+		Order:     dateparse.DateOrder(locale.DateOrder),
+		WeekStart: &locale.WeekStart,
+	}
+}
+
+// parseLocally is the rule-based fallback used when neither the user's key nor
+// the Tasklight server can parse the input.
+func parseLocally(input string, now time.Time) TaskInformation {
+	tokens := quicksyntax.Parse(input, quickSyntaxPrefixes())
+	rest, task := extractLocalFields(tokens.Rest)
+	var rule *recurrence.Rule
+	if m, ok := recurrence.Extract(rest); ok {
+		rest, rule = m.Rest, &m.Rule
+	}
+	result := dateparse.Parse(rest, dateOptions(now))
+
+	task.Title = result.Title
+	if task.Title == "" {
+		task.Title = strings.TrimSpace(input)
+	}
+
+	layout := dateLayout
+	if result.HasTime {
+		layout = dateTimeLayout
+		task.TimeZone = result.TimeZone
+	}
+	if result.Date != nil {
+		date := result.Date.Format(layout)
+		task.Date = &date
+	}
+	if result.End != nil {
+		end := result.End.Format(layout)
+		task.EndDate = &end
+	}
+
+	task = applyQuickSyntax(task, tokens, now)
+
+	if rule != nil {
+		task.Recurrence = rule.String()
+		start, _ := normalizeTaskDate(task.Date, now.Location())
+		end, _ := normalizeTaskDate(task.EndDate, now.Location())
+		if recurring, err := applyRecurrence(task, start, end, now); err == nil {
+			task = recurring
+		}
+	}
+	return task
+}
+
+// selectProvider builds the user's configured parser provider and returns (provider, userProvided)
+func (ts *TaskService) selectProvider() (llm.Provider, bool) {
 	if !c.AppConfig.UseOpenAI {
-		return "", false
+		return nil, false
 	}
 
-	key, err := ts.settings.GetOpenAIKey()
-	if err != nil {
-		log.Println("selectOpenAIKey: failed to load OpenAI key:", err)
-		return "", false
+	kind, cfg := ts.settings.ActiveParserProvider()
+
+	key, err := ts.settings.GetProviderAPIKey(cfg.APIKeyRef)
+	if err != nil && llm.RequiresAPIKey(kind) {
+		log.Printf("selectProvider: failed to load %s API key: %v", kind, err)
+		return nil, false
 	}
-	if key == "" {
-		return "", false
+
+	provider, err := llm.New(llm.Config{
+		Kind:       kind,
+		BaseURL:    cfg.BaseURL,
+		Model:      cfg.Model,
+		APIKey:     key,
+		APIVersion: cfg.APIVersion,
+		Timeout:    time.Duration(cfg.TimeoutSeconds) * time.Second,
+	})
+	if err != nil {
+		log.Println("selectProvider:", err)
+		return nil, false
 	}
 
-	return key, true
+	return provider, true
+}
+
+// languageNames names common input languages in the prompt.
+var languageNames = map[string]string{
+	"en": "English", "de": "German", "fr": "French", "es": "Spanish", "it": "Italian",
+	"nl": "Dutch", "pt": "Portuguese", "sv": "Swedish", "da": "Danish", "pl": "Polish",
+}
+
+// dateOrderExamples shows the model how to read an ambiguous numeric date.
+var dateOrderExamples = map[string]string{
+	"MDY": `"03/04" is March 4`,
+	"DMY": `"03/04" is 3 April`,
+	"YMD": `"2026/03/04" is March 4, 2026`,
 }
 
 // buildParsePrompt returns the exact prompt text for parsing
-func buildParsePrompt(input string) string {
-	today := time.Now().Format("2006-01-02") // ISO 8601
-	weekday := time.Now().Weekday().String()
+func buildParsePrompt(input string, now time.Time, zone string, locale settingsservice.Locale) string {
+	today := now.Format("2006-01-02") // ISO 8601
+	weekday := now.Weekday().String()
+	clock := now.Format("15:04")
+	if zone == "" {
+		zone = now.Format("-07:00")
+	}
+
+	base, _, _ := strings.Cut(strings.ToLower(locale.Language), "-")
+	language := languageNames[base]
+	if language == "" {
+		language = locale.Language
+	}
+	example := dateOrderExamples[locale.DateOrder]
+
+	// Resolve the week-relative phrases locally so the model and the
+	// fallback parser agree on them.
+	opts := dateparse.Options{Now: now, WeekStart: &locale.WeekStart}
+	nextWeek := dateparse.Parse("next week", opts).Date.Format(dateLayout)
+	weekend := dateparse.Parse("this weekend", opts).Date.Format(dateLayout)
 	return fmt.Sprintf(`You are a precise and reliable task parsing assistant. 
 						Your job is to convert natural-language task descriptions into clean, structured data.
 			
-						Today's date is %s. Today is a %s.
+						Today's date is %s. Today is a %s. The local time is %s in %s.
+
+						The text is written in %s; keep the title in that language.
+						Numeric dates are written %s (e.g. %s).
+						Weeks start on %s: "next week" begins on %s and "this weekend" begins on %s.
 			
 						When parsing dates:
 						- Always interpret dates as referring to the **next upcoming instance in the future** (never in the past) unless the text clearly says “last” or “previous”.
 						- Correct common spelling mistakes in weekday or month names (e.g., "firday" -> "Friday", "janury" -> "January").
 						- If the intended date is ambiguous, choose the most **reasonable future** date based on context.
 						- Use ISO 8601 format (YYYY-MM-DD) for all dates.
+						- If a time of day is mentioned, use YYYY-MM-DDTHH:MM in local time instead (e.g. "3pm tomorrow" -> tomorrow's date with T15:00).
+						- If a range is mentioned (e.g. "Mon–Wed", "3-5pm"), put its start in "date" and its end in "end_date", in the same format.
+						- If the text names a time zone (e.g. "PST", "Europe/Berlin"), keep the times as written and set "time_zone" to its IANA name (e.g. "America/Los_Angeles").
+						- If the task repeats, set "recurrence" to an RRULE using only FREQ, INTERVAL, BYDAY and BYMONTHDAY (e.g. "every other Tuesday" -> "FREQ=WEEKLY;INTERVAL=2;BYDAY=TU", "on the 1st of each month" -> "FREQ=MONTHLY;BYMONTHDAY=1") and set "date" to the first occurrence.
 			
 						Parse the following sentence: "%s".
 			
-						Ignore phrases like "remind me to", "remind me on", or similar expressions—only focus on the task and the date.
+						Ignore phrases like "remind me to", "remind me on", or similar expressions—only focus on the task and its details.
+			
+						Also extract these details when present, and remove them from the title:
+						- "priority": one of "low", "medium", "high" or "urgent" (e.g. "!high" -> "high", "asap" -> "urgent").
+						- "tags": short labels such as hashtags (e.g. "#errands" -> "errands").
+						- "project": the project the task belongs to (e.g. "for project Alpha" -> "Alpha").
+						- "notes": any extra detail that does not belong in the title, keeping markdown such as "- [ ]" checklists, lists, links, bold and italic as written.
+						- "estimate_minutes": the time estimate in whole minutes (e.g. "~30m" -> 30, "2h" -> 120).
 			
 						Return only a JSON object in this exact format:
-						{ "title": ..., "date": ... }
+						{ "title": ..., "date": ..., "end_date": ..., "time_zone": ..., "recurrence": ..., "priority": ..., "tags": [...], "project": ..., "notes": ..., "estimate_minutes": ... }
 			
-						If no date is mentioned, set "date" to null.`, today, weekday, input)
+						If no date is mentioned, set "date" to null. Set any other missing detail to null, or [] for "tags".`,
+		today, weekday, clock, zone,
+		language, locale.DateOrder, example, locale.WeekStart, nextWeek, weekend,
+		input)
 }
 
-// callOpenAI sends the prompt and parses the returned JSON content into TaskInformation
-func (ts *TaskService) callOpenAI(apiKey string, prompt string) (TaskInformation, error) {
-	client := openai.NewClient(option.WithAPIKey(apiKey))
-	resp, err := client.Chat.Completions.New(context.TODO(), openai.ChatCompletionNewParams{
-		Model:    openai.ChatModelGPT4oMini,
-		Messages: []openai.ChatCompletionMessageParamUnion{openai.UserMessage(prompt)},
-	})
+// callProvider sends the prompt with the task schema and parses the returned JSON content into TaskInformation
+func (ts *TaskService) callProvider(ctx context.Context, provider llm.Provider, prompt string) (TaskInformation, error) {
+	content, err := provider.Complete(ctx, llm.Request{Prompt: prompt, Schema: taskResponseSchema})
 	if err != nil {
-		return TaskInformation{}, err
+		return TaskInformation{}, fmt.Errorf("%s: %w", provider.Name(), err)
 	}
-	if len(resp.Choices) == 0 || resp.Choices[0].Message.Content == "" {
-		return TaskInformation{}, fmt.Errorf("empty AI response")
+
+	task, err := parseTaskFromContent(content)
+	if err != nil {
+		log.Printf("callProvider: %s response rejected (%v): %q", provider.Name(), err, content)
+		return TaskInformation{}, err
 	}
-	return parseTaskFromContent(resp.Choices[0].Message.Content)
+	return task, nil
 }
 
 // callServerParse sends the text to the backend, which handles OpenAI calls and usage accounting
-func (ts *TaskService) callServerParse(input string) (TaskInformation, error) {
+func (ts *TaskService) callServerParse(ctx context.Context, input string, now time.Time, zone string, locale settingsservice.Locale) (TaskInformation, error) {
 	userID := c.GetCurrentUserId()
 	if userID == "" {
 		return TaskInformation{}, fmt.Errorf("no current user id set; connect Notion")
 	}
-	payload := map[string]string{"text": input}
+	payload := map[string]any{
+		"text":      input,
+		"now":       now.Format(time.RFC3339),
+		"time_zone": zone,
+		"locale": map[string]string{
+			"language":   locale.Language,
+			"date_order": locale.DateOrder,
+			"week_start": strings.ToLower(locale.WeekStart.String()),
+		},
+		"fields": []string{"title", "date", "end_date", "time_zone", "recurrence", "priority", "tags", "project", "notes", "estimate_minutes"},
+	}
 	body, err := json.Marshal(payload)
 	if err != nil {
 		return TaskInformation{}, err
@@ -155,14 +632,14 @@ func (ts *TaskService) callServerParse(input string) (TaskInformation, error) {
 	}
 	endpoint := strings.TrimRight(apiBase, "/") + "/tasklight/parse"
 
-	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewBuffer(body))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewBuffer(body))
 	if err != nil {
 		return TaskInformation{}, err
 	}
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("X-Notion-User-Id", userID)
 
-	resp, err := (&http.Client{Timeout: 10 * time.Second}).Do(req)
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return TaskInformation{}, err
 	}
@@ -180,110 +657,270 @@ func (ts *TaskService) callServerParse(input string) (TaskInformation, error) {
 	return parsed, nil
 }
 
-// parseTaskFromContent converts the model's JSON string into TaskInformation
-func parseTaskFromContent(content string) (TaskInformation, error) {
-	var task TaskInformation
-	if err := json.Unmarshal([]byte(content), &task); err != nil {
-		return TaskInformation{}, err
-	}
-	return task, nil
+func (ts *TaskService) SendToNotion(task TaskInformation) PageResult {
+	return ts.captureNotionPage(ts.ctx, task)
 }
 
-func (ts *TaskService) SendToNotion(task TaskInformation) string {
+// pageTarget is the data source a task is written to and the properties
+// that receive its fields.
+type pageTarget struct {
+	token        string
+	dataSourceID string
+	dataSource   *NotionDataSourceDetail
+	titleProp    string
+	dateProp     string
+	fields       taskFieldProperties
+	// destination is the name of the destination; empty is the default.
+	destination string
+	// warnings describe default values left out because they no longer fit
+	// the schema.
+	warnings []string
+}
+
+// resolveTarget loads the data source of the named destination, or of the
+// default destination for "". Its errors are the messages shown to the user.
+func (ts *TaskService) resolveTarget(ctx context.Context, destination string) (*pageTarget, error) {
 	token, err := ts.settings.GetNotionToken(true)
 	if err != nil || token == "" {
-		msg := "Notion token unavailable; reconnect Notion from settings"
 		if err != nil {
 			log.Println("SendToNotion: failed to load token:", err)
-			msg = "Failed to load Notion token"
+			return nil, withCategory(ErrorAuth, errors.New("Failed to load Notion token"))
 		}
-		return msg
+		return nil, withCategory(ErrorAuth, errors.New("Notion token unavailable; reconnect Notion from settings"))
 	}
 
 	if c.AppConfig == nil {
 		log.Println("SendToNotion: configuration not initialised")
-		return "Tasklight configuration not ready; reopen the app."
+		return nil, withCategory(ErrorConfig, errors.New("Tasklight configuration not ready; reopen the app."))
+	}
+
+	var warnings []string
+	dest, ok := c.AppConfig.FindDestination(destination)
+	if !ok {
+		// A queued task may outlive the destination it was routed to.
+		warnings = append(warnings, fmt.Sprintf("Destination %q no longer exists; the task went to the default destination.", destination))
+		dest = c.AppConfig.DefaultDestination()
 	}
 
-	if c.AppConfig.NotionDataSourceID == "" {
+	if dest.DataSourceID == "" {
+		if dest.Name != "" {
+			return nil, withCategory(ErrorConfig, fmt.Errorf("Destination %q has no data source.", dest.Name))
+		}
 		log.Println("SendToNotion: data source not selected")
-		return "Data source not selected for this Notion database."
+		return nil, withCategory(ErrorConfig, errors.New("Data source not selected for this Notion database."))
 	}
 
-	dataSource, err := ts.loadDataSourceDetail(token, c.AppConfig.NotionDataSourceID)
+	dataSource, err := ts.loadDataSourceDetail(ctx, token, dest.DataSourceID)
 	if err != nil {
 		log.Println("SendToNotion: data source load failed:", err)
-		return fmt.Sprintf("Failed to load Notion data source: %v", err)
+		if dest.Name != "" {
+			return nil, fmt.Errorf("Failed to load the Notion data source of %q: %w", dest.Name, err)
+		}
+		return nil, fmt.Errorf("Failed to load Notion data source: %w", err)
 	}
-
 	titlePropName, err := detectTitleProperty(dataSource)
 	if err != nil {
 		log.Println("SendToNotion:", err)
-		return err.Error()
+		return nil, withCategory(ErrorConfig, err)
 	}
 
-	if c.AppConfig.DatePropertyName != "" {
-		prop, ok := dataSource.Properties[c.AppConfig.DatePropertyName]
-		if !ok || prop.Type != "date" {
-			msg := fmt.Sprintf("Selected Notion property %q is not available on the chosen data source.", c.AppConfig.DatePropertyName)
-			log.Println("SendToNotion:", msg)
-			return msg
+	dateProp, err := destinationDateProperty(dest, dataSource)
+	if err != nil {
+		log.Println("SendToNotion:", err)
+		return nil, withCategory(ErrorConfig, err)
+	}
+
+	fields := detectTaskFieldProperties(dataSource, c.AppConfig.RecurrencePropertyName)
+	for _, problem := range applyPropertyMappings(&fields, dataSource, dest.PropertyMappings()) {
+		log.Println("SendToNotion: ignoring property mapping:", problem)
+	}
+	fields.Unmatched = dest.UnmatchedOptions()
+	if fields.People != nil && fields.People.Type == "people" {
+		if fields.Users, err = ts.workspaceUsers(ctx, token); err != nil {
+			log.Println("SendToNotion: failed to list workspace members, leaving people empty:", err)
 		}
 	}
 
-	payload := buildNotionPagePayload(task, c.AppConfig.NotionDataSourceID, titlePropName, c.AppConfig.DatePropertyName)
+	if defaults := dest.PropertyDefaults(); len(defaults) > 0 {
+		var skipped []string
+		fields.Defaults, skipped = ts.resolveDefaults(ctx, token, dataSource, defaults)
+		warnings = append(warnings, skipped...)
+	}
+	for _, warning := range warnings {
+		log.Println("SendToNotion:", warning)
+	}
 
-	req, err := notionapi.NewJSONRequest(http.MethodPost, "https://api.notion.com/v1/pages", token, payload)
-	if err != nil {
-		log.Println("SendToNotion: failed to create request:", err)
-		return err.Error()
+	return &pageTarget{
+		token:        token,
+		dataSourceID: dest.DataSourceID,
+		dataSource:   dataSource,
+		titleProp:    titlePropName,
+		dateProp:     dateProp,
+		fields:       fields,
+		destination:  dest.Name,
+		warnings:     warnings,
+	}, nil
+}
+
+// dateProperty returns the current name of the configured date property,
+// found by ID so a rename in Notion does not break it. Settings saved before
+// the ID was recorded are matched by name.
+func dateProperty(detail *NotionDataSourceDetail, id, name string) (string, error) {
+	if id != "" {
+		if prop := propertyByID(detail, id); prop != nil && prop.Type == "date" {
+			return prop.Name, nil
+		}
+	}
+	if name == "" {
+		return "", nil
+	}
+	if prop, ok := detail.Properties[name]; ok && prop.Type == "date" {
+		return name, nil
 	}
+	return "", fmt.Errorf("Selected Notion property %q is not available on the chosen data source.", name)
+}
 
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		msg := fmt.Sprintf("Error sending request: %v", err)
-		log.Println(msg)
-		return msg
+// createNotionPage creates the page for task in the selected data source.
+func (ts *TaskService) createNotionPage(ctx context.Context, task TaskInformation) PageResult {
+	return ts.timedPost(ctx, task, settingsservice.DuplicatesOff)
+}
+
+// captureNotionPage creates the page for a captured task, first handling any
+// open page that duplicates it as the settings say.
+func (ts *TaskService) captureNotionPage(ctx context.Context, task TaskInformation) PageResult {
+	duplicates := settingsservice.DuplicatesOff
+	if c.AppConfig != nil {
+		duplicates = c.AppConfig.Duplicates()
 	}
-	status := resp.Status
+	return ts.timedPost(ctx, task, duplicates)
+}
 
-	if err := notionapi.ParseResponse(resp, nil, ErrNotionTokenMissing); err != nil {
-		log.Println("SendToNotion: Notion API error:", err)
-		return err.Error()
+func (ts *TaskService) timedPost(ctx context.Context, task TaskInformation, duplicates settingsservice.DuplicateAction) PageResult {
+	ctx, cancel := notionStage(ctx)
+	defer cancel()
+
+	started := time.Now()
+	result := ts.postNotionPage(ctx, task, duplicates)
+	result.LatencyMS = time.Since(started).Milliseconds()
+	return result
+}
+
+func (ts *TaskService) postNotionPage(ctx context.Context, task TaskInformation, duplicates settingsservice.DuplicateAction) PageResult {
+	result := ts.routedNotionPage(ctx, task, duplicates)
+	result.Destination, result.RoutedBy = task.Destination, task.RoutedBy
+	return result
+}
+
+func (ts *TaskService) routedNotionPage(ctx context.Context, task TaskInformation, duplicates settingsservice.DuplicateAction) PageResult {
+	target, err := ts.resolveTarget(ctx, task.Destination)
+	if err != nil {
+		return failedPage(err)
+	}
+	warnings := target.warnings
+	task, matches := matchTaskOptions(task, target.fields)
+	task, link, err := ts.linkProject(ctx, target, task, true)
+	if err != nil {
+		log.Println("SendToNotion: failed to link the project:", err)
+		warnings = append(slices.Clip(warnings), fmt.Sprintf("Project %q not linked: %v.", task.Project, err))
+	}
+	if link != nil {
+		matches = append(matches, *link)
 	}
 
-	log.Printf("Notion page created (status %s) using data source %s", status, c.AppConfig.NotionDataSourceID)
-	return status
+	result := ts.writeNotionPage(ctx, target, task, duplicates)
+	result.Warnings = warnings
+	result.Options = matches
+	if link != nil && link.Outcome == OptionCreated {
+		result.CreatedProject = task.Project
+	}
+	return result
 }
 
-func (ts *TaskService) loadDataSourceDetail(token, dataSourceID string) (*NotionDataSourceDetail, error) {
-	req, err := notionapi.NewRequest(http.MethodGet, fmt.Sprintf("https://api.notion.com/v1/data_sources/%s", dataSourceID), token)
+// writeNotionPage creates the page for task in target, or handles the open
+// page it duplicates.
+func (ts *TaskService) writeNotionPage(ctx context.Context, target *pageTarget, task TaskInformation, duplicates settingsservice.DuplicateAction) PageResult {
+	var err error
+	var duplicate *DuplicateMatch
+	failed := func(err error) PageResult {
+		result := failedPage(err)
+		result.DataSourceID = target.dataSourceID
+		result.Duplicate = duplicate
+		return result
+	}
+
+	payload := buildNotionPagePayload(task, target.dataSourceID, target.titleProp, target.dateProp, target.fields)
+	overflow := splitPageBody(payload)
+
+	if duplicates != settingsservice.DuplicatesOff {
+		duplicate, err = ts.findDuplicate(ctx, target, task)
+		if err != nil {
+			log.Println("SendToNotion: duplicate check failed, creating the page anyway:", err)
+		}
+	}
+	if duplicate != nil {
+		duplicate.Action = duplicates
+		log.Printf("SendToNotion: %q looks like existing page %q (%s)", task.Title, duplicate.Title, duplicates)
+
+		switch duplicates {
+		case settingsservice.DuplicatesSkip:
+			return PageResult{PageID: duplicate.PageID, URL: duplicate.URL, DataSourceID: target.dataSourceID, Duplicate: duplicate}
+		case settingsservice.DuplicatesUpdate:
+			// Defaults are for new pages; the existing page keeps its values.
+			fields := target.fields
+			fields.Defaults = nil
+			update := buildNotionPagePayload(task, target.dataSourceID, target.titleProp, target.dateProp, fields)
+			if err := ts.updateNotionPage(ctx, target.token, duplicate.PageID, update["properties"]); err != nil {
+				log.Println("SendToNotion: failed to update the existing page:", err)
+				return failed(err)
+			}
+			return PageResult{PageID: duplicate.PageID, URL: duplicate.URL, DataSourceID: target.dataSourceID, Duplicate: duplicate}
+		}
+	}
+
+	req, err := notionapi.NewJSONRequest(ctx, http.MethodPost, notionapi.URL("/pages"), target.token, payload)
 	if err != nil {
-		return nil, err
+		log.Println("SendToNotion: failed to create request:", err)
+		return failed(withCategory(ErrorValidation, err))
 	}
 
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		return nil, err
+		log.Println("SendToNotion: error sending request:", err)
+		return failed(fmt.Errorf("Error sending request: %w", err))
 	}
 
-	var detail NotionDataSourceDetail
-	if err := notionapi.ParseResponse(resp, &detail, ErrNotionTokenMissing); err != nil {
-		return nil, err
+	var created struct {
+		ID  string `json:"id"`
+		URL string `json:"url"`
 	}
-
-	if detail.Properties == nil {
-		detail.Properties = map[string]PropertyObj{}
+	if err := notionapi.ParseResponse(resp, &created, ErrNotionTokenMissing); err != nil {
+		log.Println("SendToNotion: Notion API error:", err)
+		result := failed(err)
+		if result.Category == ErrorValidation && ts.schemas != nil {
+			// The schema may have changed since it was cached.
+			ts.schemas.Invalidate(target.dataSourceID)
+		}
+		return result
 	}
 
-	for key, prop := range detail.Properties {
-		if prop.Name == "" {
-			prop.Name = key
-			detail.Properties[key] = prop
+	if len(overflow) > 0 {
+		if err := ts.appendBlocks(ctx, target.token, created.ID, overflow); err != nil {
+			// The page exists, so failing the capture would only create it twice.
+			log.Println("SendToNotion: page created without the end of its body:", err)
 		}
 	}
 
-	return &detail, nil
+	log.Printf("Notion page created using data source %s", target.dataSourceID)
+	return PageResult{PageID: created.ID, URL: created.URL, DataSourceID: target.dataSourceID, Duplicate: duplicate}
+}
+
+// loadDataSourceDetail returns the schema of a data source, from the schema
+// cache when one is set.
+func (ts *TaskService) loadDataSourceDetail(ctx context.Context, token, dataSourceID string) (*NotionDataSourceDetail, error) {
+	if ts.schemas == nil {
+		return fetchDataSourceDetail(ctx, token, dataSourceID)
+	}
+	return ts.schemas.Detail(ctx, token, dataSourceID)
 }
 
 func detectTitleProperty(detail *NotionDataSourceDetail) (string, error) {
@@ -298,7 +935,92 @@ func detectTitleProperty(detail *NotionDataSourceDetail) (string, error) {
 	return "", fmt.Errorf("Selected Notion data source is missing a title property.")
 }
 
-func buildNotionPagePayload(task TaskInformation, dataSourceID, titlePropertyName, datePropertyName string) map[string]any {
+// taskFieldProperties holds the data-source properties that receive the
+// optional task fields. A nil entry means the data source has no such property.
+type taskFieldProperties struct {
+	Priority   *PropertyObj
+	Tags       *PropertyObj
+	Project    *PropertyObj
+	Notes      *PropertyObj
+	Estimate   *PropertyObj
+	Recurrence *PropertyObj
+	// URL receives a link captured from the clipboard.
+	URL *PropertyObj
+	// People takes @person tokens. Name matching only finds multi-select and
+	// text properties; a people property must be mapped.
+	People *PropertyObj
+	// Users maps workspace members to user IDs for a people property; see
+	// workspaceUsers.
+	Users map[string]string
+	// Defaults are encoded property values, keyed by property name, for the
+	// properties no task field fills.
+	Defaults map[string]map[string]any
+	// Unmatched says what happens to tags and projects matching no option,
+	// keyed by property ID; see matchTaskOptions.
+	Unmatched map[string]settingsservice.UnmatchedOption
+}
+
+// detectTaskFieldProperties finds the properties for the optional task fields.
+// recurrenceProperty is the configured RRULE property; when empty a property
+// named "Recurrence", "Repeat" or "RRULE" is used if present.
+func detectTaskFieldProperties(detail *NotionDataSourceDetail, recurrenceProperty string) taskFieldProperties {
+	recurrenceNames := []string{"recurrence", "repeat", "rrule"}
+	if recurrenceProperty != "" {
+		recurrenceNames = []string{recurrenceProperty}
+	}
+
+	return taskFieldProperties{
+		Recurrence: findProperty(detail, recurrenceNames, "rich_text"),
+		Priority:   findProperty(detail, []string{"priority"}, "select"),
+		Tags:       findProperty(detail, []string{"tags", "tag", "labels", "label"}, "multi_select"),
+		Project:    findProperty(detail, []string{"project"}, "select", "rich_text", "relation"),
+		Notes:      findProperty(detail, []string{"notes", "note", "description", "details"}, "rich_text"),
+		Estimate:   findProperty(detail, []string{"estimate", "time estimate", "estimate (min)", "estimate (h)", "estimated minutes"}, "number"),
+		People:     findProperty(detail, []string{"people", "person", "assignee", "assignees", "owner"}, "multi_select", "rich_text"),
+		URL:        urlProperty(detail),
+	}
+}
+
+// urlProperty is the property named like a link, or else the data source's
+// only URL property.
+func urlProperty(detail *NotionDataSourceDetail) *PropertyObj {
+	if prop := findProperty(detail, []string{"url", "link", "source", "website"}, "url"); prop != nil {
+		return prop
+	}
+	var only *PropertyObj
+	for _, prop := range detail.Properties {
+		if prop.Type != "url" {
+			continue
+		}
+		if only != nil {
+			return nil
+		}
+		found := prop
+		only = &found
+	}
+	return only
+}
+
+// findProperty returns the first property whose name matches one of names
+// (case-insensitively, in order of preference) and whose type is one of types.
+func findProperty(detail *NotionDataSourceDetail, names []string, types ...string) *PropertyObj {
+	for _, name := range names {
+		for _, prop := range detail.Properties {
+			if !strings.EqualFold(prop.Name, name) {
+				continue
+			}
+			for _, typ := range types {
+				if prop.Type == typ {
+					found := prop
+					return &found
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func buildNotionPagePayload(task TaskInformation, dataSourceID, titlePropertyName, datePropertyName string, fields taskFieldProperties) map[string]any {
 	nameProp := map[string]any{
 		"type": "title",
 		"title": []map[string]any{
@@ -316,18 +1038,118 @@ func buildNotionPagePayload(task TaskInformation, dataSourceID, titlePropertyNam
 	}
 
 	if task.Date != nil && datePropertyName != "" {
+		date := map[string]any{
+			"start": *task.Date,
+		}
+		if task.EndDate != nil {
+			date["end"] = *task.EndDate
+		}
+		if task.TimeZone != "" && isDateTime(task.Date) {
+			date["time_zone"] = task.TimeZone
+		}
 		properties[datePropertyName] = map[string]any{
-			"date": map[string]any{
-				"start": *task.Date,
-			},
+			"date": date,
 		}
 	}
 
-	return map[string]any{
+	setProperty := func(prop *PropertyObj, values ...string) {
+		if prop == nil {
+			return
+		}
+		if value, ok := propertyValue(*prop, values, fields.Users); ok {
+			properties[prop.Name] = value
+		}
+	}
+
+	if fields.Priority != nil && task.Priority != "" {
+		if fields.Priority.Type == "checkbox" {
+			setProperty(fields.Priority, strconv.FormatBool(task.Priority == "high" || task.Priority == "urgent"))
+		} else {
+			setProperty(fields.Priority, task.Priority)
+		}
+	}
+	setProperty(fields.Tags, task.Tags...)
+	setProperty(fields.Project, task.Project)
+
+	if fields.Notes != nil && task.Notes != "" && notionblocks.IsInline(task.Notes) {
+		properties[fields.Notes.Name] = map[string]any{"rich_text": notionblocks.Inline(task.Notes)}
+	}
+
+	setProperty(fields.People, task.People...)
+	if task.Recurrence != "" {
+		setProperty(fields.Recurrence, "RRULE:"+task.Recurrence)
+	}
+
+	if fields.Estimate != nil && task.EstimateMinutes > 0 {
+		var estimate any = task.EstimateMinutes
+		if estimateInHours(*fields.Estimate) {
+			estimate = float64(task.EstimateMinutes) / 60
+		}
+		if fields.Estimate.Type == "number" {
+			properties[fields.Estimate.Name] = map[string]any{"number": estimate}
+		} else {
+			setProperty(fields.Estimate, fmt.Sprintf("%d min", task.EstimateMinutes))
+		}
+	}
+
+	payload := map[string]any{
 		"parent": map[string]any{
 			"type":           "data_source_id",
 			"data_source_id": dataSourceID,
 		},
 		"properties": properties,
 	}
+
+	if body := pageBody(task, fields, properties); len(body) > 0 {
+		payload["children"] = body
+	}
+
+	for name, value := range fields.Defaults {
+		if _, set := properties[name]; !set {
+			properties[name] = value
+		}
+	}
+
+	return payload
+}
+
+func richTextValue(content string) map[string]any {
+	return map[string]any{
+		"rich_text": []map[string]any{
+			{
+				"type": "text",
+				"text": map[string]any{
+					"content": content,
+				},
+			},
+		},
+	}
+}
+
+// matchOption reuses an existing option's spelling when value matches it
+// case-insensitively, so "high" lands on "High" instead of creating a new option.
+func matchOption(prop PropertyObj, value string) string {
+	if name, ok := existingOption(prop, value); ok {
+		return name
+	}
+	if prop.Type == "select" && value != "" {
+		first, size := utf8.DecodeRuneInString(value)
+		return string(unicode.ToUpper(first)) + value[size:]
+	}
+	return value
+}
+
+// existingOption returns the option of prop matching value case-insensitively.
+func existingOption(prop PropertyObj, value string) (string, bool) {
+	for _, opt := range prop.Options() {
+		if strings.EqualFold(opt.Name, value) {
+			return opt.Name, true
+		}
+	}
+	return "", false
+}
+
+func estimateInHours(prop PropertyObj) bool {
+	name := strings.ToLower(prop.Name)
+	return strings.Contains(name, "hour") || strings.Contains(name, "(h)")
 }
@@ -1,9 +1,21 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	c "github.com/imjamesonzeller/tasklight-v3/config"
+	"github.com/imjamesonzeller/tasklight-v3/llm"
+	"github.com/imjamesonzeller/tasklight-v3/notionapi"
+	"github.com/imjamesonzeller/tasklight-v3/quicksyntax"
 	"github.com/imjamesonzeller/tasklight-v3/settingsservice"
 )
 
@@ -34,6 +46,31 @@ func TestParseTaskFromContent(t *testing.T) {
 			input:   `{"title":"oops"`,
 			wantErr: true,
 		},
+		{
+			name:  "json code fence",
+			input: "```json\n{\"title\":\"Write report\",\"date\":\"2024-10-01\"}\n```",
+			want:  TaskInformation{Title: "Write report", Date: ptr("2024-10-01")},
+		},
+		{
+			name:  "leading and trailing prose",
+			input: `Here's the task: {"title":"Call the bank","date":null} Let me know if you need anything else!`,
+			want:  TaskInformation{Title: "Call the bank"},
+		},
+		{
+			name:  "single quotes",
+			input: `{'title': 'Buy "good" coffee', 'date': '2024-10-01'}`,
+			want:  TaskInformation{Title: `Buy "good" coffee`, Date: ptr("2024-10-01")},
+		},
+		{
+			name:  "braces inside strings",
+			input: `{"title":"Fix {braces} bug","date":null}`,
+			want:  TaskInformation{Title: "Fix {braces} bug"},
+		},
+		{
+			name:    "no object",
+			input:   "I could not find a task in that sentence.",
+			wantErr: true,
+		},
 	}
 
 	for _, tc := range tests {
@@ -69,6 +106,55 @@ func TestParseTaskFromContent(t *testing.T) {
 	}
 }
 
+func TestValidateParsedTask(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, time.October, 15, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		task     TaskInformation
+		input    string
+		wantDate *string
+		wantErr  bool
+	}{
+		{name: "future date", task: TaskInformation{Title: "Plan", Date: ptr("2025-10-17")}, input: "plan friday", wantDate: ptr("2025-10-17")},
+		{name: "today", task: TaskInformation{Title: "Plan", Date: ptr("2025-10-15")}, input: "plan today", wantDate: ptr("2025-10-15")},
+		{name: "no date", task: TaskInformation{Title: "Plan"}, input: "plan"},
+		{name: "null string date", task: TaskInformation{Title: "Plan", Date: ptr("null")}, input: "plan"},
+		{name: "empty title", task: TaskInformation{Title: "  ", Date: nil}, input: "plan", wantErr: true},
+		{name: "not iso", task: TaskInformation{Title: "Plan", Date: ptr("Oct 17")}, input: "plan oct 17", wantErr: true},
+		{name: "impossible date", task: TaskInformation{Title: "Plan", Date: ptr("2025-02-30")}, input: "plan", wantErr: true},
+		{name: "past date", task: TaskInformation{Title: "Plan", Date: ptr("2025-10-10")}, input: "plan friday", wantErr: true},
+		{name: "past date requested", task: TaskInformation{Title: "Log hours", Date: ptr("2025-10-10")}, input: "log hours from last friday", wantDate: ptr("2025-10-10")},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := validateParsedTask(tc.task, tc.input, now)
+			if tc.wantErr {
+				if !errors.Is(err, errResponseRejected) {
+					t.Fatalf("expected rejection, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			switch {
+			case tc.wantDate == nil && got.Date != nil:
+				t.Fatalf("expected nil date, got %v", *got.Date)
+			case tc.wantDate != nil && (got.Date == nil || *got.Date != *tc.wantDate):
+				t.Fatalf("expected date %v, got %v", *tc.wantDate, got.Date)
+			}
+		})
+	}
+}
+
 func TestBuildNotionPagePayload(t *testing.T) {
 	t.Parallel()
 
@@ -81,7 +167,7 @@ func TestBuildNotionPagePayload(t *testing.T) {
 		DatePropertyName:   "Due",
 	}
 
-	payload := buildNotionPagePayload(TaskInformation{Title: "Plan", Date: &date}, "ds-456", "Name", "Due")
+	payload := buildNotionPagePayload(TaskInformation{Title: "Plan", Date: &date}, "ds-456", "Name", "Due", taskFieldProperties{})
 
 	parent, ok := payload["parent"].(map[string]any)
 	if !ok {
@@ -123,7 +209,7 @@ func TestBuildNotionPagePayload(t *testing.T) {
 func TestBuildNotionPagePayloadWithoutDate(t *testing.T) {
 	t.Parallel()
 
-	payload := buildNotionPagePayload(TaskInformation{Title: "Plan", Date: nil}, "ds-456", "Name", "")
+	payload := buildNotionPagePayload(TaskInformation{Title: "Plan", Date: nil}, "ds-456", "Name", "", taskFieldProperties{})
 
 	parent := payload["parent"].(map[string]any)
 	if parent["data_source_id"] != "ds-456" {
@@ -139,6 +225,195 @@ func TestBuildNotionPagePayloadWithoutDate(t *testing.T) {
 	}
 }
 
+func TestBuildNotionPagePayloadWithTaskFields(t *testing.T) {
+	t.Parallel()
+
+	detail := &NotionDataSourceDetail{Properties: map[string]PropertyObj{
+		"Name":         {Name: "Name", Type: "title"},
+		"Priority":     {Name: "Priority", Type: "select", Select: &SelectConfig{Options: []SelectOption{{Name: "High"}}}},
+		"Tags":         {Name: "Tags", Type: "multi_select", MultiSelect: &SelectConfig{Options: []SelectOption{{Name: "Errands"}}}},
+		"Project":      {Name: "Project", Type: "rich_text"},
+		"Notes":        {Name: "Notes", Type: "rich_text"},
+		"Estimate (h)": {Name: "Estimate (h)", Type: "number"},
+	}}
+	fields := detectTaskFieldProperties(detail, "")
+
+	task := TaskInformation{
+		Title:           "Buy milk",
+		Priority:        "high",
+		Tags:            []string{"errands", "home"},
+		Project:         "Alpha",
+		Notes:           "semi-skimmed",
+		EstimateMinutes: 30,
+	}
+	props := buildNotionPagePayload(task, "ds-456", "Name", "", fields)["properties"].(map[string]any)
+
+	priority := props["Priority"].(map[string]any)["select"].(map[string]any)
+	if priority["name"] != "High" {
+		t.Fatalf("expected existing option spelling, got %v", priority["name"])
+	}
+
+	tags := props["Tags"].(map[string]any)["multi_select"].([]map[string]any)
+	if len(tags) != 2 || tags[0]["name"] != "Errands" || tags[1]["name"] != "home" {
+		t.Fatalf("unexpected tags: %v", tags)
+	}
+
+	project := props["Project"].(map[string]any)["rich_text"].([]map[string]any)
+	if project[0]["text"].(map[string]any)["content"] != "Alpha" {
+		t.Fatalf("unexpected project: %v", project)
+	}
+
+	if _, ok := props["Notes"]; !ok {
+		t.Fatalf("expected notes property")
+	}
+
+	estimate := props["Estimate (h)"].(map[string]any)["number"]
+	if estimate != 0.5 {
+		t.Fatalf("expected estimate in hours, got %v", estimate)
+	}
+}
+
+func TestBuildNotionPagePayloadSkipsMissingProperties(t *testing.T) {
+	t.Parallel()
+
+	task := TaskInformation{Title: "Plan", Priority: "low", Tags: []string{"work"}, EstimateMinutes: 15}
+	props := buildNotionPagePayload(task, "ds-456", "Name", "", taskFieldProperties{})["properties"].(map[string]any)
+	if len(props) != 1 {
+		t.Fatalf("only title property expected, got %v", props)
+	}
+}
+
+func TestMatchOption(t *testing.T) {
+	t.Parallel()
+
+	prop := PropertyObj{Name: "Project", Type: "select", Select: &SelectConfig{Options: []SelectOption{{Name: "Alpha"}}}}
+	for value, want := range map[string]string{"alpha": "Alpha", "élan": "Élan", "ünïcode": "Ünïcode", "beta": "Beta", "日本": "日本"} {
+		if got := matchOption(prop, value); got != want {
+			t.Errorf("matchOption(%q) = %q, want %q", value, got, want)
+		}
+	}
+}
+
+func TestExtractLocalFields(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		input    string
+		wantRest string
+		want     TaskInformation
+	}{
+		{
+			input:    "buy milk urgent priority for project Alpha, ~30m",
+			wantRest: "buy milk ,",
+			want:     TaskInformation{Priority: "urgent", Project: "Alpha", EstimateMinutes: 30},
+		},
+		{
+			input:    "write report high priority ~1h30m notes: include Q3 numbers",
+			wantRest: "write report",
+			want:     TaskInformation{Priority: "high", EstimateMinutes: 90, Notes: "include Q3 numbers"},
+		},
+		{
+			input:    "review https://example.com/#anchor project:Docs ~1.5h",
+			wantRest: "review https://example.com/#anchor",
+			want:     TaskInformation{Project: "Docs", EstimateMinutes: 90},
+		},
+		{
+			input:    "finish project report",
+			wantRest: "finish project report",
+		},
+	}
+
+	for _, tc := range tests {
+		rest, got := extractLocalFields(tc.input)
+		if rest != tc.wantRest {
+			t.Errorf("%q: rest = %q, want %q", tc.input, rest, tc.wantRest)
+		}
+		if got.Priority != tc.want.Priority || got.Project != tc.want.Project || got.Notes != tc.want.Notes ||
+			got.EstimateMinutes != tc.want.EstimateMinutes || strings.Join(got.Tags, ",") != strings.Join(tc.want.Tags, ",") {
+			t.Errorf("%q: got %+v, want %+v", tc.input, got, tc.want)
+		}
+	}
+}
+
+func TestApplyQuickSyntax(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, time.October, 15, 9, 0, 0, 0, time.UTC)
+	tokens := quicksyntax.Parse("#errands +home !1 ^fri @sam", quicksyntax.DefaultPrefixes())
+
+	model := TaskInformation{
+		Title:    "Buy milk",
+		Date:     ptr("2025-10-16T15:00:00"),
+		EndDate:  ptr("2025-10-16T16:00:00"),
+		Priority: "low",
+		Tags:     []string{"shopping"},
+		Project:  "Groceries",
+	}
+	got := applyQuickSyntax(model, tokens, now)
+
+	if got.Priority != "urgent" || got.Project != "home" || strings.Join(got.Tags, ",") != "errands" || strings.Join(got.People, ",") != "sam" {
+		t.Fatalf("tokens should win over the model: %+v", got)
+	}
+	if *got.Date != "2025-10-17T15:00:00" || *got.EndDate != "2025-10-17T16:00:00" {
+		t.Fatalf("expected the range to move to Friday, got %s..%s", *got.Date, *got.EndDate)
+	}
+
+	iso := applyQuickSyntax(TaskInformation{Title: "File taxes", Date: ptr("2025-10-20")}, quicksyntax.Parse("^2025-11-03", quicksyntax.DefaultPrefixes()), now)
+	if *iso.Date != "2025-11-03" {
+		t.Fatalf("unexpected due date: %s", *iso.Date)
+	}
+
+	unknown := applyQuickSyntax(TaskInformation{Title: "Plan", Date: ptr("2025-10-20")}, quicksyntax.Parse("^someday", quicksyntax.DefaultPrefixes()), now)
+	if *unknown.Date != "2025-10-20" {
+		t.Fatalf("an unrecognised due date should leave the date alone, got %s", *unknown.Date)
+	}
+}
+
+func TestParseLocallyQuickSyntax(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, time.October, 15, 9, 0, 0, 0, time.UTC)
+
+	got := parseLocally("call plumber tomorrow ^fri !2 #home", now)
+	if got.Title != "call plumber" || got.Priority != "high" || strings.Join(got.Tags, ",") != "home" {
+		t.Fatalf("unexpected task: %+v", got)
+	}
+	if got.Date == nil || *got.Date != "2025-10-17" {
+		t.Fatalf("the due token should beat the parsed date, got %v", got.Date)
+	}
+}
+
+func TestValidateParsedTaskNormalizesFields(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, time.October, 15, 9, 0, 0, 0, time.UTC)
+	task := TaskInformation{
+		Title:           "Plan",
+		Priority:        "HIGH",
+		Tags:            []string{"#work", "Work", " "},
+		EstimateMinutes: -5,
+	}
+
+	got, err := validateParsedTask(task, "plan", now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Priority != "high" {
+		t.Fatalf("unexpected priority: %q", got.Priority)
+	}
+	if len(got.Tags) != 1 || got.Tags[0] != "work" {
+		t.Fatalf("unexpected tags: %v", got.Tags)
+	}
+	if got.EstimateMinutes != 0 {
+		t.Fatalf("negative estimate should be cleared, got %d", got.EstimateMinutes)
+	}
+
+	got, _ = validateParsedTask(TaskInformation{Title: "Plan", Priority: "whenever"}, "plan", now)
+	if got.Priority != "" {
+		t.Fatalf("unknown priority should be dropped, got %q", got.Priority)
+	}
+}
+
 func TestDetectTitleProperty(t *testing.T) {
 	t.Parallel()
 
@@ -181,6 +456,556 @@ func TestDetectTitlePropertyMissing(t *testing.T) {
 	}
 }
 
+func TestParseLocally(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, time.October, 15, 9, 0, 0, 0, time.UTC)
+
+	got := parseLocally("pay rent next friday", now)
+	if got.Title != "pay rent" {
+		t.Fatalf("unexpected title: %q", got.Title)
+	}
+	if got.Date == nil || *got.Date != "2025-10-24" {
+		t.Fatalf("unexpected date: %v", got.Date)
+	}
+
+	undated := parseLocally("buy milk", now)
+	if undated.Title != "buy milk" || undated.Date != nil {
+		t.Fatalf("unexpected undated task: %+v", undated)
+	}
+
+	detailed := parseLocally("call plumber tomorrow !urgent #home", now)
+	if detailed.Title != "call plumber" || detailed.Priority != "urgent" || len(detailed.Tags) != 1 {
+		t.Fatalf("unexpected detailed task: %+v", detailed)
+	}
+	if detailed.Date == nil || *detailed.Date != "2025-10-16" {
+		t.Fatalf("unexpected date: %v", detailed.Date)
+	}
+}
+
+func TestBuildNotionPagePayloadWithTimedRange(t *testing.T) {
+	t.Parallel()
+
+	task := TaskInformation{
+		Title:    "Workshop",
+		Date:     ptr("2025-10-17T15:00:00"),
+		EndDate:  ptr("2025-10-17T17:00:00"),
+		TimeZone: "America/Los_Angeles",
+	}
+	props := buildNotionPagePayload(task, "ds-456", "Name", "Due", taskFieldProperties{})["properties"].(map[string]any)
+
+	date := props["Due"].(map[string]any)["date"].(map[string]any)
+	if date["start"] != "2025-10-17T15:00:00" || date["end"] != "2025-10-17T17:00:00" {
+		t.Fatalf("unexpected range: %v", date)
+	}
+	if date["time_zone"] != "America/Los_Angeles" {
+		t.Fatalf("unexpected time zone: %v", date["time_zone"])
+	}
+
+	allDay := TaskInformation{Title: "Conference", Date: ptr("2025-10-20"), EndDate: ptr("2025-10-22"), TimeZone: "Europe/Berlin"}
+	props = buildNotionPagePayload(allDay, "ds-456", "Name", "Due", taskFieldProperties{})["properties"].(map[string]any)
+	date = props["Due"].(map[string]any)["date"].(map[string]any)
+	if _, ok := date["time_zone"]; ok {
+		t.Fatalf("all-day dates must not carry a time zone: %v", date)
+	}
+	if date["end"] != "2025-10-22" {
+		t.Fatalf("unexpected end: %v", date["end"])
+	}
+}
+
+func TestValidateParsedTaskDateTimes(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, time.October, 15, 9, 0, 0, 0, time.UTC)
+
+	got, err := validateParsedTask(TaskInformation{Title: "Call", Date: ptr("2025-10-16T15:00"), TimeZone: "Europe/Berlin"}, "call at 3pm tomorrow", now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if *got.Date != "2025-10-16T15:00:00" || got.TimeZone != "Europe/Berlin" {
+		t.Fatalf("unexpected normalised task: %+v", got)
+	}
+
+	got, err = validateParsedTask(TaskInformation{Title: "Call", Date: ptr("2025-10-16T15:00"), TimeZone: "Nowhere/Special"}, "call", now)
+	if err != nil || got.TimeZone != "" {
+		t.Fatalf("unknown zone should be dropped, got %+v (err %v)", got, err)
+	}
+
+	rejects := []TaskInformation{
+		{Title: "Trip", Date: ptr("2025-10-20"), EndDate: ptr("2025-10-18")},
+		{Title: "Trip", Date: ptr("2025-10-20"), EndDate: ptr("2025-10-21T10:00")},
+		{Title: "Trip", Date: ptr("2025-10-20"), EndDate: ptr("next week")},
+	}
+	for _, task := range rejects {
+		if _, err := validateParsedTask(task, "trip", now); !errors.Is(err, errResponseRejected) {
+			t.Errorf("expected rejection for %v..%v, got %v", *task.Date, *task.EndDate, err)
+		}
+	}
+}
+
+func TestParseLocallyTimesAndRanges(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, time.October, 15, 9, 0, 0, 0, time.UTC)
+
+	timed := parseLocally("call Sam at 3pm tomorrow", now)
+	if timed.Title != "call Sam" || timed.Date == nil || *timed.Date != "2025-10-16T15:00:00" {
+		t.Fatalf("unexpected timed task: %+v", timed)
+	}
+
+	zoned := parseLocally("standup 9:30 PST", now)
+	if zoned.TimeZone != "America/Los_Angeles" || zoned.Date == nil || *zoned.Date != "2025-10-15T09:30:00" {
+		t.Fatalf("unexpected zoned task: %+v", zoned)
+	}
+
+	ranged := parseLocally("conference Mon–Wed", now)
+	if ranged.Date == nil || ranged.EndDate == nil || *ranged.Date != "2025-10-20" || *ranged.EndDate != "2025-10-22" {
+		t.Fatalf("unexpected range: %+v", ranged)
+	}
+}
+
+func TestParseLocallyRecurrence(t *testing.T) {
+	t.Parallel()
+
+	// Wednesday.
+	now := time.Date(2025, time.October, 15, 9, 0, 0, 0, time.UTC)
+
+	got := parseLocally("water plants every other Tuesday", now)
+	if got.Title != "water plants" || got.Recurrence != "FREQ=WEEKLY;INTERVAL=2;BYDAY=TU" {
+		t.Fatalf("unexpected recurring task: %+v", got)
+	}
+	if got.Date == nil || *got.Date != "2025-10-21" {
+		t.Fatalf("expected the first occurrence, got %v", got.Date)
+	}
+}
+
+func TestValidateParsedTaskRecurrence(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, time.October, 15, 9, 0, 0, 0, time.UTC)
+
+	got, err := validateParsedTask(TaskInformation{Title: "Rent", Recurrence: "RRULE:FREQ=MONTHLY;BYMONTHDAY=1"}, "rent on the 1st of each month", now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Date == nil || *got.Date != "2025-11-01" || got.Recurrence != "FREQ=MONTHLY;BYMONTHDAY=1" {
+		t.Fatalf("unexpected recurring task: %+v", got)
+	}
+
+	if _, err := validateParsedTask(TaskInformation{Title: "Rent", Recurrence: "FREQ=HOURLY"}, "rent", now); !errors.Is(err, errResponseRejected) {
+		t.Fatalf("expected unsupported rule to be rejected, got %v", err)
+	}
+}
+
+func TestBuildNotionPagePayloadWithRecurrence(t *testing.T) {
+	t.Parallel()
+
+	detail := &NotionDataSourceDetail{Properties: map[string]PropertyObj{
+		"Name":   {Name: "Name", Type: "title"},
+		"Repeat": {Name: "Repeat", Type: "rich_text"},
+		"Rule":   {Name: "Rule", Type: "rich_text"},
+	}}
+	task := TaskInformation{Title: "Water plants", Date: ptr("2025-10-21"), Recurrence: "FREQ=WEEKLY;INTERVAL=2;BYDAY=TU"}
+
+	props := buildNotionPagePayload(task, "ds-456", "Name", "", detectTaskFieldProperties(detail, ""))["properties"].(map[string]any)
+	repeat := props["Repeat"].(map[string]any)["rich_text"].([]map[string]any)
+	if repeat[0]["text"].(map[string]any)["content"] != "RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=TU" {
+		t.Fatalf("unexpected rule: %v", repeat)
+	}
+
+	props = buildNotionPagePayload(task, "ds-456", "Name", "", detectTaskFieldProperties(detail, "Rule"))["properties"].(map[string]any)
+	if _, ok := props["Rule"]; !ok {
+		t.Fatalf("expected the configured property, got %v", props)
+	}
+	if _, ok := props["Repeat"]; ok {
+		t.Fatalf("only the configured property should be written, got %v", props)
+	}
+}
+
+func TestWithTimeZone(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, time.October, 15, 9, 0, 0, 0, time.FixedZone("", 2*60*60))
+
+	named := withTimeZone(TaskInformation{Title: "Call", Date: ptr("2025-10-16T15:00:00")}, "Europe/Berlin", now)
+	if named.TimeZone != "Europe/Berlin" {
+		t.Fatalf("expected configured zone, got %q", named.TimeZone)
+	}
+
+	offset := withTimeZone(TaskInformation{Title: "Call", Date: ptr("2025-10-16T15:00:00")}, "", now)
+	if offset.TimeZone != "" || *offset.Date != "2025-10-16T15:00:00+02:00" {
+		t.Fatalf("expected an offset date-time, got %+v", offset)
+	}
+
+	allDay := withTimeZone(TaskInformation{Title: "Call", Date: ptr("2025-10-16")}, "Europe/Berlin", now)
+	if allDay.TimeZone != "" {
+		t.Fatalf("all-day tasks need no zone, got %q", allDay.TimeZone)
+	}
+}
+
+func TestProcessedThroughAIFallsBackToLocalParser(t *testing.T) {
+	originalConfig := c.AppConfig
+	originalUser := c.GetCurrentUserId()
+	t.Cleanup(func() {
+		c.AppConfig = originalConfig
+		c.SetCurrentUserId(originalUser)
+	})
+
+	// No OpenAI key and no connected user: both AI paths are unavailable.
+	c.AppConfig = &settingsservice.ApplicationSettings{UseOpenAI: false}
+	c.SetCurrentUserId("")
+
+	ts := NewTaskService(nil, nil)
+	got := ts.ProcessedThroughAI("call mom tomorrow")
+
+	want := time.Now().AddDate(0, 0, 1).Format("2006-01-02")
+	if got.Title != "call mom" {
+		t.Fatalf("unexpected title: %q", got.Title)
+	}
+	if got.Date == nil || *got.Date != want {
+		t.Fatalf("expected date %s, got %v", want, got.Date)
+	}
+}
+
+func TestProcessedThroughAIKeepsNotes(t *testing.T) {
+	originalConfig := c.AppConfig
+	originalUser := c.GetCurrentUserId()
+	t.Cleanup(func() {
+		c.AppConfig = originalConfig
+		c.SetCurrentUserId(originalUser)
+	})
+	c.AppConfig = &settingsservice.ApplicationSettings{UseOpenAI: false}
+	c.SetCurrentUserId("")
+
+	ts := NewTaskService(nil, nil)
+	got := ts.ProcessedThroughAI("pack for Berlin tomorrow\n- [ ] passport\n- [ ] charger")
+	if got.Title != "pack for Berlin" || got.Date == nil || got.Notes != "- [ ] passport\n- [ ] charger" {
+		t.Fatalf("expected the checklist kept as notes, got %+v", got)
+	}
+
+	got = ts.ProcessedThroughAI("pack for Berlin - [ ] passport - [x] charger")
+	if got.Title != "pack for Berlin" || got.Notes != "- [ ] passport - [x] charger" {
+		t.Fatalf("expected the inline checklist kept as notes, got %+v", got)
+	}
+}
+
+func TestProcessedThroughAIUsesConfiguredProvider(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"choices":[{"index":0,"message":{"role":"assistant","content":"{\"title\":\"Pay rent\",\"date\":\"2030-01-04\"}"}}]}`)
+	}))
+	t.Cleanup(srv.Close)
+
+	originalConfig := c.AppConfig
+	t.Cleanup(func() { c.AppConfig = originalConfig })
+
+	settings := &settingsservice.SettingsService{
+		AppSettings: settingsservice.ApplicationSettings{
+			UseOpenAI:      true,
+			ParserProvider: llm.KindOpenAICompatible,
+			ParserProviders: map[string]settingsservice.ParserProviderSettings{
+				llm.KindOpenAICompatible: {BaseURL: srv.URL + "/v1", Model: "llama3.1"},
+			},
+		},
+	}
+	c.AppConfig = &settings.AppSettings
+
+	ts := NewTaskService(nil, settings)
+	got := ts.ProcessedThroughAI("pay rent friday")
+
+	if got.Title != "Pay rent" {
+		t.Fatalf("unexpected title: %q", got.Title)
+	}
+	if got.Date == nil || *got.Date != "2030-01-04" {
+		t.Fatalf("unexpected date: %v", got.Date)
+	}
+}
+
+func TestProcessedThroughAIKeepsTokensFromModel(t *testing.T) {
+	var prompt string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		prompt = string(body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"choices":[{"index":0,"message":{"role":"assistant","content":"{\"title\":\"Pay rent\",\"date\":\"2030-01-04\",\"priority\":\"low\",\"tags\":[\"bills\"]}"}}]}`)
+	}))
+	t.Cleanup(srv.Close)
+
+	originalConfig := c.AppConfig
+	t.Cleanup(func() { c.AppConfig = originalConfig })
+
+	settings := &settingsservice.SettingsService{
+		AppSettings: settingsservice.ApplicationSettings{
+			UseOpenAI:      true,
+			ParserProvider: llm.KindOpenAICompatible,
+			ParserProviders: map[string]settingsservice.ParserProviderSettings{
+				llm.KindOpenAICompatible: {BaseURL: srv.URL + "/v1", Model: "llama3.1"},
+			},
+			QuickSyntax: quicksyntax.Prefixes{Tag: "%"},
+		},
+	}
+	c.AppConfig = &settings.AppSettings
+
+	ts := NewTaskService(nil, settings)
+	got := ts.ProcessedThroughAI("pay rent %home !1 ^2030-01-03")
+
+	if strings.Contains(prompt, "%home") || strings.Contains(prompt, "^2030") {
+		t.Fatalf("tokens must not reach the model: %s", prompt)
+	}
+	if got.Priority != "urgent" || strings.Join(got.Tags, ",") != "home" {
+		t.Fatalf("tokens should win over the model: %+v", got)
+	}
+	if got.Date == nil || *got.Date != "2030-01-03" {
+		t.Fatalf("unexpected date: %v", got.Date)
+	}
+}
+
+func TestParseLocallyUsesLocale(t *testing.T) {
+	originalConfig := c.AppConfig
+	t.Cleanup(func() { c.AppConfig = originalConfig })
+	c.AppConfig = &settingsservice.ApplicationSettings{Language: "de", DateOrder: "DMY", WeekStart: "sunday"}
+
+	now := time.Date(2025, time.October, 15, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		input     string
+		wantTitle string
+		wantDate  string
+	}{
+		{"morgen Zahnarzt", "Zahnarzt", "2025-10-16"},
+		{"Miete zahlen 03/11", "Miete zahlen", "2025-11-03"},
+		{"Bericht nächste Woche", "Bericht", "2025-10-19"},
+	}
+	for _, tt := range tests {
+		got := parseLocally(tt.input, now)
+		if got.Title != tt.wantTitle || got.Date == nil || *got.Date != tt.wantDate {
+			t.Errorf("parseLocally(%q) = %q on %v, want %q on %s", tt.input, got.Title, got.Date, tt.wantTitle, tt.wantDate)
+		}
+	}
+}
+
+func TestBuildParsePromptLocale(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2025, time.October, 15, 9, 0, 0, 0, time.UTC)
+	locale := settingsservice.Locale{Language: "de-AT", DateOrder: "DMY", WeekStart: time.Sunday}
+	prompt := buildParsePrompt("morgen Zahnarzt", now, "Europe/Vienna", locale)
+
+	for _, want := range []string{
+		"written in German",
+		`"03/04" is 3 April`,
+		`Weeks start on Sunday: "next week" begins on 2025-10-19 and "this weekend" begins on 2025-10-18`,
+	} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt is missing %q", want)
+		}
+	}
+}
+
+func TestCallServerParseSendsLocale(t *testing.T) {
+	var payload map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		_, _ = io.WriteString(w, `{"title":"Zahnarzt","date":"2025-10-16"}`)
+	}))
+	t.Cleanup(srv.Close)
+	t.Setenv("TASKLIGHT_API_BASE", srv.URL)
+
+	originalUser := c.GetCurrentUserId()
+	t.Cleanup(func() { c.SetCurrentUserId(originalUser) })
+	c.SetCurrentUserId("user-1")
+
+	ts := NewTaskService(nil, nil)
+	locale := settingsservice.Locale{Language: "de", DateOrder: "DMY", WeekStart: time.Monday}
+	if _, err := ts.callServerParse(context.Background(), "morgen Zahnarzt", time.Now(), "Europe/Berlin", locale); err != nil {
+		t.Fatalf("callServerParse: %v", err)
+	}
+
+	got, _ := payload["locale"].(map[string]any)
+	if got["language"] != "de" || got["date_order"] != "DMY" || got["week_start"] != "monday" {
+		t.Fatalf("unexpected locale payload: %v", payload["locale"])
+	}
+}
+
+func TestSplitTasks(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"buy milk; call mom tomorrow; submit report friday", []string{"buy milk", "call mom tomorrow", "submit report friday"}},
+		{"buy milk\ncall mom\r\n\n", []string{"buy milk", "call mom"}},
+		{"pick up keys And Then drop off car", []string{"pick up keys", "drop off car"}},
+		{"write the handbook", []string{"write the handbook"}},
+		{" ; ", nil},
+		// Lists and code are notes of the task before them.
+		{"pack for Berlin\n- [ ] passport; visa\n- charger\n1. check in\ncall mom", []string{"pack for Berlin\n- [ ] passport; visa\n- charger\n1. check in", "call mom"}},
+		{"fix the build\n```go\nx := 1; y := 2\n\n  return\n```\nship it", []string{"fix the build\n```go\nx := 1; y := 2\n\n  return\n```", "ship it"}},
+		// A list with no task line before it is a list of tasks.
+		{"- buy milk\n- call mom", []string{"buy milk", "call mom"}},
+		{"- [ ] passport\n2. charger; adapter\nwater plants\n- twice", []string{"passport", "charger", "adapter", "water plants\n- twice"}},
+	}
+
+	for _, tt := range tests {
+		got := splitTasks(tt.input)
+		if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("splitTasks(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestProcessTasksReportsEachTask(t *testing.T) {
+	originalConfig := c.AppConfig
+	originalUser := c.GetCurrentUserId()
+	t.Cleanup(func() {
+		c.AppConfig = originalConfig
+		c.SetCurrentUserId(originalUser)
+	})
+	c.AppConfig = &settingsservice.ApplicationSettings{UseOpenAI: false}
+	c.SetCurrentUserId("")
+
+	var mu sync.Mutex
+	running, peak := 0, 0
+	ts := NewTaskService(nil, nil)
+	ts.createPage = func(_ context.Context, task TaskInformation) PageResult {
+		mu.Lock()
+		running++
+		peak = max(peak, running)
+		mu.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+
+		mu.Lock()
+		running--
+		mu.Unlock()
+
+		if task.Title == "call mom" {
+			return failedPage(&notionapi.APIError{Status: 400, Code: "validation_error"})
+		}
+		return PageResult{PageID: "page-" + task.Title}
+	}
+
+	inputs := []string{"buy milk", "call mom tomorrow", "submit report", "water plants", "book flights"}
+	results := ts.processTasks(context.Background(), inputs, "")
+
+	if len(results) != len(inputs) {
+		t.Fatalf("expected %d results, got %d", len(inputs), len(results))
+	}
+	for i, result := range results {
+		if result.Input != inputs[i] {
+			t.Errorf("result %d is for %q, want %q", i, result.Input, inputs[i])
+		}
+		if wantOK := i != 1; result.OK != wantOK {
+			t.Errorf("result %d: ok = %v, want %v (%+v)", i, result.OK, wantOK, result)
+		}
+	}
+	if results[1].Title != "call mom" || results[1].Error == "" || results[1].Category != ErrorValidation {
+		t.Fatalf("expected the failure to be reported, got %+v", results[1])
+	}
+	if peak > maxConcurrentPages {
+		t.Fatalf("expected at most %d pages at once, got %d", maxConcurrentPages, peak)
+	}
+}
+
 func ptr[T any](v T) *T {
 	return &v
 }
+
+// hangingServer accepts requests and answers none of them before the test
+// ends.
+func hangingServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-release:
+		}
+	}))
+	t.Cleanup(srv.Close)
+	t.Cleanup(func() { close(release) })
+	return srv
+}
+
+func TestStageTimeoutsFollowSettings(t *testing.T) {
+	originalConfig := c.AppConfig
+	t.Cleanup(func() { c.AppConfig = originalConfig })
+	c.AppConfig = &settingsservice.ApplicationSettings{ParseTimeoutSeconds: 3, NotionTimeoutSeconds: 7}
+
+	for name, stage := range map[string]func(context.Context) (context.Context, context.CancelFunc){
+		"parse":  parseStage,
+		"notion": notionStage,
+	} {
+		ctx, cancel := stage(context.Background())
+		deadline, ok := ctx.Deadline()
+		cancel()
+
+		want := 3 * time.Second
+		if name == "notion" {
+			want = 7 * time.Second
+		}
+		if left := time.Until(deadline); !ok || left > want || left < want-time.Second {
+			t.Errorf("%s stage: expected a %s deadline, got %s", name, want, left)
+		}
+	}
+}
+
+func TestParseTaskFallsBackWhenServerHangs(t *testing.T) {
+	srv := hangingServer(t)
+	t.Setenv("TASKLIGHT_API_BASE", srv.URL)
+
+	originalConfig := c.AppConfig
+	originalUser := c.GetCurrentUserId()
+	t.Cleanup(func() {
+		c.AppConfig = originalConfig
+		c.SetCurrentUserId(originalUser)
+	})
+	c.AppConfig = &settingsservice.ApplicationSettings{}
+	c.SetCurrentUserId("user-1")
+
+	ts := NewTaskService(nil, nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	started := time.Now()
+	got, fallback := ts.parseTask(ctx, "pay rent", time.Now(), "UTC")
+	if elapsed := time.Since(started); elapsed > 5*time.Second {
+		t.Fatalf("parse ignored the deadline and took %s", elapsed)
+	}
+	if got.Title != "pay rent" || !fallback {
+		t.Fatalf("expected the local parser to take over, got %+v (fallback %v)", got, fallback)
+	}
+}
+
+func TestCreateNotionPageHonorsDeadline(t *testing.T) {
+	srv := hangingServer(t)
+	originalBase := notionapi.BaseURL
+	t.Cleanup(func() { notionapi.BaseURL = originalBase })
+	notionapi.BaseURL = srv.URL
+
+	originalConfig := c.AppConfig
+	t.Cleanup(func() { c.AppConfig = originalConfig })
+	settings := &settingsservice.SettingsService{
+		AppSettings: settingsservice.ApplicationSettings{
+			NotionAccessToken:  "secret",
+			NotionDataSourceID: "ds-1",
+		},
+	}
+	c.AppConfig = &settings.AppSettings
+
+	ts := NewTaskService(nil, settings)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	started := time.Now()
+	result := ts.createNotionPage(ctx, TaskInformation{Title: "pay rent"})
+	if elapsed := time.Since(started); elapsed > 5*time.Second {
+		t.Fatalf("request ignored the deadline and took %s", elapsed)
+	}
+	if result.OK() || result.Category != ErrorNetwork {
+		t.Fatalf("expected a network failure, got %+v", result)
+	}
+}
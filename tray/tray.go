@@ -4,7 +4,7 @@ import (
 	"github.com/wailsapp/wails/v3/pkg/application"
 )
 
-func Setup(app *application.App, windowService WindowServiceInterface, trayIcon []byte) {
+func Setup(app *application.App, windowService WindowServiceInterface, undoLast func(), trayIcon []byte) {
 	tray := app.NewSystemTray()
 	menu := application.NewMenu()
 
@@ -12,6 +12,9 @@ func Setup(app *application.App, windowService WindowServiceInterface, trayIcon
 	menu.Add("Show").OnClick(func(_ *application.Context) {
 		windowService.Show("main")
 	})
+	menu.Add("Undo Last Task").OnClick(func(_ *application.Context) {
+		undoLast()
+	})
 	menu.Add("Settings").OnClick(func(_ *application.Context) {
 		windowService.Show("settings")
 	})
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	c "github.com/imjamesonzeller/tasklight-v3/config"
+	"github.com/imjamesonzeller/tasklight-v3/notionapi"
+)
+
+// maxUndoHistory bounds the created pages remembered for UndoLast.
+const maxUndoHistory = 20
+
+// createdPage is a page made by this session that UndoLast may trash.
+type createdPage struct {
+	PageID string
+	Title  string
+	At     time.Time
+	// ProjectPageID is the project page created for the task, trashed with
+	// it.
+	ProjectPageID string
+}
+
+// UndoResult reports what UndoLast removed.
+type UndoResult struct {
+	Title string `json:"title"`
+	// Cancelled is set when the task was still queued and was dropped
+	// without calling Notion.
+	Cancelled bool `json:"cancelled"`
+}
+
+// UndoLast removes the most recent capture if it is within the undo window:
+// a task still in the outbox is cancelled locally, abandoning any request in
+// flight, otherwise the last created page is moved to the Notion trash along
+// with a project page created for it. Called from frontend
+func (ts *TaskService) UndoLast() (UndoResult, error) {
+	ts.undoMu.Lock()
+	defer ts.undoMu.Unlock()
+
+	page, hasPage := ts.lastCreated()
+	window := c.AppConfig.UndoWindow()
+
+	if ts.outbox != nil {
+		entry, ok := ts.outbox.latest()
+		if ok && (!hasPage || entry.SubmittedAt.After(page.At)) {
+			if time.Since(entry.SubmittedAt) > window {
+				return UndoResult{}, fmt.Errorf("%q was queued more than %s ago and can no longer be undone", entry.title(), window)
+			}
+			if err := ts.outbox.Discard(entry.ID); err != nil {
+				return UndoResult{}, err
+			}
+			return ts.undone(UndoResult{Title: entry.title(), Cancelled: true}), nil
+		}
+	}
+
+	if !hasPage {
+		return UndoResult{}, errors.New("nothing to undo")
+	}
+	if time.Since(page.At) > window {
+		return UndoResult{}, fmt.Errorf("%q was added more than %s ago and can no longer be undone", page.Title, window)
+	}
+
+	if err := ts.trashPage(ts.ctx, page.PageID); err != nil {
+		return UndoResult{}, fmt.Errorf("failed to remove %q: %w", page.Title, err)
+	}
+	ts.created = ts.created[:len(ts.created)-1]
+
+	if page.ProjectPageID != "" {
+		if err := ts.trashPage(ts.ctx, page.ProjectPageID); err != nil {
+			log.Printf("UndoLast: the project page created for %q could not be removed: %v", page.Title, err)
+		} else {
+			ts.forgetProjectPage(page.ProjectPageID)
+		}
+	}
+	if ts.recurrence != nil {
+		// Only recurring tasks are registered; others report not found.
+		_ = ts.recurrence.StopRecurring(page.PageID)
+	}
+	return ts.undone(UndoResult{Title: page.Title}), nil
+}
+
+func (ts *TaskService) undone(result UndoResult) UndoResult {
+	log.Printf("UndoLast: removed %q (cancelled locally: %v)", result.Title, result.Cancelled)
+	if ts.app != nil {
+		ts.app.EmitEvent("Backend:TaskUndone", result)
+	}
+	return result
+}
+
+// recordCreated remembers a created page, and the project page created for
+// it if any, for UndoLast.
+func (ts *TaskService) recordCreated(pageID, title, projectPageID string) {
+	ts.undoMu.Lock()
+	defer ts.undoMu.Unlock()
+
+	ts.created = append(ts.created, createdPage{PageID: pageID, Title: title, At: time.Now(), ProjectPageID: projectPageID})
+	if len(ts.created) > maxUndoHistory {
+		ts.created = ts.created[len(ts.created)-maxUndoHistory:]
+	}
+}
+
+// forgetCreated drops pageID from the pages UndoLast may trash.
+func (ts *TaskService) forgetCreated(pageID string) {
+	ts.undoMu.Lock()
+	defer ts.undoMu.Unlock()
+
+	for i, p := range ts.created {
+		if p.PageID == pageID {
+			ts.created = append(ts.created[:i], ts.created[i+1:]...)
+			return
+		}
+	}
+}
+
+// lastCreated returns the most recent created page. The caller holds
+// ts.undoMu.
+func (ts *TaskService) lastCreated() (createdPage, bool) {
+	if len(ts.created) == 0 {
+		return createdPage{}, false
+	}
+	return ts.created[len(ts.created)-1], true
+}
+
+// trashNotionPage moves a page to the Notion trash.
+func (ts *TaskService) trashNotionPage(ctx context.Context, pageID string) error {
+	ctx, cancel := notionStage(ctx)
+	defer cancel()
+
+	token, err := ts.settings.GetNotionToken(true)
+	if err != nil {
+		return err
+	}
+	if token == "" {
+		return ErrNotionTokenMissing
+	}
+
+	req, err := notionapi.NewJSONRequest(ctx, http.MethodPatch, notionapi.URL("/pages/"+pageID), token, map[string]any{"in_trash": true})
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	return notionapi.ParseResponse(resp, nil, ErrNotionTokenMissing)
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUndoLastTrashesRecentPage(t *testing.T) {
+	now := time.Now()
+	o := newTestOutbox(t, &now, func(_ context.Context, task TaskInformation) PageResult {
+		return PageResult{PageID: "page-" + task.Title}
+	})
+	ts := o.tasks
+
+	var trashed []string
+	ts.trashPage = func(_ context.Context, pageID string) error {
+		trashed = append(trashed, pageID)
+		return nil
+	}
+
+	ts.commitTask(context.Background(), "buy milk", TaskInformation{Title: "buy milk"})
+	ts.commitTask(context.Background(), "call mom", TaskInformation{Title: "call mom"})
+
+	result, err := ts.UndoLast()
+	if err != nil || result.Title != "call mom" || result.Cancelled {
+		t.Fatalf("unexpected undo: %+v (err %v)", result, err)
+	}
+	if _, err := ts.UndoLast(); err != nil {
+		t.Fatalf("second undo: %v", err)
+	}
+	if strings.Join(trashed, ",") != "page-call mom,page-buy milk" {
+		t.Fatalf("expected the newest page first, trashed %v", trashed)
+	}
+	if _, err := ts.UndoLast(); err == nil {
+		t.Fatal("expected nothing left to undo")
+	}
+}
+
+func TestUndoLastRespectsWindow(t *testing.T) {
+	now := time.Now()
+	o := newTestOutbox(t, &now, nil)
+	ts := o.tasks
+	ts.trashPage = func(context.Context, string) error {
+		t.Fatal("pages outside the undo window must not be trashed")
+		return nil
+	}
+	ts.created = []createdPage{{PageID: "old", Title: "buy milk", At: time.Now().Add(-time.Hour)}}
+
+	if _, err := ts.UndoLast(); err == nil || !strings.Contains(err.Error(), "can no longer be undone") {
+		t.Fatalf("expected the window to have passed, got %v", err)
+	}
+}
+
+func TestUndoLastRespectsWindowForQueuedTask(t *testing.T) {
+	now := time.Now().Add(-time.Hour)
+	o := newTestOutbox(t, &now, nil)
+	ts := o.tasks
+	if _, err := o.enqueue([]string{"water plants"}, nil, ""); err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+
+	if _, err := ts.UndoLast(); err == nil || !strings.Contains(err.Error(), "can no longer be undone") {
+		t.Fatalf("expected the window to have passed, got %v", err)
+	}
+	if queue, _ := o.GetQueue(); len(queue) != 1 {
+		t.Fatalf("an old queued task must stay queued, got %+v", queue)
+	}
+}
+
+func TestUndoLastTrashesCreatedProject(t *testing.T) {
+	now := time.Now()
+	o := newTestOutbox(t, &now, func(_ context.Context, task TaskInformation) PageResult {
+		return PageResult{PageID: "page-1", CreatedProject: "project-1"}
+	})
+	ts := o.tasks
+	var trashed []string
+	ts.trashPage = func(_ context.Context, pageID string) error {
+		trashed = append(trashed, pageID)
+		return nil
+	}
+	ts.projects = map[string]projectList{"ds-projects": {pages: []ProjectPage{{PageID: "project-1", Title: "Kitchen"}}, fetched: now}}
+
+	ts.commitTask(context.Background(), "pick tiles for Kitchen", TaskInformation{Title: "pick tiles"})
+	if _, err := ts.UndoLast(); err != nil {
+		t.Fatalf("UndoLast: %v", err)
+	}
+	if strings.Join(trashed, ",") != "page-1,project-1" {
+		t.Fatalf("expected the task and its project trashed, got %v", trashed)
+	}
+	if pages := ts.projects["ds-projects"].pages; len(pages) != 0 {
+		t.Fatalf("a trashed project must not be linked again, got %+v", pages)
+	}
+}
+
+func TestUndoLastKeepsPageWhenTrashFails(t *testing.T) {
+	now := time.Now()
+	o := newTestOutbox(t, &now, nil)
+	ts := o.tasks
+	ts.trashPage = func(context.Context, string) error { return errors.New("notion api error: status 502, body: ") }
+	ts.recordCreated("page-1", "buy milk", "")
+
+	if _, err := ts.UndoLast(); err == nil {
+		t.Fatal("expected the failure to be reported")
+	}
+	if len(ts.created) != 1 {
+		t.Fatalf("a failed undo must be retryable, got %+v", ts.created)
+	}
+}
+
+func TestUndoLastCancelsQueuedTask(t *testing.T) {
+	now := time.Now()
+	o := newTestOutbox(t, &now, func(_ context.Context, task TaskInformation) PageResult {
+		if task.Title == "water plants" {
+			return PageResult{Category: ErrorNetwork, Error: "offline"}
+		}
+		return PageResult{PageID: "page-1"}
+	})
+	ts := o.tasks
+	ts.trashPage = func(context.Context, string) error {
+		t.Fatal("queued tasks must be cancelled without calling Notion")
+		return nil
+	}
+
+	ts.commitTask(context.Background(), "buy milk", TaskInformation{Title: "buy milk"})
+	now = now.Add(time.Second)
+	entries, _ := o.enqueue([]string{"water plants"}, nil, "")
+
+	result, err := ts.UndoLast()
+	if err != nil || !result.Cancelled || result.Title != "water plants" {
+		t.Fatalf("unexpected undo: %+v (err %v)", result, err)
+	}
+
+	// The submission was claimed for sending; delivering it now must not
+	// bring it back.
+	if results := o.deliver(entries); results[0].OK || results[0].Queued {
+		t.Fatalf("a cancelled task must not be sent, got %+v", results[0])
+	}
+	if queue, _ := o.GetQueue(); len(queue) != 0 {
+		t.Fatalf("expected the queue to be empty, got %+v", queue)
+	}
+}
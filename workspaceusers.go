@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/imjamesonzeller/tasklight-v3/notionapi"
+)
+
+// workspaceUsersTTL is how long the member list used for people properties
+// is trusted.
+const workspaceUsersTTL = 10 * time.Minute
+
+// workspaceUsers returns the people of the workspace keyed by lower-cased
+// full name, first name and email, for filling people properties from
+// @person tokens. Keys shared by several people are left out.
+func (ts *TaskService) workspaceUsers(ctx context.Context, token string) (map[string]string, error) {
+	ts.usersMu.Lock()
+	defer ts.usersMu.Unlock()
+	if ts.users != nil && time.Since(ts.usersFetched) < workspaceUsersTTL {
+		return ts.users, nil
+	}
+
+	type notionUser struct {
+		ID     string `json:"id"`
+		Type   string `json:"type"`
+		Name   string `json:"name"`
+		Person struct {
+			Email string `json:"email"`
+		} `json:"person"`
+	}
+
+	var members []notionUser
+	cursor := ""
+	for {
+		query := url.Values{"page_size": {"100"}}
+		if cursor != "" {
+			query.Set("start_cursor", cursor)
+		}
+		req, err := notionapi.NewRequest(ctx, http.MethodGet, notionapi.URL("/users?"+query.Encode()), token)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		var page struct {
+			Results    []notionUser `json:"results"`
+			HasMore    bool         `json:"has_more"`
+			NextCursor string       `json:"next_cursor"`
+		}
+		if err := notionapi.ParseResponse(resp, &page, ErrNotionTokenMissing); err != nil {
+			return nil, err
+		}
+		members = append(members, page.Results...)
+		if !page.HasMore || page.NextCursor == "" {
+			break
+		}
+		cursor = page.NextCursor
+	}
+
+	users := map[string]string{}
+	ambiguous := map[string]bool{}
+	add := func(key, id string) {
+		key = strings.ToLower(strings.TrimSpace(key))
+		if key == "" || ambiguous[key] {
+			return
+		}
+		if other, ok := users[key]; ok && other != id {
+			delete(users, key)
+			ambiguous[key] = true
+			return
+		}
+		users[key] = id
+	}
+	for _, m := range members {
+		if m.Type != "person" {
+			continue
+		}
+		add(m.Name, m.ID)
+		if first, _, ok := strings.Cut(m.Name, " "); ok {
+			add(first, m.ID)
+		}
+		add(m.Person.Email, m.ID)
+	}
+
+	ts.users, ts.usersFetched = users, time.Now()
+	return users, nil
+}